@@ -66,7 +66,12 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -75,13 +80,17 @@ import (
 	"hi-cfo/server/internal/logger"
 	"hi-cfo/server/internal/router"
 	"hi-cfo/server/internal/shared/auth"
+	"hi-cfo/server/internal/shared/trash"
 
 	"hi-cfo/server/internal/config"
 	"hi-cfo/server/internal/infrastructure/cache"
 	"hi-cfo/server/internal/infrastructure/database"
+	"hi-cfo/server/internal/infrastructure/storage"
 
 	"hi-cfo/server/internal/domains/account"
 	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/domains/fileupload"
+	"hi-cfo/server/internal/domains/merchant"
 	"hi-cfo/server/internal/domains/transaction"
 	"hi-cfo/server/internal/domains/user"
 
@@ -115,7 +124,11 @@ func main() {
 	router.SetDB(db)
 	router.SetRedisClient(redisClient)
 
-	deps := setupDependencies(db, redisClient, appLogger)
+	// Cancelled on SIGINT/SIGTERM, stopping the background jobs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	deps, importWorkersDone := setupDependencies(ctx, db, redisClient, appLogger)
 
 	r := router.SetupRoutes(deps)
 
@@ -125,12 +138,34 @@ func main() {
 	logger.Infof("Health check available at: http://localhost:%s/health", port)
 	logger.Infof("API documentation available at: http://localhost:%s/swagger/index.html", port)
 
-	if err := r.Run("0.0.0.0:" + port); err != nil {
-		logger.Fatal("Failed to start server:", err)
+	server := &http.Server{
+		Addr:    "0.0.0.0:" + port,
+		Handler: r,
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	logger.Info("Shutting down server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Server shutdown did not complete:", err)
+	}
+
+	// Running imports roll back and requeue themselves
+	select {
+	case <-importWorkersDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Import workers did not stop in time")
 	}
 }
 
-func setupDependencies(db *database.DB, redisClient *redis.Client, appLogger *logrus.Logger) *router.Dependencies {
+func setupDependencies(ctx context.Context, db *database.DB, redisClient *redis.Client, appLogger *logrus.Logger) (*router.Dependencies, <-chan struct{}) {
 	// Auth service with Redis for token blacklisting
 	authService := auth.NewService(redisClient)
 
@@ -146,18 +181,47 @@ func setupDependencies(db *database.DB, redisClient *redis.Client, appLogger *lo
 	categoryService := category.NewCategoryService(categoryRepo)
 	categoryHandler := category.NewCategoryHandler(categoryService)
 
+	merchantRepo := merchant.NewMerchantRepository(db)
+	merchantService := merchant.NewMerchantService(merchantRepo, categoryService)
+	if err := merchantService.SeedSystemMerchants(context.Background()); err != nil {
+		logger.Warn("Failed to seed system merchants:", err)
+	}
+	merchantHandler := merchant.NewMerchantHandler(merchantService)
+
+	fileUploadRepo := fileupload.NewFileUploadRepository(db)
+	fileUploadService := fileupload.NewFileUploadService(fileUploadRepo)
+	fileUploadHandler := fileupload.NewFileUploadHandler(fileUploadService)
+
+	// Background imports are queued in Redis
+	var importQueue *transaction.ImportJobQueue
+	if redisClient != nil {
+		importQueue = transaction.NewImportJobQueue(redisClient)
+	}
+
+	// Transaction attachments are kept on the local filesystem
+	attachmentStore, err := storage.NewLocalStorage(config.GetAttachmentStorageDir())
+	if err != nil {
+		logger.Fatal("Failed to initialize attachment storage:", err)
+	}
+
 	transactionRepo := transaction.NewTransactionRepository(db)
-	transactionService := transaction.NewTransactionService(transactionRepo, categoryService)
+	transactionService := transaction.NewTransactionService(transactionRepo, categoryService, accountService, fileUploadService, merchantService, attachmentStore, importQueue)
+	importWorkersDone := transactionService.StartImportWorkers(ctx, config.GetImportWorkers())
 	transactionHandler := transaction.NewTransactionHandler(transactionService)
 
+	// Transactions go first, accounts are only purged once none refer to them
+	trash.StartPurgeJob(ctx, config.GetTrashPurgeInterval(), transactionService, categoryService, accountService)
+
 	return &router.Dependencies{
 		UserHandler:        userHandler,
 		TransactionHandler: transactionHandler,
 		AccountHandler:     accountHandler,
 		CategoryHandler:    categoryHandler,
+		FileUploadHandler:  fileUploadHandler,
+		MerchantHandler:    merchantHandler,
 		AuthService:        authService,
 		DB:                 db,
 		RedisClient:        redisClient,
 		Logger:             appLogger,
-	}
+	}, importWorkersDone
 }
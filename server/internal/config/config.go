@@ -154,6 +154,243 @@ func GetMaxFileSize() int64 {
 	return size
 }
 
+// GetImportWorkers returns how many background import jobs run concurrently
+func GetImportWorkers() int {
+	workersStr := os.Getenv("IMPORT_WORKERS")
+	if workersStr == "" {
+		return 2
+	}
+
+	workers, err := strconv.Atoi(workersStr)
+	if err != nil || workers < 0 {
+		return 2
+	}
+
+	return workers
+}
+
+// GetBulkEditMaxRows returns how many transactions a single bulk edit can change
+func GetBulkEditMaxRows() int {
+	rowsStr := os.Getenv("BULK_EDIT_MAX_ROWS")
+	if rowsStr == "" {
+		return 5000
+	}
+
+	rows, err := strconv.Atoi(rowsStr)
+	if err != nil || rows < 1 {
+		return 5000
+	}
+
+	return rows
+}
+
+// Duplicate detection configuration
+
+// GetDuplicateDateWindowDays returns how many days apart two transactions can
+// be booked and still be flagged as possible duplicates
+func GetDuplicateDateWindowDays() int {
+	daysStr := os.Getenv("DUPLICATE_DATE_WINDOW_DAYS")
+	if daysStr == "" {
+		return 3
+	}
+
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days < 0 {
+		return 3
+	}
+
+	return days
+}
+
+// GetDuplicateAmountTolerance returns the largest absolute amount difference
+// between possible duplicates
+func GetDuplicateAmountTolerance() float64 {
+	toleranceStr := os.Getenv("DUPLICATE_AMOUNT_TOLERANCE")
+	if toleranceStr == "" {
+		return 0.01
+	}
+
+	tolerance, err := strconv.ParseFloat(toleranceStr, 64)
+	if err != nil || tolerance < 0 {
+		return 0.01
+	}
+
+	return tolerance
+}
+
+// GetDuplicateMinSimilarity returns the description similarity (0-1) a
+// possible duplicate has to reach
+func GetDuplicateMinSimilarity() float64 {
+	similarityStr := os.Getenv("DUPLICATE_MIN_SIMILARITY")
+	if similarityStr == "" {
+		return 0.6
+	}
+
+	similarity, err := strconv.ParseFloat(similarityStr, 64)
+	if err != nil || similarity < 0 || similarity > 1 {
+		return 0.6
+	}
+
+	return similarity
+}
+
+// Transfer detection configuration
+
+// GetTransferDateWindowDays returns how many days apart the two sides of a
+// transfer between the user's own accounts can be booked
+func GetTransferDateWindowDays() int {
+	daysStr := os.Getenv("TRANSFER_DATE_WINDOW_DAYS")
+	if daysStr == "" {
+		return 3
+	}
+
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days < 0 {
+		return 3
+	}
+
+	return days
+}
+
+// Pending transaction matching configuration
+
+// GetPendingDateWindowDays returns how many days after a pending authorisation
+// its posted transaction can be booked and still replace it
+func GetPendingDateWindowDays() int {
+	daysStr := os.Getenv("PENDING_DATE_WINDOW_DAYS")
+	if daysStr == "" {
+		return 7
+	}
+
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days < 0 {
+		return 7
+	}
+
+	return days
+}
+
+// GetPendingAmountTolerance returns how far a posted amount can differ from the
+// pending one, as a fraction of the pending amount. Tips and currency
+// conversion often change the amount between authorisation and posting.
+func GetPendingAmountTolerance() float64 {
+	toleranceStr := os.Getenv("PENDING_AMOUNT_TOLERANCE")
+	if toleranceStr == "" {
+		return 0.2
+	}
+
+	tolerance, err := strconv.ParseFloat(toleranceStr, 64)
+	if err != nil || tolerance < 0 {
+		return 0.2
+	}
+
+	return tolerance
+}
+
+// GetPendingMinSimilarity returns the description similarity (0-1) a posted
+// transaction has to reach to match a pending one with a different merchant
+func GetPendingMinSimilarity() float64 {
+	similarityStr := os.Getenv("PENDING_MIN_SIMILARITY")
+	if similarityStr == "" {
+		return 0.5
+	}
+
+	similarity, err := strconv.ParseFloat(similarityStr, 64)
+	if err != nil || similarity < 0 || similarity > 1 {
+		return 0.5
+	}
+
+	return similarity
+}
+
+// Recurring transaction detection configuration
+
+// GetRecurringLookbackDays returns how many days of history the recurring
+// transaction detector scans. Two years lets annual series repeat once.
+func GetRecurringLookbackDays() int {
+	daysStr := os.Getenv("RECURRING_LOOKBACK_DAYS")
+	if daysStr == "" {
+		return 730
+	}
+
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days <= 0 {
+		return 730
+	}
+
+	return days
+}
+
+// GetRecurringAmountTolerance returns how far amounts in one recurring series
+// can differ, as a fraction of the smallest amount
+func GetRecurringAmountTolerance() float64 {
+	toleranceStr := os.Getenv("RECURRING_AMOUNT_TOLERANCE")
+	if toleranceStr == "" {
+		return 0.1
+	}
+
+	tolerance, err := strconv.ParseFloat(toleranceStr, 64)
+	if err != nil || tolerance < 0 {
+		return 0.1
+	}
+
+	return tolerance
+}
+
+// GetRecurringMinConfidence returns the confidence (0-1) a detected series
+// needs to be proposed
+func GetRecurringMinConfidence() float64 {
+	confidenceStr := os.Getenv("RECURRING_MIN_CONFIDENCE")
+	if confidenceStr == "" {
+		return 0.6
+	}
+
+	confidence, err := strconv.ParseFloat(confidenceStr, 64)
+	if err != nil || confidence < 0 || confidence > 1 {
+		return 0.6
+	}
+
+	return confidence
+}
+
+// Trash configuration
+
+// GetTrashRetentionDays returns how many days soft-deleted records stay in the
+// trash before the purge job removes them for good. 0 keeps them forever.
+func GetTrashRetentionDays() int {
+	daysStr := os.Getenv("TRASH_RETENTION_DAYS")
+	if daysStr == "" {
+		return 30
+	}
+
+	days, err := strconv.Atoi(daysStr)
+	if err != nil || days < 0 {
+		return 30
+	}
+
+	return days
+}
+
+// GetTrashPurgeInterval returns how often the purge job looks for expired trash
+func GetTrashPurgeInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("TRASH_PURGE_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Hour
+	}
+
+	return interval
+}
+
+// Attachment configuration
+
+// GetAttachmentStorageDir returns the directory transaction attachments are stored in
+func GetAttachmentStorageDir() string {
+	if dir := os.Getenv("ATTACHMENT_STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return "./data/attachments"
+}
+
 // parseSize parses size strings like "10MB", "5GB"
 func parseSize(sizeStr string) (int64, error) {
 	// Simple implementation - you might want to use a library
@@ -20,7 +20,10 @@ type Account struct {
 	BankName            string         `json:"bank_name" gorm:"size:100;not null"`
 	RoutingNumber       *string        `json:"routing_number,omitempty" gorm:"size:20"`
 	IsActive            bool           `json:"is_active" gorm:"default:true"`
-	CurrentBalance      *float64       `json:"current_balance,omitempty" gorm:"type:decimal(12,2)"`
+	CurrentBalance      *float64       `json:"current_balance,omitempty" gorm:"type:decimal(12,2)"` // Computed from the opening balance and transactions once the account has one
+	OpeningBalance      *float64       `json:"opening_balance,omitempty" gorm:"type:decimal(12,2)"` // Balance at the start of OpeningBalanceDate, anchors the running balance
+	OpeningBalanceDate  *time.Time     `json:"opening_balance_date,omitempty" gorm:"type:date"`     // Transactions before it are counted backwards from the anchor
+	BalanceDrift        *float64       `json:"balance_drift,omitempty" gorm:"type:decimal(12,2)"`   // Last imported statement balance minus the computed one, unset when they agreed
 	Currency            string         `json:"currency" gorm:"size:3;default:'USD'"`
 	CreatedAt           time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt           time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
@@ -79,3 +82,54 @@ type AccountDetailResponse struct {
 func (a *Account) ToResponse() AccountDetailResponse {
 	return AccountDetailResponse{Account: *a}
 }
+
+// ==================================
+// TRASH
+// ==================================
+
+type TrashFilter struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// DeletedAccount is a soft-deleted account waiting in the trash
+type DeletedAccount struct {
+	Account
+	PurgeAt *time.Time `json:"purge_at,omitempty"` // When the retention job removes it, unset if trash is kept forever
+}
+
+type DeletedAccountResponse = PaginatedResponse[DeletedAccount]
+
+// ==================================
+// BALANCES
+// ==================================
+
+// SetOpeningBalanceRequest anchors the running balance of an account
+type SetOpeningBalanceRequest struct {
+	OpeningBalance     *float64 `json:"opening_balance" binding:"required"`
+	OpeningBalanceDate string   `json:"opening_balance_date" binding:"required"`
+}
+
+type BalanceHistoryFilter struct {
+	StartDate *time.Time `form:"-"` // Defaults to 90 days before EndDate
+	EndDate   *time.Time `form:"-"` // Defaults to today
+}
+
+// BalancePoint is the balance at the end of one day
+type BalancePoint struct {
+	Date             string  `json:"date"` // YYYY-MM-DD
+	Balance          float64 `json:"balance"`
+	Change           float64 `json:"change"`
+	TransactionCount int64   `json:"transaction_count"`
+}
+
+// BalanceHistory is a daily balance series for one account
+type BalanceHistory struct {
+	AccountID          uuid.UUID      `json:"account_id"`
+	Currency           string         `json:"currency"`
+	OpeningBalance     float64        `json:"opening_balance"`
+	OpeningBalanceDate time.Time      `json:"opening_balance_date"`
+	StartDate          string         `json:"start_date"`
+	EndDate            string         `json:"end_date"`
+	Points             []BalancePoint `json:"points"`
+}
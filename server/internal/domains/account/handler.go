@@ -302,3 +302,100 @@ func (h *AccountHandler) GetAccountSummary(c *gin.Context) {
 
 	h.RespondWithSuccess(c, http.StatusOK, summary)
 }
+
+// GetDeletedAccounts handles GET /accounts/trash
+func (h *AccountHandler) GetDeletedAccounts(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter TrashFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	accounts, err := h.service.GetDeletedAccounts(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving deleted accounts")
+		h.RespondWithInternalError(c, "Failed to retrieve deleted accounts")
+		return
+	}
+
+	shared.RespondWithPaginated(c, accounts.Data, accounts.Total, accounts.Page, accounts.Limit, accounts.Pages)
+}
+
+// RestoreAccount handles POST /accounts/trash/:id/restore
+func (h *AccountHandler) RestoreAccount(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	account, err := h.service.RestoreAccount(c.Request.Context(), userID, accountID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error restoring account")
+		h.RespondWithInternalError(c, "Failed to restore account")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, account, "Account restored successfully")
+}
+
+// PurgeAccount handles DELETE /accounts/trash/:id
+func (h *AccountHandler) PurgeAccount(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.PurgeAccount(c.Request.Context(), userID, accountID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error purging account")
+		h.RespondWithInternalError(c, "Failed to purge account")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Account permanently deleted")
+}
@@ -24,6 +24,13 @@ type Repository interface {
 	DeleteAccount(ctx context.Context, userID, accountID uuid.UUID) error
 	GetAccountSummary(ctx context.Context, userID uuid.UUID) (*AccountSummary, error)
 	CheckAccountExists(ctx context.Context, userID uuid.UUID, accountName string) (bool, error)
+
+	// trash
+	GetDeletedAccounts(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*PaginatedResponse[Account], error)
+	GetDeletedAccountByID(ctx context.Context, userID, accountID uuid.UUID) (*Account, error)
+	RestoreAccount(ctx context.Context, userID, accountID uuid.UUID) error
+	PurgeAccount(ctx context.Context, userID, accountID uuid.UUID) error
+	PurgeExpiredAccounts(ctx context.Context, before time.Time) (int64, error)
 }
 
 type AccountRepository struct {
@@ -174,21 +181,32 @@ func (r *AccountRepository) UpdateAccount(ctx context.Context, userID, accountID
 	return r.GetAccountByID(ctx, userID, accountID)
 }
 
+// DeleteAccount moves an account to the trash together with its transactions,
+// which get the same deleted_at so a restore can bring back exactly those
 func (r *AccountRepository) DeleteAccount(ctx context.Context, userID, accountID uuid.UUID) error {
-	result := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, accountID).Delete(&Account{})
-	if result.Error != nil {
-		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to delete account").
-			WithDomain("account").
-			WithDetails(map[string]any{
-				"user_id":    userID,
-				"account_id": accountID,
-			})
-		appErr.Log()
-		return appErr
-	}
-	if result.RowsAffected == 0 {
-		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found").
-			WithDomain("transaction").
+	deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Account{}).
+			Where("user_id = ? AND id = ?", userID, accountID).
+			UpdateColumn("deleted_at", deletedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Account not found")
+		}
+
+		// UpdateColumn leaves updated_at alone, like the other bookkeeping writes
+		return tx.Table("transactions").
+			Where("user_id = ? AND account_id = ? AND deleted_at IS NULL", userID, accountID).
+			UpdateColumn("deleted_at", deletedAt).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete account")
+		}
+		appErr = appErr.WithDomain("account").
 			WithDetails(map[string]any{
 				"user_id":    userID,
 				"account_id": accountID,
@@ -260,3 +278,186 @@ func (r *AccountRepository) CheckAccountExists(ctx context.Context, userID uuid.
 
 	return count > 0, err
 }
+
+// ========================================
+// TRASH
+// ========================================
+
+func (r *AccountRepository) GetDeletedAccounts(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*PaginatedResponse[Account], error) {
+	var accounts []Account
+	var total int64
+
+	query := r.db.WithContext(ctx).Unscoped().Model(&Account{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count deleted accounts").
+			WithDomain("account").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, appErr
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Offset(offset).
+		Limit(filter.Limit).
+		Order("deleted_at DESC, id").
+		Find(&accounts).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch deleted accounts").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"offset":  offset,
+				"limit":   filter.Limit,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return &PaginatedResponse[Account]{
+		Data:  accounts,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+func (r *AccountRepository) GetDeletedAccountByID(ctx context.Context, userID, accountID uuid.UUID) (*Account, error) {
+	var account Account
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, accountID).
+		First(&account).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Account not found in trash").
+				WithDomain("account").
+				WithDetails(map[string]any{
+					"user_id":    userID,
+					"account_id": accountID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get deleted account").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &account, nil
+}
+
+// RestoreAccount takes an account out of the trash along with the
+// transactions deleted with it. Transactions deleted on their own stay deleted.
+func (r *AccountRepository) RestoreAccount(ctx context.Context, userID, accountID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var account Account
+		if err := tx.Unscoped().
+			Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, accountID).
+			First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return customerrors.New(customerrors.ErrCodeNotFound, "Account not found in trash")
+			}
+			return err
+		}
+
+		if err := tx.Unscoped().Model(&Account{}).
+			Where("id = ?", accountID).
+			Updates(map[string]any{"deleted_at": nil, "updated_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		return tx.Table("transactions").
+			Where("user_id = ? AND account_id = ? AND deleted_at = ?", userID, accountID, account.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to restore account")
+		}
+		appErr = appErr.WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// PurgeAccount permanently deletes an account from the trash. Accounts still
+// referenced by transactions, deleted or not, are kept.
+func (r *AccountRepository) PurgeAccount(ctx context.Context, userID, accountID uuid.UUID) error {
+	var transactions int64
+	if err := r.db.WithContext(ctx).Table("transactions").
+		Where("account_id = ?", accountID).
+		Count(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to check account transactions").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if transactions > 0 {
+		return customerrors.New(customerrors.ErrCodeConflict, "Account still has transactions, purge them before the account").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"account_id":   accountID,
+				"transactions": transactions,
+			})
+	}
+
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, accountID).
+		Delete(&Account{})
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to purge account").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if result.RowsAffected == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Account not found in trash").
+			WithDomain("account").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// PurgeExpiredAccounts permanently deletes accounts of every user that were
+// deleted before the cutoff. Their transactions are purged first by the
+// transaction purger, which also takes the transactions of expired accounts.
+func (r *AccountRepository) PurgeExpiredAccounts(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM transactions WHERE transactions.account_id = accounts.id)").
+		Delete(&Account{})
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to purge expired accounts").
+			WithDomain("account").
+			WithDetail("before", before)
+		appErr.Log()
+		return 0, appErr
+	}
+	return result.RowsAffected, nil
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"hi-cfo/server/internal/logger"
+	"hi-cfo/server/internal/shared"
 	"hi-cfo/server/internal/shared/errors"
+	"hi-cfo/server/internal/shared/trash"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -20,6 +23,11 @@ type AccountStore interface {
 	GetAccountSummary(ctx context.Context, userID uuid.UUID) (*AccountSummary, error)
 	ValidateAccount(account *Account) error
 	ValidateAccountRequest(req *CreateAccountRequest) error
+	SetOpeningBalance(ctx context.Context, userID, accountID uuid.UUID, balance float64, date time.Time) error
+	SetBalanceDrift(ctx context.Context, userID, accountID uuid.UUID, drift *float64) error
+	GetDeletedAccounts(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*DeletedAccountResponse, error)
+	RestoreAccount(ctx context.Context, userID, accountID uuid.UUID) (*Account, error)
+	PurgeAccount(ctx context.Context, userID, accountID uuid.UUID) error
 }
 
 type CreateAccountRequest struct {
@@ -28,10 +36,15 @@ type CreateAccountRequest struct {
 	AccountType         string   `json:"account_type" binding:"required,oneof=checking savings credit_card investment loan other"`
 	BankName            string   `json:"bank_name" binding:"required,min=1,max=100"`
 	RoutingNumber       *string  `json:"routing_number,omitempty" binding:"omitempty,max=20"`
-	CurrentBalance      *float64 `json:"current_balance,omitempty"`
+	CurrentBalance      *float64 `json:"current_balance,omitempty"` // Opening balance as of today when opening_balance is not given
+	OpeningBalance      *float64 `json:"opening_balance,omitempty"`
+	OpeningBalanceDate  *string  `json:"opening_balance_date,omitempty"` // Defaults to today
 	Currency            *string  `json:"currency,omitempty" binding:"omitempty,len=3"`
 }
 
+// UpdateAccountRequest changes account details. Once an account has an
+// opening balance its current balance is computed, and the opening balance
+// changes through PUT /accounts/:id/opening-balance.
 type UpdateAccountRequest struct {
 	AccountName         *string  `json:"account_name,omitempty" binding:"omitempty,min=1,max=100"`
 	AccountNumberMasked *string  `json:"account_number_masked,omitempty" binding:"omitempty,max=20"`
@@ -157,6 +170,37 @@ func (s *AccountService) CreateAccount(ctx context.Context, userID uuid.UUID, re
 		account.Currency = *request.Currency
 	}
 
+	// Anchor the running balance. A new account has no transactions yet, so
+	// its current balance is the opening balance.
+	opening := request.OpeningBalance
+	if opening == nil {
+		opening = request.CurrentBalance
+	}
+	if opening != nil {
+		anchor := time.Now().UTC().Truncate(24 * time.Hour)
+		if request.OpeningBalanceDate != nil {
+			parsed, err := shared.ParseFlexibleDate(*request.OpeningBalanceDate)
+			if err != nil {
+				appErr := errors.Wrap(err, errors.ErrCodeValidation, "Invalid opening_balance_date format").
+					WithDomain("account").
+					WithUserID(userID).
+					WithDetail("opening_balance_date", *request.OpeningBalanceDate)
+				appErr.Log()
+				return nil, appErr
+			}
+			anchor = parsed.UTC().Truncate(24 * time.Hour)
+		}
+		account.OpeningBalance = opening
+		account.OpeningBalanceDate = &anchor
+		account.CurrentBalance = opening
+	} else if request.OpeningBalanceDate != nil {
+		appErr := errors.New(errors.ErrCodeValidation, "opening_balance_date needs an opening_balance").
+			WithDomain("account").
+			WithUserID(userID)
+		appErr.Log()
+		return nil, appErr
+	}
+
 	if err := s.ValidateAccount(account); err != nil {
 		appErr := errors.Wrap(err, errors.ErrCodeValidation, "Account validation failed").
 			WithDomain("account").
@@ -256,6 +300,14 @@ func (s *AccountService) UpdateAccount(ctx context.Context, userID, accountID uu
 		updates["is_active"] = *req.IsActive
 	}
 	if req.CurrentBalance != nil {
+		if existingAccount.OpeningBalance != nil {
+			appErr := errors.New(errors.ErrCodeValidation, "Current balance is computed from transactions, set the opening balance instead").
+				WithDomain("account").
+				WithUserID(userID).
+				WithDetail("account_id", accountID)
+			appErr.Log()
+			return nil, appErr
+		}
 		updates["current_balance"] = *req.CurrentBalance
 	}
 	if req.Currency != nil {
@@ -291,8 +343,7 @@ func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID uu
 		"account_id": accountID,
 	}).Debug("Deleting account")
 
-	// TODO: Check if account has transactions before allowing deletion
-	// For now, we'll allow deletion
+	// Its transactions go to the trash with it
 	err := s.repo.DeleteAccount(ctx, userID, accountID)
 	if err != nil {
 		appErr := errors.Wrap(err, errors.ErrCodeInternal, "Failed to delete account").
@@ -407,3 +458,134 @@ func (s *AccountService) ValidateAccountRequest(req *CreateAccountRequest) error
 
 	return nil
 }
+
+// ========================================
+// BALANCES
+// ========================================
+
+// SetOpeningBalance moves the anchor of the account's running balance. The
+// caller recomputes the balances, which needs the account's transactions.
+func (s *AccountService) SetOpeningBalance(ctx context.Context, userID, accountID uuid.UUID, balance float64, date time.Time) error {
+	anchor := date.UTC().Truncate(24 * time.Hour)
+	_, err := s.repo.UpdateAccount(ctx, userID, accountID, map[string]any{
+		"opening_balance":      balance,
+		"opening_balance_date": anchor,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":              userID,
+		"account_id":           accountID,
+		"opening_balance_date": anchor.Format("2006-01-02"),
+	}).Info("Opening balance set successfully")
+
+	return nil
+}
+
+// SetBalanceDrift records how far the last imported statement was off from
+// the computed balance, nil when it agreed
+func (s *AccountService) SetBalanceDrift(ctx context.Context, userID, accountID uuid.UUID, drift *float64) error {
+	var value any
+	if drift != nil {
+		value = *drift
+	}
+	_, err := s.repo.UpdateAccount(ctx, userID, accountID, map[string]any{"balance_drift": value})
+	return err
+}
+
+// ========================================
+// TRASH
+// ========================================
+
+// GetDeletedAccounts lists the user's soft-deleted accounts, most recently deleted first
+func (s *AccountService) GetDeletedAccounts(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*DeletedAccountResponse, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+
+	result, err := s.repo.GetDeletedAccounts(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]DeletedAccount, 0, len(result.Data))
+	for _, account := range result.Data {
+		deleted = append(deleted, DeletedAccount{
+			Account: account,
+			PurgeAt: trash.PurgeAt(account.DeletedAt.Time),
+		})
+	}
+
+	return &DeletedAccountResponse{
+		Data:  deleted,
+		Total: result.Total,
+		Page:  result.Page,
+		Limit: result.Limit,
+		Pages: result.Pages,
+	}, nil
+}
+
+// RestoreAccount moves an account out of the trash. It fails when another
+// account has taken its name in the meantime.
+func (s *AccountService) RestoreAccount(ctx context.Context, userID, accountID uuid.UUID) (*Account, error) {
+	deleted, err := s.repo.GetDeletedAccountByID(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.repo.CheckAccountExists(ctx, userID, deleted.AccountName)
+	if err != nil {
+		appErr := errors.Wrap(err, errors.ErrCodeInternal, "Failed to check account existence").
+			WithDomain("account").
+			WithUserID(userID).
+			WithDetail("account_name", deleted.AccountName)
+		appErr.Log()
+		return nil, appErr
+	}
+	if exists {
+		appErr := errors.New(errors.ErrCodeConflict, fmt.Sprintf("Account with name '%s' already exists", deleted.AccountName)).
+			WithDomain("account").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"account_id":   accountID,
+				"account_name": deleted.AccountName,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	if err := s.repo.RestoreAccount(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": accountID,
+	}).Info("Account restored successfully")
+
+	return s.repo.GetAccountByID(ctx, userID, accountID)
+}
+
+// PurgeAccount permanently deletes an account that is in the trash
+func (s *AccountService) PurgeAccount(ctx context.Context, userID, accountID uuid.UUID) error {
+	if err := s.repo.PurgeAccount(ctx, userID, accountID); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": accountID,
+	}).Info("Account purged successfully")
+
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes accounts deleted before the cutoff, for all users
+func (s *AccountService) PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.PurgeExpiredAccounts(ctx, before)
+}
@@ -78,6 +78,11 @@ type CategoryFilter struct {
 	IsActive         *bool   `form:"is_active"`
 }
 
+type TrashFilter struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
 // ========================================
 // Response DTOs
 // ========================================
@@ -92,6 +97,14 @@ type PaginatedResponse[T any] struct {
 
 type CategoryResponse = PaginatedResponse[Category]
 
+// DeletedCategory is a soft-deleted category waiting in the trash
+type DeletedCategory struct {
+	Category
+	PurgeAt *time.Time `json:"purge_at,omitempty"` // When the retention job removes it, unset if trash is kept forever
+}
+
+type DeletedCategoryResponse = PaginatedResponse[DeletedCategory]
+
 type MethodPerformance struct {
 	UsageCount        int     `json:"usage_count"`
 	TotalConfidence   float64 `json:"total_confidence"`
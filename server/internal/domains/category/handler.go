@@ -318,6 +318,113 @@ func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 	h.RespondWithSuccess(c, http.StatusOK, nil, "Category deleted successfully")
 }
 
+// GetDeletedCategories handles GET /categories/trash
+func (h *CategoryHandler) GetDeletedCategories(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter TrashFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	categories, err := h.service.GetDeletedCategories(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving deleted categories")
+		h.RespondWithInternalError(c, "Failed to retrieve deleted categories")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, categories)
+}
+
+// RestoreCategory handles POST /categories/trash/:id/restore
+func (h *CategoryHandler) RestoreCategory(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	categoryID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	category, err := h.service.RestoreCategory(c.Request.Context(), userID, categoryID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"category_id": categoryID,
+			"error":       err.Error(),
+		}).Error("Unexpected error restoring category")
+		h.RespondWithInternalError(c, "Failed to restore category")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"category_id": categoryID,
+	}).Info("Category restored successfully")
+
+	h.RespondWithSuccess(c, http.StatusOK, category, "Category restored successfully")
+}
+
+// PurgeCategory handles DELETE /categories/trash/:id
+func (h *CategoryHandler) PurgeCategory(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	categoryID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.PurgeCategory(c.Request.Context(), userID, categoryID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"category_id": categoryID,
+			"error":       err.Error(),
+		}).Error("Unexpected error purging category")
+		h.RespondWithInternalError(c, "Failed to purge category")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"category_id": categoryID,
+	}).Info("Category purged successfully")
+
+	h.RespondWithSuccess(c, http.StatusOK, nil, "Category permanently deleted")
+}
+
 // AutoCategorize handles POST /categories/auto-categorize
 func (h *CategoryHandler) AutoCategorize(c *gin.Context) {
 	// Extract user ID
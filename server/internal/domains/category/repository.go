@@ -26,6 +26,13 @@ type Repository interface {
 	MatchCategoryByMerchant(ctx context.Context, userID uuid.UUID, merchantName string) (*CategoryMatchResult, error)
 	GetMatchingStats(ctx context.Context, userID uuid.UUID, merchantName string) (*MatchingStats, error)
 	UpdateConfidenceThreshold(ctx context.Context, userID uuid.UUID, newThreshold float64) error
+
+	// trash
+	GetDeletedCategories(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*CategoryResponse, error)
+	GetDeletedCategoryByID(ctx context.Context, userID, categoryID uuid.UUID) (*Category, error)
+	RestoreCategory(ctx context.Context, userID, categoryID uuid.UUID) error
+	PurgeCategory(ctx context.Context, userID, categoryID uuid.UUID) error
+	PurgeExpiredCategories(ctx context.Context, before time.Time) (int64, error)
 }
 
 type CategoryRepository struct {
@@ -263,3 +270,175 @@ func (r *CategoryRepository) UpdateConfidenceThreshold(ctx context.Context, user
 
 	return nil
 }
+
+// ========================================
+// TRASH
+// ========================================
+
+func (r *CategoryRepository) GetDeletedCategories(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*CategoryResponse, error) {
+	var categories []Category
+	var total int64
+
+	query := r.db.WithContext(ctx).Unscoped().Model(&Category{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count deleted categories").
+			WithDomain("category").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, appErr
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Offset(offset).
+		Limit(filter.Limit).
+		Order("deleted_at DESC, id").
+		Find(&categories).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch deleted categories").
+			WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"offset":  offset,
+				"limit":   filter.Limit,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return &CategoryResponse{
+		Data:  categories,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+func (r *CategoryRepository) GetDeletedCategoryByID(ctx context.Context, userID, categoryID uuid.UUID) (*Category, error) {
+	var category Category
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, categoryID).
+		First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Category not found in trash").
+				WithDomain("category").
+				WithDetails(map[string]any{
+					"user_id":     userID,
+					"category_id": categoryID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get deleted category").
+			WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"category_id": categoryID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &category, nil
+}
+
+func (r *CategoryRepository) RestoreCategory(ctx context.Context, userID, categoryID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&Category{}).
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, categoryID).
+		Updates(map[string]any{"deleted_at": nil, "updated_at": time.Now()})
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to restore category").
+			WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"category_id": categoryID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if result.RowsAffected == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Category not found in trash").
+			WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"category_id": categoryID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// PurgeCategory permanently deletes a category from the trash. Transactions,
+// split lines and subcategories that used it are left uncategorized.
+func (r *CategoryRepository) PurgeCategory(ctx context.Context, userID, categoryID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		purged, err := purgeCategories(tx, tx.Unscoped().Model(&Category{}).
+			Select("id").
+			Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, categoryID))
+		if err != nil {
+			return err
+		}
+		if purged == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Category not found in trash")
+		}
+		return nil
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to purge category")
+		}
+		appErr = appErr.WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"category_id": categoryID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// PurgeExpiredCategories permanently deletes categories of every user that
+// were deleted before the cutoff
+func (r *CategoryRepository) PurgeExpiredCategories(ctx context.Context, before time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		purged, err = purgeCategories(tx, tx.Unscoped().Model(&Category{}).
+			Select("id").
+			Where("deleted_at < ?", before))
+		return err
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to purge expired categories").
+			WithDomain("category").
+			WithDetail("before", before)
+		appErr.Log()
+		return 0, appErr
+	}
+	return purged, nil
+}
+
+// purgeCategories hard-deletes the categories whose ids the subquery selects,
+// clearing every reference to them first
+func purgeCategories(tx *gorm.DB, ids *gorm.DB) (int64, error) {
+	references := []struct{ table, column string }{
+		{"transactions", "category_id"},
+		{"transaction_splits", "category_id"},
+		{"categories", "parent_category_id"},
+	}
+	for _, ref := range references {
+		if err := tx.Table(ref.table).
+			Where(ref.column+" IN (?)", ids).
+			UpdateColumn(ref.column, nil).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Unscoped().Where("id IN (?)", ids).Delete(&Category{})
+	return result.RowsAffected, result.Error
+}
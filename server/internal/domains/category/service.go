@@ -2,10 +2,12 @@ package category
 
 import (
 	"context"
+	"time"
 
 	"hi-cfo/server/internal/logger"
 
 	customerrors "hi-cfo/server/internal/shared/errors"
+	"hi-cfo/server/internal/shared/trash"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -25,6 +27,9 @@ type CategoryStore interface {
 	DeleteCategory(ctx context.Context, userID, categoryID uuid.UUID) error
 	ValidateCategory(category *Category) error
 	ValidateCategoryRequest(req *CreateCategoryRequest) error
+	GetDeletedCategories(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*DeletedCategoryResponse, error)
+	RestoreCategory(ctx context.Context, userID, categoryID uuid.UUID) (*Category, error)
+	PurgeCategory(ctx context.Context, userID, categoryID uuid.UUID) error
 }
 
 type CategoryService struct {
@@ -219,6 +224,94 @@ func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID
 	return s.repo.DeleteCategory(ctx, userID, categoryID)
 }
 
+// ============== TRASH ==============//
+
+// GetDeletedCategories lists the user's soft-deleted categories, most recently deleted first
+func (s *CategoryService) GetDeletedCategories(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*DeletedCategoryResponse, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+
+	result, err := s.repo.GetDeletedCategories(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]DeletedCategory, 0, len(result.Data))
+	for _, category := range result.Data {
+		deleted = append(deleted, DeletedCategory{
+			Category: category,
+			PurgeAt:  trash.PurgeAt(category.DeletedAt.Time),
+		})
+	}
+
+	return &DeletedCategoryResponse{
+		Data:  deleted,
+		Total: result.Total,
+		Page:  result.Page,
+		Limit: result.Limit,
+		Pages: result.Pages,
+	}, nil
+}
+
+// RestoreCategory moves a category out of the trash. Its parent has to be
+// restored first, and its name must not have been reused in the meantime.
+func (s *CategoryService) RestoreCategory(ctx context.Context, userID, categoryID uuid.UUID) (*Category, error) {
+	deleted, err := s.repo.GetDeletedCategoryByID(ctx, userID, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if deleted.ParentCategoryID != nil {
+		if _, err := s.repo.GetCategoryByID(ctx, userID, *deleted.ParentCategoryID); err != nil {
+			appErr := customerrors.New(customerrors.ErrCodeValidation, "Restore the parent category first").
+				WithDomain("category").
+				WithDetails(map[string]any{
+					"user_id":            userID,
+					"category_id":        categoryID,
+					"parent_category_id": *deleted.ParentCategoryID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+	}
+
+	exists, err := s.repo.CheckCategoryExists(ctx, userID, deleted.Name)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		appErr := customerrors.New(customerrors.ErrCodeConflict, "Category with this name already exists").
+			WithDomain("category").
+			WithDetails(map[string]any{
+				"user_id":       userID,
+				"category_id":   categoryID,
+				"category_name": deleted.Name,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	if err := s.repo.RestoreCategory(ctx, userID, categoryID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetCategoryByID(ctx, userID, categoryID)
+}
+
+// PurgeCategory permanently deletes a category that is in the trash
+func (s *CategoryService) PurgeCategory(ctx context.Context, userID, categoryID uuid.UUID) error {
+	return s.repo.PurgeCategory(ctx, userID, categoryID)
+}
+
+// PurgeExpiredTrash permanently deletes categories deleted before the cutoff, for all users
+func (s *CategoryService) PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.PurgeExpiredCategories(ctx, before)
+}
+
 // ============== VALIDATION ==============//
 
 func (s *CategoryService) ValidateCategory(category *Category) error {
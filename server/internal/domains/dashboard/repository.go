@@ -2,6 +2,8 @@ package dashboard
 
 import (
 	"context"
+	"fmt"
+	"math"
 
 	"hi-cfo/server/internal/domains/transaction"
 	"time"
@@ -93,16 +95,49 @@ func (r *DashboardRepository) GetMonthlyData(ctx context.Context, userID uuid.UU
 	return monthlyData, nil
 }
 
+// GetCategoryBreakdown sums expenses per category between startDate and endDate.
+// Split transactions count once per split line under the line's category, and
+// linked transfers between the user's own accounts are left out.
 func (r *DashboardRepository) GetCategoryBreakdown(ctx context.Context, userID uuid.UUID, startDate, endDate time.Time) ([]CategoryData, error) {
-	var categoryData []CategoryData
-	// This is a placeholder - implement your actual category breakdown
+	var rows []struct {
+		Category string
+		Amount   float64
+	}
 
-	// Mock data for now
-	categoryData = []CategoryData{
-		{Category: "Marketing", Amount: 8000, Percentage: 35.5},
-		{Category: "Operations", Amount: 6000, Percentage: 26.7},
-		{Category: "Technology", Amount: 4500, Percentage: 20.0},
-		{Category: "Other", Amount: 4000, Percentage: 17.8},
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT COALESCE(c.name, 'Uncategorized') AS category,
+			-SUM(COALESCE(s.amount, t.amount)) AS amount
+		FROM transactions t
+		LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+		LEFT JOIN categories c ON c.id = COALESCE(s.category_id, t.category_id)
+		WHERE t.user_id = ? AND
+			t.transaction_date BETWEEN ? AND ? AND
+			t.amount < 0 AND
+			`+transaction.NotLinkedTransfer("t")+` AND
+			t.deleted_at IS NULL
+		GROUP BY 1
+		ORDER BY 2 DESC
+	`, userID, startDate, endDate).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate category breakdown: %w", err)
+	}
+
+	var total float64
+	for _, row := range rows {
+		total += row.Amount
+	}
+
+	categoryData := make([]CategoryData, 0, len(rows))
+	for _, row := range rows {
+		percentage := 0.0
+		if total != 0 {
+			percentage = math.Round(row.Amount/total*1000) / 10
+		}
+		categoryData = append(categoryData, CategoryData{
+			Category:   row.Category,
+			Amount:     row.Amount,
+			Percentage: percentage,
+		})
 	}
 
 	return categoryData, nil
@@ -0,0 +1,98 @@
+package fileupload
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ==================================
+// FILE UPLOAD MODEL
+// ==================================
+
+// Processing statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusDuplicate  = "duplicate"
+	StatusReverted   = "reverted"
+	StatusCancelled  = "cancelled"
+)
+
+// FileUpload - One imported statement file and the outcome of its import
+type FileUpload struct {
+	ID                     uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID                 uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index;index:idx_file_uploads_user_hash,priority:1;uniqueIndex:idx_file_uploads_imported_hash,priority:1,where:processing_status IN ('pending'\\,'processing') OR (processing_status = 'completed' AND transactions_imported > 0)"`
+	AccountID              *uuid.UUID `json:"account_id,omitempty" gorm:"type:uuid;index"`
+	OriginalFilename       string     `json:"original_filename" gorm:"size:255;not null"`
+	FileType               string     `json:"file_type" gorm:"size:20;not null;check:file_type IN ('pdf','csv','ofx','qif','xlsx','camt053','mt940','json')"`
+	FileSizeBytes          int64      `json:"file_size_bytes" gorm:"not null"`
+	FileHash               string     `json:"file_hash" gorm:"size:64;index:idx_file_uploads_user_hash,priority:2;uniqueIndex:idx_file_uploads_imported_hash,priority:2"` // SHA-256, hex encoded, one queued, running or imported upload per file
+	StoragePath            *string    `json:"-" gorm:"size:500"`
+	ProcessingStatus       string     `json:"processing_status" gorm:"size:20;default:'pending';index;check:processing_status IN ('pending','processing','completed','failed','duplicate','reverted','cancelled')"`
+	Queued                 bool       `json:"queued" gorm:"not null;default:false"` // Run by a background worker rather than within the request
+	ProcessingStartedAt    *time.Time `json:"processing_started_at,omitempty"`
+	ProcessingCompletedAt  *time.Time `json:"processing_completed_at,omitempty"`
+	ErrorMessage           *string    `json:"error_message,omitempty" gorm:"type:text"`
+	TransactionsImported   int        `json:"transactions_imported" gorm:"default:0"`
+	TransactionsDuplicates int        `json:"transactions_duplicates" gorm:"default:0"`
+	DateRangeStart         *time.Time `json:"date_range_start,omitempty" gorm:"type:date"`
+	DateRangeEnd           *time.Time `json:"date_range_end,omitempty" gorm:"type:date"`
+	RevertedAt             *time.Time `json:"reverted_at,omitempty"` // Matches deleted_at on the transactions the revert removed
+	CreatedAt              time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (FileUpload) TableName() string {
+	return "file_uploads"
+}
+
+// BeforeCreate GORM hook
+func (f *FileUpload) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// ==================================
+// REQUEST / RESPONSE DTOs
+// ==================================
+
+// NewUpload - A file about to be imported
+type NewUpload struct {
+	AccountID uuid.UUID // uuid.Nil for batches that span accounts
+	Filename  string
+	FileType  string
+	Size      int64
+	Hash      string
+	Queued    bool // Recorded as pending until a worker picks it up
+}
+
+// ImportOutcome - Counts and date range taken from the import batch result
+type ImportOutcome struct {
+	Imported       int
+	Duplicates     int
+	DateRangeStart *time.Time
+	DateRangeEnd   *time.Time
+}
+
+type FileUploadFilter struct {
+	Page      int        `form:"page" binding:"omitempty,min=1"`
+	Limit     int        `form:"limit" binding:"omitempty,min=1,max=100"`
+	AccountID *uuid.UUID `form:"account_id"`
+	FileType  *string    `form:"file_type"`
+	Status    *string    `form:"status" binding:"omitempty,oneof=pending processing completed failed duplicate reverted cancelled"`
+}
+
+type PaginatedResponse[T any] struct {
+	Data  []T   `json:"data"`
+	Total int64 `json:"total"`
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Pages int   `json:"pages"`
+}
+
+type FileUploadResponse = PaginatedResponse[FileUpload]
@@ -0,0 +1,90 @@
+package fileupload
+
+import (
+	"net/http"
+
+	"hi-cfo/server/internal/logger"
+	"hi-cfo/server/internal/shared"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type FileUploadHandler struct {
+	shared.BaseHandler
+	service *FileUploadService
+	logger  *logrus.Entry
+}
+
+func NewFileUploadHandler(service *FileUploadService) *FileUploadHandler {
+	return &FileUploadHandler{
+		service: service,
+		logger:  logger.WithDomain("fileupload"),
+	}
+}
+
+// GET /file-uploads
+func (h *FileUploadHandler) GetFileUploads(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter FileUploadFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	uploads, err := h.service.GetFileUploads(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving file uploads")
+		h.RespondWithInternalError(c, "Failed to retrieve file uploads")
+		return
+	}
+
+	shared.RespondWithPaginated(c, uploads.Data, uploads.Total, uploads.Page, uploads.Limit, uploads.Pages)
+}
+
+// GET /file-uploads/:id
+func (h *FileUploadHandler) GetFileUploadByID(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	uploadID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	upload, err := h.service.GetFileUploadByID(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":   userID,
+			"upload_id": uploadID,
+			"error":     err.Error(),
+		}).Error("Unexpected error getting file upload")
+		h.RespondWithInternalError(c, "Failed to get file upload")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, upload)
+}
@@ -0,0 +1,211 @@
+package fileupload
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"hi-cfo/server/internal/logger"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	GetFileUploads(ctx context.Context, userID uuid.UUID, filter FileUploadFilter) (*FileUploadResponse, error)
+	GetFileUploadByID(ctx context.Context, userID, uploadID uuid.UUID) (*FileUpload, error)
+	FindImportedByHash(ctx context.Context, userID uuid.UUID, hash string) (*FileUpload, error)
+	CreateFileUpload(ctx context.Context, upload *FileUpload) error
+	UpdateFileUpload(ctx context.Context, uploadID uuid.UUID, updates map[string]any) error
+	TransitionFileUpload(ctx context.Context, uploadID uuid.UUID, from []string, updates map[string]any) (bool, error)
+	FindUnfinishedUploads(ctx context.Context, before time.Time, limit int) ([]FileUpload, error)
+}
+
+type FileUploadRepository struct {
+	db     *gorm.DB
+	logger *logrus.Entry
+}
+
+func NewFileUploadRepository(db *gorm.DB) *FileUploadRepository {
+	return &FileUploadRepository{
+		db:     db,
+		logger: logger.WithDomain("fileupload"),
+	}
+}
+
+func (r *FileUploadRepository) GetFileUploads(ctx context.Context, userID uuid.UUID, filter FileUploadFilter) (*FileUploadResponse, error) {
+	var uploads []FileUpload
+	var total int64
+
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+
+	if filter.AccountID != nil {
+		query = query.Where("account_id = ?", *filter.AccountID)
+	}
+	if filter.FileType != nil {
+		query = query.Where("file_type = ?", *filter.FileType)
+	}
+	if filter.Status != nil {
+		query = query.Where("processing_status = ?", *filter.Status)
+	}
+
+	if err := query.Model(&FileUpload{}).Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count file uploads").
+			WithDomain("fileupload").
+			WithDetail("operation", "count_file_uploads")
+		appErr.Log()
+		return nil, appErr
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Offset(offset).
+		Limit(filter.Limit).
+		Order("created_at DESC").
+		Find(&uploads).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch file uploads").
+			WithDomain("fileupload").
+			WithDetails(map[string]any{
+				"operation": "fetch_file_uploads",
+				"user_id":   userID,
+				"offset":    offset,
+				"limit":     filter.Limit,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	pages := int(math.Ceil(float64(total) / float64(filter.Limit)))
+
+	return &FileUploadResponse{
+		Data:  uploads,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: pages,
+	}, nil
+}
+
+func (r *FileUploadRepository) GetFileUploadByID(ctx context.Context, userID, uploadID uuid.UUID) (*FileUpload, error) {
+	var upload FileUpload
+	err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, uploadID).First(&upload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customerrors.New(customerrors.ErrCodeNotFound, "File upload not found").
+				WithDomain("fileupload").
+				WithDetails(map[string]any{
+					"user_id":   userID,
+					"upload_id": uploadID,
+				})
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get file upload").
+			WithDomain("fileupload").
+			WithDetails(map[string]any{
+				"user_id":   userID,
+				"upload_id": uploadID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &upload, nil
+}
+
+// FindImportedByHash returns the most recent queued or in-flight upload of the
+// same file content, or completed one that created transactions, or nil when
+// there is none. Empty and reverted imports do not count.
+func (r *FileUploadRepository) FindImportedByHash(ctx context.Context, userID uuid.UUID, hash string) (*FileUpload, error) {
+	var upload FileUpload
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND file_hash = ? AND (processing_status IN ? OR (processing_status = ? AND transactions_imported > 0))",
+			userID, hash, []string{StatusPending, StatusProcessing}, StatusCompleted).
+		Order("created_at DESC").
+		First(&upload).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to check file hash").
+			WithDomain("fileupload").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, appErr
+	}
+	return &upload, nil
+}
+
+func (r *FileUploadRepository) CreateFileUpload(ctx context.Context, upload *FileUpload) error {
+	if err := r.db.WithContext(ctx).Create(upload).Error; err != nil {
+		// idx_file_uploads_imported_hash allows one queued, running or imported upload per file
+		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
+			appErr := customerrors.New(customerrors.ErrCodeConflict, "This file has already been imported").
+				WithDomain("fileupload").
+				WithUserID(upload.UserID).
+				WithDetail("filename", upload.OriginalFilename)
+			appErr.Log()
+			return appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create file upload").
+			WithDomain("fileupload").
+			WithDetails(map[string]any{
+				"user_id":  upload.UserID,
+				"filename": upload.OriginalFilename,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+func (r *FileUploadRepository) UpdateFileUpload(ctx context.Context, uploadID uuid.UUID, updates map[string]any) error {
+	if err := r.db.WithContext(ctx).Model(&FileUpload{}).Where("id = ?", uploadID).Updates(updates).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to update file upload").
+			WithDomain("fileupload").
+			WithDetail("upload_id", uploadID)
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// TransitionFileUpload applies updates only while the upload is in one of the
+// from statuses. It reports false when another writer moved it on first.
+func (r *FileUploadRepository) TransitionFileUpload(ctx context.Context, uploadID uuid.UUID, from []string, updates map[string]any) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&FileUpload{}).
+		Where("id = ? AND processing_status IN ?", uploadID, from).
+		Updates(updates)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to update file upload status").
+			WithDomain("fileupload").
+			WithDetails(map[string]any{
+				"upload_id": uploadID,
+				"from":      from,
+			})
+		appErr.Log()
+		return false, appErr
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// FindUnfinishedUploads returns background uploads of every user that are
+// still queued or running and were created before the cutoff, oldest first.
+// Synchronous imports have no job to lose and are left alone.
+func (r *FileUploadRepository) FindUnfinishedUploads(ctx context.Context, before time.Time, limit int) ([]FileUpload, error) {
+	var uploads []FileUpload
+	err := r.db.WithContext(ctx).
+		Where("queued AND processing_status IN ? AND created_at < ?", []string{StatusPending, StatusProcessing}, before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&uploads).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to find unfinished file uploads").
+			WithDomain("fileupload").
+			WithDetail("before", before)
+		appErr.Log()
+		return nil, appErr
+	}
+	return uploads, nil
+}
@@ -0,0 +1,244 @@
+package fileupload
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hi-cfo/server/internal/logger"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type FileUploadStore interface {
+	GetFileUploads(ctx context.Context, userID uuid.UUID, filter FileUploadFilter) (*FileUploadResponse, error)
+	GetFileUploadByID(ctx context.Context, userID, uploadID uuid.UUID) (*FileUpload, error)
+
+	// Import lifecycle, driven by the transaction importers
+	BeginUpload(ctx context.Context, userID uuid.UUID, upload NewUpload) (*FileUpload, error)
+	StartUpload(ctx context.Context, uploadID uuid.UUID) (bool, error)
+	CancelUpload(ctx context.Context, uploadID uuid.UUID, from, reason string) (bool, error)
+	CompleteUpload(ctx context.Context, uploadID uuid.UUID, outcome ImportOutcome) error
+	FailUpload(ctx context.Context, uploadID uuid.UUID, reason string) error
+	RequeueUpload(ctx context.Context, uploadID uuid.UUID) (bool, error)
+	FailUnfinishedUpload(ctx context.Context, uploadID uuid.UUID, reason string) (bool, error)
+	GetUnfinishedUploads(ctx context.Context, before time.Time, limit int) ([]FileUpload, error)
+	RevertUpload(ctx context.Context, uploadID uuid.UUID, revertedAt time.Time) error
+	RestoreUpload(ctx context.Context, userID uuid.UUID, upload *FileUpload) error
+}
+
+type FileUploadService struct {
+	repo   Repository
+	logger *logrus.Entry
+}
+
+func NewFileUploadService(repo Repository) *FileUploadService {
+	return &FileUploadService{
+		repo:   repo,
+		logger: logger.WithDomain("fileupload"),
+	}
+}
+
+// ========================================
+// QUERIES
+// ========================================
+
+func (s *FileUploadService) GetFileUploads(ctx context.Context, userID uuid.UUID, filter FileUploadFilter) (*FileUploadResponse, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+	return s.repo.GetFileUploads(ctx, userID, filter)
+}
+
+func (s *FileUploadService) GetFileUploadByID(ctx context.Context, userID, uploadID uuid.UUID) (*FileUpload, error) {
+	return s.repo.GetFileUploadByID(ctx, userID, uploadID)
+}
+
+// ========================================
+// IMPORT LIFECYCLE
+// ========================================
+
+// BeginUpload records a file as processing. A file whose hash matches an
+// earlier successful (or still running) import is recorded as a duplicate
+// and rejected with a conflict error.
+func (s *FileUploadService) BeginUpload(ctx context.Context, userID uuid.UUID, upload NewUpload) (*FileUpload, error) {
+	existing, err := s.repo.FindImportedByHash(ctx, userID, upload.Hash)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	record := &FileUpload{
+		UserID:              userID,
+		OriginalFilename:    upload.Filename,
+		FileType:            upload.FileType,
+		FileSizeBytes:       upload.Size,
+		FileHash:            upload.Hash,
+		ProcessingStatus:    StatusProcessing,
+		ProcessingStartedAt: &now,
+	}
+	if upload.AccountID != uuid.Nil {
+		record.AccountID = &upload.AccountID
+	}
+	if upload.Queued {
+		record.ProcessingStatus = StatusPending
+		record.ProcessingStartedAt = nil
+		record.Queued = true
+	}
+
+	if existing == nil {
+		err := s.repo.CreateFileUpload(ctx, record)
+		if err == nil {
+			return record, nil
+		}
+		// Another upload of the same file got in between the check and the insert
+		if !customerrors.Is(err, customerrors.ErrCodeConflict) {
+			return nil, err
+		}
+		if existing, err = s.repo.FindImportedByHash(ctx, userID, upload.Hash); err != nil {
+			return nil, err
+		}
+		if existing == nil {
+			return nil, customerrors.New(customerrors.ErrCodeConflict, "This file is already being imported").
+				WithDomain("fileupload").
+				WithUserID(userID).
+				WithDetail("file_hash", upload.Hash)
+		}
+	}
+
+	message := fmt.Sprintf("file was already imported on %s as '%s'", existing.CreatedAt.Format("2006-01-02"), existing.OriginalFilename)
+	record.ProcessingStatus = StatusDuplicate
+	record.ProcessingCompletedAt = &now
+	record.ErrorMessage = &message
+	if err := s.repo.CreateFileUpload(ctx, record); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":         userID,
+		"file_hash":       upload.Hash,
+		"existing_upload": existing.ID,
+		"rejected_upload": record.ID,
+		"original_file":   existing.OriginalFilename,
+		"rejected_file":   upload.Filename,
+	}).Info("Rejected duplicate file upload")
+
+	return nil, customerrors.New(customerrors.ErrCodeConflict, "This file has already been imported").
+		WithDomain("fileupload").
+		WithUserID(userID).
+		WithDetails(map[string]any{
+			"file_upload_id":    existing.ID,
+			"original_filename": existing.OriginalFilename,
+			"imported_at":       existing.CreatedAt,
+			"processing_status": existing.ProcessingStatus,
+		})
+}
+
+// StartUpload moves a queued upload to processing. It returns false when the
+// upload is no longer pending, e.g. because it was cancelled while queued.
+func (s *FileUploadService) StartUpload(ctx context.Context, uploadID uuid.UUID) (bool, error) {
+	updates := map[string]any{
+		"processing_status":     StatusProcessing,
+		"processing_started_at": time.Now(),
+	}
+	return s.repo.TransitionFileUpload(ctx, uploadID, []string{StatusPending}, updates)
+}
+
+// CancelUpload marks an upload cancelled if it is still in the from status
+func (s *FileUploadService) CancelUpload(ctx context.Context, uploadID uuid.UUID, from, reason string) (bool, error) {
+	updates := map[string]any{
+		"processing_status":       StatusCancelled,
+		"processing_completed_at": time.Now(),
+		"error_message":           reason,
+	}
+	return s.repo.TransitionFileUpload(ctx, uploadID, []string{from}, updates)
+}
+
+func (s *FileUploadService) CompleteUpload(ctx context.Context, uploadID uuid.UUID, outcome ImportOutcome) error {
+	updates := map[string]any{
+		"processing_status":       StatusCompleted,
+		"processing_completed_at": time.Now(),
+		"transactions_imported":   outcome.Imported,
+		"transactions_duplicates": outcome.Duplicates,
+		"date_range_start":        outcome.DateRangeStart,
+		"date_range_end":          outcome.DateRangeEnd,
+	}
+	return s.repo.UpdateFileUpload(ctx, uploadID, updates)
+}
+
+func (s *FileUploadService) FailUpload(ctx context.Context, uploadID uuid.UUID, reason string) error {
+	updates := map[string]any{
+		"processing_status":       StatusFailed,
+		"processing_completed_at": time.Now(),
+		"error_message":           reason,
+	}
+	return s.repo.UpdateFileUpload(ctx, uploadID, updates)
+}
+
+// RequeueUpload puts an interrupted upload back to pending so a worker runs it
+// again. It returns false when the upload already finished.
+func (s *FileUploadService) RequeueUpload(ctx context.Context, uploadID uuid.UUID) (bool, error) {
+	updates := map[string]any{
+		"processing_status":     StatusPending,
+		"processing_started_at": nil,
+	}
+	return s.repo.TransitionFileUpload(ctx, uploadID, []string{StatusPending, StatusProcessing}, updates)
+}
+
+// FailUnfinishedUpload marks a queued or running upload failed, e.g. when its
+// job was lost. It returns false when the upload already finished.
+func (s *FileUploadService) FailUnfinishedUpload(ctx context.Context, uploadID uuid.UUID, reason string) (bool, error) {
+	updates := map[string]any{
+		"processing_status":       StatusFailed,
+		"processing_completed_at": time.Now(),
+		"error_message":           reason,
+	}
+	return s.repo.TransitionFileUpload(ctx, uploadID, []string{StatusPending, StatusProcessing}, updates)
+}
+
+// GetUnfinishedUploads lists background uploads of every user still queued or
+// running that were created before the cutoff
+func (s *FileUploadService) GetUnfinishedUploads(ctx context.Context, before time.Time, limit int) ([]FileUpload, error) {
+	return s.repo.FindUnfinishedUploads(ctx, before, limit)
+}
+
+// RevertUpload marks an import as rolled back. revertedAt must be the exact
+// deleted_at written to the upload's transactions.
+func (s *FileUploadService) RevertUpload(ctx context.Context, uploadID uuid.UUID, revertedAt time.Time) error {
+	updates := map[string]any{
+		"processing_status": StatusReverted,
+		"reverted_at":       revertedAt,
+	}
+	return s.repo.UpdateFileUpload(ctx, uploadID, updates)
+}
+
+// RestoreUpload puts a reverted import back to completed. It is rejected when
+// the same file has been imported again since the revert.
+func (s *FileUploadService) RestoreUpload(ctx context.Context, userID uuid.UUID, upload *FileUpload) error {
+	existing, err := s.repo.FindImportedByHash(ctx, userID, upload.FileHash)
+	if err != nil {
+		return err
+	}
+	if existing != nil && existing.ID != upload.ID {
+		return customerrors.New(customerrors.ErrCodeConflict, "This file has been imported again since it was reverted").
+			WithDomain("fileupload").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"file_upload_id":    upload.ID,
+				"conflicting_id":    existing.ID,
+				"original_filename": existing.OriginalFilename,
+				"imported_at":       existing.CreatedAt,
+			})
+	}
+
+	updates := map[string]any{
+		"processing_status": StatusCompleted,
+		"reverted_at":       nil,
+	}
+	return s.repo.UpdateFileUpload(ctx, upload.ID, updates)
+}
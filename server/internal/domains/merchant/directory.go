@@ -0,0 +1,152 @@
+package merchant
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ========================================
+// MERCHANT DIRECTORY
+// ========================================
+
+// SystemAliases maps the canonical names of the system merchants to the
+// spellings found in bank descriptors. Spellings are in alias key form.
+var SystemAliases = map[string][]string{
+	"Amazon":               {"AMAZON", "AMZN", "AMZ", "AMZNMKTPLACE", "AMAZON MKTPLACE", "AMZN MKTP"},
+	"Amazon Prime":         {"AMAZON PRIME", "AMZN PRIME", "PRIME VIDEO"},
+	"Apple":                {"APPLE", "ITUNES", "APL ITUNES"},
+	"Google":               {"GOOGLE"},
+	"YouTube":              {"YOUTUBE", "GOOGLE YOUTUBE"},
+	"Microsoft":            {"MICROSOFT", "MSFT"},
+	"Netflix":              {"NETFLIX"},
+	"Spotify":              {"SPOTIFY"},
+	"Disney+":              {"DISNEY PLUS", "DISNEYPLUS"},
+	"Uber":                 {"UBER", "UBER TRIP", "UBER BV"},
+	"Uber Eats":            {"UBER EATS", "UBEREATS"},
+	"Deliveroo":            {"DELIVEROO"},
+	"Just Eat":             {"JUST EAT", "JUSTEAT"},
+	"PayPal":               {"PAYPAL"},
+	"Airbnb":               {"AIRBNB"},
+	"Booking.com":          {"BOOKING"},
+	"Zipcar":               {"ZIPCAR"},
+	"Trainline":            {"TRAINLINE"},
+	"Transport for London": {"TFL", "TFL TRAVEL", "TRANSPORT FOR LONDON"},
+	"Tesco":                {"TESCO"},
+	"Sainsbury's":          {"SAINSBURYS", "SAINSBURY", "JS ONLINE"},
+	"Asda":                 {"ASDA"},
+	"Morrisons":            {"MORRISONS", "WM MORRISON"},
+	"Waitrose":             {"WAITROSE"},
+	"Aldi":                 {"ALDI"},
+	"Lidl":                 {"LIDL"},
+	"Co-op":                {"COOP", "CO OP"},
+	"Marks & Spencer":      {"M S", "MARKS SPENCER", "MARKS AND SPENCER"},
+	"Boots":                {"BOOTS"},
+	"Pret A Manger":        {"PRET", "PRET A MANGER"},
+	"Starbucks":            {"STARBUCKS"},
+	"Costa Coffee":         {"COSTA", "COSTA COFFEE"},
+	"McDonald's":           {"MCDONALDS", "MC DONALDS"},
+	"Burger King":          {"BURGER KING"},
+	"KFC":                  {"KFC"},
+	"Greggs":               {"GREGGS"},
+	"Subway":               {"SUBWAY"},
+	"Shell":                {"SHELL"},
+	"BP":                   {"BP"},
+	"Esso":                 {"ESSO"},
+	"Walmart":              {"WALMART", "WAL MART", "WM SUPERCENTER"},
+	"Costco":               {"COSTCO"},
+	"Target":               {"TARGET"},
+}
+
+// SystemMerchants returns the system merchants seeded on start
+func SystemMerchants() []Merchant {
+	merchants := make([]Merchant, 0, len(SystemAliases))
+	for name, aliases := range SystemAliases {
+		merchants = append(merchants, Merchant{
+			Name:             name,
+			Aliases:          aliases,
+			IsSystemMerchant: true,
+		})
+	}
+	return merchants
+}
+
+// aliasKeyReplacer turns display punctuation into the form aliases are keyed by
+var aliasKeyReplacer = strings.NewReplacer("'", "", "&", " ", "-", " ")
+
+// AliasKey returns the form names and aliases are compared in: upper case,
+// apostrophes removed, "&" and "-" read as spaces
+func AliasKey(name string) string {
+	return strings.Join(strings.Fields(aliasKeyReplacer.Replace(strings.ToUpper(name))), " ")
+}
+
+type directoryPattern struct {
+	pattern  *regexp.Regexp
+	merchant *Merchant
+}
+
+// Directory matches transactions to the merchants visible to one user. User
+// merchants win over system merchants with the same alias or pattern.
+type Directory struct {
+	aliases  map[string]*Merchant
+	patterns []directoryPattern
+}
+
+func NewDirectory(merchants []Merchant) *Directory {
+	d := &Directory{aliases: make(map[string]*Merchant)}
+
+	// System merchants first so user merchants overwrite their aliases
+	for _, system := range []bool{true, false} {
+		for i := range merchants {
+			m := &merchants[i]
+			if (m.UserID == nil) != system {
+				continue
+			}
+			d.aliases[AliasKey(m.Name)] = m
+			for _, alias := range m.Aliases {
+				if key := AliasKey(alias); key != "" {
+					d.aliases[key] = m
+				}
+			}
+		}
+	}
+
+	// User patterns are tried first
+	for _, system := range []bool{false, true} {
+		for i := range merchants {
+			m := &merchants[i]
+			if (m.UserID == nil) != system {
+				continue
+			}
+			for _, pattern := range m.Patterns {
+				if re, err := compilePattern(pattern); err == nil {
+					d.patterns = append(d.patterns, directoryPattern{pattern: re, merchant: m})
+				}
+			}
+		}
+	}
+
+	return d
+}
+
+// Match finds the merchant for a transaction, trying patterns on the raw
+// description first and then the longest alias the normalized name starts with
+func (d *Directory) Match(name, description string) *Merchant {
+	for _, p := range d.patterns {
+		if p.pattern.MatchString(description) || (name != "" && p.pattern.MatchString(name)) {
+			return p.merchant
+		}
+	}
+
+	words := strings.Fields(AliasKey(name))
+	for n := len(words); n > 0; n-- {
+		if m, ok := d.aliases[strings.Join(words[:n], " ")]; ok {
+			return m
+		}
+	}
+	return nil
+}
+
+// compilePattern compiles a merchant pattern, always case-insensitive
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
@@ -0,0 +1,136 @@
+package merchant
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// ========================================
+// Core Domain Model (Database Entity)
+// ========================================
+
+type Merchant struct {
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID            *uuid.UUID     `json:"user_id,omitempty" gorm:"type:uuid;uniqueIndex:idx_merchants_user_name"` // NULL for system merchants
+	Name              string         `json:"name" gorm:"size:200;not null;uniqueIndex:idx_merchants_user_name"`
+	Aliases           pq.StringArray `json:"aliases,omitempty" gorm:"type:text[]"`  // Spellings matched against the leading words of a cleaned descriptor
+	Patterns          pq.StringArray `json:"patterns,omitempty" gorm:"type:text[]"` // Case-insensitive regular expressions matched against the raw description
+	DefaultCategoryID *uuid.UUID     `json:"default_category_id,omitempty" gorm:"type:uuid"`
+	Website           *string        `json:"website,omitempty" gorm:"size:255"`
+	LogoURL           *string        `json:"logo_url,omitempty" gorm:"size:500"`
+	OverridesID       *uuid.UUID     `json:"overrides_id,omitempty" gorm:"type:uuid;index"`   // System merchant this user record replaces
+	MergedIntoID      *uuid.UUID     `json:"merged_into_id,omitempty" gorm:"type:uuid;index"` // Set on an override whose system merchant was merged away, it then only hides it
+	IsSystemMerchant  bool           `json:"is_system_merchant" gorm:"default:false"`
+	CreatedAt         time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt         time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Merchant) TableName() string {
+	return "merchants"
+}
+
+// BeforeCreate GORM hook
+func (m *Merchant) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// ========================================
+// Request DTOs (Data Transfer Objects)
+// ========================================
+
+type CreateMerchantRequest struct {
+	Name              string     `json:"name" binding:"required,min=1,max=200"`
+	Aliases           []string   `json:"aliases,omitempty"`
+	Patterns          []string   `json:"patterns,omitempty"`
+	DefaultCategoryID *uuid.UUID `json:"default_category_id,omitempty"`
+	Website           *string    `json:"website,omitempty" binding:"omitempty,max=255"`
+	LogoURL           *string    `json:"logo_url,omitempty" binding:"omitempty,max=500"`
+}
+
+// UpdateMerchantRequest changes a merchant's matching rules and details.
+// Names change through a rename so linked transactions follow.
+type UpdateMerchantRequest struct {
+	Aliases              []string   `json:"aliases,omitempty"`
+	Patterns             []string   `json:"patterns,omitempty"`
+	DefaultCategoryID    *uuid.UUID `json:"default_category_id,omitempty"`
+	ClearDefaultCategory bool       `json:"clear_default_category,omitempty"`
+	Website              *string    `json:"website,omitempty" binding:"omitempty,max=255"`
+	LogoURL              *string    `json:"logo_url,omitempty" binding:"omitempty,max=500"`
+}
+
+type RenameMerchantRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=200"`
+}
+
+// MergeMerchantsRequest folds the source merchants into the target: their
+// transactions move over, their names and rules become the target's aliases
+type MergeMerchantsRequest struct {
+	SourceIDs []uuid.UUID `json:"source_ids" binding:"required,min=1,max=100"`
+	TargetID  uuid.UUID   `json:"target_id" binding:"required"`
+}
+
+// ========================================
+// Query/Filter DTOs
+// ========================================
+
+type MerchantFilter struct {
+	Page             int     `form:"page" binding:"omitempty,min=1"`
+	Limit            int     `form:"limit" binding:"omitempty,min=1,max=100"`
+	Search           *string `form:"search"`
+	IsSystemMerchant *bool   `form:"is_system_merchant"`
+}
+
+// MerchantQuery is one transaction to find a merchant for
+type MerchantQuery struct {
+	Name        string // Normalized merchant name
+	Description string // Raw bank description
+}
+
+type SpendFilter struct {
+	StartDate *time.Time `form:"-"`
+	EndDate   *time.Time `form:"-"`
+	Limit     int        `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// ========================================
+// Response DTOs
+// ========================================
+
+type PaginatedResponse[T any] struct {
+	Data  []T   `json:"data"`
+	Total int64 `json:"total"`
+	Page  int   `json:"page"`
+	Limit int   `json:"limit"`
+	Pages int   `json:"pages"`
+}
+
+type MerchantResponse = PaginatedResponse[Merchant]
+
+type RenameMerchantResult struct {
+	Merchant            *Merchant `json:"merchant"`
+	TransactionsUpdated int64     `json:"transactions_updated"`
+}
+
+type MergeMerchantsResult struct {
+	Merchant            *Merchant   `json:"merchant"`
+	MergedIDs           []uuid.UUID `json:"merged_ids"`
+	TransactionsUpdated int64       `json:"transactions_updated"`
+}
+
+// MerchantSpend - Money spent at one merchant over a period
+type MerchantSpend struct {
+	MerchantID          uuid.UUID `json:"merchant_id"`
+	MerchantName        string    `json:"merchant_name"`
+	LogoURL             *string   `json:"logo_url,omitempty"`
+	TotalSpent          float64   `json:"total_spent"`    // Expenses and fees
+	TotalRefunded       float64   `json:"total_refunded"` // Refunds
+	NetSpent            float64   `json:"net_spent"`
+	TransactionCount    int64     `json:"transaction_count"`
+	LastTransactionDate time.Time `json:"last_transaction_date"`
+}
@@ -0,0 +1,171 @@
+package merchant
+
+import (
+	"net/http"
+
+	"hi-cfo/server/internal/logger"
+	"hi-cfo/server/internal/shared"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MerchantHandler struct {
+	shared.BaseHandler
+	service *MerchantService
+	logger  *logrus.Entry
+}
+
+func NewMerchantHandler(service *MerchantService) *MerchantHandler {
+	return &MerchantHandler{
+		service: service,
+		logger:  logger.WithDomain("merchant"),
+	}
+}
+
+// GetMerchants handles GET /merchants
+func (h *MerchantHandler) GetMerchants(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter MerchantFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"filter":  filter,
+	}).Debug("Getting merchants for user")
+
+	merchants, err := h.service.GetMerchants(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving merchants")
+		h.RespondWithInternalError(c, "Failed to retrieve merchants")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, merchants)
+}
+
+// CreateMerchant handles POST /merchants
+func (h *MerchantHandler) CreateMerchant(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var req CreateMerchantRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	merchant, err := h.service.CreateMerchant(c.Request.Context(), userID, &req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":       userID,
+			"merchant_name": req.Name,
+			"error":         err.Error(),
+		}).Error("Unexpected error creating merchant")
+		h.RespondWithInternalError(c, "Failed to create merchant")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"merchant_id":   merchant.ID,
+		"merchant_name": merchant.Name,
+	}).Info("Merchant created successfully")
+
+	h.RespondWithSuccess(c, http.StatusCreated, merchant, "Merchant created successfully")
+}
+
+// GetMerchantByID handles GET /merchants/:id
+func (h *MerchantHandler) GetMerchantByID(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	merchantID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	merchant, err := h.service.GetMerchantByID(c.Request.Context(), userID, merchantID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"merchant_id": merchantID,
+			"error":       err.Error(),
+		}).Error("Unexpected error getting merchant")
+		h.RespondWithNotFound(c, "Merchant")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, merchant)
+}
+
+// DeleteMerchant handles DELETE /merchants/:id
+func (h *MerchantHandler) DeleteMerchant(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	merchantID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMerchant(c.Request.Context(), userID, merchantID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"merchant_id": merchantID,
+			"error":       err.Error(),
+		}).Error("Unexpected error deleting merchant")
+		h.RespondWithInternalError(c, "Failed to delete merchant")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"merchant_id": merchantID,
+	}).Info("Merchant deleted successfully")
+
+	h.RespondWithSuccess(c, http.StatusOK, nil, "Merchant deleted successfully")
+}
@@ -0,0 +1,383 @@
+package merchant
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"hi-cfo/server/internal/logger"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	GetMerchants(ctx context.Context, userID uuid.UUID, filter MerchantFilter) (*MerchantResponse, error)
+	GetDirectoryMerchants(ctx context.Context, userID uuid.UUID) ([]Merchant, error)
+	GetMerchantByID(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error)
+	GetMerchantOverride(ctx context.Context, userID, systemMerchantID uuid.UUID) (*Merchant, error)
+	CreateMerchant(ctx context.Context, merchant *Merchant) error
+	CreateMissingMerchants(ctx context.Context, userID uuid.UUID, merchants []Merchant) ([]Merchant, error)
+	CreateMerchantOverride(ctx context.Context, userID uuid.UUID, override *Merchant) error
+	UpdateMerchant(ctx context.Context, userID, merchantID uuid.UUID, updates map[string]any) (*Merchant, error)
+	DeleteMerchants(ctx context.Context, userID uuid.UUID, merchantIDs []uuid.UUID) (int64, error)
+	RetireOverrides(ctx context.Context, userID uuid.UUID, mergedIDs []uuid.UUID, mergedIntoID uuid.UUID) error
+	CheckMerchantExists(ctx context.Context, userID uuid.UUID, name string, excludeID *uuid.UUID) (bool, error)
+	SeedSystemMerchants(ctx context.Context, merchants []Merchant) (int64, error)
+}
+
+type MerchantRepository struct {
+	db     *gorm.DB
+	logger *logrus.Entry
+}
+
+func NewMerchantRepository(db *gorm.DB) *MerchantRepository {
+	return &MerchantRepository{
+		db:     db,
+		logger: logger.WithDomain("merchant"),
+	}
+}
+
+// visibleMerchants scopes a query to the user's merchants and the system
+// merchants the user has not overridden. Overrides left behind by a merge
+// only hide their system merchant.
+func visibleMerchants(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Where(`(merchants.user_id = ? AND merchants.merged_into_id IS NULL) OR (merchants.user_id IS NULL AND merchants.id NOT IN (
+		SELECT o.overrides_id FROM merchants o WHERE o.user_id = ? AND o.overrides_id IS NOT NULL
+	))`, userID, userID)
+}
+
+func (r *MerchantRepository) GetMerchants(ctx context.Context, userID uuid.UUID, filter MerchantFilter) (*MerchantResponse, error) {
+	var merchants []Merchant
+	var total int64
+
+	query := visibleMerchants(r.db.WithContext(ctx).Model(&Merchant{}), userID)
+
+	if filter.IsSystemMerchant != nil {
+		query = query.Where("is_system_merchant = ?", *filter.IsSystemMerchant)
+	}
+	if filter.Search != nil {
+		searchPattern := "%" + *filter.Search + "%"
+		query = query.Where("name ILIKE ? OR array_to_string(aliases, ' ') ILIKE ?", searchPattern, searchPattern)
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count merchants").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"filter":  filter,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 50
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.Offset(offset).Limit(filter.Limit).Order("name ASC, is_system_merchant DESC").Find(&merchants).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch merchants").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"filter":  filter,
+				"offset":  offset,
+				"limit":   filter.Limit,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return &MerchantResponse{
+		Data:  merchants,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// GetDirectoryMerchants loads every merchant transactions of the user can be matched to
+func (r *MerchantRepository) GetDirectoryMerchants(ctx context.Context, userID uuid.UUID) ([]Merchant, error) {
+	var merchants []Merchant
+	if err := visibleMerchants(r.db.WithContext(ctx), userID).Find(&merchants).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to load merchant directory").
+			WithDomain("merchant").
+			WithUserID(userID)
+		appErr.Log()
+		return nil, appErr
+	}
+	return merchants, nil
+}
+
+func (r *MerchantRepository) GetMerchantByID(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error) {
+	var merchant Merchant
+	err := r.db.WithContext(ctx).Where("(user_id = ? OR user_id IS NULL) AND id = ? AND merged_into_id IS NULL", userID, merchantID).First(&merchant).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Merchant not found").
+				WithDomain("merchant").
+				WithDetails(map[string]any{
+					"user_id":     userID,
+					"merchant_id": merchantID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get merchant").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": merchantID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &merchant, nil
+}
+
+// GetMerchantOverride returns the user's copy of a system merchant, nil when there is none
+func (r *MerchantRepository) GetMerchantOverride(ctx context.Context, userID, systemMerchantID uuid.UUID) (*Merchant, error) {
+	var merchants []Merchant
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND overrides_id = ?", userID, systemMerchantID).
+		Limit(1).
+		Find(&merchants).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get merchant override").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": systemMerchantID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	if len(merchants) == 0 {
+		return nil, nil
+	}
+	return &merchants[0], nil
+}
+
+func (r *MerchantRepository) CreateMerchant(ctx context.Context, merchant *Merchant) error {
+	if merchant.ID == uuid.Nil {
+		merchant.ID = uuid.New()
+	}
+
+	if err := r.db.WithContext(ctx).Create(merchant).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create merchant").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"merchant_name": merchant.Name,
+				"user_id":       merchant.UserID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// CreateMissingMerchants creates the user merchants that do not exist yet and
+// returns all of them, including those created concurrently by another import
+func (r *MerchantRepository) CreateMissingMerchants(ctx context.Context, userID uuid.UUID, merchants []Merchant) ([]Merchant, error) {
+	if len(merchants) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, len(merchants))
+	for i := range merchants {
+		merchants[i].UserID = &userID
+		names[i] = merchants[i].Name
+	}
+
+	var created []Merchant
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(merchants, 500).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND name IN ? AND merged_into_id IS NULL", userID, names).Find(&created).Error
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create merchants").
+			WithDomain("merchant").
+			WithUserID(userID).
+			WithDetail("merchant_count", len(merchants))
+		appErr.Log()
+		return nil, appErr
+	}
+	return created, nil
+}
+
+// CreateMerchantOverride stores the user's copy of a system merchant. The
+// transaction domain moves the user's transactions over to it, recording the change.
+func (r *MerchantRepository) CreateMerchantOverride(ctx context.Context, userID uuid.UUID, override *Merchant) error {
+	if err := r.db.WithContext(ctx).Create(override).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to override system merchant").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": override.OverridesID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+func (r *MerchantRepository) UpdateMerchant(ctx context.Context, userID, merchantID uuid.UUID, updates map[string]any) (*Merchant, error) {
+	updates["updated_at"] = time.Now()
+
+	// Only user merchants are updated, system merchants are overridden instead
+	result := r.db.WithContext(ctx).Model(&Merchant{}).Where("user_id = ? AND id = ?", userID, merchantID).Updates(updates)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to update merchant").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": merchantID,
+				"updates":     updates,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	if result.RowsAffected == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Merchant not found").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": merchantID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return r.GetMerchantByID(ctx, userID, merchantID)
+}
+
+// RetireOverrides keeps the system merchant overrides among the merged
+// merchants only to hide their system merchant. Overrides retired into one of
+// the merged merchants earlier follow it to the new target.
+func (r *MerchantRepository) RetireOverrides(ctx context.Context, userID uuid.UUID, mergedIDs []uuid.UUID, mergedIntoID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Merchant{}).
+			Where("user_id = ? AND merged_into_id IN ?", userID, mergedIDs).
+			Update("merged_into_id", mergedIntoID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Merchant{}).
+			Where("user_id = ? AND id IN ? AND overrides_id IS NOT NULL", userID, mergedIDs).
+			Updates(map[string]any{
+				"merged_into_id": mergedIntoID,
+				"aliases":        nil,
+				"patterns":       nil,
+				"updated_at":     time.Now(),
+			}).Error
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to retire merged merchants").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"merchant_ids": mergedIDs,
+				"merchant_id":  mergedIntoID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// DeleteMerchants deletes user merchants. Transactions that referenced an
+// override go back to its system merchant, the others lose their merchant.
+func (r *MerchantRepository) DeleteMerchants(ctx context.Context, userID uuid.UUID, merchantIDs []uuid.UUID) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("transactions").
+			Where("user_id = ? AND merchant_id IN ?", userID, merchantIDs).
+			UpdateColumn("merchant_id", gorm.Expr("(SELECT m.overrides_id FROM merchants m WHERE m.id = transactions.merchant_id)")).Error; err != nil {
+			return err
+		}
+
+		// System merchants merged into a deleted merchant come back
+		if err := tx.Where("user_id = ? AND merged_into_id IN ?", userID, merchantIDs).Delete(&Merchant{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("user_id = ? AND id IN ?", userID, merchantIDs).Delete(&Merchant{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete merchants").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"merchant_ids": merchantIDs,
+			})
+		appErr.Log()
+		return 0, appErr
+	}
+	return deleted, nil
+}
+
+func (r *MerchantRepository) CheckMerchantExists(ctx context.Context, userID uuid.UUID, name string, excludeID *uuid.UUID) (bool, error) {
+	var count int64
+	query := r.db.WithContext(ctx).Model(&Merchant{}).Where("user_id = ? AND name ILIKE ? AND merged_into_id IS NULL", userID, name)
+	if excludeID != nil {
+		query = query.Where("id <> ?", *excludeID)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to check merchant existence").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":       userID,
+				"merchant_name": name,
+			})
+		appErr.Log()
+		return false, appErr
+	}
+	return count > 0, nil
+}
+
+// SeedSystemMerchants creates the system merchants that do not exist yet.
+// Existing ones are left alone so their aliases can be curated in the database.
+func (r *MerchantRepository) SeedSystemMerchants(ctx context.Context, merchants []Merchant) (int64, error) {
+	var existing []string
+	if err := r.db.WithContext(ctx).Model(&Merchant{}).Where("user_id IS NULL").Pluck("name", &existing).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to load system merchants").
+			WithDomain("merchant")
+		appErr.Log()
+		return 0, appErr
+	}
+	seeded := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		seeded[name] = true
+	}
+
+	missing := make([]Merchant, 0, len(merchants))
+	for _, m := range merchants {
+		if !seeded[m.Name] {
+			missing = append(missing, m)
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	if err := r.db.WithContext(ctx).CreateInBatches(missing, 500).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to seed system merchants").
+			WithDomain("merchant").
+			WithDetail("merchant_count", len(missing))
+		appErr.Log()
+		return 0, appErr
+	}
+	return int64(len(missing)), nil
+}
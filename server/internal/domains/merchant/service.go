@@ -0,0 +1,429 @@
+package merchant
+
+import (
+	"context"
+	"strings"
+
+	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/logger"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type MerchantStore interface {
+	GetMerchants(ctx context.Context, userID uuid.UUID, filter MerchantFilter) (*MerchantResponse, error)
+	GetMerchantByID(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error)
+	CreateMerchant(ctx context.Context, userID uuid.UUID, req *CreateMerchantRequest) (*Merchant, error)
+	UpdateMerchant(ctx context.Context, userID, merchantID uuid.UUID, req *UpdateMerchantRequest) (*Merchant, error)
+	DeleteMerchant(ctx context.Context, userID, merchantID uuid.UUID) error
+	RenameMerchant(ctx context.Context, userID, merchantID uuid.UUID, name string) (*Merchant, error)
+	PrepareMerge(ctx context.Context, userID uuid.UUID, req MergeMerchantsRequest) (*Merchant, []Merchant, error)
+	CompleteMerge(ctx context.Context, userID uuid.UUID, target *Merchant, sources []Merchant) (*Merchant, error)
+	EditableMerchant(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error)
+	ResolveMerchants(ctx context.Context, userID uuid.UUID, queries []MerchantQuery) ([]*Merchant, error)
+	SeedSystemMerchants(ctx context.Context) error
+}
+
+type MerchantService struct {
+	repo            Repository
+	categoryService *category.CategoryService
+	logger          *logrus.Entry
+}
+
+func NewMerchantService(repo Repository, categoryService *category.CategoryService) *MerchantService {
+	return &MerchantService{
+		repo:            repo,
+		categoryService: categoryService,
+		logger:          logger.WithDomain("merchant"),
+	}
+}
+
+func (s *MerchantService) GetMerchants(ctx context.Context, userID uuid.UUID, filter MerchantFilter) (*MerchantResponse, error) {
+	return s.repo.GetMerchants(ctx, userID, filter)
+}
+
+func (s *MerchantService) GetMerchantByID(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error) {
+	return s.repo.GetMerchantByID(ctx, userID, merchantID)
+}
+
+func (s *MerchantService) CreateMerchant(ctx context.Context, userID uuid.UUID, req *CreateMerchantRequest) (*Merchant, error) {
+	name := strings.TrimSpace(req.Name)
+	if err := s.checkNameAvailable(ctx, userID, name, nil); err != nil {
+		return nil, err
+	}
+	if err := s.validateRules(userID, req.Patterns); err != nil {
+		return nil, err
+	}
+	if err := s.validateDefaultCategory(ctx, userID, req.DefaultCategoryID); err != nil {
+		return nil, err
+	}
+
+	merchant := &Merchant{
+		UserID:            &userID,
+		Name:              name,
+		Aliases:           pq.StringArray(mergeAliases(name, nil, req.Aliases...)),
+		Patterns:          pq.StringArray(mergePatterns(nil, req.Patterns...)),
+		DefaultCategoryID: req.DefaultCategoryID,
+		Website:           req.Website,
+		LogoURL:           req.LogoURL,
+	}
+
+	if err := s.repo.CreateMerchant(ctx, merchant); err != nil {
+		return nil, err
+	}
+
+	return merchant, nil
+}
+
+// UpdateMerchant changes a user merchant. Changing a system merchant gives
+// the user their own copy of it, which takes its place from then on.
+func (s *MerchantService) UpdateMerchant(ctx context.Context, userID, merchantID uuid.UUID, req *UpdateMerchantRequest) (*Merchant, error) {
+	if err := s.validateRules(userID, req.Patterns); err != nil {
+		return nil, err
+	}
+	if err := s.validateDefaultCategory(ctx, userID, req.DefaultCategoryID); err != nil {
+		return nil, err
+	}
+
+	merchant, err := s.EditableMerchant(ctx, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+	if req.Aliases != nil {
+		updates["aliases"] = pq.StringArray(mergeAliases(merchant.Name, nil, req.Aliases...))
+	}
+	if req.Patterns != nil {
+		updates["patterns"] = pq.StringArray(mergePatterns(nil, req.Patterns...))
+	}
+	if req.ClearDefaultCategory {
+		updates["default_category_id"] = nil
+	} else if req.DefaultCategoryID != nil {
+		updates["default_category_id"] = *req.DefaultCategoryID
+	}
+	if req.Website != nil {
+		updates["website"] = *req.Website
+	}
+	if req.LogoURL != nil {
+		updates["logo_url"] = *req.LogoURL
+	}
+
+	return s.repo.UpdateMerchant(ctx, userID, merchant.ID, updates)
+}
+
+func (s *MerchantService) DeleteMerchant(ctx context.Context, userID, merchantID uuid.UUID) error {
+	merchant, err := s.repo.GetMerchantByID(ctx, userID, merchantID)
+	if err != nil {
+		return err
+	}
+
+	if merchant.UserID == nil {
+		appErr := customerrors.New(customerrors.ErrCodeForbidden, "Cannot delete system merchants").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"merchant_id": merchantID,
+			})
+		appErr.Log()
+		return appErr
+	}
+
+	_, err = s.repo.DeleteMerchants(ctx, userID, []uuid.UUID{merchantID})
+	return err
+}
+
+// EditableMerchant returns the user's own record for a merchant: the
+// merchant itself, or the user's copy of a system merchant, made on first use.
+// The caller moves the user's transactions of the system merchant to a new copy.
+func (s *MerchantService) EditableMerchant(ctx context.Context, userID, merchantID uuid.UUID) (*Merchant, error) {
+	merchant, err := s.repo.GetMerchantByID(ctx, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if merchant.UserID != nil {
+		return merchant, nil
+	}
+
+	override, err := s.repo.GetMerchantOverride(ctx, userID, merchant.ID)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil && override.MergedIntoID != nil {
+		// The system merchant was merged away, its target stands in for it
+		return s.repo.GetMerchantByID(ctx, userID, *override.MergedIntoID)
+	}
+	if override != nil {
+		return override, nil
+	}
+
+	override = &Merchant{
+		UserID:            &userID,
+		Name:              merchant.Name,
+		Aliases:           merchant.Aliases,
+		Patterns:          merchant.Patterns,
+		DefaultCategoryID: merchant.DefaultCategoryID,
+		Website:           merchant.Website,
+		LogoURL:           merchant.LogoURL,
+		OverridesID:       &merchant.ID,
+	}
+	if err := s.repo.CreateMerchantOverride(ctx, userID, override); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"merchant_id": merchant.ID,
+		"override_id": override.ID,
+	}).Info("System merchant overridden")
+
+	return override, nil
+}
+
+// RenameMerchant gives a merchant a new name. The old name stays as an alias
+// so descriptors spelled that way keep matching.
+func (s *MerchantService) RenameMerchant(ctx context.Context, userID, merchantID uuid.UUID, name string) (*Merchant, error) {
+	name = strings.TrimSpace(name)
+
+	merchant, err := s.EditableMerchant(ctx, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if merchant.Name == name {
+		return merchant, nil
+	}
+	if err := s.checkNameAvailable(ctx, userID, name, &merchant.ID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateMerchant(ctx, userID, merchant.ID, map[string]any{
+		"name":    name,
+		"aliases": pq.StringArray(mergeAliases(name, merchant.Aliases, merchant.Name)),
+	})
+}
+
+// PrepareMerge checks a merge and returns the user's records for its target
+// and sources, copying system merchants where needed
+func (s *MerchantService) PrepareMerge(ctx context.Context, userID uuid.UUID, req MergeMerchantsRequest) (*Merchant, []Merchant, error) {
+	for _, sourceID := range req.SourceIDs {
+		if sourceID == req.TargetID {
+			appErr := customerrors.New(customerrors.ErrCodeValidation, "A merchant cannot be merged into itself").
+				WithDomain("merchant").
+				WithUserID(userID).
+				WithDetail("merchant_id", sourceID)
+			appErr.Log()
+			return nil, nil, appErr
+		}
+	}
+
+	target, err := s.EditableMerchant(ctx, userID, req.TargetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := make([]Merchant, 0, len(req.SourceIDs))
+	seen := map[uuid.UUID]bool{target.ID: true}
+	for _, sourceID := range req.SourceIDs {
+		source, err := s.EditableMerchant(ctx, userID, sourceID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen[source.ID] {
+			continue
+		}
+		seen[source.ID] = true
+		sources = append(sources, *source)
+	}
+
+	return target, sources, nil
+}
+
+// CompleteMerge takes over the names and rules of the sources into the target
+// and deletes the sources. Copies of system merchants are kept to hide their
+// system merchant instead. Their transactions have to be moved first.
+func (s *MerchantService) CompleteMerge(ctx context.Context, userID uuid.UUID, target *Merchant, sources []Merchant) (*Merchant, error) {
+	aliases := target.Aliases
+	patterns := target.Patterns
+	mergedIDs := make([]uuid.UUID, len(sources))
+	sourceIDs := make([]uuid.UUID, 0, len(sources))
+	for i, source := range sources {
+		aliases = mergeAliases(target.Name, aliases, append([]string{source.Name}, source.Aliases...)...)
+		patterns = mergePatterns(patterns, source.Patterns...)
+		mergedIDs[i] = source.ID
+		if source.OverridesID == nil {
+			sourceIDs = append(sourceIDs, source.ID)
+		}
+	}
+
+	updates := map[string]any{
+		"aliases":  pq.StringArray(aliases),
+		"patterns": pq.StringArray(patterns),
+	}
+	if target.DefaultCategoryID == nil {
+		for _, source := range sources {
+			if source.DefaultCategoryID != nil {
+				updates["default_category_id"] = *source.DefaultCategoryID
+				break
+			}
+		}
+	}
+
+	merged, err := s.repo.UpdateMerchant(ctx, userID, target.ID, updates)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RetireOverrides(ctx, userID, mergedIDs, target.ID); err != nil {
+		return nil, err
+	}
+	if len(sourceIDs) > 0 {
+		if _, err := s.repo.DeleteMerchants(ctx, userID, sourceIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"merchant_id":  target.ID,
+		"merged_count": len(sources),
+	}).Info("Merchants merged successfully")
+
+	return merged, nil
+}
+
+// ResolveMerchants finds the merchant for each query in the user's directory,
+// creating user merchants for names it does not know yet. Queries without a
+// name and no matching pattern resolve to nil.
+func (s *MerchantService) ResolveMerchants(ctx context.Context, userID uuid.UUID, queries []MerchantQuery) ([]*Merchant, error) {
+	merchants, err := s.repo.GetDirectoryMerchants(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	directory := NewDirectory(merchants)
+
+	resolved := make([]*Merchant, len(queries))
+	missing := make([]Merchant, 0)
+	missingKeys := make(map[string]bool)
+	for i, query := range queries {
+		if m := directory.Match(query.Name, query.Description); m != nil {
+			resolved[i] = m
+			continue
+		}
+		key := AliasKey(query.Name)
+		if key != "" && !missingKeys[key] {
+			missingKeys[key] = true
+			missing = append(missing, Merchant{Name: strings.TrimSpace(query.Name)})
+		}
+	}
+	if len(missing) == 0 {
+		return resolved, nil
+	}
+
+	created, err := s.repo.CreateMissingMerchants(ctx, userID, missing)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*Merchant, len(created))
+	for i := range created {
+		byKey[AliasKey(created[i].Name)] = &created[i]
+	}
+	for i, query := range queries {
+		if resolved[i] == nil {
+			resolved[i] = byKey[AliasKey(query.Name)]
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":       userID,
+		"query_count":   len(queries),
+		"created_count": len(created),
+	}).Debug("Merchants resolved")
+
+	return resolved, nil
+}
+
+// SeedSystemMerchants creates the built-in system merchants missing from the database
+func (s *MerchantService) SeedSystemMerchants(ctx context.Context) error {
+	seeded, err := s.repo.SeedSystemMerchants(ctx, SystemMerchants())
+	if err != nil {
+		return err
+	}
+	if seeded > 0 {
+		s.logger.WithField("seeded_count", seeded).Info("System merchants seeded")
+	}
+	return nil
+}
+
+// ============== VALIDATION ==============//
+
+func (s *MerchantService) checkNameAvailable(ctx context.Context, userID uuid.UUID, name string, excludeID *uuid.UUID) error {
+	exists, err := s.repo.CheckMerchantExists(ctx, userID, name, excludeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		appErr := customerrors.New(customerrors.ErrCodeConflict, "Merchant with this name already exists, merge them instead").
+			WithDomain("merchant").
+			WithDetails(map[string]any{
+				"user_id":       userID,
+				"merchant_name": name,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+func (s *MerchantService) validateRules(userID uuid.UUID, patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := compilePattern(pattern); err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeValidation, "Invalid merchant pattern").
+				WithDomain("merchant").
+				WithUserID(userID).
+				WithDetail("pattern", pattern)
+			appErr.Log()
+			return appErr
+		}
+	}
+	return nil
+}
+
+func (s *MerchantService) validateDefaultCategory(ctx context.Context, userID uuid.UUID, categoryID *uuid.UUID) error {
+	if categoryID == nil || s.categoryService == nil {
+		return nil
+	}
+	_, err := s.categoryService.GetCategoryByID(ctx, userID, *categoryID)
+	return err
+}
+
+// mergeAliases adds aliases in key form, skipping duplicates and the merchant's own name
+func mergeAliases(name string, aliases []string, extra ...string) []string {
+	merged := make([]string, 0, len(aliases)+len(extra))
+	seen := map[string]bool{AliasKey(name): true}
+	for _, alias := range append(append([]string{}, aliases...), extra...) {
+		key := AliasKey(alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, key)
+	}
+	return merged
+}
+
+// mergePatterns adds patterns, skipping blank ones and duplicates
+func mergePatterns(patterns []string, extra ...string) []string {
+	merged := make([]string, 0, len(patterns)+len(extra))
+	seen := make(map[string]bool)
+	for _, pattern := range append(append([]string{}, patterns...), extra...) {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		merged = append(merged, pattern)
+	}
+	return merged
+}
@@ -0,0 +1,362 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // registers the GIF decoder for thumbnails
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder for thumbnails
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"hi-cfo/server/internal/config"
+	"hi-cfo/server/internal/infrastructure/storage"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// ATTACHMENTS
+// ========================================
+
+const (
+	thumbnailSize            = 256              // Longest side of a thumbnail in pixels
+	thumbnailContentType     = "image/jpeg"     // Thumbnails are always re-encoded as JPEG
+	maxThumbnailSourcePixels = 12 * 1000 * 1000 // Larger images are stored without a thumbnail, decoding one takes up to 48MB
+)
+
+// attachmentContentTypes are the sniffed media types accepted as attachments
+var attachmentContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"text/plain":      true,
+}
+
+// thumbnailContentTypes are the media types the standard library can decode
+var thumbnailContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// AttachmentDownload is an attachment file opened for sending. The caller
+// has to close Body.
+type AttachmentDownload struct {
+	Filename    string
+	ContentType string
+	Size        int64 // -1 when unknown
+	Body        io.ReadCloser
+}
+
+// GetAttachments lists the attachments of a transaction
+func (s *TransactionService) GetAttachments(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionAttachment, error) {
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAttachments(ctx, userID, transactionID)
+}
+
+// AddAttachment stores an uploaded file for a transaction. The content type
+// is sniffed from the content and has to be an image, a PDF or plain text;
+// JPEG, PNG and GIF images also get a thumbnail.
+func (s *TransactionService) AddAttachment(ctx context.Context, userID, transactionID uuid.UUID, filename string, file io.Reader) (*TransactionAttachment, error) {
+	if s.attachmentStore == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "attachment storage not available").WithDomain("transaction")
+	}
+
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+
+	maxSize := config.GetMaxFileSize()
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to read uploaded file").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("File too large (max %d bytes)", maxSize)).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+	if len(data) == 0 {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Uploaded file is empty").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+
+	contentType := http.DetectContentType(data)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !attachmentContentTypes[mediaType] {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("Unsupported file type '%s'", mediaType)).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"transaction_id": transactionID,
+				"filename":       filename,
+			})
+	}
+
+	checksum := sha256.Sum256(data)
+	attachment := &TransactionAttachment{
+		ID:            uuid.New(),
+		TransactionID: transactionID,
+		UserID:        userID,
+		Filename:      sanitizeAttachmentFilename(filename),
+		ContentType:   contentType,
+		SizeBytes:     int64(len(data)),
+		Checksum:      hex.EncodeToString(checksum[:]),
+	}
+	attachment.StorageKey = path.Join(userID.String(), transactionID.String(), attachment.ID.String())
+
+	if err := s.attachmentStore.Put(ctx, attachment.StorageKey, bytes.NewReader(data)); err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to store attachment").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+		appErr.Log()
+		return nil, appErr
+	}
+
+	if thumbnailContentTypes[mediaType] {
+		// A missing thumbnail does not fail the upload
+		thumbnail, err := makeThumbnail(data)
+		if err == nil {
+			err = s.attachmentStore.Put(ctx, attachment.ThumbnailKey(), bytes.NewReader(thumbnail))
+		}
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+				"content_type":   mediaType,
+				"error":          err.Error(),
+			}).Warn("Failed to create attachment thumbnail")
+		} else {
+			attachment.HasThumbnail = true
+		}
+	}
+
+	if err := s.repo.CreateAttachment(ctx, attachment); err != nil {
+		s.removeAttachmentFiles(ctx, []TransactionAttachment{*attachment})
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"attachment_id":  attachment.ID,
+		"content_type":   mediaType,
+		"size_bytes":     attachment.SizeBytes,
+	}).Info("Attachment added successfully")
+
+	return attachment, nil
+}
+
+// OpenAttachment opens an attachment file, or its thumbnail, for download
+func (s *TransactionService) OpenAttachment(ctx context.Context, userID, transactionID, attachmentID uuid.UUID, thumbnail bool) (*AttachmentDownload, error) {
+	if s.attachmentStore == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "attachment storage not available").WithDomain("transaction")
+	}
+
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+
+	attachment, err := s.repo.GetAttachmentByID(ctx, userID, transactionID, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	download := &AttachmentDownload{
+		Filename:    attachment.Filename,
+		ContentType: attachment.ContentType,
+		Size:        attachment.SizeBytes,
+	}
+	key := attachment.StorageKey
+	if thumbnail {
+		if !attachment.HasThumbnail {
+			return nil, customerrors.New(customerrors.ErrCodeNotFound, "Attachment has no thumbnail").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("attachment_id", attachmentID)
+		}
+		key = attachment.ThumbnailKey()
+		download.ContentType = thumbnailContentType
+		download.Size = -1
+		download.Filename = strings.TrimSuffix(attachment.Filename, path.Ext(attachment.Filename)) + "_thumb.jpg"
+	}
+
+	download.Body, err = s.attachmentStore.Open(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Attachment file not found").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("attachment_id", attachmentID)
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to open attachment").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("attachment_id", attachmentID)
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return download, nil
+}
+
+// DeleteAttachment removes an attachment and its files
+func (s *TransactionService) DeleteAttachment(ctx context.Context, userID, transactionID, attachmentID uuid.UUID) error {
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return err
+	}
+
+	attachment, err := s.repo.GetAttachmentByID(ctx, userID, transactionID, attachmentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteAttachment(ctx, userID, transactionID, attachmentID); err != nil {
+		return err
+	}
+	s.removeAttachmentFiles(ctx, []TransactionAttachment{*attachment})
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"attachment_id":  attachmentID,
+	}).Info("Attachment deleted successfully")
+
+	return nil
+}
+
+// removeAttachmentFiles deletes the stored files of attachments whose records
+// are gone. Failures are only logged, the files are orphaned at worst.
+func (s *TransactionService) removeAttachmentFiles(ctx context.Context, attachments []TransactionAttachment) {
+	if s.attachmentStore == nil {
+		return
+	}
+	for _, attachment := range attachments {
+		keys := []string{attachment.StorageKey}
+		if attachment.HasThumbnail {
+			keys = append(keys, attachment.ThumbnailKey())
+		}
+		for _, key := range keys {
+			if err := s.attachmentStore.Delete(ctx, key); err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"attachment_id": attachment.ID,
+					"storage_key":   key,
+					"error":         err.Error(),
+				}).Warn("Failed to delete attachment file")
+			}
+		}
+	}
+}
+
+// sanitizeAttachmentFilename keeps the base name of an uploaded file without
+// control characters, cut to fit the filename column
+func sanitizeAttachmentFilename(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
+
+// makeThumbnail scales an image down to fit thumbnailSize, averaging the
+// source pixels behind each thumbnail pixel. Transparency is flattened onto
+// white because the result is a JPEG.
+func makeThumbnail(data []byte) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, errors.New("image is empty")
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxThumbnailSourcePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 {
+		return nil, errors.New("image is empty")
+	}
+	dstW, dstH := srcW, srcH
+	if srcW > thumbnailSize || srcH > thumbnailSize {
+		if srcW >= srcH {
+			dstW, dstH = thumbnailSize, max(1, srcH*thumbnailSize/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*thumbnailSize/srcH), thumbnailSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					// RGBA is alpha-premultiplied, adding the missing alpha puts it on white
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					b += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
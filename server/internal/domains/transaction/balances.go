@@ -0,0 +1,192 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	"hi-cfo/server/internal/domains/account"
+	"hi-cfo/server/internal/shared"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// BALANCES
+// ========================================
+
+const (
+	balanceHistoryDefaultDays = 90
+	balanceHistoryMaxDays     = 5 * 366
+)
+
+// balanceFields are the transaction columns a running balance depends on
+var balanceFields = []string{"account_id", "amount", "transaction_date", "status"}
+
+// balanceFieldsChanged reports whether an update touches a running balance
+func balanceFieldsChanged(updates map[string]any) bool {
+	for _, field := range balanceFields {
+		if _, ok := updates[field]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshBalances brings the running balances of the accounts up to date
+// after their transactions changed. Failures are logged, not returned: the
+// change itself is saved and the next recalculation catches up.
+func (s *TransactionService) refreshBalances(ctx context.Context, userID uuid.UUID, accountIDs ...uuid.UUID) {
+	seen := make(map[uuid.UUID]bool, len(accountIDs))
+	for _, accountID := range accountIDs {
+		if accountID == uuid.Nil || seen[accountID] {
+			continue
+		}
+		seen[accountID] = true
+
+		if _, err := s.repo.RecalculateBalances(ctx, userID, accountID); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id":    userID,
+				"account_id": accountID,
+				"error":      err.Error(),
+			}).Warn("Failed to recalculate account balance")
+		}
+	}
+}
+
+// refreshImportBalances recalculates the accounts an import wrote to
+func (s *TransactionService) refreshImportBalances(ctx context.Context, userID, uploadID uuid.UUID) {
+	accountIDs, err := s.repo.GetFileUploadAccountIDs(ctx, userID, uploadID)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"file_upload_id": uploadID,
+			"error":          err.Error(),
+		}).Warn("Failed to find accounts of import for balance recalculation")
+		return
+	}
+	s.refreshBalances(ctx, userID, accountIDs...)
+}
+
+// SetOpeningBalance anchors the account's running balance and recomputes
+// BalanceAfter of its transactions and its current balance from it
+func (s *TransactionService) SetOpeningBalance(ctx context.Context, userID, accountID uuid.UUID, req account.SetOpeningBalanceRequest) (*account.Account, error) {
+	if s.accountService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "account service not available").WithDomain("transaction")
+	}
+
+	date, err := shared.ParseFlexibleDate(req.OpeningBalanceDate)
+	if err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, "Invalid opening_balance_date format").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("opening_balance_date", req.OpeningBalanceDate)
+	}
+
+	if _, err := s.accountService.GetAccountByID(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+	if err := s.accountService.SetOpeningBalance(ctx, userID, accountID, *req.OpeningBalance, date); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.RecalculateBalances(ctx, userID, accountID); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": accountID,
+	}).Info("Account balance recalculated")
+
+	return s.accountService.GetAccountByID(ctx, userID, accountID)
+}
+
+// GetBalanceHistory returns the account's balance at the end of every day in
+// the filter's range, including days without transactions
+func (s *TransactionService) GetBalanceHistory(ctx context.Context, userID, accountID uuid.UUID, filter account.BalanceHistoryFilter) (*account.BalanceHistory, error) {
+	if s.accountService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "account service not available").WithDomain("transaction")
+	}
+
+	acc, err := s.accountService.GetAccountByID(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if acc.OpeningBalance == nil || acc.OpeningBalanceDate == nil {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Account has no opening balance, set one to track its balance").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("account_id", accountID)
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if filter.EndDate != nil {
+		end = filter.EndDate.UTC().Truncate(24 * time.Hour)
+	}
+	start := end.AddDate(0, 0, -(balanceHistoryDefaultDays - 1))
+	if filter.StartDate != nil {
+		start = filter.StartDate.UTC().Truncate(24 * time.Hour)
+	}
+	if start.After(end) {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "start_date must not be after end_date").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; days > balanceHistoryMaxDays {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Balance history covers at most 5 years").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("days", days)
+	}
+
+	delta, err := s.repo.GetBalanceDelta(ctx, userID, accountID, *acc.OpeningBalanceDate, start)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.repo.GetDailyBalanceChanges(ctx, userID, accountID, start, end.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]DailyBalanceChange, len(changes))
+	for _, change := range changes {
+		byDay[change.Date.Format("2006-01-02")] = change
+	}
+
+	// Sum in cents so a long series does not pick up float error
+	balanceCents := toCents(*acc.OpeningBalance) + toCents(delta)
+	history := &account.BalanceHistory{
+		AccountID:          accountID,
+		Currency:           acc.Currency,
+		OpeningBalance:     *acc.OpeningBalance,
+		OpeningBalanceDate: *acc.OpeningBalanceDate,
+		StartDate:          start.Format("2006-01-02"),
+		EndDate:            end.Format("2006-01-02"),
+		Points:             make([]account.BalancePoint, 0, int(end.Sub(start).Hours()/24)+1),
+	}
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		change := byDay[key]
+		changeCents := toCents(change.Change)
+		balanceCents += changeCents
+		history.Points = append(history.Points, account.BalancePoint{
+			Date:             key,
+			Balance:          float64(balanceCents) / 100,
+			Change:           float64(changeCents) / 100,
+			TransactionCount: change.TransactionCount,
+		})
+	}
+
+	return history, nil
+}
+
+// balanceAtEndOf computes the account's balance after the last transaction on day
+func (s *TransactionService) balanceAtEndOf(ctx context.Context, userID uuid.UUID, acc *account.Account, day time.Time) (float64, error) {
+	next := day.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	delta, err := s.repo.GetBalanceDelta(ctx, userID, acc.ID, *acc.OpeningBalanceDate, next)
+	if err != nil {
+		return 0, err
+	}
+	return float64(toCents(*acc.OpeningBalance)+toCents(delta)) / 100, nil
+}
@@ -0,0 +1,130 @@
+package transaction
+
+import (
+	"time"
+)
+
+// ========================================
+// BANK STATEMENT MODELS
+// ========================================
+
+// BankStatement is the common result of the camt.053 and MT940 parsers. Files
+// holding several consecutive statements are merged: the opening balance comes
+// from the first one and the closing balance from the last.
+type BankStatement struct {
+	Format         string               `json:"format"` // "camt053" or "mt940"
+	AccountID      string               `json:"account_id,omitempty"`
+	Currency       string               `json:"currency"`
+	OpeningBalance *float64             `json:"opening_balance,omitempty"`
+	OpeningDate    *time.Time           `json:"opening_date,omitempty"`
+	ClosingBalance *float64             `json:"closing_balance,omitempty"`
+	ClosingDate    *time.Time           `json:"closing_date,omitempty"`
+	Entries        []BankStatementEntry `json:"entries"`
+}
+
+// BankStatementEntry is one booked or pending movement on the statement
+type BankStatementEntry struct {
+	Reference       string     `json:"reference,omitempty"`
+	BookingDate     time.Time  `json:"booking_date"`
+	ValueDate       *time.Time `json:"value_date,omitempty"`
+	Amount          float64    `json:"amount"` // Negative for debits
+	Currency        string     `json:"currency,omitempty"`
+	Counterparty    string     `json:"counterparty,omitempty"`
+	Description     string     `json:"description,omitempty"`
+	TransactionCode string     `json:"transaction_code,omitempty"`
+	Pending         bool       `json:"pending,omitempty"`
+}
+
+// ========================================
+// MAPPING
+// ========================================
+
+// Info summarises the statement for the import result
+func (s *BankStatement) Info(filename string) *StatementInfo {
+	info := &StatementInfo{
+		Format:           s.Format,
+		Filename:         filename,
+		BankAccountID:    s.AccountID,
+		Currency:         s.Currency,
+		OpeningBalance:   s.OpeningBalance,
+		ClosingBalance:   s.ClosingBalance,
+		BalanceDate:      s.ClosingDate,
+		TransactionCount: len(s.Entries),
+	}
+	for i := range s.Entries {
+		date := s.Entries[i].BookingDate
+		if info.StartDate == nil || date.Before(*info.StartDate) {
+			info.StartDate = &date
+		}
+		if info.EndDate == nil || date.After(*info.EndDate) {
+			info.EndDate = &date
+		}
+	}
+	return info
+}
+
+// ToTransactionRequests maps the statement into the unified batch input.
+// Booking dates become TransactionDate and value dates of booked entries PostedDate.
+func (s *BankStatement) ToTransactionRequests(accountID string) []TransactionRequest {
+	requests := make([]TransactionRequest, 0, len(s.Entries))
+	for _, entry := range s.Entries {
+		requests = append(requests, entry.toTransactionRequest(accountID, s.Currency))
+	}
+	return requests
+}
+
+func (e BankStatementEntry) toTransactionRequest(accountID, statementCurrency string) TransactionRequest {
+	description := e.Description
+	if description == "" {
+		description = e.Counterparty
+	}
+	if description == "" {
+		description = "Imported Transaction"
+	}
+
+	currency := e.Currency
+	if currency == "" {
+		currency = statementCurrency
+	}
+
+	transactionType := "expense"
+	if e.Amount > 0 {
+		transactionType = "income"
+	}
+
+	request := TransactionRequest{
+		AccountID:       accountID,
+		TransactionDate: e.BookingDate.Format("2006-01-02"),
+		Description:     description,
+		Amount:          e.Amount,
+		TransactionType: transactionType,
+		Currency:        currency,
+	}
+
+	if e.Pending {
+		// A booked entry that keeps the reference settles this one by its FitID
+		request.Status = TransactionStatusPending
+	}
+	if e.Reference != "" {
+		reference := e.Reference
+		request.FitID = &reference
+		request.ReferenceNumber = &reference
+	}
+	if e.ValueDate != nil && !e.Pending {
+		posted := e.ValueDate.Format("2006-01-02")
+		request.PostedDate = &posted
+	}
+	request.MerchantName = importedMerchantName(e.Counterparty)
+
+	return request
+}
+
+func signedAmount(amount float64, debit bool) float64 {
+	if amount < 0 {
+		amount = -amount
+	}
+	if debit {
+		return -amount
+	}
+	return amount
+}
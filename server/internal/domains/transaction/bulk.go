@@ -0,0 +1,112 @@
+package transaction
+
+import (
+	"context"
+	"strings"
+
+	"hi-cfo/server/internal/config"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// BULK EDIT
+// ========================================
+
+// BulkUpdateTransactions applies one set of changes to the transactions in
+// req.IDs, or to every transaction matching filter when no IDs are given.
+// Reconciled transactions in req.IDs refuse the edit, a filter skips them.
+func (s *TransactionService) BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, req BulkUpdateRequest, filter TransactionFilter) (*BulkUpdateResult, error) {
+	changes := req.Changes
+	changes.AddTags = s.cleanStringSlice(changes.AddTags)
+	changes.RemoveTags = s.cleanStringSlice(changes.RemoveTags)
+	if changes.UserNotes != nil {
+		notes := strings.TrimSpace(*changes.UserNotes)
+		changes.UserNotes = &notes
+	}
+
+	if err := s.validateBulkChanges(ctx, userID, changes); err != nil {
+		return nil, err
+	}
+	if len(req.IDs) == 0 && !filter.hasConditions() {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Bulk edit needs ids or at least one filter condition").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	affected, err := s.repo.BulkUpdateTransactions(ctx, userID, req.IDs, filter, changes, config.GetBulkEditMaxRows(), req.DryRun, ChangeSourceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkUpdateResult{
+		Count:  len(affected),
+		IDs:    affected,
+		DryRun: req.DryRun,
+	}
+	if len(req.IDs) > 0 {
+		found := make(map[uuid.UUID]bool, len(affected))
+		for _, id := range affected {
+			found[id] = true
+		}
+		for _, id := range req.IDs {
+			if !found[id] {
+				result.Missing = append(result.Missing, id)
+				found[id] = true
+			}
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"count":   result.Count,
+		"missing": len(result.Missing),
+		"dry_run": req.DryRun,
+	}).Info("Bulk transaction edit completed")
+
+	return result, nil
+}
+
+// validateBulkChanges makes sure a bulk edit changes something and that its
+// category belongs to the user
+func (s *TransactionService) validateBulkChanges(ctx context.Context, userID uuid.UUID, changes BulkTransactionChanges) error {
+	if changes.CategoryID == nil && !changes.ClearCategory && len(changes.AddTags) == 0 && len(changes.RemoveTags) == 0 &&
+		changes.IsHidden == nil && changes.NeedsReview == nil && changes.UserNotes == nil {
+		return customerrors.New(customerrors.ErrCodeValidation, "Bulk edit has no changes").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+	if changes.CategoryID != nil && changes.ClearCategory {
+		return customerrors.New(customerrors.ErrCodeValidation, "Set category_id or clear_category, not both").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+	for _, tag := range changes.AddTags {
+		for _, removed := range changes.RemoveTags {
+			if tag == removed {
+				return customerrors.New(customerrors.ErrCodeValidation, "A tag cannot be added and removed in the same edit").
+					WithDomain("transaction").
+					WithUserID(userID).
+					WithDetail("tag", tag)
+			}
+		}
+	}
+
+	if changes.CategoryID != nil && s.categoryService != nil {
+		if _, err := s.categoryService.GetCategoryByID(ctx, userID, *changes.CategoryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasConditions reports whether the filter narrows the user's transactions at all
+func (f TransactionFilter) hasConditions() bool {
+	return len(f.AccountIDs) > 0 || len(f.CategoryIDs) > 0 || f.Uncategorized || f.FileUploadID != nil ||
+		f.StartDate != nil || f.EndDate != nil || f.TransactionType != nil || f.Status != nil || f.Currency != nil ||
+		f.MinAmount != nil || f.MaxAmount != nil || len(f.Tags) > 0 ||
+		f.NeedsReview != nil || f.IsHidden != nil || f.IsRecurring != nil ||
+		(f.SearchTerm != nil && searchQuery(*f.SearchTerm) != "")
+}
@@ -0,0 +1,250 @@
+package transaction
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================
+// CAMT.053 XML MODEL
+// ========================================
+
+// Element names only; encoding/xml matches them in any namespace, so every
+// camt.053.001.xx version is read by the same structs.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string        `xml:"Id"`
+	IBAN    string        `xml:"Acct>Id>IBAN"`
+	OtherID string        `xml:"Acct>Id>Othr>Id"`
+	Ccy     string        `xml:"Acct>Ccy"`
+	Bals    []camtBalance `xml:"Bal"`
+	Entries []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Code      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	CdtDbtInd string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtEntry struct {
+	NtryRef       string          `xml:"NtryRef"`
+	AcctSvcrRef   string          `xml:"AcctSvcrRef"`
+	Amount        camtAmount      `xml:"Amt"`
+	CdtDbtInd     string          `xml:"CdtDbtInd"`
+	RvslInd       bool            `xml:"RvslInd"`
+	Status        camtStatus      `xml:"Sts"`
+	BookingDate   camtDate        `xml:"BookgDt"`
+	ValueDate     camtDate        `xml:"ValDt"`
+	BankTxCode    string          `xml:"BkTxCd>Domn>Fmly>SubFmlyCd"`
+	ProprietaryCd string          `xml:"BkTxCd>Prtry>Cd"`
+	AddtlInfo     string          `xml:"AddtlNtryInf"`
+	Details       []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTxDetails struct {
+	AcctSvcrRef  string   `xml:"Refs>AcctSvcrRef"`
+	TxID         string   `xml:"Refs>TxId"`
+	EndToEndID   string   `xml:"Refs>EndToEndId"`
+	Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorPty    string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	AddtlInfo    string   `xml:"AddtlTxInf"`
+}
+
+// camtStatus is plain text up to camt.053.001.08 and a <Cd> child afterwards
+type camtStatus struct {
+	Value string `xml:",chardata"`
+	Code  string `xml:"Cd"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// ========================================
+// PARSING
+// ========================================
+
+// ParseCAMT053 reads an ISO 20022 BankToCustomerStatement. Pending entries are
+// kept as pending transactions, which stay out of the booked balance until the
+// booked entry replaces them. Other statuses, such as INFO, are skipped.
+func ParseCAMT053(r io.Reader) (*BankStatement, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid camt.053 XML: %w", err)
+	}
+	if len(doc.Statements) == 0 {
+		return nil, errors.New("invalid camt.053 format: missing BkToCstmrStmt/Stmt")
+	}
+
+	statement := &BankStatement{
+		Format:  "camt053",
+		Entries: make([]BankStatementEntry, 0),
+	}
+
+	for i, stmt := range doc.Statements {
+		if statement.AccountID == "" {
+			statement.AccountID = firstNonEmpty(stmt.IBAN, stmt.OtherID)
+		}
+		if statement.Currency == "" {
+			statement.Currency = strings.ToUpper(stmt.Ccy)
+		}
+
+		for _, bal := range stmt.Bals {
+			amount, err := bal.Amount.float()
+			if err != nil {
+				return nil, fmt.Errorf("statement %s: invalid balance amount '%s'", stmt.ID, bal.Amount.Value)
+			}
+			amount = signedAmount(amount, bal.CdtDbtInd == "DBIT")
+			date := bal.Date.time()
+
+			switch bal.Code {
+			case "OPBD", "PRCD":
+				// Only the first statement's opening balance applies to a merged file
+				if i == 0 && statement.OpeningBalance == nil {
+					statement.OpeningBalance = &amount
+					statement.OpeningDate = date
+				}
+			case "CLBD":
+				statement.ClosingBalance = &amount
+				statement.ClosingDate = date
+			}
+			if statement.Currency == "" {
+				statement.Currency = strings.ToUpper(bal.Amount.Currency)
+			}
+		}
+
+		for _, ntry := range stmt.Entries {
+			entry, ok, err := ntry.toEntry()
+			if err != nil {
+				return nil, fmt.Errorf("statement %s: %w", stmt.ID, err)
+			}
+			if ok {
+				statement.Entries = append(statement.Entries, entry)
+			}
+		}
+	}
+
+	if statement.Currency == "" {
+		statement.Currency = "EUR"
+	}
+	if len(statement.Entries) == 0 {
+		return nil, errors.New("no booked or pending entries found in camt.053 statement")
+	}
+
+	return statement, nil
+}
+
+func (n camtEntry) toEntry() (BankStatementEntry, bool, error) {
+	status := strings.ToUpper(strings.TrimSpace(firstNonEmpty(n.Status.Code, n.Status.Value)))
+	if status != "" && status != "BOOK" && status != "PDNG" {
+		return BankStatementEntry{}, false, nil
+	}
+
+	amount, err := n.Amount.float()
+	if err != nil {
+		return BankStatementEntry{}, false, fmt.Errorf("invalid entry amount '%s'", n.Amount.Value)
+	}
+	debit := n.CdtDbtInd == "DBIT"
+	if n.RvslInd {
+		debit = !debit
+	}
+
+	booking := n.BookingDate.time()
+	value := n.ValueDate.time()
+	if booking == nil {
+		booking = value
+	}
+	if booking == nil {
+		return BankStatementEntry{}, false, fmt.Errorf("entry %s has no booking or value date", n.NtryRef)
+	}
+
+	entry := BankStatementEntry{
+		BookingDate:     *booking,
+		ValueDate:       value,
+		Amount:          signedAmount(amount, debit),
+		Currency:        strings.ToUpper(n.Amount.Currency),
+		TransactionCode: firstNonEmpty(n.BankTxCode, n.ProprietaryCd),
+		Reference:       firstNonEmpty(n.AcctSvcrRef, n.NtryRef),
+		Pending:         status == "PDNG",
+	}
+
+	// Batched entries can carry several details; the first one describes the entry
+	var remittance []string
+	if len(n.Details) > 0 {
+		details := n.Details[0]
+		if entry.Reference == "" {
+			entry.Reference = firstNonEmpty(details.AcctSvcrRef, details.TxID, notProvided(details.EndToEndID))
+		}
+		// The counterparty is whoever is on the other side of the movement
+		if debit {
+			entry.Counterparty = firstNonEmpty(details.Creditor, details.CreditorPty)
+		} else {
+			entry.Counterparty = firstNonEmpty(details.Debtor, details.DebtorPty)
+		}
+		remittance = details.Unstructured
+		if len(remittance) == 0 && details.AddtlInfo != "" {
+			remittance = []string{details.AddtlInfo}
+		}
+	}
+
+	entry.Description = strings.Join(strings.Fields(strings.Join(remittance, " ")), " ")
+	if entry.Description == "" {
+		entry.Description = strings.TrimSpace(n.AddtlInfo)
+	}
+
+	return entry, true, nil
+}
+
+func (a camtAmount) float() (float64, error) {
+	return strconv.ParseFloat(strings.TrimSpace(a.Value), 64)
+}
+
+func (d camtDate) time() *time.Time {
+	if value := strings.TrimSpace(d.Date); value != "" {
+		if t, err := time.Parse("2006-01-02", value); err == nil {
+			return &t
+		}
+	}
+	if value := strings.TrimSpace(d.DateTime); len(value) >= 10 {
+		if t, err := time.Parse("2006-01-02", value[:10]); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// notProvided drops the ISO 20022 placeholder for a missing end-to-end id
+func notProvided(value string) string {
+	if strings.EqualFold(strings.TrimSpace(value), "NOTPROVIDED") {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,234 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const camtStatementXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+<BkToCstmrStmt>
+<Stmt>
+<Id>STMT-1</Id>
+<Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy></Acct>
+<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2025-06-01</Dt></Dt></Bal>
+<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">50.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Dt><Dt>2025-06-30</Dt></Dt></Bal>
+<Ntry>
+<Amt Ccy="EUR">120.00</Amt>
+<CdtDbtInd>DBIT</CdtDbtInd>
+<Sts><Cd>BOOK</Cd></Sts>
+<BookgDt><Dt>2025-06-02</Dt></BookgDt>
+<ValDt><Dt>2025-06-03</Dt></ValDt>
+<AcctSvcrRef>REF-1</AcctSvcrRef>
+<BkTxCd><Domn><Fmly><SubFmlyCd>ESCT</SubFmlyCd></Fmly></Domn></BkTxCd>
+<NtryDtls><TxDtls>
+<RltdPties><Cdtr><Nm>ACME GmbH</Nm></Cdtr><Dbtr><Nm>Me</Nm></Dbtr></RltdPties>
+<RmtInf><Ustrd>Invoice   42</Ustrd><Ustrd>June</Ustrd></RmtInf>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">30.00</Amt>
+<CdtDbtInd>DBIT</CdtDbtInd>
+<RvslInd>true</RvslInd>
+<Sts>BOOK</Sts>
+<BookgDt><DtTm>2025-06-04T10:00:00</DtTm></BookgDt>
+<NtryDtls><TxDtls>
+<Refs><EndToEndId>NOTPROVIDED</EndToEndId><TxId>TX-2</TxId></Refs>
+<RltdPties><Dbtr><Pty><Nm>Shop Ltd</Nm></Pty></Dbtr></RltdPties>
+<AddtlTxInf>Refund</AddtlTxInf>
+</TxDtls></NtryDtls>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">9.99</Amt>
+<CdtDbtInd>DBIT</CdtDbtInd>
+<Sts><Cd>PDNG</Cd></Sts>
+<BookgDt><Dt>2025-06-05</Dt></BookgDt>
+<NtryRef>PEND-1</NtryRef>
+<AddtlNtryInf>Card payment</AddtlNtryInf>
+</Ntry>
+<Ntry>
+<Amt Ccy="EUR">1.00</Amt>
+<CdtDbtInd>CRDT</CdtDbtInd>
+<Sts><Cd>INFO</Cd></Sts>
+<BookgDt><Dt>2025-06-06</Dt></BookgDt>
+</Ntry>
+</Stmt>
+</BkToCstmrStmt>
+</Document>`
+
+func TestParseCAMT053(t *testing.T) {
+	statement, err := ParseCAMT053(strings.NewReader(camtStatementXML))
+	if err != nil {
+		t.Fatalf("ParseCAMT053() error = %v", err)
+	}
+	if statement.AccountID != "DE89370400440532013000" || statement.Currency != "EUR" {
+		t.Errorf("account/currency = %s/%s", statement.AccountID, statement.Currency)
+	}
+	if statement.OpeningBalance == nil || *statement.OpeningBalance != 100 {
+		t.Errorf("OpeningBalance = %v, want 100", statement.OpeningBalance)
+	}
+	if statement.ClosingBalance == nil || *statement.ClosingBalance != -50 {
+		t.Errorf("ClosingBalance = %v, want -50", statement.ClosingBalance)
+	}
+
+	tests := []struct {
+		name         string
+		reference    string
+		booking      string
+		value        string
+		amount       float64
+		counterparty string
+		description  string
+		code         string
+		pending      bool
+	}{
+		{
+			name:         "booked debit with remittance lines",
+			reference:    "REF-1",
+			booking:      "2025-06-02",
+			value:        "2025-06-03",
+			amount:       -120,
+			counterparty: "ACME GmbH",
+			description:  "Invoice 42 June",
+			code:         "ESCT",
+		},
+		{
+			name:         "reversed debit is money in",
+			reference:    "TX-2",
+			booking:      "2025-06-04",
+			amount:       30,
+			counterparty: "Shop Ltd",
+			description:  "Refund",
+		},
+		{
+			name:        "pending entry",
+			reference:   "PEND-1",
+			booking:     "2025-06-05",
+			amount:      -9.99,
+			description: "Card payment",
+			pending:     true,
+		},
+	}
+
+	if len(statement.Entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(statement.Entries), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statement.Entries[i]
+			value := ""
+			if got.ValueDate != nil {
+				value = got.ValueDate.Format("2006-01-02")
+			}
+			if got.Reference != tt.reference || got.BookingDate.Format("2006-01-02") != tt.booking || value != tt.value ||
+				got.Amount != tt.amount || got.Counterparty != tt.counterparty || got.Description != tt.description ||
+				got.TransactionCode != tt.code || got.Pending != tt.pending {
+				t.Errorf("entry = %+v, want %+v", got, tt)
+			}
+		})
+	}
+}
+
+func TestParseCAMT053Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "not XML", input: "hello", wantErr: "invalid camt.053 XML"},
+		{name: "no statement", input: "<Document><BkToCstmrStmt></BkToCstmrStmt></Document>", wantErr: "missing BkToCstmrStmt/Stmt"},
+		{
+			name:    "bad amount",
+			input:   "<Document><BkToCstmrStmt><Stmt><Id>S</Id><Ntry><Amt>x</Amt><BookgDt><Dt>2025-01-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>",
+			wantErr: "invalid entry amount",
+		},
+		{
+			name:    "no date",
+			input:   "<Document><BkToCstmrStmt><Stmt><Id>S</Id><Ntry><NtryRef>N1</NtryRef><Amt>1</Amt></Ntry></Stmt></BkToCstmrStmt></Document>",
+			wantErr: "entry N1 has no booking or value date",
+		},
+		{
+			name:    "only informational entries",
+			input:   "<Document><BkToCstmrStmt><Stmt><Id>S</Id><Ntry><Amt>1</Amt><Sts>INFO</Sts><BookgDt><Dt>2025-01-01</Dt></BookgDt></Ntry></Stmt></BkToCstmrStmt></Document>",
+			wantErr: "no booked or pending entries",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCAMT053(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseCAMT053() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBankStatementEntryRequest(t *testing.T) {
+	booking, _ := time.Parse("2006-01-02", "2025-06-02")
+	value, _ := time.Parse("2006-01-02", "2025-06-03")
+
+	tests := []struct {
+		name        string
+		entry       BankStatementEntry
+		status      string
+		fitID       string
+		posted      string
+		description string
+		merchant    string
+		txType      string
+	}{
+		{
+			name:        "booked entry posts on the value date",
+			entry:       BankStatementEntry{Reference: "REF-1", BookingDate: booking, ValueDate: &value, Amount: -120, Counterparty: "ACME GmbH", Description: "Invoice 42"},
+			fitID:       "REF-1",
+			posted:      "2025-06-03",
+			description: "Invoice 42",
+			merchant:    "Acme",
+			txType:      "expense",
+		},
+		{
+			name:        "pending entry keeps its reference but not the value date",
+			entry:       BankStatementEntry{Reference: "PEND-1", BookingDate: booking, ValueDate: &value, Amount: -9.99, Pending: true, Counterparty: "Coffee Shop"},
+			status:      TransactionStatusPending,
+			fitID:       "PEND-1",
+			description: "Coffee Shop",
+			merchant:    "Coffee Shop",
+			txType:      "expense",
+		},
+		{
+			name:        "credit without details",
+			entry:       BankStatementEntry{BookingDate: booking, Amount: 30},
+			description: "Imported Transaction",
+			txType:      "income",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.entry.toTransactionRequest("acc", "EUR")
+			if request.Status != tt.status {
+				t.Errorf("Status = %q, want %q", request.Status, tt.status)
+			}
+			if got := derefString(request.FitID); got != tt.fitID {
+				t.Errorf("FitID = %q, want %q", got, tt.fitID)
+			}
+			if got := derefString(request.ReferenceNumber); got != tt.fitID {
+				t.Errorf("ReferenceNumber = %q, want %q", got, tt.fitID)
+			}
+			if got := derefString(request.PostedDate); got != tt.posted {
+				t.Errorf("PostedDate = %q, want %q", got, tt.posted)
+			}
+			if got := derefString(request.MerchantName); got != tt.merchant {
+				t.Errorf("MerchantName = %q, want %q", got, tt.merchant)
+			}
+			if request.Description != tt.description || request.TransactionType != tt.txType || request.Currency != "EUR" {
+				t.Errorf("description/type/currency = %q/%s/%s", request.Description, request.TransactionType, request.Currency)
+			}
+			if request.TransactionDate != "2025-06-02" {
+				t.Errorf("TransactionDate = %s, want 2025-06-02", request.TransactionDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package transaction
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================
+// PARSING
+// ========================================
+
+// csvDateTokens translates the user-facing layout tokens into Go reference
+// layout. Longer tokens must come first so "YYYY" is not read as two "YY".
+var csvDateTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "1"}, // Unpadded layouts accept both "1" and "01"
+	{"M", "1"},
+	{"DD", "2"},
+	{"D", "2"},
+}
+
+// ParseCSV reads a bank export with the given profile. Every data row is
+// returned; rows that cannot be mapped carry an Error instead of a Transaction
+// so callers can decide whether to show or drop them. limit <= 0 reads all rows.
+func ParseCSV(r io.Reader, profile *ImportProfile, accountID string, limit int) ([]CSVParsedRow, int, error) {
+	layout, err := csvDateLayout(profile.DateLayout)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = []rune(profile.Delimiter)[0]
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	rows := make([]CSVParsedRow, 0)
+	total := 0
+	read := 0
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		read++
+		if read <= profile.SkipRows || isBlankCSVRecord(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		total++
+		if limit > 0 && len(rows) >= limit {
+			continue
+		}
+
+		row := CSVParsedRow{Row: line, Raw: record}
+		request, err := profile.mapRecord(record, layout, accountID)
+		if err != nil {
+			row.Error = err.Error()
+		} else {
+			row.Transaction = request
+		}
+		rows = append(rows, row)
+	}
+
+	if total == 0 {
+		return nil, 0, errors.New("no data rows found in CSV file")
+	}
+
+	return rows, total, nil
+}
+
+func (p *ImportProfile) mapRecord(record []string, layout, accountID string) (*TransactionRequest, error) {
+	field := func(index *int) string {
+		if index == nil || *index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[*index])
+	}
+
+	rawDate := field(&p.Columns.Date)
+	date, err := time.Parse(layout, rawDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date '%s' for layout %s", rawDate, p.DateLayout)
+	}
+
+	amount, err := p.recordAmount(field)
+	if err != nil {
+		return nil, err
+	}
+
+	description := field(&p.Columns.Description)
+	if description == "" {
+		description = "Imported Transaction"
+	}
+
+	currency := p.DefaultCurrency
+	if raw := field(p.Columns.Currency); raw != "" {
+		currency = normalizeOFXCurrency(raw)
+	}
+
+	transactionType := "expense"
+	if amount > 0 {
+		transactionType = "income"
+	}
+
+	request := &TransactionRequest{
+		AccountID:       accountID,
+		TransactionDate: date.Format("2006-01-02"),
+		Description:     description,
+		Amount:          amount,
+		TransactionType: transactionType,
+		Currency:        currency,
+	}
+
+	merchant := field(p.Columns.Merchant)
+	if merchant == "" {
+		merchant = description
+	}
+	request.MerchantName = importedMerchantName(merchant)
+	if memo := field(p.Columns.Memo); memo != "" {
+		request.Memo = &memo
+	}
+	if reference := field(p.Columns.Reference); reference != "" {
+		request.ReferenceNumber = &reference
+	}
+
+	return request, nil
+}
+
+// recordAmount applies the profile's sign convention so that the result is
+// always negative for money leaving the account
+func (p *ImportProfile) recordAmount(field func(*int) string) (float64, error) {
+	switch p.SignConvention {
+	case "debit_credit":
+		debit, err := parseCSVAmount(field(p.Columns.Debit), p.DecimalSeparator)
+		if err != nil {
+			return 0, fmt.Errorf("invalid debit '%s'", field(p.Columns.Debit))
+		}
+		credit, err := parseCSVAmount(field(p.Columns.Credit), p.DecimalSeparator)
+		if err != nil {
+			return 0, fmt.Errorf("invalid credit '%s'", field(p.Columns.Credit))
+		}
+		if debit == 0 && credit == 0 {
+			return 0, errors.New("both debit and credit are empty")
+		}
+		return math.Abs(credit) - math.Abs(debit), nil
+	default:
+		raw := field(p.Columns.Amount)
+		if raw == "" {
+			return 0, errors.New("amount is empty")
+		}
+		amount, err := parseCSVAmount(raw, p.DecimalSeparator)
+		if err != nil {
+			return 0, fmt.Errorf("invalid amount '%s'", raw)
+		}
+		if p.SignConvention == "inverted" {
+			amount = -amount
+		}
+		return amount, nil
+	}
+}
+
+// parseCSVAmount handles currency symbols, thousands separators, "(12.50)"
+// accounting negatives and trailing "-", "CR" or "DR" markers
+func parseCSVAmount(value, decimalSeparator string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	negative := false
+	upper := strings.ToUpper(value)
+	switch {
+	case strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")"):
+		negative = true
+		value = value[1 : len(value)-1]
+	case strings.HasSuffix(value, "-"):
+		negative = true
+		value = value[:len(value)-1]
+	case strings.HasSuffix(upper, "DR"):
+		negative = true
+		value = value[:len(value)-2]
+	case strings.HasSuffix(upper, "CR"):
+		value = value[:len(value)-2]
+	}
+
+	thousands := ","
+	if decimalSeparator == "," {
+		thousands = "."
+	}
+
+	var b strings.Builder
+	for _, ch := range value {
+		switch {
+		case ch >= '0' && ch <= '9', ch == '-', ch == '+':
+			b.WriteRune(ch)
+		case string(ch) == decimalSeparator:
+			b.WriteByte('.')
+		case string(ch) == thousands, ch == ' ', ch == '\'':
+			// grouping characters
+		default:
+			// currency symbols and codes
+		}
+	}
+
+	amount, err := strconv.ParseFloat(b.String(), 64)
+	if err != nil {
+		return 0, err
+	}
+	if negative {
+		amount = -math.Abs(amount)
+	}
+	return amount, nil
+}
+
+func csvDateLayout(layout string) (string, error) {
+	layout = strings.TrimSpace(layout)
+	if layout == "" {
+		return "", errors.New("date layout is required")
+	}
+
+	var b strings.Builder
+	hasYear, hasMonth, hasDay := false, false, false
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, t := range csvDateTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				b.WriteString(t.layout)
+				switch t.token[0] {
+				case 'Y':
+					hasYear = true
+				case 'M':
+					hasMonth = true
+				case 'D':
+					hasDay = true
+				}
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(layout[i])
+			i++
+		}
+	}
+
+	if !hasYear || !hasMonth || !hasDay {
+		return "", fmt.Errorf("date layout '%s' must contain day, month and year (e.g. DD/MM/YYYY)", layout)
+	}
+	return b.String(), nil
+}
+
+func isBlankCSVRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ========================================
+// VALIDATION
+// ========================================
+
+// Validate checks that the column mapping matches the sign convention
+func (p *ImportProfile) Validate() error {
+	if _, err := csvDateLayout(p.DateLayout); err != nil {
+		return err
+	}
+	if len([]rune(p.Delimiter)) != 1 {
+		return errors.New("delimiter must be a single character")
+	}
+	if p.DecimalSeparator != "." && p.DecimalSeparator != "," {
+		return errors.New("decimal separator must be '.' or ','")
+	}
+	if p.DecimalSeparator == p.Delimiter {
+		return errors.New("decimal separator and delimiter must differ")
+	}
+
+	switch p.SignConvention {
+	case "debit_credit":
+		if p.Columns.Debit == nil || p.Columns.Credit == nil {
+			return errors.New("debit and credit columns are required for the debit_credit sign convention")
+		}
+	case "signed", "inverted":
+		if p.Columns.Amount == nil {
+			return errors.New("amount column is required for the signed and inverted sign conventions")
+		}
+	default:
+		return fmt.Errorf("unknown sign convention '%s'", p.SignConvention)
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCSV(t *testing.T) {
+	column := func(i int) *int { return &i }
+
+	type row struct {
+		line        int
+		date        string
+		amount      float64
+		description string
+		merchant    string
+		err         string // Substring of the row error, empty for a mapped row
+	}
+
+	tests := []struct {
+		name    string
+		profile ImportProfile
+		input   string
+		want    []row
+	}{
+		{
+			name: "signed amounts with a header",
+			profile: ImportProfile{
+				Delimiter: ",", SkipRows: 1, DateLayout: "DD/MM/YYYY", DecimalSeparator: ".", SignConvention: "signed",
+				Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: column(2)},
+			},
+			input: "Date,Description,Amount\n" +
+				"02/06/2025,TESCO STORES 1234 LONDON GB,-12.50\n" +
+				"03/06/2025,SALARY ACME LTD,\"1,234.56\"\n",
+			want: []row{
+				{line: 2, date: "2025-06-02", amount: -12.5, description: "TESCO STORES 1234 LONDON GB", merchant: "Tesco"},
+				{line: 3, date: "2025-06-03", amount: 1234.56, description: "SALARY ACME LTD"},
+			},
+		},
+		{
+			name: "semicolons and decimal commas",
+			profile: ImportProfile{
+				Delimiter: ";", SkipRows: 1, DateLayout: "DD.MM.YYYY", DecimalSeparator: ",", SignConvention: "signed",
+				Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: column(2)},
+			},
+			input: "Datum;Buchungstext;Betrag\n" +
+				"02.06.2025;REWE MARKT 4471;-12,50\n" +
+				"03.06.2025;Miete Juni;-1.234,56\n",
+			want: []row{
+				{line: 2, date: "2025-06-02", amount: -12.5, description: "REWE MARKT 4471", merchant: "Rewe Markt"},
+				{line: 3, date: "2025-06-03", amount: -1234.56, description: "Miete Juni", merchant: "Miete Juni"},
+			},
+		},
+		{
+			name: "debit and credit columns",
+			profile: ImportProfile{
+				Delimiter: ",", SkipRows: 1, DateLayout: "YYYY-MM-DD", DecimalSeparator: ".", SignConvention: "debit_credit",
+				Columns: CSVColumnMapping{Date: 0, Description: 1, Debit: column(2), Credit: column(3)},
+			},
+			input: "Date,Description,Paid out,Paid in\n" +
+				"2025-06-02,CARD PAYMENT TO NETFLIX.COM,15.99,\n" +
+				"2025-06-03,REFUND FROM BOOTS,,20.00\n" +
+				"2025-06-04,NOTHING MOVED,,\n",
+			want: []row{
+				{line: 2, date: "2025-06-02", amount: -15.99, description: "CARD PAYMENT TO NETFLIX.COM", merchant: "Netflix"},
+				{line: 3, date: "2025-06-03", amount: 20, description: "REFUND FROM BOOTS", merchant: "Refund From Boots"},
+				{line: 4, err: "both debit and credit are empty"},
+			},
+		},
+		{
+			name: "inverted amounts and a merchant column",
+			profile: ImportProfile{
+				Delimiter: "\t", DateLayout: "D MMM YYYY", DecimalSeparator: ".", SignConvention: "inverted",
+				Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: column(2), Merchant: column(3)},
+			},
+			input: "2 Jun 2025\tCARD 1234\t42.00\tSQ *BLUE BOTTLE COFFEE\n" +
+				"3 Jun 2025\tCARD 1234\t-5.00\t\n",
+			want: []row{
+				{line: 1, date: "2025-06-02", amount: -42, description: "CARD 1234", merchant: "Blue Bottle Coffee"},
+				{line: 2, date: "2025-06-03", amount: 5, description: "CARD 1234", merchant: "Card"},
+			},
+		},
+		{
+			name: "malformed rows carry an error, blank lines are skipped",
+			profile: ImportProfile{
+				Delimiter: ",", SkipRows: 1, DateLayout: "DD/MM/YYYY", DecimalSeparator: ".", SignConvention: "signed",
+				Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: column(2)},
+			},
+			input: "Date,Description,Amount\n" +
+				"2025-06-02,WRONG DATE LAYOUT,-1.00\n" +
+				"02/06/2025,NOT A NUMBER,abc\n" +
+				"\n" +
+				"03/06/2025,NO AMOUNT,\n" +
+				"04/06/2025,SHORT ROW\n" +
+				"05/06/2025,,-3.00\n",
+			want: []row{
+				{line: 2, err: "invalid date '2025-06-02'"},
+				{line: 3, err: "invalid amount 'abc'"},
+				{line: 5, err: "amount is empty"},
+				{line: 6, err: "amount is empty"},
+				{line: 7, date: "2025-06-05", amount: -3, description: "Imported Transaction", merchant: "Imported Transaction"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.profile.DefaultCurrency = "GBP"
+			rows, total, err := ParseCSV(strings.NewReader(tt.input), &tt.profile, "account-1", 0)
+			if err != nil {
+				t.Fatalf("ParseCSV() error = %v", err)
+			}
+			if total != len(tt.want) || len(rows) != len(tt.want) {
+				t.Fatalf("ParseCSV() = %d rows of %d, want %d", len(rows), total, len(tt.want))
+			}
+
+			for i, want := range tt.want {
+				got := rows[i]
+				if got.Row != want.line {
+					t.Errorf("row %d: line = %d, want %d", i, got.Row, want.line)
+				}
+				if want.err != "" {
+					if got.Transaction != nil || !strings.Contains(got.Error, want.err) {
+						t.Errorf("row %d: error = %q, want %q", i, got.Error, want.err)
+					}
+					continue
+				}
+				request := got.Transaction
+				if request == nil {
+					t.Fatalf("row %d: error = %q, want a transaction", i, got.Error)
+				}
+				if request.TransactionDate != want.date || request.Amount != want.amount || request.Description != want.description {
+					t.Errorf("row %d: %s %v %q, want %s %v %q", i, request.TransactionDate, request.Amount, request.Description, want.date, want.amount, want.description)
+				}
+				if merchant := derefString(request.MerchantName); merchant != want.merchant {
+					t.Errorf("row %d: MerchantName = %q, want %q", i, merchant, want.merchant)
+				}
+				if request.AccountID != "account-1" || request.Currency != "GBP" {
+					t.Errorf("row %d: account %s currency %s, want account-1 GBP", i, request.AccountID, request.Currency)
+				}
+				if wantType := map[bool]string{true: "income", false: "expense"}[want.amount > 0]; request.TransactionType != wantType {
+					t.Errorf("row %d: TransactionType = %s, want %s", i, request.TransactionType, wantType)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCSVLimit(t *testing.T) {
+	amount := 2
+	profile := &ImportProfile{
+		Delimiter: ",", DateLayout: "YYYY-MM-DD", DecimalSeparator: ".", SignConvention: "signed",
+		Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: &amount},
+	}
+	input := "2025-06-01,A,-1\n2025-06-02,B,-2\n2025-06-03,C,-3\n"
+
+	rows, total, err := ParseCSV(strings.NewReader(input), profile, "account-1", 2)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(rows) != 2 || total != 3 {
+		t.Errorf("ParseCSV() = %d rows of %d, want 2 of 3", len(rows), total)
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	amount := 2
+	profile := func(layout string) *ImportProfile {
+		return &ImportProfile{
+			Delimiter: ",", SkipRows: 1, DateLayout: layout, DecimalSeparator: ".", SignConvention: "signed",
+			Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: &amount},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		profile *ImportProfile
+		input   string
+		wantErr string
+	}{
+		{name: "header only", profile: profile("YYYY-MM-DD"), input: "Date,Description,Amount\n", wantErr: "no data rows"},
+		{name: "blank lines only", profile: profile("YYYY-MM-DD"), input: "Date,Description,Amount\n,,\n\n", wantErr: "no data rows"},
+		{name: "layout without a day", profile: profile("MM/YYYY"), input: "Date,Description,Amount\n06/2025,A,-1\n", wantErr: "must contain day, month and year"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseCSV(strings.NewReader(tt.input), tt.profile, "account-1", 0)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseCSV() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCSVAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		decimal string
+		want    float64
+		wantErr bool
+	}{
+		{value: "-12.50", decimal: ".", want: -12.5},
+		{value: "(12.50)", decimal: ".", want: -12.5},
+		{value: "12.50-", decimal: ".", want: -12.5},
+		{value: "12.50 DR", decimal: ".", want: -12.5},
+		{value: "12.50cr", decimal: ".", want: 12.5},
+		{value: "£1,234.56", decimal: ".", want: 1234.56},
+		{value: "1.234,56", decimal: ",", want: 1234.56},
+		{value: "1 234,56 EUR", decimal: ",", want: 1234.56},
+		{value: "1'234.56", decimal: ".", want: 1234.56},
+		{value: "", decimal: ".", want: 0},
+		{value: "abc", decimal: ".", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseCSVAmount(tt.value, tt.decimal)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseCSVAmount(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("parseCSVAmount(%q, %q) = %v, %v, want %v", tt.value, tt.decimal, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVDateLayout(t *testing.T) {
+	tests := []struct {
+		layout  string
+		want    string
+		wantErr bool
+	}{
+		{layout: "DD/MM/YYYY", want: "2/1/2006"},
+		{layout: "YYYY-MM-DD", want: "2006-1-2"},
+		{layout: "D MMM YY", want: "2 Jan 06"},
+		{layout: "MMMM D, YYYY", want: "January 2, 2006"},
+		{layout: "MM/YYYY", wantErr: true},
+		{layout: " ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			got, err := csvDateLayout(tt.layout)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("csvDateLayout(%q) = %q, %v, want %q", tt.layout, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportProfileValidate(t *testing.T) {
+	column := func(i int) *int { return &i }
+	valid := func() ImportProfile {
+		return ImportProfile{
+			Delimiter: ";", DateLayout: "DD.MM.YYYY", DecimalSeparator: ",", SignConvention: "signed",
+			Columns: CSVColumnMapping{Date: 0, Description: 1, Amount: column(2)},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(p *ImportProfile)
+		wantErr string
+	}{
+		{name: "valid", change: func(p *ImportProfile) {}},
+		{name: "debit and credit", change: func(p *ImportProfile) {
+			p.SignConvention, p.Columns.Debit, p.Columns.Credit = "debit_credit", column(2), column(3)
+		}},
+		{name: "long delimiter", change: func(p *ImportProfile) { p.Delimiter = ";;" }, wantErr: "single character"},
+		{name: "decimal separator", change: func(p *ImportProfile) { p.DecimalSeparator = "'" }, wantErr: "decimal separator must be"},
+		{name: "delimiter is the decimal separator", change: func(p *ImportProfile) { p.Delimiter = "," }, wantErr: "must differ"},
+		{name: "debit without credit", change: func(p *ImportProfile) {
+			p.SignConvention, p.Columns.Debit = "debit_credit", column(2)
+		}, wantErr: "debit and credit columns"},
+		{name: "no amount", change: func(p *ImportProfile) { p.Columns.Amount = nil }, wantErr: "amount column is required"},
+		{name: "unknown convention", change: func(p *ImportProfile) { p.SignConvention = "absolute" }, wantErr: "unknown sign convention"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := valid()
+			tt.change(&profile)
+			err := profile.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
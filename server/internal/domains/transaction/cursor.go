@@ -0,0 +1,108 @@
+package transaction
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ========================================
+// KEYSET PAGINATION
+// ========================================
+
+// transactionListOrder is the default order of transaction listings. The id
+// makes it total, so a cursor always points at exactly one position.
+const transactionListOrder = "transactions.transaction_date DESC, transactions.created_at DESC, transactions.id DESC"
+
+// transactionCursor is the position of a row in the date order of a listing,
+// newest first unless the listing is sorted ascending. A backward cursor points
+// at the first row of a page and reads the rows before it.
+type transactionCursor struct {
+	Date      string    `json:"d"`
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// encodeTransactionCursor returns the opaque cursor for the position of tx
+func encodeTransactionCursor(tx *Transaction, backward bool) string {
+	data, _ := json.Marshal(transactionCursor{
+		Date:      tx.TransactionDate.Format("2006-01-02"),
+		CreatedAt: tx.CreatedAt,
+		ID:        tx.ID,
+		Backward:  backward,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTransactionCursor parses a cursor returned by encodeTransactionCursor
+func decodeTransactionCursor(value string) (*transactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor transactionCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	if _, err := time.Parse("2006-01-02", cursor.Date); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// cursorRead returns the filter ordering the rows read after cursor and the
+// keyset comparison selecting them. Reads go towards older rows on a
+// newest-first listing; ascending listings and backward cursors flip both.
+func (f TransactionFilter) cursorRead(cursor *transactionCursor) (TransactionFilter, string) {
+	readFilter := f
+	readFilter.SortOrder = "desc"
+	if f.sortDescending() == cursor.Backward {
+		readFilter.SortOrder = "asc"
+	}
+	if readFilter.SortOrder == "asc" {
+		return readFilter, ">"
+	}
+	return readFilter, "<"
+}
+
+// pageCursors returns the cursors to the pages after and before the rows of a
+// page, empty where there is none. page is in listing order, hasMore tells
+// whether the read found a row past it and offset is the position of a
+// page-number read.
+func pageCursors(page []Transaction, cursor *transactionCursor, hasMore bool, offset int) (next, prev string) {
+	if len(page) == 0 {
+		return "", ""
+	}
+	backward := cursor != nil && cursor.Backward
+	// A backward page always has rows after it: the page it was read from
+	if backward || hasMore {
+		next = encodeTransactionCursor(&page[len(page)-1], false)
+	}
+	if (backward && hasMore) || (!backward && (cursor != nil || offset > 0)) {
+		prev = encodeTransactionCursor(&page[0], true)
+	}
+	return next, prev
+}
+
+// includeTotal reports whether the matching rows should be counted
+func (f TransactionFilter) includeTotal() bool {
+	return f.IncludeTotal == nil || *f.IncludeTotal
+}
+
+// sortedByDate reports whether the listing uses the date order cursors are keyed on
+func (f TransactionFilter) sortedByDate() bool {
+	return f.SortBy == "" || f.SortBy == TransactionSortDate
+}
+
+// sortDescending reports the sort direction. Merchants default to A-Z,
+// everything else to newest or largest first.
+func (f TransactionFilter) sortDescending() bool {
+	if f.SortOrder == "" {
+		return f.SortBy != TransactionSortMerchant
+	}
+	return f.SortOrder == "desc"
+}
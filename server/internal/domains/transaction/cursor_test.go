@@ -0,0 +1,268 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestTransactionCursorRoundTrip(t *testing.T) {
+	tx := &Transaction{
+		ID:              uuid.New(),
+		TransactionDate: time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC),
+		CreatedAt:       time.Date(2025, 6, 3, 10, 11, 12, 123456000, time.UTC),
+	}
+
+	for _, backward := range []bool{false, true} {
+		cursor, err := decodeTransactionCursor(encodeTransactionCursor(tx, backward))
+		if err != nil {
+			t.Fatalf("decodeTransactionCursor() error = %v", err)
+		}
+		if cursor.Date != "2025-06-02" || !cursor.CreatedAt.Equal(tx.CreatedAt) || cursor.ID != tx.ID || cursor.Backward != backward {
+			t.Errorf("cursor = %+v, want the position of %v", cursor, tx.ID)
+		}
+	}
+}
+
+func TestDecodeTransactionCursorErrors(t *testing.T) {
+	encode := func(value string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not base64", value: "***"},
+		{name: "not JSON", value: encode("hello")},
+		{name: "bad date", value: encode(`{"d":"2025-13-01","c":"2025-06-03T10:00:00Z","i":"` + uuid.NewString() + `"}`)},
+		{name: "missing date", value: encode(`{"c":"2025-06-03T10:00:00Z","i":"` + uuid.NewString() + `"}`)},
+		{name: "bad id", value: encode(`{"d":"2025-06-01","c":"2025-06-03T10:00:00Z","i":"x"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodeTransactionCursor(tt.value); err == nil {
+				t.Errorf("decodeTransactionCursor(%q) = %+v, want an error", tt.value, cursor)
+			}
+		})
+	}
+}
+
+func TestCursorRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		sortOrder  string
+		backward   bool
+		wantOrder  string
+		comparison string
+	}{
+		{name: "newest first, forward", wantOrder: "desc", comparison: "<"},
+		{name: "newest first, backward", backward: true, wantOrder: "asc", comparison: ">"},
+		{name: "oldest first, forward", sortOrder: "asc", wantOrder: "asc", comparison: ">"},
+		{name: "oldest first, backward", sortOrder: "asc", backward: true, wantOrder: "desc", comparison: "<"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := TransactionFilter{SortOrder: tt.sortOrder}
+			readFilter, comparison := filter.cursorRead(&transactionCursor{Backward: tt.backward})
+			if readFilter.SortOrder != tt.wantOrder || comparison != tt.comparison {
+				t.Errorf("cursorRead() = %s %s, want %s %s", readFilter.SortOrder, comparison, tt.wantOrder, tt.comparison)
+			}
+		})
+	}
+}
+
+func TestPageCursors(t *testing.T) {
+	page := []Transaction{{ID: uuid.New()}, {ID: uuid.New()}}
+	forward := &transactionCursor{}
+	backward := &transactionCursor{Backward: true}
+
+	tests := []struct {
+		name     string
+		page     []Transaction
+		cursor   *transactionCursor
+		hasMore  bool
+		offset   int
+		wantNext bool
+		wantPrev bool
+	}{
+		{name: "first page with more", page: page, hasMore: true, wantNext: true},
+		{name: "only page", page: page},
+		{name: "last numbered page", page: page, offset: 20, wantPrev: true},
+		{name: "forward cursor with more", page: page, cursor: forward, hasMore: true, wantNext: true, wantPrev: true},
+		{name: "forward cursor at the end", page: page, cursor: forward, wantPrev: true},
+		{name: "backward cursor with more", page: page, cursor: backward, hasMore: true, wantNext: true, wantPrev: true},
+		{name: "backward cursor at the start", page: page, cursor: backward, wantNext: true},
+		{name: "empty page", cursor: forward, hasMore: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, prev := pageCursors(tt.page, tt.cursor, tt.hasMore, tt.offset)
+			if (next != "") != tt.wantNext || (prev != "") != tt.wantPrev {
+				t.Fatalf("pageCursors() next=%t prev=%t, want next=%t prev=%t", next != "", prev != "", tt.wantNext, tt.wantPrev)
+			}
+			if next != "" {
+				if cursor, _ := decodeTransactionCursor(next); cursor.ID != tt.page[len(tt.page)-1].ID || cursor.Backward {
+					t.Errorf("next cursor = %+v, want forward from the last row", cursor)
+				}
+			}
+			if prev != "" {
+				if cursor, _ := decodeTransactionCursor(prev); cursor.ID != tt.page[0].ID || !cursor.Backward {
+					t.Errorf("prev cursor = %+v, want backward from the first row", cursor)
+				}
+			}
+		})
+	}
+}
+
+// readKeysetPage pages rows in memory the way GetTransactions pages them in SQL
+func readKeysetPage(rows []Transaction, filter TransactionFilter, limit int) ([]Transaction, string, string) {
+	compare := func(a, b Transaction) int {
+		if c := a.TransactionDate.Compare(b.TransactionDate); c != 0 {
+			return c
+		}
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return bytes.Compare(a.ID[:], b.ID[:])
+	}
+	sorted := func(rows []Transaction, descending bool) []Transaction {
+		rows = slices.Clone(rows)
+		slices.SortFunc(rows, compare)
+		if descending {
+			slices.Reverse(rows)
+		}
+		return rows
+	}
+
+	var read []Transaction
+	if filter.cursor == nil {
+		read = sorted(rows, filter.sortDescending())
+	} else {
+		readFilter, comparison := filter.cursorRead(filter.cursor)
+		date, _ := time.Parse("2006-01-02", filter.cursor.Date)
+		position := Transaction{TransactionDate: date, CreatedAt: filter.cursor.CreatedAt, ID: filter.cursor.ID}
+		for _, row := range sorted(rows, readFilter.sortDescending()) {
+			if c := compare(row, position); (comparison == "<" && c < 0) || (comparison == ">" && c > 0) {
+				read = append(read, row)
+			}
+		}
+	}
+
+	hasMore := len(read) > limit
+	if hasMore {
+		read = read[:limit]
+	}
+	if filter.cursor != nil && filter.cursor.Backward {
+		slices.Reverse(read)
+	}
+	next, prev := pageCursors(read, filter.cursor, hasMore, 0)
+	return read, next, prev
+}
+
+func TestKeysetPagingWalksEveryRow(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	var rows []Transaction
+	for i := 0; i < 11; i++ {
+		// Shared dates and creation times leave the id to break ties
+		rows = append(rows, Transaction{
+			ID:              uuid.New(),
+			TransactionDate: start.AddDate(0, 0, i/3),
+			CreatedAt:       created.Add(time.Duration(i%2) * time.Minute),
+		})
+	}
+
+	for _, sortOrder := range []string{"desc", "asc"} {
+		t.Run(sortOrder, func(t *testing.T) {
+			filter := TransactionFilter{SortOrder: sortOrder}
+			all, _, _ := readKeysetPage(rows, filter, len(rows))
+
+			var pages [][]Transaction
+			page, next, _ := readKeysetPage(rows, filter, 4)
+			pages = append(pages, page)
+			for next != "" {
+				filter.cursor, _ = decodeTransactionCursor(next)
+				page, next, _ = readKeysetPage(rows, filter, 4)
+				pages = append(pages, page)
+			}
+			if got := slices.Concat(pages...); !slices.EqualFunc(got, all, func(a, b Transaction) bool { return a.ID == b.ID }) {
+				t.Fatalf("forward walk returned %d rows out of order or incomplete, want %d", len(got), len(all))
+			}
+
+			// Walk back from the last page and meet every earlier page again
+			_, _, prev := readKeysetPage(rows, filter, 4)
+			for i := len(pages) - 2; i >= 0; i-- {
+				if prev == "" {
+					t.Fatalf("no prev cursor before page %d", i)
+				}
+				filter.cursor, _ = decodeTransactionCursor(prev)
+				page, _, prev = readKeysetPage(rows, filter, 4)
+				if !slices.EqualFunc(page, pages[i], func(a, b Transaction) bool { return a.ID == b.ID }) {
+					t.Fatalf("backward page %d differs from the forward one", i)
+				}
+			}
+			if prev != "" {
+				t.Errorf("first page read backward still has a prev cursor")
+			}
+		})
+	}
+}
+
+// cursorRepository records the filter the service passes on
+type cursorRepository struct {
+	Repository
+	filter TransactionFilter
+}
+
+func (r *cursorRepository) GetTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*TransactionListResponse, error) {
+	r.filter = filter
+	return &TransactionListResponse{}, nil
+}
+
+func TestGetTransactionsCursorValidation(t *testing.T) {
+	valid := encodeTransactionCursor(&Transaction{ID: uuid.New(), TransactionDate: time.Now()}, false)
+
+	tests := []struct {
+		name    string
+		filter  TransactionFilter
+		wantErr string
+	}{
+		{name: "cursor with the date sort", filter: TransactionFilter{Cursor: valid, SortBy: TransactionSortDate}},
+		{name: "cursor with the default sort", filter: TransactionFilter{Cursor: valid}},
+		{name: "cursor with another sort", filter: TransactionFilter{Cursor: valid, SortBy: TransactionSortAmount}, wantErr: "needs the date sort"},
+		{name: "invalid cursor", filter: TransactionFilter{Cursor: "nope"}, wantErr: "Invalid pagination cursor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &cursorRepository{}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+			_, err := service.GetTransactions(context.Background(), uuid.New(), tt.filter)
+			if tt.wantErr != "" {
+				appErr, ok := err.(*customerrors.AppError)
+				if !ok || appErr.Code != customerrors.ErrCodeValidation || !strings.Contains(appErr.Message, tt.wantErr) {
+					t.Fatalf("GetTransactions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTransactions() error = %v", err)
+			}
+			if repo.filter.cursor == nil {
+				t.Errorf("GetTransactions() passed no decoded cursor to the repository")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package transaction
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+
+	"hi-cfo/server/internal/domains/category"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// DUPLICATE REVIEW
+// ========================================
+
+// GetDuplicateCandidates lists the transactions flagged as possible duplicates,
+// each next to the transaction it was matched against on import
+func (s *TransactionService) GetDuplicateCandidates(ctx context.Context, userID uuid.UUID, filter DuplicateFilter) (*DuplicateListResponse, error) {
+	transactions, total, err := s.repo.GetDuplicateCandidates(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	originalIDs := make([]uuid.UUID, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.DuplicateOfID != nil {
+			originalIDs = append(originalIDs, *tx.DuplicateOfID)
+		}
+	}
+	originals, err := s.repo.GetTransactionsByIDs(ctx, userID, originalIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := &category.JaccardMatcher{}
+	candidates := make([]DuplicateCandidate, len(transactions))
+	for i := range transactions {
+		tx := &transactions[i]
+		candidates[i] = DuplicateCandidate{Transaction: tx.ToListItem()}
+		if tx.DuplicateOfID == nil {
+			continue
+		}
+		if original := originals[*tx.DuplicateOfID]; original != nil {
+			item := original.ToListItem()
+			candidates[i].Original = &item
+			candidates[i].Similarity = matcher.CalculateSimilarity(strings.TrimSpace(tx.Description), strings.TrimSpace(original.Description))
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"count":   len(candidates),
+		"total":   total,
+	}).Debug("Retrieved duplicate candidates")
+
+	return &DuplicateListResponse{
+		Data:  candidates,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// ResolveDuplicate applies the user's decision to a flagged duplicate and
+// returns the transaction that remains, or nil when the duplicate was deleted
+func (s *TransactionService) ResolveDuplicate(ctx context.Context, userID, transactionID uuid.UUID, action string) (*Transaction, error) {
+	duplicate, err := s.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if !duplicate.IsDuplicate || !duplicate.NeedsReview {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Transaction is not flagged as a possible duplicate").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+
+	logFields := logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"action":         action,
+	}
+
+	switch action {
+	case DuplicateKeepBoth:
+		resolved, err := s.repo.UpdateTransaction(ctx, userID, transactionID, map[string]any{
+			"is_duplicate":    false,
+			"needs_review":    false,
+			"duplicate_of_id": nil,
+		}, ChangeSourceAPI)
+		if err != nil {
+			return nil, err
+		}
+		// Flagged duplicates are left out of the balance until kept
+		s.refreshBalances(ctx, userID, resolved.AccountID)
+		s.logger.WithFields(logFields).Info("Duplicate kept as a separate transaction")
+		return s.GetTransactionByID(ctx, userID, transactionID)
+
+	case DuplicateDelete:
+		if err := s.repo.DeleteTransaction(ctx, userID, transactionID); err != nil {
+			return nil, err
+		}
+		s.logger.WithFields(logFields).Info("Duplicate transaction deleted")
+		return nil, nil
+
+	case DuplicateMerge:
+		var original *Transaction
+		if duplicate.DuplicateOfID != nil {
+			originals, err := s.repo.GetTransactionsByIDs(ctx, userID, []uuid.UUID{*duplicate.DuplicateOfID})
+			if err != nil {
+				return nil, err
+			}
+			original = originals[*duplicate.DuplicateOfID]
+		}
+		if original == nil {
+			return nil, customerrors.New(customerrors.ErrCodeConflict, "The matched transaction no longer exists, keep or delete the duplicate instead").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("transaction_id", transactionID)
+		}
+		if err := checkUnlocked(userID, original); err != nil {
+			return nil, err
+		}
+
+		updates := mergeDuplicateUpdates(original, duplicate)
+		if err := s.repo.MergeDuplicate(ctx, userID, duplicate.ID, original.ID, updates, ChangeSourceAPI); err != nil {
+			return nil, err
+		}
+		if balanceFieldsChanged(updates) {
+			s.refreshBalances(ctx, userID, original.AccountID)
+		}
+		logFields["original_id"] = original.ID
+		s.logger.WithFields(logFields).Info("Duplicate merged into original transaction")
+		return s.GetTransactionByID(ctx, userID, original.ID)
+
+	default:
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Unknown duplicate resolution").
+			WithDomain("transaction").
+			WithDetail("action", action)
+	}
+}
+
+// mergeDuplicateUpdates copies the details the duplicate has and the original
+// lacks. The original's own values always win; tags are combined.
+func mergeDuplicateUpdates(original, duplicate *Transaction) map[string]any {
+	updates := make(map[string]any)
+
+	if original.CategoryID == nil && duplicate.CategoryID != nil {
+		updates["category_id"] = *duplicate.CategoryID
+	}
+	if original.FitID == nil && duplicate.FitID != nil {
+		updates["fit_id"] = *duplicate.FitID
+	}
+	if original.PostedDate == nil && duplicate.PostedDate != nil {
+		updates["posted_date"] = *duplicate.PostedDate
+	}
+	if original.MerchantName == nil && duplicate.MerchantName != nil {
+		updates["merchant_name"] = *duplicate.MerchantName
+	}
+	if original.ReferenceNumber == nil && duplicate.ReferenceNumber != nil {
+		updates["reference_number"] = *duplicate.ReferenceNumber
+	}
+	if original.Memo == nil && duplicate.Memo != nil {
+		updates["memo"] = *duplicate.Memo
+	}
+	if original.UserDescription == nil && duplicate.UserDescription != nil {
+		updates["user_description"] = *duplicate.UserDescription
+	}
+	if original.UserNotes == nil && duplicate.UserNotes != nil {
+		updates["user_notes"] = *duplicate.UserNotes
+	}
+
+	tags := slices.Clone([]string(original.Tags))
+	for _, tag := range duplicate.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > len(original.Tags) {
+		updates["tags"] = pq.StringArray(tags)
+	}
+
+	return updates
+}
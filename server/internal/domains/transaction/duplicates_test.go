@@ -0,0 +1,257 @@
+package transaction
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestAssignDuplicateMatches(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		scored []duplicateScore
+		want   map[int]uuid.UUID
+	}{
+		{
+			name: "most similar original wins",
+			scored: []duplicateScore{
+				{idx: 0, originalID: a, similarity: 0.8},
+				{idx: 0, originalID: b, similarity: 1},
+			},
+			want: map[int]uuid.UUID{0: b},
+		},
+		{
+			name: "equal similarity goes to the closest date",
+			scored: []duplicateScore{
+				{idx: 0, originalID: a, similarity: 1, dayDiff: 2},
+				{idx: 0, originalID: b, similarity: 1, dayDiff: 1},
+			},
+			want: map[int]uuid.UUID{0: b},
+		},
+		{
+			name: "the weaker row falls back to its next original",
+			scored: []duplicateScore{
+				{idx: 0, originalID: a, similarity: 0.9},
+				{idx: 0, originalID: b, similarity: 0.7},
+				{idx: 1, originalID: a, similarity: 1},
+			},
+			want: map[int]uuid.UUID{0: b, 1: a},
+		},
+		{
+			name: "two identical purchases pair with two originals",
+			scored: []duplicateScore{
+				{idx: 0, originalID: a, similarity: 1},
+				{idx: 0, originalID: b, similarity: 1},
+				{idx: 1, originalID: a, similarity: 1},
+				{idx: 1, originalID: b, similarity: 1},
+			},
+			want: map[int]uuid.UUID{0: a, 1: b},
+		},
+		{
+			name: "an original is used once",
+			scored: []duplicateScore{
+				{idx: 0, originalID: c, similarity: 1},
+				{idx: 1, originalID: c, similarity: 1},
+			},
+			want: map[int]uuid.UUID{0: c},
+		},
+		{
+			name: "nothing scored",
+			want: map[int]uuid.UUID{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignDuplicateMatches(tt.scored); !maps.Equal(got, tt.want) {
+				t.Errorf("assignDuplicateMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchWithinBatch(t *testing.T) {
+	type row struct {
+		account     string
+		day         int
+		amount      float64
+		description string
+		flagged     bool // Already matched against the database
+	}
+
+	cfg := DuplicateMatchConfig{DateWindowDays: 3, AmountTolerance: 0.01, MinSimilarity: 0.6}
+
+	tests := []struct {
+		name string
+		rows []row
+		want map[int]int // Row index to the index of its original
+	}{
+		{
+			name: "a repeated row",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 1, -5, "COFFEE SHOP", false}},
+			want: map[int]int{1: 0},
+		},
+		{
+			name: "each original once, flagged rows are not originals",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 0, -5, "COFFEE SHOP", false}, {"a", 0, -5, "COFFEE SHOP", false}},
+			want: map[int]int{1: 0},
+		},
+		{
+			name: "rows matched in the database are left alone",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", true}, {"a", 0, -5, "COFFEE SHOP", false}},
+			want: map[int]int{},
+		},
+		{
+			name: "other account",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"b", 0, -5, "COFFEE SHOP", false}},
+			want: map[int]int{},
+		},
+		{
+			name: "outside the date window",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 4, -5, "COFFEE SHOP", false}},
+			want: map[int]int{},
+		},
+		{
+			name: "outside the amount tolerance",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 0, -5.02, "COFFEE SHOP", false}},
+			want: map[int]int{},
+		},
+		{
+			name: "different description",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 0, -5, "BOOK STORE", false}},
+			want: map[int]int{},
+		},
+		{
+			name: "most similar earlier row",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP LONDON", false}, {"a", 4, -5, "COFFEE SHOP", false}, {"a", 2, -5, "COFFEE SHOP", false}},
+			want: map[int]int{2: 1},
+		},
+		{
+			name: "equal similarity goes to the closest date",
+			rows: []row{{"a", 0, -5, "COFFEE SHOP", false}, {"a", 4, -5, "COFFEE SHOP", false}, {"a", 3, -5, "COFFEE SHOP", false}},
+			want: map[int]int{2: 1},
+		},
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	accounts := map[string]uuid.UUID{"a": uuid.New(), "b": uuid.New()}
+	existing := uuid.New()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions := make([]*Transaction, len(tt.rows))
+			matches := make(map[int]uuid.UUID)
+			for i, r := range tt.rows {
+				transactions[i] = &Transaction{
+					AccountID:       accounts[r.account],
+					TransactionDate: start.AddDate(0, 0, r.day),
+					Amount:          r.amount,
+					Description:     r.description,
+				}
+				if r.flagged {
+					matches[i] = existing
+				}
+			}
+
+			matchWithinBatch(transactions, matches, cfg)
+
+			for i, r := range tt.rows {
+				original, ok := tt.want[i]
+				switch {
+				case ok:
+					if transactions[original].ID == uuid.Nil || matches[i] != transactions[original].ID {
+						t.Errorf("row %d matched %v, want row %d", i, matches[i], original)
+					}
+				case r.flagged:
+					if matches[i] != existing {
+						t.Errorf("row %d lost its database match", i)
+					}
+				default:
+					if id, matched := matches[i]; matched {
+						t.Errorf("row %d matched %v, want no match", i, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitKnownFitIDs(t *testing.T) {
+	known := &Transaction{Description: "known", FitID: stringPtr(" F1 ")}
+	noFitID := &Transaction{Description: "no fitid"}
+	blank := &Transaction{Description: "blank fitid", FitID: stringPtr("  ")}
+	fresh := &Transaction{Description: "new fitid", FitID: stringPtr("F2")}
+	repeated := &Transaction{Description: "new fitid again", FitID: stringPtr("f2")}
+	existing := map[string]*Transaction{"f1": {}}
+
+	describe := func(transactions []*Transaction) []string {
+		descriptions := make([]string, len(transactions))
+		for i, tx := range transactions {
+			descriptions[i] = tx.Description
+		}
+		return descriptions
+	}
+
+	// Every order of the input comes back in that same order
+	for _, input := range [][]*Transaction{
+		{known, noFitID, blank, fresh, repeated},
+		{repeated, fresh, blank, noFitID, known},
+		{fresh, known, repeated, noFitID, blank},
+	} {
+		candidates, skipped := splitKnownFitIDs(input, existing)
+
+		var wantCandidates []*Transaction
+		for _, tx := range input {
+			if tx != known {
+				wantCandidates = append(wantCandidates, tx)
+			}
+		}
+		if got, want := describe(candidates), describe(wantCandidates); !slices.Equal(got, want) {
+			t.Errorf("candidates = %v, want %v", got, want)
+		}
+		if got := describe(skipped); !slices.Equal(got, []string{"known"}) {
+			t.Errorf("known = %v, want [known]", got)
+		}
+	}
+}
+
+func TestMergeDuplicateUpdates(t *testing.T) {
+	categoryA, categoryB := uuid.New(), uuid.New()
+
+	original := &Transaction{
+		CategoryID: &categoryA,
+		Memo:       stringPtr("mine"),
+		Tags:       pq.StringArray{"food"},
+	}
+	duplicate := &Transaction{
+		CategoryID:      &categoryB,
+		FitID:           stringPtr("F1"),
+		Memo:            stringPtr("theirs"),
+		ReferenceNumber: stringPtr("R1"),
+		Tags:            pq.StringArray{"food", "work"},
+	}
+
+	updates := mergeDuplicateUpdates(original, duplicate)
+	want := map[string]any{
+		"fit_id":           "F1",
+		"reference_number": "R1",
+	}
+	tags, ok := updates["tags"].(pq.StringArray)
+	if !ok || !slices.Equal(tags, pq.StringArray{"food", "work"}) {
+		t.Errorf("tags = %v, want [food work]", updates["tags"])
+	}
+	delete(updates, "tags")
+	if !maps.Equal(updates, want) {
+		t.Errorf("mergeDuplicateUpdates() = %v, want %v", updates, want)
+	}
+
+	if updates := mergeDuplicateUpdates(duplicate, duplicate); len(updates) != 0 {
+		t.Errorf("mergeDuplicateUpdates() of identical rows = %v, want none", updates)
+	}
+}
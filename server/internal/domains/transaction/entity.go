@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/domains/fileupload"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -22,20 +23,28 @@ type Transaction struct {
 	CategoryID       *uuid.UUID     `json:"category_id,omitempty" gorm:"type:uuid"`
 	FileUploadID     *uuid.UUID     `json:"file_upload_id,omitempty" gorm:"type:uuid"`
 	FitID            *string        `json:"fit_id,omitempty" gorm:"size:100;index"` // For OFX imports
-	TransactionDate  time.Time      `json:"transaction_date" gorm:"not null;index"`
-	PostedDate       *time.Time     `json:"posted_date,omitempty"`
+	TransactionDate  time.Time      `json:"transaction_date" gorm:"type:date;not null;index"`
+	PostedDate       *time.Time     `json:"posted_date,omitempty" gorm:"type:date"` // Date the bank posted the transaction, unset while pending
+	Status           string         `json:"status" gorm:"size:20;not null;default:'posted';check:status IN ('pending','posted')"`
 	Description      string         `json:"description" gorm:"not null"`
 	MerchantName     *string        `json:"merchant_name,omitempty" gorm:"size:200"`
+	MerchantID       *uuid.UUID     `json:"merchant_id,omitempty" gorm:"type:uuid;index"` // Directory merchant, MerchantName mirrors its name
 	Amount           float64        `json:"amount" gorm:"type:decimal(12,2);not null"`
 	TransactionType  string         `json:"transaction_type" gorm:"size:20;default:'expense';check:transaction_type IN ('income','expense','transfer','fee','interest','dividend','refund')"`
+	CounterpartID    *uuid.UUID     `json:"counterpart_id,omitempty" gorm:"type:uuid;index"` // Other side of a transfer between the user's own accounts
+	TypeBeforeLink   *string        `json:"-" gorm:"size:20"`                                // Transaction type to restore when the transfer is unlinked
 	Currency         string         `json:"currency" gorm:"size:3;default:'USD'"`
 	ReferenceNumber  *string        `json:"reference_number,omitempty" gorm:"size:100"`
 	Memo             *string        `json:"memo,omitempty"`
 	BalanceAfter     *float64       `json:"balance_after,omitempty" gorm:"type:decimal(12,2)"`
+	ReconciliationID *uuid.UUID     `json:"reconciliation_id,omitempty" gorm:"type:uuid;index"` // Reconciliation the transaction was cleared in
+	ReconciledAt     *time.Time     `json:"reconciled_at,omitempty"`                            // Set when that reconciliation is completed, locks the transaction
 	IsRecurring      bool           `json:"is_recurring" gorm:"default:false"`
 	RecurringPattern *string        `json:"recurring_pattern,omitempty" gorm:"size:50"`
+	RecurringID      *uuid.UUID     `json:"recurring_id,omitempty" gorm:"type:uuid;index"` // Accepted recurring series the transaction belongs to
 	Tags             pq.StringArray `json:"tags,omitempty" gorm:"type:text[]"`
 	IsDuplicate      bool           `json:"is_duplicate" gorm:"default:false"`
+	DuplicateOfID    *uuid.UUID     `json:"duplicate_of_id,omitempty" gorm:"type:uuid;index"` // Existing transaction a flagged duplicate was matched against
 	ConfidenceScore  *float64       `json:"confidence_score,omitempty" gorm:"type:decimal(3,2)"`
 	NeedsReview      bool           `json:"needs_review" gorm:"default:false"`
 	IsHidden         bool           `json:"is_hidden" gorm:"default:false"`
@@ -44,12 +53,21 @@ type Transaction struct {
 	CreatedAt        time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt        time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
+
+	Splits []TransactionSplit `json:"splits,omitempty" gorm:"foreignKey:TransactionID;constraint:OnDelete:CASCADE"` // Loaded for single transaction views
 }
 
 func (Transaction) TableName() string {
 	return "transactions"
 }
 
+// Transaction statuses. Pending card authorisations are kept out of balances
+// until the posted transaction replaces them.
+const (
+	TransactionStatusPending = "pending"
+	TransactionStatusPosted  = "posted"
+)
+
 // BeforeCreate GORM hook
 func (a *Transaction) BeforeCreate(tx *gorm.DB) error {
 	if a.ID == uuid.Nil {
@@ -72,11 +90,24 @@ type TransactionRequest struct {
 	Currency        string  `json:"currency" binding:"required"`
 
 	// Optional fields
+	Status          string   `json:"status,omitempty"`      // "pending" or "posted", the default
+	PostedDate      *string  `json:"posted_date,omitempty"` // Date the bank cleared the transaction
 	MerchantName    *string  `json:"merchant_name,omitempty"`
 	Memo            *string  `json:"memo,omitempty"`
 	Tags            []string `json:"tags,omitempty"`
 	ReferenceNumber *string  `json:"reference_number,omitempty"`
 	UserNotes       *string  `json:"user_notes,omitempty"`
+	CategoryName    *string  `json:"category_name,omitempty"` // Category hint from the source file, resolved by name when CategoryID is empty
+
+	// Split lines from the source file (e.g. QIF "S"/"$" lines), kept only when they add up to Amount
+	Splits []ImportSplitLine `json:"splits,omitempty"`
+}
+
+// ImportSplitLine - A split line read from an imported file
+type ImportSplitLine struct {
+	Amount       float64 `json:"amount"`
+	CategoryName *string `json:"category_name,omitempty"` // Resolved by name like TransactionRequest.CategoryName
+	Memo         *string `json:"memo,omitempty"`
 }
 
 // Container for batch operations
@@ -85,6 +116,14 @@ type BatchTransactionRequest struct {
 	Source       string               `json:"source,omitempty"` // "api", "csv", "ofx", "form"
 }
 
+// ProcessedSplit - An imported split line ready for the database
+type ProcessedSplit struct {
+	Amount       float64
+	CategoryID   *uuid.UUID
+	CategoryName *string
+	Memo         *string
+}
+
 // Intermediate processing model (service layer)
 type ProcessedTransaction struct {
 	// Parsed and validated fields ready for database
@@ -93,15 +132,19 @@ type ProcessedTransaction struct {
 	FitID           *string
 	FileUploadID    *uuid.UUID
 	TransactionDate time.Time
+	PostedDate      *time.Time
 	Description     string
 	Amount          float64
 	TransactionType string
 	Currency        string
+	Status          string
 	MerchantName    *string
+	MerchantID      *uuid.UUID
 	Memo            *string
 	Tags            []string
 	ReferenceNumber *string
 	UserNotes       *string
+	Splits          []ProcessedSplit
 
 	// Processing metadata
 	OriginalInput TransactionRequest `json:"-"`
@@ -116,6 +159,7 @@ type UpdateTransactionRequest struct {
 	Description     *string    `json:"description,omitempty"`
 	Amount          *float64   `json:"amount,omitempty"`
 	TransactionType *string    `json:"transaction_type,omitempty"`
+	Status          *string    `json:"status,omitempty" binding:"omitempty,oneof=pending posted"`
 	MerchantName    *string    `json:"merchant_name,omitempty"`
 	Memo            *string    `json:"memo,omitempty"`
 	Tags            []string   `json:"tags,omitempty"`
@@ -124,14 +168,408 @@ type UpdateTransactionRequest struct {
 
 // Single result type for all batch operations
 type BatchOperationResult struct {
-	Total        int         `json:"total"`
-	Created      int         `json:"created"`
-	Skipped      int         `json:"skipped"`
-	CreatedIDs   []uuid.UUID `json:"created_ids,omitempty"`
-	Duplicates   []string    `json:"duplicates,omitempty"` // FitIDs of duplicates
-	Errors       []string    `json:"errors,omitempty"`
-	Source       string      `json:"source,omitempty"` // Source that created this batch
-	FileUploadID *string     `json:"file_upload_id,omitempty"`
+	Total        int            `json:"total"`
+	Created      int            `json:"created"`
+	Skipped      int            `json:"skipped"`
+	CreatedIDs   []uuid.UUID    `json:"created_ids,omitempty"`
+	Duplicates   []string       `json:"duplicates,omitempty"`  // FitIDs of duplicates
+	Flagged      int            `json:"flagged"`               // Created rows flagged as possible duplicates for review
+	Transfers    int            `json:"transfers"`             // Created rows paired with a transfer counterpart
+	Matched      int            `json:"matched"`               // Posted rows that replaced a pending transaction instead of being created
+	MatchedIDs   []uuid.UUID    `json:"matched_ids,omitempty"` // The pending transactions that were posted
+	Errors       []string       `json:"errors,omitempty"`
+	Source       string         `json:"source,omitempty"` // Source that created this batch
+	FileUploadID *string        `json:"file_upload_id,omitempty"`
+	Statement    *StatementInfo `json:"statement,omitempty"` // Set for file imports
+}
+
+// StatementInfo - Metadata read from an imported statement file
+type StatementInfo struct {
+	Format           string        `json:"format"` // "ofx", "csv", ...
+	Filename         string        `json:"filename,omitempty"`
+	BankAccountID    string        `json:"bank_account_id,omitempty"`
+	Currency         string        `json:"currency,omitempty"`
+	StartDate        *time.Time    `json:"start_date,omitempty"`
+	EndDate          *time.Time    `json:"end_date,omitempty"`
+	OpeningBalance   *float64      `json:"opening_balance,omitempty"`
+	ClosingBalance   *float64      `json:"closing_balance,omitempty"`
+	BalanceDate      *time.Time    `json:"balance_date,omitempty"`
+	TransactionCount int           `json:"transaction_count"`
+	BalanceCheck     *BalanceCheck `json:"balance_check,omitempty"`
+}
+
+// BalanceCheck - Statement closing balance compared with the account balance
+// computed for the statement's balance date, or with Account.CurrentBalance
+// when the account has no opening balance
+type BalanceCheck struct {
+	StatementBalance float64    `json:"statement_balance"`
+	AccountBalance   *float64   `json:"account_balance"`        // nil when the account has no tracked balance
+	BalanceDate      *time.Time `json:"balance_date,omitempty"` // Day AccountBalance was computed for, unset for the current balance
+	Difference       *float64   `json:"difference,omitempty"`
+	Matches          bool       `json:"matches"`
+}
+
+// ImportOptions - Format specific settings for a file import. Queued imports
+// store it with the job, so it has to survive a JSON round trip.
+type ImportOptions struct {
+	Format    string         `json:"format"` // "ofx", "qif", "csv", "camt053", "mt940" or "json" (bulk batches)
+	Filename  string         `json:"filename"`
+	DateOrder string         `json:"date_order,omitempty"` // QIF only
+	Currency  string         `json:"currency,omitempty"`   // QIF only
+	Profile   *ImportProfile `json:"profile,omitempty"`    // CSV only
+}
+
+// ImportJobProgress - Row counts of a queued import, kept in Redis while it runs
+type ImportJobProgress struct {
+	TotalRows     int       `json:"total_rows"`
+	ProcessedRows int       `json:"processed_rows"`
+	Created       int       `json:"created"`
+	Skipped       int       `json:"skipped"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// ImportJobStatus - A queued import as reported by the job status endpoint
+type ImportJobStatus struct {
+	Upload          *fileupload.FileUpload `json:"upload"`
+	Progress        *ImportJobProgress     `json:"progress,omitempty"`
+	Percent         float64                `json:"percent"`
+	CancelRequested bool                   `json:"cancel_requested,omitempty"`
+}
+
+// RevertImportRequest - Options for rolling back an imported file
+type RevertImportRequest struct {
+	KeepEdited bool `json:"keep_edited"` // Leave rows changed since the import in place
+	DryRun     bool `json:"dry_run"`     // Only report what would be reverted
+}
+
+// ImportRevertResult - Outcome of reverting or restoring an imported file
+type ImportRevertResult struct {
+	FileUploadID uuid.UUID   `json:"file_upload_id"`
+	Status       string      `json:"status"` // Upload processing_status after the operation
+	Total        int         `json:"total"`  // Active transactions linked to the upload
+	Reverted     int         `json:"reverted"`
+	Restored     int         `json:"restored"`
+	Edited       int         `json:"edited"` // Rows changed since the import
+	EditedIDs    []uuid.UUID `json:"edited_ids,omitempty"`
+	Kept         int         `json:"kept"`
+	Locked       int         `json:"locked"` // Reconciled rows, always kept
+	DryRun       bool        `json:"dry_run,omitempty"`
+}
+
+// FileUploadTransactionRef - An active transaction created by an import
+type FileUploadTransactionRef struct {
+	ID         uuid.UUID
+	Edited     bool
+	Reconciled bool
+}
+
+// ========================================
+// EXPORT
+// ========================================
+
+// Formats supported by GET /transactions/export
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatOFX  = "ofx"
+	ExportFormatJSON = "json"
+	ExportFormatXLSX = "xlsx"
+)
+
+// TransactionExportRow - One exported transaction with category and account names resolved
+type TransactionExportRow struct {
+	ID              uuid.UUID      `json:"id"`
+	TransactionDate time.Time      `json:"transaction_date"`
+	PostedDate      *time.Time     `json:"posted_date,omitempty"`
+	AccountID       uuid.UUID      `json:"account_id"`
+	AccountName     string         `json:"account_name"`
+	Description     string         `json:"description"`
+	MerchantName    *string        `json:"merchant_name,omitempty"`
+	Amount          float64        `json:"amount"`
+	Currency        string         `json:"currency"`
+	TransactionType string         `json:"transaction_type"`
+	CategoryID      *uuid.UUID     `json:"category_id,omitempty"`
+	CategoryName    *string        `json:"category_name,omitempty"`
+	FitID           *string        `json:"fit_id,omitempty"`
+	ReferenceNumber *string        `json:"reference_number,omitempty"`
+	Memo            *string        `json:"memo,omitempty"`
+	Tags            pq.StringArray `json:"tags,omitempty" gorm:"type:text[]"`
+}
+
+// ========================================
+// PENDING TRANSACTIONS
+// ========================================
+
+// PendingMatchConfig - Tolerances for matching a posted transaction to the
+// pending authorisation it settles on the same account
+type PendingMatchConfig struct {
+	DateWindowDays  int     // Max days the posting can follow the authorisation
+	AmountTolerance float64 // Max amount difference as a fraction of the pending amount
+	MinSimilarity   float64 // Min description similarity, 0-1, when the merchants differ
+}
+
+// ========================================
+// DUPLICATE REVIEW
+// ========================================
+
+// DuplicateMatchConfig - Tolerances for flagging an incoming row as a possible
+// duplicate of an active transaction on the same account
+type DuplicateMatchConfig struct {
+	DateWindowDays  int     // Max days between the transaction dates
+	AmountTolerance float64 // Max absolute difference between the amounts
+	MinSimilarity   float64 // Min description similarity, 0-1
+}
+
+// Resolutions for a flagged duplicate
+const (
+	DuplicateKeepBoth = "keep_both" // Both are real transactions, clear the flag
+	DuplicateMerge    = "merge"     // Copy missing details onto the original and delete the duplicate
+	DuplicateDelete   = "delete"    // Delete the duplicate
+)
+
+// DuplicateFilter - Query parameters for the duplicate review queue
+type DuplicateFilter struct {
+	Page      int        `form:"page" binding:"min=1"`
+	Limit     int        `form:"limit" binding:"min=1,max=100"`
+	AccountID *uuid.UUID `form:"account_id"`
+}
+
+// DuplicateCandidate - A flagged transaction next to the one it was matched against
+type DuplicateCandidate struct {
+	Transaction TransactionListItem  `json:"transaction"`
+	Original    *TransactionListItem `json:"original,omitempty"` // nil once the original has been deleted
+	Similarity  float64              `json:"similarity"`         // Description similarity, 0-1
+}
+
+// ResolveDuplicateRequest - How to resolve a flagged duplicate
+type ResolveDuplicateRequest struct {
+	Action string `json:"action" binding:"required,oneof=keep_both merge delete"`
+}
+
+// ========================================
+// SPLITS
+// ========================================
+
+// TransactionSplit - One category line of a transaction split across several
+// categories. The lines of a transaction always sum to its amount.
+type TransactionSplit struct {
+	ID            uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey"`
+	TransactionID uuid.UUID      `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	CategoryID    *uuid.UUID     `json:"category_id,omitempty" gorm:"type:uuid;index"`
+	Amount        float64        `json:"amount" gorm:"type:decimal(12,2);not null"`
+	Memo          *string        `json:"memo,omitempty"`
+	Tags          pq.StringArray `json:"tags,omitempty" gorm:"type:text[]"`
+	CreatedAt     time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (TransactionSplit) TableName() string {
+	return "transaction_splits"
+}
+
+// BeforeCreate GORM hook
+func (s *TransactionSplit) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// SplitLineRequest - One line of a split
+type SplitLineRequest struct {
+	Amount     float64    `json:"amount" binding:"required"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Memo       *string    `json:"memo,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+}
+
+// SetSplitsRequest - Replaces all split lines of a transaction
+type SetSplitsRequest struct {
+	Splits []SplitLineRequest `json:"splits" binding:"required,min=2,dive"`
+}
+
+// ========================================
+// CHANGE HISTORY
+// ========================================
+
+// Change sources, what made a change to a transaction
+const (
+	ChangeSourceAPI             = "api"
+	ChangeSourceImport          = "import"
+	ChangeSourceRule            = "rule"
+	ChangeSourceAutoCategorizer = "auto_categorizer"
+	ChangeSourceRevert          = "revert"
+)
+
+// TransactionChange - One field changed on a transaction. All fields changed
+// by the same write share a version; version N is the transaction as it was
+// after that write, version 0 as it was before the first recorded change.
+type TransactionChange struct {
+	ID            uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey"`
+	TransactionID uuid.UUID   `json:"transaction_id" gorm:"type:uuid;not null;index:idx_transaction_changes_version,priority:1"`
+	UserID        uuid.UUID   `json:"user_id" gorm:"type:uuid;not null;index"`
+	Version       int         `json:"version" gorm:"not null;index:idx_transaction_changes_version,priority:2"`
+	Field         string      `json:"field" gorm:"size:50;not null"`
+	OldValue      ChangeValue `json:"old_value" gorm:"type:text"`
+	NewValue      ChangeValue `json:"new_value" gorm:"type:text"`
+	Source        string      `json:"source" gorm:"size:30;not null;check:source IN ('api','import','rule','auto_categorizer','revert')"`
+	ActorID       *uuid.UUID  `json:"actor_id,omitempty" gorm:"type:uuid"` // User who made the change, empty for automatic changes
+	CreatedAt     time.Time   `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (TransactionChange) TableName() string {
+	return "transaction_changes"
+}
+
+// BeforeCreate GORM hook
+func (c *TransactionChange) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// ChangeValue - A field value in the change history, stored as JSON text.
+// Empty means the field was not set.
+type ChangeValue string
+
+func (v ChangeValue) MarshalJSON() ([]byte, error) {
+	if v == "" {
+		return []byte("null"), nil
+	}
+	return []byte(v), nil
+}
+
+// TransactionHistory - Change history of one transaction, newest first
+type TransactionHistory struct {
+	TransactionID  uuid.UUID           `json:"transaction_id"`
+	CurrentVersion int                 `json:"current_version"`
+	Changes        []TransactionChange `json:"changes"`
+}
+
+// RevertTransactionRequest - Version to restore, 0 restores the transaction
+// as it was before its first recorded change
+type RevertTransactionRequest struct {
+	Version *int `json:"version" binding:"required,min=0"`
+}
+
+// ========================================
+// TRANSFERS
+// ========================================
+
+// TransferMatch - Opposite-sign transactions on two of the user's accounts
+// paired as one transfer
+type TransferMatch struct {
+	TransactionID uuid.UUID `json:"transaction_id"`
+	CounterpartID uuid.UUID `json:"counterpart_id"`
+}
+
+// LinkTransferRequest - Manually pair a transaction with the other side of a transfer
+type LinkTransferRequest struct {
+	CounterpartID uuid.UUID `json:"counterpart_id" binding:"required"`
+}
+
+// DetectTransfersRequest - Date range to scan for unpaired transfers, open ended when empty
+type DetectTransfersRequest struct {
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+}
+
+// TransferDetectionResult - Pairs linked by a detection run
+type TransferDetectionResult struct {
+	Linked int             `json:"linked"`
+	Pairs  []TransferMatch `json:"pairs"`
+}
+
+// ========================================
+// IMPORT PROFILES
+// ========================================
+
+// ImportProfile - Saved CSV layout for a bank export, per user and optionally per account
+type ImportProfile struct {
+	ID               uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index"`
+	AccountID        *uuid.UUID       `json:"account_id,omitempty" gorm:"type:uuid;index"` // NULL = usable for any account
+	Name             string           `json:"name" gorm:"size:100;not null"`
+	BankName         *string          `json:"bank_name,omitempty" gorm:"size:100"`
+	Delimiter        string           `json:"delimiter" gorm:"size:1;default:','"`
+	SkipRows         int              `json:"skip_rows" gorm:"default:1"`                  // Header rows to skip
+	DateLayout       string           `json:"date_layout" gorm:"size:30;not null"`         // e.g. "DD/MM/YYYY"
+	DecimalSeparator string           `json:"decimal_separator" gorm:"size:1;default:'.'"` // "." or ","
+	SignConvention   string           `json:"sign_convention" gorm:"size:20;default:'signed';check:sign_convention IN ('signed','inverted','debit_credit')"`
+	DefaultCurrency  string           `json:"default_currency" gorm:"size:3;default:'USD'"`
+	Columns          CSVColumnMapping `json:"columns" gorm:"embedded;embeddedPrefix:col_"`
+	CreatedAt        time.Time        `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time        `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        gorm.DeletedAt   `json:"-" gorm:"index"`
+}
+
+func (ImportProfile) TableName() string {
+	return "import_profiles"
+}
+
+// BeforeCreate GORM hook
+func (p *ImportProfile) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// CSVColumnMapping - Zero-based column positions in the bank export
+type CSVColumnMapping struct {
+	Date        int  `json:"date" binding:"min=0"`
+	Description int  `json:"description" binding:"min=0"`
+	Amount      *int `json:"amount,omitempty" binding:"omitempty,min=0"` // "signed" and "inverted" conventions
+	Debit       *int `json:"debit,omitempty" binding:"omitempty,min=0"`  // "debit_credit" convention
+	Credit      *int `json:"credit,omitempty" binding:"omitempty,min=0"` // "debit_credit" convention
+	Merchant    *int `json:"merchant,omitempty" binding:"omitempty,min=0"`
+	Memo        *int `json:"memo,omitempty" binding:"omitempty,min=0"`
+	Reference   *int `json:"reference,omitempty" binding:"omitempty,min=0"`
+	Currency    *int `json:"currency,omitempty" binding:"omitempty,min=0"`
+}
+
+type CreateImportProfileRequest struct {
+	AccountID        *uuid.UUID       `json:"account_id,omitempty"`
+	Name             string           `json:"name" binding:"required,min=1,max=100"`
+	BankName         *string          `json:"bank_name,omitempty" binding:"omitempty,max=100"`
+	Delimiter        string           `json:"delimiter" binding:"omitempty,len=1"`
+	SkipRows         *int             `json:"skip_rows,omitempty" binding:"omitempty,min=0,max=50"`
+	DateLayout       string           `json:"date_layout" binding:"required,max=30"`
+	DecimalSeparator string           `json:"decimal_separator" binding:"omitempty,oneof=. ,"`
+	SignConvention   string           `json:"sign_convention" binding:"omitempty,oneof=signed inverted debit_credit"`
+	DefaultCurrency  string           `json:"default_currency" binding:"omitempty,len=3"`
+	Columns          CSVColumnMapping `json:"columns" binding:"required"`
+}
+
+type UpdateImportProfileRequest struct {
+	AccountID        *uuid.UUID        `json:"account_id,omitempty"`
+	ClearAccount     bool              `json:"clear_account,omitempty"` // Make the profile account-agnostic
+	Name             *string           `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	BankName         *string           `json:"bank_name,omitempty" binding:"omitempty,max=100"`
+	Delimiter        *string           `json:"delimiter,omitempty" binding:"omitempty,len=1"`
+	SkipRows         *int              `json:"skip_rows,omitempty" binding:"omitempty,min=0,max=50"`
+	DateLayout       *string           `json:"date_layout,omitempty" binding:"omitempty,max=30"`
+	DecimalSeparator *string           `json:"decimal_separator,omitempty" binding:"omitempty,oneof=. ,"`
+	SignConvention   *string           `json:"sign_convention,omitempty" binding:"omitempty,oneof=signed inverted debit_credit"`
+	DefaultCurrency  *string           `json:"default_currency,omitempty" binding:"omitempty,len=3"`
+	Columns          *CSVColumnMapping `json:"columns,omitempty"`
+}
+
+// CSVImportPreview - First N parsed rows of a CSV file, nothing is persisted
+type CSVImportPreview struct {
+	ProfileID *uuid.UUID     `json:"profile_id,omitempty"`
+	TotalRows int            `json:"total_rows"`
+	ValidRows int            `json:"valid_rows"`
+	Rows      []CSVParsedRow `json:"rows"`
+}
+
+// CSVParsedRow - One data row mapped to the unified input, or the reason it could not be
+type CSVParsedRow struct {
+	Row         int                 `json:"row"` // 1-based line number in the file
+	Raw         []string            `json:"raw"`
+	Transaction *TransactionRequest `json:"transaction,omitempty"`
+	Error       string              `json:"error,omitempty"`
 }
 
 // ========================================
@@ -148,6 +586,8 @@ type TransactionView struct {
 	MerchantName    *string    `json:"merchant_name,omitempty"`
 	Amount          float64    `json:"amount"`
 	TransactionType string     `json:"transaction_type"`
+	Status          string     `json:"status"`
+	CounterpartID   *uuid.UUID `json:"counterpart_id,omitempty"`
 	Currency        string     `json:"currency"`
 	Tags            []string   `json:"tags,omitempty"`
 }
@@ -214,33 +654,322 @@ type CategorizationAnalysis struct {
 // RESPONSE CONTAINERS
 // ========================================
 
+// PaginatedResponse is one page of a list. Total and Pages are -1 when the
+// count was skipped, Page is 0 for pages fetched by cursor.
 type PaginatedResponse[T any] struct {
-	Data  []T   `json:"data"`
-	Total int64 `json:"total"`
-	Page  int   `json:"page"`
-	Limit int   `json:"limit"`
-	Pages int   `json:"pages"`
+	Data       []T    `json:"data"`
+	Total      int64  `json:"total"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Pages      int    `json:"pages"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 // Specific response types
 type TransactionListResponse = PaginatedResponse[TransactionListItem]
+type DuplicateListResponse = PaginatedResponse[DuplicateCandidate]
+type TransactionSearchResponse = PaginatedResponse[TransactionSearchResult]
+type DeletedTransactionResponse = PaginatedResponse[DeletedTransaction]
+
+// ========================================
+// BULK EDIT
+// ========================================
+
+// BulkTransactionChanges are the fields a bulk edit can change, unset fields are left alone
+type BulkTransactionChanges struct {
+	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
+	ClearCategory bool       `json:"clear_category,omitempty"` // Remove the category instead of setting one
+	AddTags       []string   `json:"add_tags,omitempty"`
+	RemoveTags    []string   `json:"remove_tags,omitempty"`
+	IsHidden      *bool      `json:"is_hidden,omitempty"`
+	NeedsReview   *bool      `json:"needs_review,omitempty"`
+	UserNotes     *string    `json:"user_notes,omitempty"` // Empty string clears the notes
+}
+
+// BulkUpdateRequest edits the listed transactions, or every transaction
+// matching the query string filter when IDs is empty
+type BulkUpdateRequest struct {
+	IDs     []uuid.UUID            `json:"ids,omitempty"`
+	Changes BulkTransactionChanges `json:"changes"`
+	DryRun  bool                   `json:"dry_run"`
+}
+
+type BulkUpdateResult struct {
+	Count   int         `json:"count"`
+	IDs     []uuid.UUID `json:"ids"`
+	Missing []uuid.UUID `json:"missing,omitempty"` // Requested IDs that were not found
+	DryRun  bool        `json:"dry_run"`
+}
+
+// ========================================
+// SEARCH
+// ========================================
+
+// TransactionSearchResult is a transaction found by full-text search
+type TransactionSearchResult struct {
+	TransactionListItem
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"` // HTML-escaped text with matches wrapped in <mark> tags
+}
+
+// ========================================
+// TRASH
+// ========================================
+
+// TrashFilter pages through the user's soft-deleted transactions
+type TrashFilter struct {
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+// DeletedTransaction is a soft-deleted transaction waiting in the trash
+type DeletedTransaction struct {
+	Transaction
+	PurgeAt *time.Time `json:"purge_at,omitempty"` // When the retention job removes it, unset if trash is kept forever
+}
+
+// ========================================
+// BALANCES
+// ========================================
+
+// DailyBalanceChange - Net amount booked on an account on one day
+type DailyBalanceChange struct {
+	Date             time.Time
+	Change           float64
+	TransactionCount int64
+}
+
+// ========================================
+// ATTACHMENTS
+// ========================================
+
+// TransactionAttachment - A receipt or document uploaded for a transaction.
+// The file itself lives in attachment storage under StorageKey, its
+// thumbnail, if any, under StorageKey with a ".thumb" suffix.
+type TransactionAttachment struct {
+	ID            uuid.UUID `json:"id" gorm:"type:uuid;primaryKey"`
+	TransactionID uuid.UUID `json:"transaction_id" gorm:"type:uuid;not null;index"`
+	UserID        uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Filename      string    `json:"filename" gorm:"size:255;not null"`
+	ContentType   string    `json:"content_type" gorm:"size:100;not null"` // Sniffed from the content, not taken from the upload
+	SizeBytes     int64     `json:"size_bytes" gorm:"not null"`
+	Checksum      string    `json:"checksum" gorm:"size:64;not null"` // Hex SHA-256 of the content
+	StorageKey    string    `json:"-" gorm:"size:500;not null"`
+	HasThumbnail  bool      `json:"has_thumbnail" gorm:"default:false"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (TransactionAttachment) TableName() string {
+	return "transaction_attachments"
+}
+
+// BeforeCreate GORM hook
+func (a *TransactionAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// ThumbnailKey is where the attachment's thumbnail is stored
+func (a *TransactionAttachment) ThumbnailKey() string {
+	return a.StorageKey + ".thumb"
+}
+
+// ========================================
+// RECONCILIATION
+// ========================================
+
+// Reconciliation statuses
+const (
+	ReconciliationInProgress = "in_progress"
+	ReconciliationCompleted  = "completed"
+)
+
+// Reconciliation - An account checked against one bank statement. Transactions
+// are cleared into it while it is in progress and locked when it is completed.
+type Reconciliation struct {
+	ID               uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID           uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	AccountID        uuid.UUID  `json:"account_id" gorm:"type:uuid;not null;index;uniqueIndex:idx_account_reconciliations_open,where:status = 'in_progress'"` // One open reconciliation per account
+	StatementEndDate time.Time  `json:"statement_end_date" gorm:"type:date;not null"`
+	StartingBalance  float64    `json:"starting_balance" gorm:"type:decimal(12,2);not null"` // Closing balance of the previous reconciliation
+	ClosingBalance   float64    `json:"closing_balance" gorm:"type:decimal(12,2);not null"`  // Balance printed on the statement
+	Status           string     `json:"status" gorm:"size:20;not null;default:'in_progress';check:status IN ('in_progress','completed')"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (Reconciliation) TableName() string {
+	return "account_reconciliations"
+}
+
+// BeforeCreate GORM hook
+func (r *Reconciliation) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// StartReconciliationRequest - Opens a reconciliation against a statement
+type StartReconciliationRequest struct {
+	StatementEndDate string   `json:"statement_end_date" binding:"required"`
+	ClosingBalance   *float64 `json:"closing_balance" binding:"required"`
+	StartingBalance  *float64 `json:"starting_balance,omitempty"` // Defaults to the previous closing balance
+}
+
+// ClearTransactionsRequest - Marks transactions cleared, or no longer cleared
+type ClearTransactionsRequest struct {
+	TransactionIDs []uuid.UUID `json:"transaction_ids" binding:"required,min=1,max=1000"`
+	Cleared        *bool       `json:"cleared" binding:"required"`
+}
+
+// ReconciliationTotals - Transactions cleared into a reconciliation
+type ReconciliationTotals struct {
+	ClearedCount    int64   `json:"cleared_count"`
+	ClearedDeposits float64 `json:"cleared_deposits"` // Sum of cleared inflows
+	ClearedPayments float64 `json:"cleared_payments"` // Sum of cleared outflows, negative
+}
+
+// ReconciliationDetail - A reconciliation with the live difference to its statement
+type ReconciliationDetail struct {
+	Reconciliation
+	ReconciliationTotals
+	ClearedBalance float64 `json:"cleared_balance"` // Starting balance plus everything cleared
+	Difference     float64 `json:"difference"`      // Closing balance minus cleared balance, 0 once they agree
+}
+
+// ========================================
+// RECURRING TRANSACTIONS
+// ========================================
+
+// Recurring series frequencies, also stored as the RecurringPattern of their transactions
+const (
+	RecurringWeekly    = "weekly"
+	RecurringMonthly   = "monthly"
+	RecurringQuarterly = "quarterly"
+	RecurringAnnual    = "annual"
+)
+
+// RecurringTransaction - A recurring series accepted from the detector. Its
+// transactions carry its ID, IsRecurring and the frequency as RecurringPattern.
+type RecurringTransaction struct {
+	ID                       uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID                   uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	AccountID                uuid.UUID  `json:"account_id" gorm:"type:uuid;not null"`
+	CategoryID               *uuid.UUID `json:"category_id,omitempty" gorm:"type:uuid"`
+	Name                     string     `json:"name" gorm:"size:100;not null"`
+	Description              *string    `json:"description,omitempty"`
+	MerchantName             *string    `json:"merchant_name,omitempty" gorm:"size:200"`
+	Amount                   float64    `json:"amount" gorm:"type:decimal(12,2);not null"` // Latest amount
+	TransactionType          string     `json:"transaction_type" gorm:"size:20;default:'expense';check:transaction_type IN ('income','expense','transfer')"`
+	Frequency                string     `json:"frequency" gorm:"size:20;not null;check:frequency IN ('daily','weekly','bi-weekly','monthly','quarterly','annual')"`
+	NextDueDate              time.Time  `json:"next_due_date" gorm:"type:date;not null;index"`
+	StartDate                time.Time  `json:"start_date" gorm:"type:date;not null"`
+	EndDate                  *time.Time `json:"end_date,omitempty" gorm:"type:date"`
+	TypicalAmount            *float64   `json:"typical_amount,omitempty" gorm:"type:decimal(12,2)"`  // Median amount
+	AmountVariance           *float64   `json:"amount_variance,omitempty" gorm:"type:decimal(12,2)"` // Largest deviation from the typical amount
+	IsActive                 bool       `json:"is_active" gorm:"default:true;index"`
+	NotifyBeforeDays         int        `json:"notify_before_days" gorm:"default:3"`
+	LastMatchedTransactionID *uuid.UUID `json:"last_matched_transaction_id,omitempty" gorm:"type:uuid"`
+	CreatedAt                time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt                time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+func (RecurringTransaction) TableName() string {
+	return "recurring_transactions"
+}
+
+// BeforeCreate GORM hook
+func (r *RecurringTransaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// RecurringProposal - Transactions the detector found to repeat on a cadence,
+// proposed as a recurring series
+type RecurringProposal struct {
+	AccountID       uuid.UUID   `json:"account_id"`
+	CategoryID      *uuid.UUID  `json:"category_id,omitempty"` // Most common category of the transactions
+	Name            string      `json:"name"`
+	MerchantName    *string     `json:"merchant_name,omitempty"`
+	TransactionType string      `json:"transaction_type"` // "income" or "expense"
+	Frequency       string      `json:"frequency"`
+	Amount          float64     `json:"amount"`          // Latest amount
+	TypicalAmount   float64     `json:"typical_amount"`  // Median amount
+	AmountVariance  float64     `json:"amount_variance"` // Largest deviation from the typical amount
+	StartDate       time.Time   `json:"start_date"`
+	LastDate        time.Time   `json:"last_date"`
+	NextDueDate     time.Time   `json:"next_due_date"`
+	Occurrences     int         `json:"occurrences"`
+	Confidence      float64     `json:"confidence"` // 0-1, from interval regularity, amount spread and history length
+	TransactionIDs  []uuid.UUID `json:"transaction_ids"`
+}
+
+// RecurringDetectionConfig - Tuning for the recurring transaction detector
+type RecurringDetectionConfig struct {
+	LookbackDays    int     // History scanned for series
+	AmountTolerance float64 // Fraction amounts in one series may differ by
+	MinConfidence   float64 // Proposals below this confidence are dropped
+}
+
+// AcceptRecurringRequest - Accepts a proposal as a recurring series
+type AcceptRecurringRequest struct {
+	TransactionIDs []uuid.UUID `json:"transaction_ids" binding:"required,min=2,max=500"`
+	Name           *string     `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Frequency      *string     `json:"frequency,omitempty" binding:"omitempty,oneof=weekly monthly quarterly annual"` // Defaults to the detected cadence
+}
 
 // ========================================
 // FILTER MODELS
 // ========================================
 type TransactionFilter struct {
-	Page            int        `form:"page" binding:"min=1"`
-	Limit           int        `form:"limit" binding:"min=1,max=100"`
-	AccountID       *uuid.UUID `form:"account_id"`
-	CategoryID      *uuid.UUID `form:"category_id"`
-	StartDate       *time.Time `form:"-"`
-	EndDate         *time.Time `form:"-"`
-	TransactionType *string    `form:"transaction_type"`
-	MinAmount       *float64   `form:"min_amount"`
-	MaxAmount       *float64   `form:"max_amount"`
-	SearchTerm      *string    `form:"search"`
+	Page            int         `form:"page" binding:"omitempty,min=1"`
+	Limit           int         `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor          string      `form:"cursor"`        // next_cursor or prev_cursor of an earlier page, replaces page
+	IncludeTotal    *bool       `form:"include_total"` // false skips counting the matching rows
+	AccountIDs      []uuid.UUID `form:"-"`             // account_id, repeated or comma separated
+	CategoryIDs     []uuid.UUID `form:"-"`             // category_id, repeated or comma separated; matches split lines too
+	Uncategorized   bool        `form:"uncategorized"` // only transactions without a category or splits
+	FileUploadID    *uuid.UUID  `form:"-"`
+	StartDate       *time.Time  `form:"-"`
+	EndDate         *time.Time  `form:"-"`
+	TransactionType *string     `form:"transaction_type"`
+	Status          *string     `form:"status" binding:"omitempty,oneof=pending posted"`
+	Currency        *string     `form:"currency" binding:"omitempty,len=3"`
+	MinAmount       *float64    `form:"min_amount"`
+	MaxAmount       *float64    `form:"max_amount"`
+	Tags            []string    `form:"tags"` // repeated or comma separated
+	TagMatch        string      `form:"tag_match" binding:"omitempty,oneof=any all"`
+	NeedsReview     *bool       `form:"needs_review"`
+	IsHidden        *bool       `form:"is_hidden"`
+	IsRecurring     *bool       `form:"is_recurring"`
+	SearchTerm      *string     `form:"search"`
+	SortBy          string      `form:"sort_by" binding:"omitempty,oneof=date amount merchant created_at"`
+	SortOrder       string      `form:"sort_order" binding:"omitempty,oneof=asc desc"`
+
+	cursor *transactionCursor // decoded Cursor, set by the service
 }
 
+// Transaction list sort keys, cursors are only issued for TransactionSortDate
+const (
+	TransactionSortDate      = "date"
+	TransactionSortAmount    = "amount"
+	TransactionSortMerchant  = "merchant"
+	TransactionSortCreatedAt = "created_at"
+)
+
+// Tag matching modes
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
 // ========================================
 // STATISTICS MODELS
 // ========================================
@@ -252,6 +981,7 @@ type TransactionStats struct {
 	NetIncome        float64        `json:"net_income"`
 	TransactionCount int64          `json:"transaction_count"`
 	ByCategory       []CategoryStat `json:"by_category"`
+	ByMerchant       []MerchantStat `json:"by_merchant,omitempty"`
 	ByPeriod         []PeriodStat   `json:"by_period,omitempty"`
 }
 
@@ -263,6 +993,15 @@ type CategoryStat struct {
 	Count        int64      `json:"count"`
 }
 
+// MerchantStat - For merchant breakdown, transactions without a directory
+// merchant are grouped by their merchant name
+type MerchantStat struct {
+	MerchantID   *uuid.UUID `json:"merchant_id"`
+	MerchantName *string    `json:"merchant_name,omitempty"`
+	Amount       float64    `json:"amount"`
+	Count        int64      `json:"count"`
+}
+
 // PeriodStat - For time-based breakdown
 type PeriodStat struct {
 	Period string  `json:"period"`
@@ -296,6 +1035,8 @@ func (t *Transaction) ToListItem() TransactionListItem {
 			MerchantName:    t.MerchantName,
 			Amount:          t.Amount,
 			TransactionType: t.TransactionType,
+			Status:          t.Status,
+			CounterpartID:   t.CounterpartID,
 			Currency:        t.Currency,
 			Tags:            []string(t.Tags),
 		},
@@ -0,0 +1,432 @@
+package transaction
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"hi-cfo/server/internal/domains/account"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// EXPORT
+// ========================================
+
+// TransactionExport is an export that has been validated and is ready to be
+// written. Preparing it first lets the handler still answer with a JSON error
+// before any of the file has been sent.
+type TransactionExport struct {
+	Format      string
+	ContentType string
+	Filename    string
+
+	newWriter func(w io.Writer) (exportWriter, error)
+	stream    func(ctx context.Context, fn func(*TransactionExportRow) error) error
+	logger    *logrus.Entry
+}
+
+// exportWriter encodes exported rows in one file format
+type exportWriter interface {
+	WriteRow(row *TransactionExportRow) error
+	Close() error
+}
+
+// Columns of the tabular (CSV and XLSX) exports, see exportRecord
+var exportColumns = []string{
+	"date", "posted_date", "account", "description", "merchant", "amount", "currency",
+	"type", "category", "memo", "reference", "fit_id", "tags", "id",
+}
+
+// exportAmountColumn is the index of "amount" in exportColumns
+const exportAmountColumn = 5
+
+// PrepareExport validates format and the filter for an export of the user's
+// transactions. Page and Limit of the filter are ignored, every match is exported.
+func (s *TransactionService) PrepareExport(ctx context.Context, userID uuid.UUID, filter TransactionFilter, format string) (*TransactionExport, error) {
+	export := &TransactionExport{
+		Format:   format,
+		Filename: fmt.Sprintf("transactions-%s.%s", time.Now().Format("20060102"), format),
+		stream: func(ctx context.Context, fn func(*TransactionExportRow) error) error {
+			return s.repo.StreamTransactions(ctx, userID, filter, fn)
+		},
+		logger: s.logger.WithField("user_id", userID),
+	}
+
+	switch format {
+	case ExportFormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+		export.newWriter = func(w io.Writer) (exportWriter, error) {
+			return newCSVExportWriter(w)
+		}
+	case ExportFormatJSON:
+		export.ContentType = "application/json; charset=utf-8"
+		export.newWriter = func(w io.Writer) (exportWriter, error) {
+			return newJSONExportWriter(w)
+		}
+	case ExportFormatXLSX:
+		export.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		export.newWriter = func(w io.Writer) (exportWriter, error) {
+			return newXLSXExportWriter(w, exportColumns)
+		}
+	case ExportFormatOFX:
+		statement, err := s.prepareOFXExport(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		export.ContentType = "application/x-ofx"
+		export.newWriter = func(w io.Writer) (exportWriter, error) {
+			return newOFXExportWriter(w, statement)
+		}
+	default:
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Unsupported export format, use csv, ofx, json or xlsx").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("format", format)
+	}
+
+	return export, nil
+}
+
+// prepareOFXExport loads the account and statement period for an OFX export.
+// OFX statements describe a single account, so the filter has to name one.
+func (s *TransactionService) prepareOFXExport(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*ofxExportStatement, error) {
+	if len(filter.AccountIDs) != 1 {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "OFX exports need exactly one account_id, an OFX statement covers a single account").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	statement := &ofxExportStatement{
+		AccountID:   filter.AccountIDs[0].String(),
+		AccountType: "CHECKING",
+		Currency:    "USD",
+		StartDate:   filter.StartDate,
+		EndDate:     filter.EndDate,
+	}
+
+	if s.accountService != nil {
+		acc, err := s.accountService.GetAccountByID(ctx, userID, filter.AccountIDs[0])
+		if err != nil {
+			return nil, err
+		}
+		statement.applyAccount(acc)
+	}
+
+	if statement.StartDate == nil || statement.EndDate == nil {
+		start, end, err := s.repo.GetTransactionDateRange(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		if statement.StartDate == nil {
+			statement.StartDate = start
+		}
+		if statement.EndDate == nil {
+			statement.EndDate = end
+		}
+	}
+
+	return statement, nil
+}
+
+// Stream writes the export to w, reading transactions from the database as it goes
+func (e *TransactionExport) Stream(ctx context.Context, w io.Writer) error {
+	writer, err := e.newWriter(w)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	err = e.stream(ctx, func(row *TransactionExportRow) error {
+		count++
+		return writer.WriteRow(row)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"format": e.Format,
+		"rows":   count,
+	}).Info("Transaction export completed")
+	return nil
+}
+
+// exportRecord flattens a row into the exportColumns order
+func exportRecord(row *TransactionExportRow) []string {
+	record := []string{
+		row.TransactionDate.Format("2006-01-02"),
+		"",
+		row.AccountName,
+		row.Description,
+		derefString(row.MerchantName),
+		strconv.FormatFloat(row.Amount, 'f', 2, 64),
+		row.Currency,
+		row.TransactionType,
+		derefString(row.CategoryName),
+		derefString(row.Memo),
+		derefString(row.ReferenceNumber),
+		derefString(row.FitID),
+		strings.Join(row.Tags, ";"),
+		row.ID.String(),
+	}
+	if row.PostedDate != nil {
+		record[1] = row.PostedDate.Format("2006-01-02")
+	}
+	return record
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// ========================================
+// CSV
+// ========================================
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func newCSVExportWriter(w io.Writer) (*csvExportWriter, error) {
+	writer := &csvExportWriter{w: csv.NewWriter(w)}
+	if err := writer.w.Write(exportColumns); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (c *csvExportWriter) WriteRow(row *TransactionExportRow) error {
+	record := exportRecord(row)
+	for i, value := range record {
+		if i != exportAmountColumn {
+			record[i] = escapeCSVFormula(value)
+		}
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeCSVFormula stops spreadsheets from evaluating text that starts like a
+// formula, such as a bank description beginning with "=" or "@"
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ========================================
+// JSON
+// ========================================
+
+// jsonExportWriter writes a JSON array, one encoded row at a time
+type jsonExportWriter struct {
+	w     *bufio.Writer
+	enc   *json.Encoder
+	count int
+}
+
+func newJSONExportWriter(w io.Writer) (*jsonExportWriter, error) {
+	buffered := bufio.NewWriter(w)
+	if _, err := buffered.WriteString("["); err != nil {
+		return nil, err
+	}
+	return &jsonExportWriter{w: buffered, enc: json.NewEncoder(buffered)}, nil
+}
+
+func (j *jsonExportWriter) WriteRow(row *TransactionExportRow) error {
+	if j.count > 0 {
+		if _, err := j.w.WriteString(","); err != nil {
+			return err
+		}
+	}
+	j.count++
+	return j.enc.Encode(row)
+}
+
+func (j *jsonExportWriter) Close() error {
+	if _, err := j.w.WriteString("]\n"); err != nil {
+		return err
+	}
+	return j.w.Flush()
+}
+
+// ========================================
+// OFX
+// ========================================
+
+// ofxExportStatement - Account details written into the OFX statement header
+type ofxExportStatement struct {
+	BankID      string
+	AccountID   string
+	AccountType string // CHECKING, SAVINGS, ... or CREDITCARD
+	Currency    string
+	StartDate   *time.Time
+	EndDate     *time.Time
+	Balance     *float64
+}
+
+func (st *ofxExportStatement) applyAccount(acc *account.Account) {
+	if acc.AccountNumberMasked != nil && *acc.AccountNumberMasked != "" {
+		st.AccountID = *acc.AccountNumberMasked
+	}
+	if acc.RoutingNumber != nil {
+		st.BankID = *acc.RoutingNumber
+	}
+	if acc.Currency != "" {
+		st.Currency = acc.Currency
+	}
+	st.Balance = acc.CurrentBalance
+
+	switch acc.AccountType {
+	case "savings":
+		st.AccountType = "SAVINGS"
+	case "credit_card":
+		st.AccountType = "CREDITCARD"
+	case "loan":
+		st.AccountType = "CREDITLINE"
+	default:
+		st.AccountType = "CHECKING"
+	}
+}
+
+// ofxExportWriter writes an OFX 2.x statement that ParseOFX reads back
+type ofxExportWriter struct {
+	w          *bufio.Writer
+	statement  *ofxExportStatement
+	creditCard bool
+}
+
+func newOFXExportWriter(w io.Writer, statement *ofxExportStatement) (*ofxExportWriter, error) {
+	o := &ofxExportWriter{
+		w:          bufio.NewWriter(w),
+		statement:  statement,
+		creditCard: statement.AccountType == "CREDITCARD",
+	}
+
+	now := time.Now()
+	start, end := now, now
+	if statement.StartDate != nil {
+		start = *statement.StartDate
+	}
+	if statement.EndDate != nil {
+		end = *statement.EndDate
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	b.WriteString("<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n")
+	b.WriteString("<OFX>\n")
+	b.WriteString("<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>")
+	fmt.Fprintf(&b, "<DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", formatOFXDateTime(now))
+	if o.creditCard {
+		b.WriteString("<CREDITCARDMSGSRSV1><CCSTMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		fmt.Fprintf(&b, "<CCSTMTRS><CURDEF>%s</CURDEF>\n", ofxEscape(statement.Currency))
+		fmt.Fprintf(&b, "<CCACCTFROM><ACCTID>%s</ACCTID></CCACCTFROM>\n", ofxEscape(statement.AccountID))
+	} else {
+		b.WriteString("<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>\n")
+		fmt.Fprintf(&b, "<STMTRS><CURDEF>%s</CURDEF>\n", ofxEscape(statement.Currency))
+		fmt.Fprintf(&b, "<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>\n",
+			ofxEscape(statement.BankID), ofxEscape(statement.AccountID), statement.AccountType)
+	}
+	fmt.Fprintf(&b, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", formatOFXDate(start), formatOFXDate(end))
+
+	if _, err := o.w.WriteString(b.String()); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *ofxExportWriter) WriteRow(row *TransactionExportRow) error {
+	fitID := row.ID.String()
+	if row.FitID != nil && *row.FitID != "" {
+		fitID = *row.FitID
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID>",
+		ofxTransactionType(row), formatOFXDate(row.TransactionDate), strconv.FormatFloat(row.Amount, 'f', 2, 64), ofxEscape(fitID))
+	if row.ReferenceNumber != nil && *row.ReferenceNumber != "" {
+		fmt.Fprintf(&b, "<CHECKNUM>%s</CHECKNUM>", ofxEscape(*row.ReferenceNumber))
+	}
+	fmt.Fprintf(&b, "<NAME>%s</NAME>", ofxEscape(row.Description))
+	if row.Memo != nil && *row.Memo != "" {
+		fmt.Fprintf(&b, "<MEMO>%s</MEMO>", ofxEscape(*row.Memo))
+	}
+	b.WriteString("</STMTTRN>\n")
+
+	_, err := o.w.WriteString(b.String())
+	return err
+}
+
+func (o *ofxExportWriter) Close() error {
+	var b strings.Builder
+	b.WriteString("</BANKTRANLIST>\n")
+	if o.statement.Balance != nil {
+		fmt.Fprintf(&b, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n",
+			strconv.FormatFloat(*o.statement.Balance, 'f', 2, 64), formatOFXDateTime(time.Now()))
+	}
+	if o.creditCard {
+		b.WriteString("</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>\n")
+	} else {
+		b.WriteString("</STMTRS></STMTTRNRS></BANKMSGSRSV1>\n")
+	}
+	b.WriteString("</OFX>\n")
+
+	if _, err := o.w.WriteString(b.String()); err != nil {
+		return err
+	}
+	return o.w.Flush()
+}
+
+// ofxTransactionType maps a transaction to an OFX TRNTYPE
+func ofxTransactionType(row *TransactionExportRow) string {
+	switch row.TransactionType {
+	case "transfer":
+		return "XFER"
+	case "fee":
+		return "FEE"
+	case "interest":
+		return "INT"
+	case "dividend":
+		return "DIV"
+	}
+	if row.Amount < 0 {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+func formatOFXDate(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func formatOFXDateTime(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+var ofxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func ofxEscape(value string) string {
+	return ofxEscaper.Replace(value)
+}
@@ -1,15 +1,27 @@
 package transaction
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"hi-cfo/server/internal/config"
+	"hi-cfo/server/internal/domains/account"
+	"hi-cfo/server/internal/domains/merchant"
 	"hi-cfo/server/internal/logger"
 	"hi-cfo/server/internal/shared"
 	customerrors "hi-cfo/server/internal/shared/errors"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -143,13 +155,51 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		filter.Limit = 20
 	}
 
+	if !h.bindFilterParams(c, &filter) {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"filter":  filter,
+	}).Debug("Getting transactions for user")
+
+	transactions, err := h.service.GetTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving transactions")
+		h.RespondWithInternalError(c, "Failed to retrieve transactions")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"transaction_count": len(transactions.Data),
+		"total":             transactions.Total,
+	}).Info("Successfully retrieved transactions")
+
+	h.RespondWithSuccess(c, http.StatusOK, transactions)
+}
+
+// bindFilterParams reads the filter parameters query binding cannot handle:
+// dates, ids and comma separated lists
+func (h *TransactionHandler) bindFilterParams(c *gin.Context, filter *TransactionFilter) bool {
 	// Parse date strings with flexible parsing (date-only or full datetime)
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
 		if startDate, err := shared.ParseFlexibleDate(startDateStr); err == nil {
 			filter.StartDate = &startDate
 		} else {
 			h.RespondWithValidationError(c, "Invalid start_date format", err.Error())
-			return
+			return false
 		}
 	}
 	if endDateStr := c.Query("end_date"); endDateStr != "" {
@@ -157,16 +207,123 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 			filter.EndDate = &endDate
 		} else {
 			h.RespondWithValidationError(c, "Invalid end_date format", err.Error())
+			return false
+		}
+	}
+
+	var err error
+	if filter.AccountIDs, err = queryUUIDs(c, "account_id"); err != nil {
+		h.RespondWithValidationError(c, "Invalid account_id", err.Error())
+		return false
+	}
+	if filter.CategoryIDs, err = queryUUIDs(c, "category_id"); err != nil {
+		h.RespondWithValidationError(c, "Invalid category_id", err.Error())
+		return false
+	}
+	if filter.Uncategorized && len(filter.CategoryIDs) > 0 {
+		h.RespondWithValidationError(c, "Invalid query parameters", "uncategorized cannot be combined with category_id")
+		return false
+	}
+	if raw := c.Query("file_upload_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid file_upload_id", err.Error())
+			return false
+		}
+		filter.FileUploadID = &id
+	}
+	filter.Tags = splitQueryList(filter.Tags)
+	return true
+}
+
+// queryUUIDs parses a query parameter given repeated or comma separated
+func queryUUIDs(c *gin.Context, name string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, raw := range splitQueryList(c.QueryArray(name)) {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// splitQueryList flattens repeated and comma separated query values
+func splitQueryList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// GET /transactions/search
+func (h *TransactionHandler) SearchTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter TransactionFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	// Set defaults
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+
+	if !h.bindFilterParams(c, &filter) {
+		return
+	}
+
+	results, err := h.service.SearchTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
 			return
 		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error searching transactions")
+		h.RespondWithInternalError(c, "Failed to search transactions")
+		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"user_id": userID,
-		"filter":  filter,
-	}).Debug("Getting transactions for user")
+	h.RespondWithSuccess(c, http.StatusOK, results)
+}
 
-	transactions, err := h.service.GetTransactions(c.Request.Context(), userID, filter)
+// GET /transactions/export
+func (h *TransactionHandler) ExportTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	// Exports ignore paging; preset page and limit so the filter validates without them
+	filter := TransactionFilter{Page: 1, Limit: 100}
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+	if !h.bindFilterParams(c, &filter) {
+		return
+	}
+	format := strings.ToLower(c.DefaultQuery("format", ExportFormatCSV))
+
+	export, err := h.service.PrepareExport(c.Request.Context(), userID, filter, format)
 	if err != nil {
 		// Check if it's a custom error
 		if appErr, ok := err.(*customerrors.AppError); ok {
@@ -177,19 +334,26 @@ func (h *TransactionHandler) GetTransactions(c *gin.Context) {
 		// Fallback for unexpected errors
 		h.logger.WithFields(logrus.Fields{
 			"user_id": userID,
+			"format":  format,
 			"error":   err.Error(),
-		}).Error("Unexpected error retrieving transactions")
-		h.RespondWithInternalError(c, "Failed to retrieve transactions")
+		}).Error("Unexpected error preparing transaction export")
+		h.RespondWithInternalError(c, "Failed to export transactions")
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"user_id":           userID,
-		"transaction_count": len(transactions.Data),
-		"total":             transactions.Total,
-	}).Info("Successfully retrieved transactions")
+	c.Header("Content-Type", export.ContentType)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	c.Status(http.StatusOK)
 
-	h.RespondWithSuccess(c, http.StatusOK, transactions)
+	if err := export.Stream(c.Request.Context(), c.Writer); err != nil {
+		// The status line is already sent, so the client only sees a truncated file
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"format":  format,
+			"error":   err.Error(),
+		}).Error("Transaction export failed while streaming")
+		c.Abort()
+	}
 }
 
 // POST /transactions
@@ -262,8 +426,14 @@ func (h *TransactionHandler) CreateBatchTransactions(c *gin.Context) {
 		return
 	}
 
-	if len(batch.Transactions) > 1000 { // HTTP-specific limit
-		h.RespondWithValidationError(c, "Batch too large (max 1000 transactions)", "")
+	async := h.isAsyncImport(c)
+	if async {
+		if len(batch.Transactions) > maxAsyncBatchSize {
+			h.RespondWithValidationError(c, fmt.Sprintf("Batch too large (max %d transactions)", maxAsyncBatchSize), "")
+			return
+		}
+	} else if len(batch.Transactions) > 1000 { // HTTP-specific limit
+		h.RespondWithValidationError(c, "Batch too large (max 1000 transactions, use async=true for larger batches)", "")
 		return
 	}
 
@@ -272,6 +442,16 @@ func (h *TransactionHandler) CreateBatchTransactions(c *gin.Context) {
 		batch.Source = "api"
 	}
 
+	if async {
+		content, err := json.Marshal(batch)
+		if err != nil {
+			h.RespondWithInternalError(c, "Failed to queue transaction batch")
+			return
+		}
+		h.respondWithQueuedImport(c, userID, uuid.Nil, ImportOptions{Format: "json", Filename: batch.Source + "-batch.json"}, content)
+		return
+	}
+
 	h.logger.WithFields(logrus.Fields{
 		"user_id":           userID,
 		"transaction_count": len(batch.Transactions),
@@ -303,97 +483,86 @@ func (h *TransactionHandler) CreateBatchTransactions(c *gin.Context) {
 		"errors":  len(result.Errors),
 	}).Info("Transaction batch processing completed")
 
-	// Return appropriate HTTP status based on results
-	status := http.StatusCreated
-	if result.Created == 0 {
-		status = http.StatusBadRequest
-	} else if result.Skipped > 0 {
-		status = http.StatusPartialContent // 206 - some succeeded, some failed
-	}
-
-	c.JSON(status, gin.H{
-		"success": result.Created > 0,
-		"data":    result,
-	})
+	h.respondWithBatchResult(c, result)
 }
 
-func (h *TransactionHandler) GetCategorizationSettings(c *gin.Context) {
+// PATCH /transactions/bulk
+func (h *TransactionHandler) BulkUpdateTransactions(c *gin.Context) {
 	userID, ok := h.HandleUserIDExtraction(c)
 	if !ok {
 		return
 	}
 
-	// Here you would typically fetch user-specific settings from the database
-	// For now, we'll return a default settings structure
-	// You might want to add a method in the service to get user settings
-
-	// This would require access to the category service
-	// For now, return default settings structure
-
-	settings := gin.H{
-		"confidence_threshold":      0.5,
-		"auto_categorize_on_upload": true,
-		"enabled_methods": []string{
-			"keyword",
-			"jaccard",
-			"levenshtein",
-			"cosine_tfidf",
-		},
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"user_id":  userID,
-		"settings": settings,
-	})
-}
-
-func (h *TransactionHandler) UpdateCategorizationSettings(c *gin.Context) {
-	userID, ok := h.HandleUserIDExtraction(c)
-	if !ok {
+	var req BulkUpdateRequest
+	if !h.BindJSON(c, &req) {
 		return
 	}
-
-	var req struct {
-		ConfidenceThreshold    *float64 `json:"confidence_threshold"`
-		AutoCategorizeOnUpload *bool    `json:"auto_categorize_on_upload"`
-		EnabledMethods         []string `json:"enabled_methods"`
+	if len(req.IDs) > config.GetBulkEditMaxRows() {
+		h.RespondWithValidationError(c, fmt.Sprintf("Too many ids (max %d)", config.GetBulkEditMaxRows()), "")
+		return
 	}
 
-	if !h.BindJSON(c, &req) {
-		return
+	// Without ids the edit applies to every transaction matching the query string filter
+	var filter TransactionFilter
+	if len(req.IDs) == 0 {
+		if !h.BindQuery(c, &filter) {
+			return
+		}
+		if !h.bindFilterParams(c, &filter) {
+			return
+		}
 	}
 
-	// Validate confidence threshold
-	if req.ConfidenceThreshold != nil {
-		if *req.ConfidenceThreshold < 0 || *req.ConfidenceThreshold > 1 {
-			h.RespondWithValidationError(c, "Confidence threshold must be between 0 and 1", "")
+	result, err := h.service.BulkUpdateTransactions(c.Request.Context(), userID, req, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
 			return
 		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error in bulk transaction edit")
+		h.RespondWithInternalError(c, "Failed to update transactions")
+		return
 	}
 
-	// This would update the user's settings
-	// For now, just return success
-	h.RespondWithSuccess(c, http.StatusOK, gin.H{"user_id": userID}, "Settings updated successfully")
+	message := "Transactions updated successfully"
+	if result.DryRun {
+		message = "Dry run, no transactions were changed"
+	}
+	h.RespondWithSuccess(c, http.StatusOK, result, message)
 }
 
-// ============ GET /transactions/:id
-func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
+// POST /transactions/import/ofx
+func (h *TransactionHandler) ImportOFX(c *gin.Context) {
 	userID, ok := h.HandleUserIDExtraction(c)
 	if !ok {
 		return
 	}
 
-	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	upload, ok := h.bindImportFile(c, ".ofx", ".qfx")
 	if !ok {
 		return
 	}
+	defer upload.file.Close()
 
 	h.logger.WithFields(logrus.Fields{
-		"user_id":        userID,
-		"transaction_id": transactionID,
-	}).Debug("Getting transaction by ID")
+		"user_id":    userID,
+		"account_id": upload.accountID,
+		"filename":   upload.filename,
+		"size":       upload.size,
+	}).Debug("Importing OFX statement")
 
-	transaction, err := h.service.GetTransactionByID(c.Request.Context(), userID, transactionID)
+	if h.isAsyncImport(c) {
+		h.enqueueImport(c, userID, upload, ImportOptions{Format: "ofx", Filename: upload.filename})
+		return
+	}
+
+	result, err := h.service.ImportOFX(c.Request.Context(), userID, upload.accountID, upload.filename, upload.file)
 	if err != nil {
 		// Check if it's a custom error
 		if appErr, ok := err.(*customerrors.AppError); ok {
@@ -403,41 +572,59 @@ func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
 		}
 		// Fallback for unexpected errors
 		h.logger.WithFields(logrus.Fields{
-			"user_id":        userID,
-			"transaction_id": transactionID,
-			"error":          err.Error(),
-		}).Error("Unexpected error getting transaction")
-		h.RespondWithNotFound(c, "Transaction")
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error importing OFX statement")
+		h.RespondWithInternalError(c, "Failed to import OFX statement")
 		return
 	}
 
-	h.RespondWithSuccess(c, http.StatusOK, transaction)
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"created": result.Created,
+		"skipped": result.Skipped,
+		"errors":  len(result.Errors),
+	}).Info("OFX import completed")
 
+	h.respondWithBatchResult(c, result)
 }
 
-// ================== PUT /transactions/:id
-func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+// POST /transactions/import/camt053
+func (h *TransactionHandler) ImportCAMT053(c *gin.Context) {
+	h.importBankStatement(c, "camt053", ".xml", ".camt", ".053")
+}
+
+// POST /transactions/import/mt940
+func (h *TransactionHandler) ImportMT940(c *gin.Context) {
+	h.importBankStatement(c, "mt940", ".sta", ".mt940", ".940", ".txt")
+}
+
+func (h *TransactionHandler) importBankStatement(c *gin.Context, format string, extensions ...string) {
 	userID, ok := h.HandleUserIDExtraction(c)
 	if !ok {
 		return
 	}
 
-	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	upload, ok := h.bindImportFile(c, extensions...)
 	if !ok {
 		return
 	}
+	defer upload.file.Close()
 
-	var req UpdateTransactionRequest
-	if !h.BindJSON(c, &req) {
+	h.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": upload.accountID,
+		"filename":   upload.filename,
+		"size":       upload.size,
+		"format":     format,
+	}).Debug("Importing bank statement")
+
+	if h.isAsyncImport(c) {
+		h.enqueueImport(c, userID, upload, ImportOptions{Format: format, Filename: upload.filename})
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"user_id":        userID,
-		"transaction_id": transactionID,
-	}).Debug("Updating transaction")
-
-	transaction, err := h.service.UpdateTransaction(c.Request.Context(), userID, transactionID, &req)
+	result, err := h.service.ImportBankStatement(c.Request.Context(), userID, upload.accountID, format, upload.filename, upload.file)
 	if err != nil {
 		// Check if it's a custom error
 		if appErr, ok := err.(*customerrors.AppError); ok {
@@ -447,40 +634,1904 @@ func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
 		}
 		// Fallback for unexpected errors
 		h.logger.WithFields(logrus.Fields{
-			"user_id":        userID,
-			"transaction_id": transactionID,
-			"error":          err.Error(),
-		}).Error("Unexpected error updating transaction")
-		h.RespondWithInternalError(c, "Failed to update transaction")
+			"user_id": userID,
+			"format":  format,
+			"error":   err.Error(),
+		}).Error("Unexpected error importing bank statement")
+		h.RespondWithInternalError(c, "Failed to import bank statement")
 		return
 	}
 
 	h.logger.WithFields(logrus.Fields{
-		"user_id":        userID,
-		"transaction_id": transactionID,
-	}).Info("Transaction updated successfully")
+		"user_id": userID,
+		"format":  format,
+		"created": result.Created,
+		"skipped": result.Skipped,
+		"errors":  len(result.Errors),
+	}).Info("Bank statement import completed")
 
-	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transaction updated successfully")
+	h.respondWithBatchResult(c, result)
 }
 
-// =============== DELETE /transactions/:id
-func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+// POST /transactions/import/qif
+func (h *TransactionHandler) ImportQIF(c *gin.Context) {
 	userID, ok := h.HandleUserIDExtraction(c)
 	if !ok {
 		return
 	}
 
-	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	dateOrder := c.DefaultPostForm("date_order", QIFDateOrderAuto)
+	if !slices.Contains([]string{QIFDateOrderAuto, QIFDateOrderMDY, QIFDateOrderDMY}, dateOrder) {
+		h.RespondWithValidationError(c, "date_order must be one of auto, mdy, dmy", "")
+		return
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(c.DefaultPostForm("currency", "USD")))
+	if len(currency) != 3 {
+		h.RespondWithValidationError(c, "currency must be a 3-letter code", "")
+		return
+	}
+
+	upload, ok := h.bindImportFile(c, ".qif")
 	if !ok {
 		return
 	}
+	defer upload.file.Close()
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": upload.accountID,
+		"filename":   upload.filename,
+		"size":       upload.size,
+		"date_order": dateOrder,
+	}).Debug("Importing QIF file")
+
+	if h.isAsyncImport(c) {
+		h.enqueueImport(c, userID, upload, ImportOptions{Format: "qif", Filename: upload.filename, DateOrder: dateOrder, Currency: currency})
+		return
+	}
+
+	result, err := h.service.ImportQIF(c.Request.Context(), userID, upload.accountID, upload.filename, dateOrder, currency, upload.file)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error importing QIF file")
+		h.RespondWithInternalError(c, "Failed to import QIF file")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"created": result.Created,
+		"skipped": result.Skipped,
+		"errors":  len(result.Errors),
+	}).Info("QIF import completed")
+
+	h.respondWithBatchResult(c, result)
+}
+
+// POST /transactions/import/csv
+func (h *TransactionHandler) ImportCSV(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	upload, ok := h.bindImportFile(c, ".csv", ".txt")
+	if !ok {
+		return
+	}
+	defer upload.file.Close()
+
+	profile, ok := h.bindImportProfile(c, userID, upload.accountID)
+	if !ok {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"account_id": upload.accountID,
+		"profile_id": profile.ID,
+		"filename":   upload.filename,
+		"size":       upload.size,
+	}).Debug("Importing CSV file")
+
+	if h.isAsyncImport(c) {
+		h.enqueueImport(c, userID, upload, ImportOptions{Format: "csv", Filename: upload.filename, Profile: profile})
+		return
+	}
+
+	result, err := h.service.ImportCSV(c.Request.Context(), userID, upload.accountID, profile, upload.filename, upload.file)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error importing CSV file")
+		h.RespondWithInternalError(c, "Failed to import CSV file")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"created": result.Created,
+		"skipped": result.Skipped,
+		"errors":  len(result.Errors),
+	}).Info("CSV import completed")
+
+	h.respondWithBatchResult(c, result)
+}
+
+// POST /transactions/import/csv/preview
+func (h *TransactionHandler) PreviewCSVImport(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultPostForm("rows", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		h.RespondWithValidationError(c, "rows must be between 1 and 100", "")
+		return
+	}
+
+	upload, ok := h.bindImportFile(c, ".csv", ".txt")
+	if !ok {
+		return
+	}
+	defer upload.file.Close()
+
+	profile, ok := h.bindImportProfile(c, userID, upload.accountID)
+	if !ok {
+		return
+	}
+
+	preview, err := h.service.PreviewCSVImport(c.Request.Context(), userID, upload.accountID, profile, upload.file, limit)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error previewing CSV file")
+		h.RespondWithInternalError(c, "Failed to preview CSV file")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, preview)
+}
+
+// bindImportProfile reads either an inline "profile" JSON form field (used to
+// try a mapping before saving it) or an optional "profile_id"
+func (h *TransactionHandler) bindImportProfile(c *gin.Context, userID, accountID uuid.UUID) (*ImportProfile, bool) {
+	if inline := c.PostForm("profile"); inline != "" {
+		var req CreateImportProfileRequest
+		if err := json.Unmarshal([]byte(inline), &req); err != nil {
+			h.RespondWithValidationError(c, "Invalid profile", err.Error())
+			return nil, false
+		}
+		profile, err := NewImportProfile(userID, &req)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid profile", err.Error())
+			return nil, false
+		}
+		return profile, true
+	}
+
+	var profileID *uuid.UUID
+	if raw := c.PostForm("profile_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid profile_id", err.Error())
+			return nil, false
+		}
+		profileID = &id
+	}
+
+	profile, err := h.service.ResolveImportProfile(c.Request.Context(), userID, accountID, profileID)
+	if err != nil {
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			c.JSON(appErr.StatusCode, appErr)
+			return nil, false
+		}
+		h.RespondWithInternalError(c, "Failed to load import profile")
+		return nil, false
+	}
+	return profile, true
+}
+
+// GET /transactions/import/profiles
+func (h *TransactionHandler) GetImportProfiles(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var accountID *uuid.UUID
+	if raw := c.Query("account_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid account_id", err.Error())
+			return
+		}
+		accountID = &id
+	}
+
+	profiles, err := h.service.GetImportProfiles(c.Request.Context(), userID, accountID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error fetching import profiles")
+		h.RespondWithInternalError(c, "Failed to fetch import profiles")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, profiles)
+}
+
+// GET /transactions/import/profiles/:id
+func (h *TransactionHandler) GetImportProfile(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	profileID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	profile, err := h.service.GetImportProfile(c.Request.Context(), userID, profileID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"profile_id": profileID,
+			"error":      err.Error(),
+		}).Error("Unexpected error fetching import profile")
+		h.RespondWithInternalError(c, "Failed to fetch import profile")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, profile)
+}
+
+// POST /transactions/import/profiles
+func (h *TransactionHandler) CreateImportProfile(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var req CreateImportProfileRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	profile, err := h.service.CreateImportProfile(c.Request.Context(), userID, &req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error creating import profile")
+		h.RespondWithInternalError(c, "Failed to create import profile")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusCreated, profile, "Import profile created successfully")
+}
+
+// PUT /transactions/import/profiles/:id
+func (h *TransactionHandler) UpdateImportProfile(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	profileID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateImportProfileRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	profile, err := h.service.UpdateImportProfile(c.Request.Context(), userID, profileID, &req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"profile_id": profileID,
+			"error":      err.Error(),
+		}).Error("Unexpected error updating import profile")
+		h.RespondWithInternalError(c, "Failed to update import profile")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, profile, "Import profile updated successfully")
+}
+
+// DELETE /transactions/import/profiles/:id
+func (h *TransactionHandler) DeleteImportProfile(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	profileID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteImportProfile(c.Request.Context(), userID, profileID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"profile_id": profileID,
+			"error":      err.Error(),
+		}).Error("Unexpected error deleting import profile")
+		h.RespondWithInternalError(c, "Failed to delete import profile")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Import profile deleted successfully")
+}
+
+// POST /file-uploads/:id/revert
+func (h *TransactionHandler) RevertImport(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	uploadID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	// The body is optional, an empty request reverts everything
+	var req RevertImportRequest
+	if c.Request.ContentLength != 0 && !h.BindJSON(c, &req) {
+		return
+	}
 
 	h.logger.WithFields(logrus.Fields{
 		"user_id":        userID,
-		"transaction_id": transactionID,
-	}).Debug("Deleting transaction")
+		"file_upload_id": uploadID,
+		"keep_edited":    req.KeepEdited,
+		"dry_run":        req.DryRun,
+	}).Debug("Reverting import")
+
+	result, err := h.service.RevertImport(c.Request.Context(), userID, uploadID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"file_upload_id": uploadID,
+			"error":          err.Error(),
+		}).Error("Unexpected error reverting import")
+		h.RespondWithInternalError(c, "Failed to revert import")
+		return
+	}
+
+	message := "Import reverted successfully"
+	if result.DryRun {
+		message = "Import revert preview"
+	}
+	h.RespondWithSuccess(c, http.StatusOK, result, message)
+}
+
+// POST /file-uploads/:id/restore
+func (h *TransactionHandler) RestoreImport(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	uploadID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.RestoreImport(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"file_upload_id": uploadID,
+			"error":          err.Error(),
+		}).Error("Unexpected error restoring import")
+		h.RespondWithInternalError(c, "Failed to restore import")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, result, "Import restored successfully")
+}
+
+// GET /file-uploads/:id/job
+func (h *TransactionHandler) GetImportJob(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	uploadID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	status, err := h.service.GetImportJob(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"file_upload_id": uploadID,
+			"error":          err.Error(),
+		}).Error("Unexpected error getting import job")
+		h.RespondWithInternalError(c, "Failed to get import job")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, status)
+}
+
+// POST /file-uploads/:id/cancel
+func (h *TransactionHandler) CancelImportJob(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	uploadID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	status, err := h.service.CancelImportJob(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"file_upload_id": uploadID,
+			"error":          err.Error(),
+		}).Error("Unexpected error cancelling import job")
+		h.RespondWithInternalError(c, "Failed to cancel import job")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusAccepted, status, "Import cancellation requested")
+}
+
+// maxAsyncBatchSize caps /transactions/bulk when the batch is queued
+const maxAsyncBatchSize = 50000
+
+// isAsyncImport reports whether the caller asked for the import to be queued
+// with async=true, either in the query string or as a form field
+func (h *TransactionHandler) isAsyncImport(c *gin.Context) bool {
+	value := c.Query("async")
+	if value == "" {
+		value = c.PostForm("async")
+	}
+	async, _ := strconv.ParseBool(value)
+	return async
+}
+
+// enqueueImport queues an uploaded file instead of importing it in the request
+func (h *TransactionHandler) enqueueImport(c *gin.Context, userID uuid.UUID, upload *importFile, opts ImportOptions) {
+	content, err := io.ReadAll(upload.file)
+	if err != nil {
+		h.RespondWithInternalError(c, "Failed to read uploaded file")
+		return
+	}
+	h.respondWithQueuedImport(c, userID, upload.accountID, opts, content)
+}
+
+func (h *TransactionHandler) respondWithQueuedImport(c *gin.Context, userID, accountID uuid.UUID, opts ImportOptions, content []byte) {
+	queued, err := h.service.EnqueueImport(c.Request.Context(), userID, accountID, opts, content)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"format":  opts.Format,
+			"error":   err.Error(),
+		}).Error("Unexpected error queueing import")
+		h.RespondWithInternalError(c, "Failed to queue import")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusAccepted, queued, "Import queued")
+}
+
+// importFile is a statement file posted as multipart/form-data
+type importFile struct {
+	accountID uuid.UUID
+	filename  string
+	size      int64
+	file      multipart.File
+}
+
+// bindImportFile reads the "file" and "account_id" form fields shared by all import endpoints
+func (h *TransactionHandler) bindImportFile(c *gin.Context, extensions ...string) (*importFile, bool) {
+	accountID, err := uuid.Parse(c.PostForm("account_id"))
+	if err != nil {
+		h.RespondWithValidationError(c, "Invalid or missing account_id", err.Error())
+		return nil, false
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		h.RespondWithValidationError(c, "Missing statement file", err.Error())
+		return nil, false
+	}
+
+	if maxSize := config.GetMaxFileSize(); header.Size > maxSize {
+		h.RespondWithValidationError(c, fmt.Sprintf("File too large (max %d bytes)", maxSize), "")
+		return nil, false
+	}
+
+	if len(extensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(header.Filename))
+		if !slices.Contains(extensions, ext) {
+			h.RespondWithValidationError(c, fmt.Sprintf("Unsupported file type '%s'", ext), fmt.Sprintf("expected one of %v", extensions))
+			return nil, false
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.RespondWithInternalError(c, "Failed to read uploaded file")
+		return nil, false
+	}
+
+	return &importFile{
+		accountID: accountID,
+		filename:  header.Filename,
+		size:      header.Size,
+		file:      file,
+	}, true
+}
+
+// respondWithBatchResult maps batch results to an HTTP status
+func (h *TransactionHandler) respondWithBatchResult(c *gin.Context, result *BatchOperationResult) {
+	status := http.StatusCreated
+	if result.Created == 0 {
+		status = http.StatusBadRequest
+	} else if result.Skipped > 0 {
+		status = http.StatusPartialContent // 206 - some succeeded, some failed
+	}
+
+	c.JSON(status, gin.H{
+		"success": result.Created > 0,
+		"data":    result,
+	})
+}
+
+func (h *TransactionHandler) GetCategorizationSettings(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	// Here you would typically fetch user-specific settings from the database
+	// For now, we'll return a default settings structure
+	// You might want to add a method in the service to get user settings
+
+	// This would require access to the category service
+	// For now, return default settings structure
+
+	settings := gin.H{
+		"confidence_threshold":      0.5,
+		"auto_categorize_on_upload": true,
+		"enabled_methods": []string{
+			"keyword",
+			"jaccard",
+			"levenshtein",
+			"cosine_tfidf",
+		},
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user_id":  userID,
+		"settings": settings,
+	})
+}
+
+func (h *TransactionHandler) UpdateCategorizationSettings(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		ConfidenceThreshold    *float64 `json:"confidence_threshold"`
+		AutoCategorizeOnUpload *bool    `json:"auto_categorize_on_upload"`
+		EnabledMethods         []string `json:"enabled_methods"`
+	}
+
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	// Validate confidence threshold
+	if req.ConfidenceThreshold != nil {
+		if *req.ConfidenceThreshold < 0 || *req.ConfidenceThreshold > 1 {
+			h.RespondWithValidationError(c, "Confidence threshold must be between 0 and 1", "")
+			return
+		}
+	}
+
+	// This would update the user's settings
+	// For now, just return success
+	h.RespondWithSuccess(c, http.StatusOK, gin.H{"user_id": userID}, "Settings updated successfully")
+}
+
+// ============ GET /transactions/:id
+func (h *TransactionHandler) GetTransactionByID(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Debug("Getting transaction by ID")
+
+	transaction, err := h.service.GetTransactionByID(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error getting transaction")
+		h.RespondWithNotFound(c, "Transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction)
+
+}
+
+// ================== PUT /transactions/:id
+func (h *TransactionHandler) UpdateTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Debug("Updating transaction")
+
+	transaction, err := h.service.UpdateTransaction(c.Request.Context(), userID, transactionID, &req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error updating transaction")
+		h.RespondWithInternalError(c, "Failed to update transaction")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Info("Transaction updated successfully")
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transaction updated successfully")
+}
+
+// =============== DELETE /transactions/:id
+func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Debug("Deleting transaction")
+
+	if err := h.service.DeleteTransaction(c.Request.Context(), userID, transactionID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error deleting transaction")
+		h.RespondWithInternalError(c, "Failed to delete transaction")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Info("Transaction deleted successfully")
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Transaction deleted successfully")
+}
+
+// ============ GET /transactions/stats
+func (h *TransactionHandler) GetTransactionStats(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	// Parse date range
+	var startDate, endDate *time.Time
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		if parsed, err := shared.ParseFlexibleDate(startDateStr); err == nil {
+			startDate = &parsed
+		} else {
+			h.RespondWithValidationError(c, "Invalid start_date format", err.Error())
+			return
+		}
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		if parsed, err := shared.ParseFlexibleDate(endDateStr); err == nil {
+			endDate = &parsed
+		} else {
+			h.RespondWithValidationError(c, "Invalid end_date format", err.Error())
+			return
+		}
+	}
+
+	groupBy := c.DefaultQuery("group_by", "category")
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"start_date": startDate,
+		"end_date":   endDate,
+		"group_by":   groupBy,
+	}).Debug("Getting transaction stats")
+
+	stats, err := h.service.GetTransactionStats(c.Request.Context(), userID, startDate, endDate, groupBy)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving transaction stats")
+		h.RespondWithInternalError(c, "Failed to retrieve transaction stats")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"transaction_count": stats.TransactionCount,
+		"total_income":      stats.TotalIncome,
+		"total_expenses":    stats.TotalExpenses,
+	}).Info("Successfully retrieved transaction stats")
+
+	h.RespondWithSuccess(c, http.StatusOK, stats)
+}
+
+// GET /transactions/duplicates
+func (h *TransactionHandler) GetDuplicateCandidates(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter DuplicateFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+
+	candidates, err := h.service.GetDuplicateCandidates(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving duplicate candidates")
+		h.RespondWithInternalError(c, "Failed to retrieve duplicate candidates")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, candidates)
+}
+
+// POST /transactions/duplicates/:id/resolve
+func (h *TransactionHandler) ResolveDuplicate(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req ResolveDuplicateRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"action":         req.Action,
+	}).Debug("Resolving duplicate transaction")
+
+	transaction, err := h.service.ResolveDuplicate(c.Request.Context(), userID, transactionID, req.Action)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error resolving duplicate")
+		h.RespondWithInternalError(c, "Failed to resolve duplicate")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Duplicate resolved successfully")
+}
+
+// POST /transactions/transfers/detect
+func (h *TransactionHandler) DetectTransfers(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional, an empty request scans all transactions
+	var req DetectTransfersRequest
+	if c.Request.ContentLength != 0 && !h.BindJSON(c, &req) {
+		return
+	}
+
+	var startDate, endDate *time.Time
+	if req.StartDate != "" {
+		parsed, err := shared.ParseFlexibleDate(req.StartDate)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid start_date format", err.Error())
+			return
+		}
+		startDate = &parsed
+	}
+	if req.EndDate != "" {
+		parsed, err := shared.ParseFlexibleDate(req.EndDate)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid end_date format", err.Error())
+			return
+		}
+		endDate = &parsed
+	}
+
+	result, err := h.service.DetectTransfers(c.Request.Context(), userID, startDate, endDate)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error detecting transfers")
+		h.RespondWithInternalError(c, "Failed to detect transfers")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, result, "Transfer detection completed")
+}
+
+// POST /transactions/:id/transfer
+func (h *TransactionHandler) LinkTransfer(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req LinkTransferRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	transaction, err := h.service.LinkTransfer(c.Request.Context(), userID, transactionID, req.CounterpartID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"counterpart_id": req.CounterpartID,
+			"error":          err.Error(),
+		}).Error("Unexpected error linking transfer")
+		h.RespondWithInternalError(c, "Failed to link transfer")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transfer linked successfully")
+}
+
+// DELETE /transactions/:id/transfer
+func (h *TransactionHandler) UnlinkTransfer(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.service.UnlinkTransfer(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error unlinking transfer")
+		h.RespondWithInternalError(c, "Failed to unlink transfer")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transfer unlinked successfully")
+}
+
+// GET /transactions/:id/splits
+func (h *TransactionHandler) GetTransactionSplits(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	splits, err := h.service.GetSplits(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error retrieving transaction splits")
+		h.RespondWithInternalError(c, "Failed to retrieve transaction splits")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, splits)
+}
+
+// PUT /transactions/:id/splits
+func (h *TransactionHandler) SetTransactionSplits(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req SetSplitsRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	splits, err := h.service.SetSplits(c.Request.Context(), userID, transactionID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error saving transaction splits")
+		h.RespondWithInternalError(c, "Failed to save transaction splits")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, splits, "Transaction splits saved successfully")
+}
+
+// DELETE /transactions/:id/splits
+func (h *TransactionHandler) DeleteTransactionSplits(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSplits(c.Request.Context(), userID, transactionID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error deleting transaction splits")
+		h.RespondWithInternalError(c, "Failed to delete transaction splits")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Transaction splits deleted successfully")
+}
+
+// GET /transactions/:id/history
+func (h *TransactionHandler) GetTransactionHistory(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	history, err := h.service.GetTransactionHistory(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error retrieving transaction history")
+		h.RespondWithInternalError(c, "Failed to retrieve transaction history")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, history)
+}
+
+// POST /transactions/:id/revert
+func (h *TransactionHandler) RevertTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req RevertTransactionRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	transaction, err := h.service.RevertTransaction(c.Request.Context(), userID, transactionID, *req.Version)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error reverting transaction")
+		h.RespondWithInternalError(c, "Failed to revert transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transaction reverted successfully")
+}
+
+// POST /transactions/:id/unlock
+func (h *TransactionHandler) UnlockTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.service.UnlockTransaction(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error unlocking transaction")
+		h.RespondWithInternalError(c, "Failed to unlock transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transaction unlocked successfully")
+}
+
+// GET /transactions/trash
+func (h *TransactionHandler) GetDeletedTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter TrashFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
+
+	transactions, err := h.service.GetDeletedTransactions(c.Request.Context(), userID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving deleted transactions")
+		h.RespondWithInternalError(c, "Failed to retrieve deleted transactions")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transactions)
+}
+
+// POST /transactions/trash/:id/restore
+func (h *TransactionHandler) RestoreTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	transaction, err := h.service.RestoreTransaction(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error restoring transaction")
+		h.RespondWithInternalError(c, "Failed to restore transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transaction, "Transaction restored successfully")
+}
+
+// DELETE /transactions/trash/:id
+func (h *TransactionHandler) PurgeTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.PurgeTransaction(c.Request.Context(), userID, transactionID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error purging transaction")
+		h.RespondWithInternalError(c, "Failed to purge transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Transaction permanently deleted")
+}
+
+// GET /transactions/:id/attachments
+func (h *TransactionHandler) GetTransactionAttachments(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	attachments, err := h.service.GetAttachments(c.Request.Context(), userID, transactionID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"error":          err.Error(),
+		}).Error("Unexpected error retrieving transaction attachments")
+		h.RespondWithInternalError(c, "Failed to retrieve transaction attachments")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, attachments)
+}
+
+// POST /transactions/:id/attachments
+func (h *TransactionHandler) UploadTransactionAttachment(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		h.RespondWithValidationError(c, "Missing attachment file", err.Error())
+		return
+	}
+
+	if maxSize := config.GetMaxFileSize(); header.Size > maxSize {
+		h.RespondWithValidationError(c, fmt.Sprintf("File too large (max %d bytes)", maxSize), "")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.RespondWithInternalError(c, "Failed to read uploaded file")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.service.AddAttachment(c.Request.Context(), userID, transactionID, header.Filename, file)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"filename":       header.Filename,
+			"error":          err.Error(),
+		}).Error("Unexpected error uploading transaction attachment")
+		h.RespondWithInternalError(c, "Failed to upload attachment")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusCreated, attachment, "Attachment uploaded successfully")
+}
+
+// GET /transactions/:id/attachments/:attachment_id
+func (h *TransactionHandler) DownloadTransactionAttachment(c *gin.Context) {
+	h.sendAttachment(c, false)
+}
+
+// GET /transactions/:id/attachments/:attachment_id/thumbnail
+func (h *TransactionHandler) GetTransactionAttachmentThumbnail(c *gin.Context) {
+	h.sendAttachment(c, true)
+}
+
+// DELETE /transactions/:id/attachments/:attachment_id
+func (h *TransactionHandler) DeleteTransactionAttachment(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	attachmentID, ok := h.HandleUUIDParsing(c, "attachment_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAttachment(c.Request.Context(), userID, transactionID, attachmentID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"attachment_id":  attachmentID,
+			"error":          err.Error(),
+		}).Error("Unexpected error deleting transaction attachment")
+		h.RespondWithInternalError(c, "Failed to delete attachment")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Attachment deleted successfully")
+}
+
+// sendAttachment streams an attachment file or its thumbnail. Files are shown
+// inline unless ?download=true asks for a save dialog.
+func (h *TransactionHandler) sendAttachment(c *gin.Context, thumbnail bool) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	transactionID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	attachmentID, ok := h.HandleUUIDParsing(c, "attachment_id")
+	if !ok {
+		return
+	}
+
+	download, err := h.service.OpenAttachment(c.Request.Context(), userID, transactionID, attachmentID, thumbnail)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"attachment_id":  attachmentID,
+			"error":          err.Error(),
+		}).Error("Unexpected error opening transaction attachment")
+		h.RespondWithInternalError(c, "Failed to download attachment")
+		return
+	}
+	defer download.Body.Close()
+
+	disposition := "inline"
+	if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+
+	c.DataFromReader(http.StatusOK, download.Size, download.ContentType, download.Body, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": download.Filename}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// GET /accounts/:id/balance-history
+func (h *TransactionHandler) GetAccountBalanceHistory(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var filter account.BalanceHistoryFilter
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		parsed, err := shared.ParseFlexibleDate(startDateStr)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid start_date format", err.Error())
+			return
+		}
+		filter.StartDate = &parsed
+	}
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		parsed, err := shared.ParseFlexibleDate(endDateStr)
+		if err != nil {
+			h.RespondWithValidationError(c, "Invalid end_date format", err.Error())
+			return
+		}
+		filter.EndDate = &parsed
+	}
+
+	history, err := h.service.GetBalanceHistory(c.Request.Context(), userID, accountID, filter)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error retrieving account balance history")
+		h.RespondWithInternalError(c, "Failed to retrieve balance history")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, history)
+}
+
+// PUT /accounts/:id/opening-balance
+func (h *TransactionHandler) SetAccountOpeningBalance(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req account.SetOpeningBalanceRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	updated, err := h.service.SetOpeningBalance(c.Request.Context(), userID, accountID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error setting opening balance")
+		h.RespondWithInternalError(c, "Failed to set opening balance")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, updated, "Opening balance set successfully")
+}
+
+// GET /accounts/:id/reconciliations
+func (h *TransactionHandler) GetReconciliations(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliations, err := h.service.GetReconciliations(c.Request.Context(), userID, accountID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error retrieving reconciliations")
+		h.RespondWithInternalError(c, "Failed to retrieve reconciliations")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, reconciliations)
+}
+
+// POST /accounts/:id/reconciliations
+func (h *TransactionHandler) StartReconciliation(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req StartReconciliationRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	reconciliation, err := h.service.StartReconciliation(c.Request.Context(), userID, accountID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Error("Unexpected error starting reconciliation")
+		h.RespondWithInternalError(c, "Failed to start reconciliation")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusCreated, reconciliation, "Reconciliation started successfully")
+}
+
+// GET /accounts/:id/reconciliations/:reconciliation_id
+func (h *TransactionHandler) GetReconciliation(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliationID, ok := h.HandleUUIDParsing(c, "reconciliation_id")
+	if !ok {
+		return
+	}
+
+	reconciliation, err := h.service.GetReconciliation(c.Request.Context(), userID, accountID, reconciliationID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":           userID,
+			"account_id":        accountID,
+			"reconciliation_id": reconciliationID,
+			"error":             err.Error(),
+		}).Error("Unexpected error retrieving reconciliation")
+		h.RespondWithInternalError(c, "Failed to retrieve reconciliation")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, reconciliation)
+}
+
+// GET /accounts/:id/reconciliations/:reconciliation_id/transactions
+func (h *TransactionHandler) GetReconciliationTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliationID, ok := h.HandleUUIDParsing(c, "reconciliation_id")
+	if !ok {
+		return
+	}
+
+	transactions, err := h.service.GetReconciliationTransactions(c.Request.Context(), userID, accountID, reconciliationID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":           userID,
+			"account_id":        accountID,
+			"reconciliation_id": reconciliationID,
+			"error":             err.Error(),
+		}).Error("Unexpected error retrieving reconciliation transactions")
+		h.RespondWithInternalError(c, "Failed to retrieve reconciliation transactions")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, transactions)
+}
+
+// POST /accounts/:id/reconciliations/:reconciliation_id/clear
+func (h *TransactionHandler) ClearReconciliationTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliationID, ok := h.HandleUUIDParsing(c, "reconciliation_id")
+	if !ok {
+		return
+	}
+
+	var req ClearTransactionsRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	reconciliation, err := h.service.ClearTransactions(c.Request.Context(), userID, accountID, reconciliationID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":           userID,
+			"account_id":        accountID,
+			"reconciliation_id": reconciliationID,
+			"error":             err.Error(),
+		}).Error("Unexpected error clearing reconciliation transactions")
+		h.RespondWithInternalError(c, "Failed to update cleared transactions")
+		return
+	}
 
-	if err := h.service.DeleteTransaction(c.Request.Context(), userID, transactionID); err != nil {
+	h.RespondWithSuccess(c, http.StatusOK, reconciliation)
+}
+
+// POST /accounts/:id/reconciliations/:reconciliation_id/complete
+func (h *TransactionHandler) CompleteReconciliation(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliationID, ok := h.HandleUUIDParsing(c, "reconciliation_id")
+	if !ok {
+		return
+	}
+
+	reconciliation, err := h.service.CompleteReconciliation(c.Request.Context(), userID, accountID, reconciliationID)
+	if err != nil {
 		// Check if it's a custom error
 		if appErr, ok := err.(*customerrors.AppError); ok {
 			// Custom error already logged in service, just return appropriate response
@@ -489,58 +2540,203 @@ func (h *TransactionHandler) DeleteTransaction(c *gin.Context) {
 		}
 		// Fallback for unexpected errors
 		h.logger.WithFields(logrus.Fields{
-			"user_id":        userID,
-			"transaction_id": transactionID,
-			"error":          err.Error(),
-		}).Error("Unexpected error deleting transaction")
-		h.RespondWithInternalError(c, "Failed to delete transaction")
+			"user_id":           userID,
+			"account_id":        accountID,
+			"reconciliation_id": reconciliationID,
+			"error":             err.Error(),
+		}).Error("Unexpected error completing reconciliation")
+		h.RespondWithInternalError(c, "Failed to complete reconciliation")
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"user_id":        userID,
-		"transaction_id": transactionID,
-	}).Info("Transaction deleted successfully")
+	h.RespondWithSuccess(c, http.StatusOK, reconciliation, "Reconciliation completed successfully")
+}
 
-	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Transaction deleted successfully")
+// DELETE /accounts/:id/reconciliations/:reconciliation_id
+func (h *TransactionHandler) CancelReconciliation(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	accountID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	reconciliationID, ok := h.HandleUUIDParsing(c, "reconciliation_id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.CancelReconciliation(c.Request.Context(), userID, accountID, reconciliationID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":           userID,
+			"account_id":        accountID,
+			"reconciliation_id": reconciliationID,
+			"error":             err.Error(),
+		}).Error("Unexpected error cancelling reconciliation")
+		h.RespondWithInternalError(c, "Failed to cancel reconciliation")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Reconciliation cancelled successfully")
 }
 
-// ============ GET /transactions/stats
-func (h *TransactionHandler) GetTransactionStats(c *gin.Context) {
+// GET /transactions/recurring/proposals
+func (h *TransactionHandler) DetectRecurringTransactions(c *gin.Context) {
 	userID, ok := h.HandleUserIDExtraction(c)
 	if !ok {
 		return
 	}
 
-	// Parse date range
-	var startDate, endDate *time.Time
+	proposals, err := h.service.DetectRecurring(c.Request.Context(), userID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error detecting recurring transactions")
+		h.RespondWithInternalError(c, "Failed to detect recurring transactions")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, proposals)
+}
+
+// GET /transactions/recurring
+func (h *TransactionHandler) GetRecurringTransactions(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	series, err := h.service.GetRecurringTransactions(c.Request.Context(), userID)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error retrieving recurring transactions")
+		h.RespondWithInternalError(c, "Failed to retrieve recurring transactions")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, series)
+}
+
+// POST /transactions/recurring
+func (h *TransactionHandler) AcceptRecurringTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var req AcceptRecurringRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	series, err := h.service.AcceptRecurring(c.Request.Context(), userID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Error("Unexpected error accepting recurring transaction")
+		h.RespondWithInternalError(c, "Failed to accept recurring transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusCreated, series, "Recurring transaction accepted successfully")
+}
+
+// DELETE /transactions/recurring/:id
+func (h *TransactionHandler) DeleteRecurringTransaction(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	recurringID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRecurringTransaction(c.Request.Context(), userID, recurringID); err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":      userID,
+			"recurring_id": recurringID,
+			"error":        err.Error(),
+		}).Error("Unexpected error deleting recurring transaction")
+		h.RespondWithInternalError(c, "Failed to delete recurring transaction")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusNoContent, nil, "Recurring transaction deleted successfully")
+}
+
+// GET /merchants/spend
+func (h *TransactionHandler) GetMerchantSpend(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var filter merchant.SpendFilter
+	if !h.BindQuery(c, &filter) {
+		return
+	}
 	if startDateStr := c.Query("start_date"); startDateStr != "" {
-		if parsed, err := shared.ParseFlexibleDate(startDateStr); err == nil {
-			startDate = &parsed
-		} else {
+		parsed, err := shared.ParseFlexibleDate(startDateStr)
+		if err != nil {
 			h.RespondWithValidationError(c, "Invalid start_date format", err.Error())
 			return
 		}
+		filter.StartDate = &parsed
 	}
 	if endDateStr := c.Query("end_date"); endDateStr != "" {
-		if parsed, err := shared.ParseFlexibleDate(endDateStr); err == nil {
-			endDate = &parsed
-		} else {
+		parsed, err := shared.ParseFlexibleDate(endDateStr)
+		if err != nil {
 			h.RespondWithValidationError(c, "Invalid end_date format", err.Error())
 			return
 		}
+		filter.EndDate = &parsed
 	}
 
-	groupBy := c.DefaultQuery("group_by", "category")
-
-	h.logger.WithFields(logrus.Fields{
-		"user_id":    userID,
-		"start_date": startDate,
-		"end_date":   endDate,
-		"group_by":   groupBy,
-	}).Debug("Getting transaction stats")
-
-	stats, err := h.service.GetTransactionStats(c.Request.Context(), userID, startDate, endDate, groupBy)
+	spend, err := h.service.GetMerchantSpend(c.Request.Context(), userID, filter)
 	if err != nil {
 		// Check if it's a custom error
 		if appErr, ok := err.(*customerrors.AppError); ok {
@@ -552,17 +2748,119 @@ func (h *TransactionHandler) GetTransactionStats(c *gin.Context) {
 		h.logger.WithFields(logrus.Fields{
 			"user_id": userID,
 			"error":   err.Error(),
-		}).Error("Unexpected error retrieving transaction stats")
-		h.RespondWithInternalError(c, "Failed to retrieve transaction stats")
+		}).Error("Unexpected error retrieving merchant spend")
+		h.RespondWithInternalError(c, "Failed to retrieve merchant spend")
 		return
 	}
 
-	h.logger.WithFields(logrus.Fields{
-		"user_id":           userID,
-		"transaction_count": stats.TransactionCount,
-		"total_income":      stats.TotalIncome,
-		"total_expenses":    stats.TotalExpenses,
-	}).Info("Successfully retrieved transaction stats")
+	h.RespondWithSuccess(c, http.StatusOK, spend)
+}
 
-	h.RespondWithSuccess(c, http.StatusOK, stats)
+// POST /merchants/merge
+func (h *TransactionHandler) MergeMerchants(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	var req merchant.MergeMerchantsRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.service.MergeMerchants(c.Request.Context(), userID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"merchant_id": req.TargetID,
+			"error":       err.Error(),
+		}).Error("Unexpected error merging merchants")
+		h.RespondWithInternalError(c, "Failed to merge merchants")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, result, "Merchants merged successfully")
+}
+
+// PUT /merchants/:id
+func (h *TransactionHandler) UpdateMerchant(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	merchantID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req merchant.UpdateMerchantRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	updated, err := h.service.UpdateMerchant(c.Request.Context(), userID, merchantID, &req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"merchant_id": merchantID,
+			"error":       err.Error(),
+		}).Error("Unexpected error updating merchant")
+		h.RespondWithInternalError(c, "Failed to update merchant")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, updated, "Merchant updated successfully")
+}
+
+// POST /merchants/:id/rename
+func (h *TransactionHandler) RenameMerchant(c *gin.Context) {
+	userID, ok := h.HandleUserIDExtraction(c)
+	if !ok {
+		return
+	}
+
+	merchantID, ok := h.HandleUUIDParsing(c, "id")
+	if !ok {
+		return
+	}
+
+	var req merchant.RenameMerchantRequest
+	if !h.BindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.service.RenameMerchant(c.Request.Context(), userID, merchantID, req)
+	if err != nil {
+		// Check if it's a custom error
+		if appErr, ok := err.(*customerrors.AppError); ok {
+			// Custom error already logged in service, just return appropriate response
+			c.JSON(appErr.StatusCode, appErr)
+			return
+		}
+		// Fallback for unexpected errors
+		h.logger.WithFields(logrus.Fields{
+			"user_id":     userID,
+			"merchant_id": merchantID,
+			"error":       err.Error(),
+		}).Error("Unexpected error renaming merchant")
+		h.RespondWithInternalError(c, "Failed to rename merchant")
+		return
+	}
+
+	h.RespondWithSuccess(c, http.StatusOK, result, "Merchant renamed successfully")
 }
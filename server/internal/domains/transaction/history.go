@@ -0,0 +1,262 @@
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// CHANGE HISTORY
+// ========================================
+
+// trackedField is a transaction column recorded in the change history.
+// value returns what is stored as JSON, decode turns a stored value back
+// into a column update. Fields without decode are recorded but not reverted.
+type trackedField struct {
+	column string
+	value  func(*Transaction) any
+	decode func(ChangeValue) (any, error)
+}
+
+// trackedFields are the columns whose changes are recorded
+var trackedFields = []trackedField{
+	{"account_id", func(t *Transaction) any { return t.AccountID }, decodeChange[uuid.UUID]},
+	{"category_id", func(t *Transaction) any { return t.CategoryID }, decodeChange[*uuid.UUID]},
+	{"transaction_date", func(t *Transaction) any { return t.TransactionDate.Format("2006-01-02") }, decodeChangeDate},
+	{"description", func(t *Transaction) any { return t.Description }, decodeChange[string]},
+	{"merchant_name", func(t *Transaction) any { return t.MerchantName }, decodeChange[*string]},
+	{"merchant_id", func(t *Transaction) any { return t.MerchantID }, decodeChange[*uuid.UUID]},
+	{"amount", func(t *Transaction) any { return t.Amount }, decodeChange[float64]},
+	{"transaction_type", func(t *Transaction) any { return t.TransactionType }, decodeChange[string]},
+	{"counterpart_id", func(t *Transaction) any { return t.CounterpartID }, nil}, // transfer links have their own endpoints
+	{"currency", func(t *Transaction) any { return t.Currency }, decodeChange[string]},
+	{"memo", func(t *Transaction) any { return t.Memo }, decodeChange[*string]},
+	{"tags", func(t *Transaction) any { return changeTags(t.Tags) }, decodeChangeTags},
+	{"user_description", func(t *Transaction) any { return t.UserDescription }, decodeChange[*string]},
+	{"user_notes", func(t *Transaction) any { return t.UserNotes }, decodeChange[*string]},
+	{"is_hidden", func(t *Transaction) any { return t.IsHidden }, decodeChange[bool]},
+	{"needs_review", func(t *Transaction) any { return t.NeedsReview }, decodeChange[bool]},
+	{"reconciled_at", func(t *Transaction) any { return t.ReconciledAt }, nil}, // locks are only lifted by unlock
+	{"status", func(t *Transaction) any { return t.Status }, decodeChange[string]},
+	{"posted_date", func(t *Transaction) any { return t.PostedDate }, decodeChange[*time.Time]},
+}
+
+// diffTransaction returns the tracked fields that differ between two versions
+// of a transaction, without IDs or version set
+func diffTransaction(before, after *Transaction) []TransactionChange {
+	var changes []TransactionChange
+	for _, field := range trackedFields {
+		oldValue := encodeChange(field.value(before))
+		newValue := encodeChange(field.value(after))
+		if oldValue != newValue {
+			changes = append(changes, TransactionChange{
+				TransactionID: after.ID,
+				UserID:        after.UserID,
+				Field:         field.column,
+				OldValue:      oldValue,
+				NewValue:      newValue,
+			})
+		}
+	}
+	return changes
+}
+
+// changeActor is the user behind changes from source, nil for automatic sources
+func changeActor(userID uuid.UUID, source string) *uuid.UUID {
+	if source == ChangeSourceAPI || source == ChangeSourceRevert {
+		return &userID
+	}
+	return nil
+}
+
+func encodeChange(value any) ChangeValue {
+	data, err := json.Marshal(value)
+	if err != nil || string(data) == "null" {
+		return ""
+	}
+	return ChangeValue(data)
+}
+
+func decodeChange[T any](value ChangeValue) (any, error) {
+	var decoded T
+	if value == "" {
+		return decoded, nil
+	}
+	err := json.Unmarshal([]byte(value), &decoded)
+	return decoded, err
+}
+
+func decodeChangeDate(value ChangeValue) (any, error) {
+	var date string
+	if err := json.Unmarshal([]byte(value), &date); err != nil {
+		return nil, err
+	}
+	return time.Parse("2006-01-02", date)
+}
+
+// changeTags treats no tags and an empty tag list the same
+func changeTags(tags pq.StringArray) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	return []string(tags)
+}
+
+func decodeChangeTags(value ChangeValue) (any, error) {
+	tags, err := decodeChange[[]string](value)
+	if err != nil {
+		return nil, err
+	}
+	return pq.StringArray(tags.([]string)), nil
+}
+
+// GetTransactionHistory lists the recorded changes of a transaction, newest first
+func (s *TransactionService) GetTransactionHistory(ctx context.Context, userID, transactionID uuid.UUID) (*TransactionHistory, error) {
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.GetTransactionChanges(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &TransactionHistory{
+		TransactionID: transactionID,
+		Changes:       make([]TransactionChange, 0, len(changes)),
+	}
+	for i := len(changes) - 1; i >= 0; i-- {
+		history.Changes = append(history.Changes, changes[i])
+	}
+	if len(changes) > 0 {
+		history.CurrentVersion = changes[len(changes)-1].Version
+	}
+	return history, nil
+}
+
+// RevertTransaction restores the fields of a transaction to an earlier
+// version. The revert is recorded as a new version, so it can be undone too.
+func (s *TransactionService) RevertTransaction(ctx context.Context, userID, transactionID uuid.UUID, version int) (*Transaction, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(userID, tx); err != nil {
+		return nil, err
+	}
+	changes, err := s.repo.GetTransactionChanges(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentVersion := 0
+	if len(changes) > 0 {
+		currentVersion = changes[len(changes)-1].Version
+	}
+	if version >= currentVersion {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Version must be older than the current version").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"transaction_id":  transactionID,
+				"version":         version,
+				"current_version": currentVersion,
+			})
+	}
+
+	// The first change to a field after the target version holds its value at that version
+	restored := make(map[string]ChangeValue)
+	for _, change := range changes {
+		if change.Version <= version {
+			continue
+		}
+		if _, seen := restored[change.Field]; !seen {
+			restored[change.Field] = change.OldValue
+		}
+	}
+
+	updates := make(map[string]any)
+	for _, field := range trackedFields {
+		value, ok := restored[field.column]
+		if !ok || field.decode == nil || value == encodeChange(field.value(tx)) {
+			continue
+		}
+		decoded, err := field.decode(value)
+		if err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to read transaction history").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetails(map[string]any{
+					"transaction_id": transactionID,
+					"field":          field.column,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		updates[field.column] = decoded
+	}
+	if len(updates) == 0 {
+		return tx, nil
+	}
+
+	if err := validateRevert(userID, tx, updates); err != nil {
+		return nil, err
+	}
+	unclearOnChange(tx, updates)
+
+	reverted, err := s.repo.UpdateTransaction(ctx, userID, transactionID, updates, ChangeSourceRevert)
+	if err != nil {
+		return nil, err
+	}
+	if balanceFieldsChanged(updates) {
+		s.refreshBalances(ctx, userID, tx.AccountID, reverted.AccountID)
+		if reverted, err = s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"version":        version,
+		"fields":         len(updates),
+	}).Info("Transaction reverted")
+
+	return reverted, nil
+}
+
+// validateRevert keeps a revert from breaking transfer links and split totals
+func validateRevert(userID uuid.UUID, tx *Transaction, updates map[string]any) error {
+	message := ""
+	if transactionType, ok := updates["transaction_type"]; ok {
+		if tx.CounterpartID != nil && transactionType != "transfer" {
+			message = "Unlink the transfer before reverting its transaction type"
+		}
+		if tx.CounterpartID == nil && transactionType == "transfer" {
+			message = "Link a transfer counterpart before reverting to a transfer"
+		}
+	}
+	if amount, ok := updates["amount"]; ok && len(tx.Splits) > 0 {
+		var splitCents int64
+		for _, split := range tx.Splits {
+			splitCents += toCents(split.Amount)
+		}
+		if splitCents != toCents(amount.(float64)) {
+			message = "The earlier amount no longer matches the split lines, update or remove the splits first"
+		}
+	}
+	if message == "" {
+		return nil
+	}
+	return customerrors.New(customerrors.ErrCodeValidation, message).
+		WithDomain("transaction").
+		WithUserID(userID).
+		WithDetail("transaction_id", tx.ID)
+}
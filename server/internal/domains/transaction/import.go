@@ -0,0 +1,804 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"hi-cfo/server/internal/domains/fileupload"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// FILE IMPORTS
+// ========================================
+
+// parsedFile is what every statement parser hands to executeImport
+type parsedFile struct {
+	requests  []TransactionRequest
+	info      *StatementInfo
+	rowErrors []string // Rows the parser had to drop
+}
+
+// importProgressFunc is called by executeImport after every chunk of a queued
+// import. Returning an error (errImportCancelled) stops the import.
+type importProgressFunc func(processed, total int, result *BatchOperationResult) error
+
+// runImport is the shared synchronous path for all file importers: the file
+// is recorded in file_uploads (rejecting content that was already imported),
+// then parsed and stored by executeImport.
+func (s *TransactionService) runImport(ctx context.Context, userID, accountID uuid.UUID, opts ImportOptions, r io.Reader) (*BatchOperationResult, error) {
+	parse, err := s.importParser(userID, accountID, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, "failed to read uploaded file").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	upload, err := s.beginImport(ctx, userID, accountID, opts, content, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var uploadID *uuid.UUID
+	if upload != nil {
+		uploadID = &upload.ID
+	}
+	return s.executeImport(ctx, userID, accountID, uploadID, opts, content, parse, nil)
+}
+
+// beginImport records the file in file_uploads, either as processing or, for
+// queued imports, as pending. It returns nil when upload tracking is not wired.
+func (s *TransactionService) beginImport(ctx context.Context, userID, accountID uuid.UUID, opts ImportOptions, content []byte, queued bool) (*fileupload.FileUpload, error) {
+	if s.fileUploadService == nil {
+		return nil, nil
+	}
+
+	hash := sha256.Sum256(content)
+	return s.fileUploadService.BeginUpload(ctx, userID, fileupload.NewUpload{
+		AccountID: accountID,
+		Filename:  opts.Filename,
+		FileType:  opts.Format,
+		Size:      int64(len(content)),
+		Hash:      hex.EncodeToString(hash[:]),
+		Queued:    queued,
+	})
+}
+
+// executeImport parses content, pushes it through ProcessTransactionBatch with
+// its FileUploadID, and completes the upload record from the batch result.
+// Without progress the batch is stored in one go; with it, rows are processed
+// in chunks of importChunkSize and progress is reported after each chunk.
+func (s *TransactionService) executeImport(ctx context.Context, userID, accountID uuid.UUID, uploadID *uuid.UUID, opts ImportOptions, content []byte, parse func(io.Reader) (*parsedFile, error), progress importProgressFunc) (*BatchOperationResult, error) {
+	fail := func(err error) error {
+		if uploadID != nil {
+			if failErr := s.fileUploadService.FailUpload(ctx, *uploadID, err.Error()); failErr != nil {
+				s.logger.WithFields(logrus.Fields{
+					"file_upload_id": *uploadID,
+					"error":          failErr.Error(),
+				}).Warn("Failed to mark file upload as failed")
+			}
+		}
+		return err
+	}
+
+	parsed, err := parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fail(customerrors.Wrap(err, customerrors.ErrCodeValidation, fmt.Sprintf("failed to parse %s file", strings.ToUpper(opts.Format))).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("filename", opts.Filename))
+	}
+
+	if uploadID != nil {
+		id := uploadID.String()
+		for i := range parsed.requests {
+			parsed.requests[i].FileUploadID = &id
+		}
+	}
+
+	var result *BatchOperationResult
+	if progress == nil {
+		result, err = s.ProcessTransactionBatch(ctx, userID, BatchTransactionRequest{
+			Transactions: parsed.requests,
+			Source:       opts.Format,
+		})
+		if err != nil {
+			return nil, fail(err)
+		}
+	} else {
+		result = &BatchOperationResult{Source: opts.Format}
+		total := len(parsed.requests)
+		if err := progress(0, total, result); err != nil {
+			return nil, err
+		}
+		for start := 0; start < total; start += importChunkSize {
+			end := min(start+importChunkSize, total)
+			chunk, err := s.ProcessTransactionBatch(ctx, userID, BatchTransactionRequest{
+				Transactions: parsed.requests[start:end],
+				Source:       opts.Format,
+			})
+			if ctx.Err() != nil {
+				// Shutting down, the worker rolls the run back and requeues it
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				return nil, fail(err)
+			}
+			result.merge(chunk)
+			if err := progress(end, total, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.Total += len(parsed.rowErrors)
+	result.Skipped += len(parsed.rowErrors)
+	result.Errors = append(result.Errors, parsed.rowErrors...)
+	result.Statement = parsed.info
+	if parsed.info != nil {
+		parsed.info.BalanceCheck = s.checkStatementBalance(ctx, userID, accountID, parsed.info)
+	}
+
+	if uploadID != nil {
+		id := uploadID.String()
+		result.FileUploadID = &id
+
+		outcome := fileupload.ImportOutcome{
+			Imported:   result.Created,
+			Duplicates: len(result.Duplicates),
+		}
+		if parsed.info != nil {
+			outcome.DateRangeStart = parsed.info.StartDate
+			outcome.DateRangeEnd = parsed.info.EndDate
+		}
+		if err := s.fileUploadService.CompleteUpload(ctx, *uploadID, outcome); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": *uploadID,
+				"error":          err.Error(),
+			}).Warn("Failed to record file upload outcome")
+		}
+	}
+
+	return result, nil
+}
+
+// merge folds the result of one chunk of a queued import into r
+func (r *BatchOperationResult) merge(chunk *BatchOperationResult) {
+	r.Total += chunk.Total
+	r.Created += chunk.Created
+	r.Skipped += chunk.Skipped
+	r.Flagged += chunk.Flagged
+	r.Transfers += chunk.Transfers
+	r.CreatedIDs = append(r.CreatedIDs, chunk.CreatedIDs...)
+	r.Duplicates = append(r.Duplicates, chunk.Duplicates...)
+	r.Errors = append(r.Errors, chunk.Errors...)
+}
+
+// importParser returns the parser for opts.Format, bound to the target account
+func (s *TransactionService) importParser(userID, accountID uuid.UUID, opts ImportOptions) (func(io.Reader) (*parsedFile, error), error) {
+	switch opts.Format {
+	case "ofx":
+		return func(r io.Reader) (*parsedFile, error) {
+			statement, err := ParseOFX(r)
+			if err != nil {
+				return nil, err
+			}
+
+			s.logger.WithFields(logrus.Fields{
+				"user_id":           userID,
+				"account_id":        accountID,
+				"ofx_version":       statement.Version,
+				"transaction_count": len(statement.Transactions),
+			}).Debug("Parsed OFX statement")
+
+			return &parsedFile{
+				requests: statement.ToTransactionRequests(accountID.String()),
+				info:     statement.Info(opts.Filename),
+			}, nil
+		}, nil
+
+	case "camt053", "mt940":
+		parse := ParseCAMT053
+		if opts.Format == "mt940" {
+			parse = ParseMT940
+		}
+		return func(r io.Reader) (*parsedFile, error) {
+			statement, err := parse(r)
+			if err != nil {
+				return nil, err
+			}
+
+			s.logger.WithFields(logrus.Fields{
+				"user_id":         userID,
+				"account_id":      accountID,
+				"format":          opts.Format,
+				"entry_count":     len(statement.Entries),
+				"opening_balance": statement.OpeningBalance,
+				"closing_balance": statement.ClosingBalance,
+			}).Debug("Parsed bank statement")
+
+			return &parsedFile{
+				requests: statement.ToTransactionRequests(accountID.String()),
+				info:     statement.Info(opts.Filename),
+			}, nil
+		}, nil
+
+	case "qif":
+		return func(r io.Reader) (*parsedFile, error) {
+			statement, err := ParseQIF(r, opts.DateOrder)
+			if err != nil {
+				return nil, err
+			}
+
+			s.logger.WithFields(logrus.Fields{
+				"user_id":           userID,
+				"account_id":        accountID,
+				"account_type":      statement.AccountType,
+				"date_order":        statement.DateOrder,
+				"transaction_count": len(statement.Transactions),
+			}).Debug("Parsed QIF file")
+
+			return &parsedFile{
+				requests: statement.ToTransactionRequests(accountID.String(), opts.Currency),
+				info:     statement.Info(opts.Filename, opts.Currency),
+			}, nil
+		}, nil
+
+	case "csv":
+		profile := opts.Profile
+		if profile == nil {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "CSV imports require an import profile").WithDomain("transaction")
+		}
+		return func(r io.Reader) (*parsedFile, error) {
+			rows, _, err := ParseCSV(r, profile, accountID.String(), 0)
+			if err != nil {
+				return nil, err
+			}
+
+			parsed := &parsedFile{
+				requests:  make([]TransactionRequest, 0, len(rows)),
+				rowErrors: make([]string, 0),
+			}
+			for _, row := range rows {
+				if row.Transaction == nil {
+					parsed.rowErrors = append(parsed.rowErrors, fmt.Sprintf("row %d: %s", row.Row, row.Error))
+					continue
+				}
+				parsed.requests = append(parsed.requests, *row.Transaction)
+			}
+			parsed.info = csvStatementInfo(opts.Filename, profile.DefaultCurrency, parsed.requests)
+
+			s.logger.WithFields(logrus.Fields{
+				"user_id":      userID,
+				"account_id":   accountID,
+				"profile_id":   profile.ID,
+				"rows":         len(rows),
+				"invalid_rows": len(parsed.rowErrors),
+			}).Debug("Parsed CSV file")
+
+			return parsed, nil
+		}, nil
+
+	case "json":
+		return func(r io.Reader) (*parsedFile, error) {
+			var batch BatchTransactionRequest
+			if err := json.NewDecoder(r).Decode(&batch); err != nil {
+				return nil, err
+			}
+			return &parsedFile{requests: batch.Transactions}, nil
+		}, nil
+
+	default:
+		return nil, customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("unsupported import format '%s'", opts.Format)).WithDomain("transaction")
+	}
+}
+
+// ImportOFX parses an OFX/QFX statement and feeds it through ProcessTransactionBatch
+func (s *TransactionService) ImportOFX(ctx context.Context, userID, accountID uuid.UUID, filename string, r io.Reader) (*BatchOperationResult, error) {
+	return s.runImport(ctx, userID, accountID, ImportOptions{Format: "ofx", Filename: filename}, r)
+}
+
+// ImportBankStatement parses an ISO 20022 camt.053 ("camt053") or SWIFT MT940
+// ("mt940") statement and feeds it through ProcessTransactionBatch
+func (s *TransactionService) ImportBankStatement(ctx context.Context, userID, accountID uuid.UUID, format, filename string, r io.Reader) (*BatchOperationResult, error) {
+	if format != "camt053" && format != "mt940" {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("unsupported statement format '%s'", format)).WithDomain("transaction")
+	}
+	return s.runImport(ctx, userID, accountID, ImportOptions{Format: format, Filename: filename}, r)
+}
+
+// ImportQIF parses a Quicken Interchange Format file and feeds it through
+// ProcessTransactionBatch. QIF carries no currency, so the caller passes it.
+func (s *TransactionService) ImportQIF(ctx context.Context, userID, accountID uuid.UUID, filename, dateOrder, currency string, r io.Reader) (*BatchOperationResult, error) {
+	return s.runImport(ctx, userID, accountID, ImportOptions{Format: "qif", Filename: filename, DateOrder: dateOrder, Currency: currency}, r)
+}
+
+// ImportCSV parses a CSV bank export with the given profile and feeds the
+// mapped rows through ProcessTransactionBatch. Rows the profile cannot map are
+// reported in the result errors rather than failing the whole file.
+func (s *TransactionService) ImportCSV(ctx context.Context, userID, accountID uuid.UUID, profile *ImportProfile, filename string, r io.Reader) (*BatchOperationResult, error) {
+	return s.runImport(ctx, userID, accountID, ImportOptions{Format: "csv", Filename: filename, Profile: profile}, r)
+}
+
+// checkStatementBalance compares a statement's closing balance with the
+// account balance computed for the statement's balance date, or with the
+// account's CurrentBalance when it has no opening balance. A computed balance
+// that disagrees is recorded as drift on the account. Failures are logged,
+// never returned, since the check is informational and the transactions are
+// already imported.
+func (s *TransactionService) checkStatementBalance(ctx context.Context, userID, accountID uuid.UUID, info *StatementInfo) *BalanceCheck {
+	if info.ClosingBalance == nil || s.accountService == nil {
+		return nil
+	}
+	closingBalance := *info.ClosingBalance
+
+	check := &BalanceCheck{StatementBalance: closingBalance}
+
+	acc, err := s.accountService.GetAccountByID(ctx, userID, accountID)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":    userID,
+			"account_id": accountID,
+			"error":      err.Error(),
+		}).Warn("Could not load account for statement balance check")
+		return check
+	}
+
+	accountBalance := acc.CurrentBalance
+	computed := acc.OpeningBalance != nil && acc.OpeningBalanceDate != nil
+	if computed {
+		day := info.BalanceDate
+		if day == nil {
+			day = info.EndDate
+		}
+		if day != nil {
+			balance, err := s.balanceAtEndOf(ctx, userID, acc, *day)
+			if err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"user_id":    userID,
+					"account_id": accountID,
+					"error":      err.Error(),
+				}).Warn("Could not compute account balance for statement balance check")
+				return check
+			}
+			balanceDate := day.UTC().Truncate(24 * time.Hour)
+			accountBalance = &balance
+			check.BalanceDate = &balanceDate
+		}
+	}
+	if accountBalance == nil {
+		return check
+	}
+
+	difference := math.Round((closingBalance-*accountBalance)*100) / 100
+	check.AccountBalance = accountBalance
+	check.Difference = &difference
+	check.Matches = difference == 0
+
+	if computed {
+		var drift *float64
+		if !check.Matches {
+			drift = &difference
+		}
+		if err := s.accountService.SetBalanceDrift(ctx, userID, accountID, drift); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id":    userID,
+				"account_id": accountID,
+				"error":      err.Error(),
+			}).Warn("Failed to record balance drift")
+		}
+	}
+
+	if !check.Matches {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":           userID,
+			"account_id":        accountID,
+			"statement_balance": closingBalance,
+			"account_balance":   *accountBalance,
+			"difference":        difference,
+		}).Info("Statement closing balance differs from account balance")
+	}
+
+	return check
+}
+
+// PreviewCSVImport parses the first limit rows and runs them through input
+// validation without persisting anything
+func (s *TransactionService) PreviewCSVImport(ctx context.Context, userID, accountID uuid.UUID, profile *ImportProfile, r io.Reader, limit int) (*CSVImportPreview, error) {
+	rows, total, err := ParseCSV(r, profile, accountID.String(), limit)
+	if err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, "failed to parse CSV file").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	preview := &CSVImportPreview{
+		TotalRows: total,
+		Rows:      rows,
+	}
+	if profile.ID != uuid.Nil {
+		preview.ProfileID = &profile.ID
+	}
+
+	for i := range preview.Rows {
+		row := &preview.Rows[i]
+		if row.Transaction == nil {
+			continue
+		}
+		if _, err := s.ProcessTransactionInput(ctx, userID, *row.Transaction); err != nil {
+			row.Error = err.Error()
+			continue
+		}
+		preview.ValidRows++
+	}
+
+	return preview, nil
+}
+
+func csvStatementInfo(filename, currency string, requests []TransactionRequest) *StatementInfo {
+	info := &StatementInfo{
+		Format:           "csv",
+		Filename:         filename,
+		Currency:         currency,
+		TransactionCount: len(requests),
+	}
+
+	for _, request := range requests {
+		date, err := time.Parse("2006-01-02", request.TransactionDate)
+		if err != nil {
+			continue
+		}
+		if info.StartDate == nil || date.Before(*info.StartDate) {
+			info.StartDate = &date
+		}
+		if info.EndDate == nil || date.After(*info.EndDate) {
+			info.EndDate = &date
+		}
+	}
+
+	return info
+}
+
+// ========================================
+// IMPORT ROLLBACK
+// ========================================
+
+// RevertImport soft-deletes every transaction created from an imported file and
+// marks the upload reverted. Rows edited since the import are reported, and
+// left in place when req.KeepEdited is set.
+func (s *TransactionService) RevertImport(ctx context.Context, userID, uploadID uuid.UUID, req RevertImportRequest) (*ImportRevertResult, error) {
+	if s.fileUploadService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "file upload service not available").WithDomain("transaction")
+	}
+
+	upload, err := s.fileUploadService.GetFileUploadByID(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.ProcessingStatus != fileupload.StatusCompleted {
+		return nil, customerrors.New(customerrors.ErrCodeConflict, fmt.Sprintf("only completed imports can be reverted (status is '%s')", upload.ProcessingStatus)).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("file_upload_id", uploadID)
+	}
+
+	refs, err := s.repo.GetFileUploadTransactions(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportRevertResult{
+		FileUploadID: uploadID,
+		Status:       upload.ProcessingStatus,
+		Total:        len(refs),
+		EditedIDs:    make([]uuid.UUID, 0),
+		DryRun:       req.DryRun,
+	}
+	var keepIDs []uuid.UUID
+	for _, ref := range refs {
+		if ref.Edited {
+			result.EditedIDs = append(result.EditedIDs, ref.ID)
+		}
+		// Reconciled rows are locked and always stay
+		if ref.Reconciled {
+			result.Locked++
+			keepIDs = append(keepIDs, ref.ID)
+		} else if ref.Edited && req.KeepEdited {
+			keepIDs = append(keepIDs, ref.ID)
+		}
+	}
+	result.Edited = len(result.EditedIDs)
+	result.Kept = len(keepIDs)
+
+	if req.DryRun {
+		result.Reverted = result.Total - result.Kept
+		return result, nil
+	}
+
+	// Postgres keeps microseconds; truncate so restore can match deleted_at exactly
+	revertedAt := time.Now().UTC().Truncate(time.Microsecond)
+	if err := s.fileUploadService.RevertUpload(ctx, uploadID, revertedAt); err != nil {
+		return nil, err
+	}
+
+	reverted, err := s.repo.RevertFileUploadTransactions(ctx, userID, uploadID, keepIDs, revertedAt)
+	if err != nil {
+		if restoreErr := s.fileUploadService.RestoreUpload(ctx, userID, upload); restoreErr != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": uploadID,
+				"error":          restoreErr.Error(),
+			}).Warn("Failed to reset file upload status after revert error")
+		}
+		return nil, err
+	}
+
+	result.Reverted = int(reverted)
+	result.Status = fileupload.StatusReverted
+	s.refreshImportBalances(ctx, userID, uploadID)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"file_upload_id": uploadID,
+		"reverted":       result.Reverted,
+		"edited":         result.Edited,
+		"kept":           result.Kept,
+		"locked":         result.Locked,
+	}).Info("Import reverted")
+
+	return result, nil
+}
+
+// RestoreImport undoes RevertImport, bringing back the transactions it deleted
+func (s *TransactionService) RestoreImport(ctx context.Context, userID, uploadID uuid.UUID) (*ImportRevertResult, error) {
+	if s.fileUploadService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "file upload service not available").WithDomain("transaction")
+	}
+
+	upload, err := s.fileUploadService.GetFileUploadByID(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.ProcessingStatus != fileupload.StatusReverted || upload.RevertedAt == nil {
+		return nil, customerrors.New(customerrors.ErrCodeConflict, fmt.Sprintf("only reverted imports can be restored (status is '%s')", upload.ProcessingStatus)).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("file_upload_id", uploadID)
+	}
+
+	revertedAt := *upload.RevertedAt
+	if err := s.fileUploadService.RestoreUpload(ctx, userID, upload); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.repo.RestoreFileUploadTransactions(ctx, userID, uploadID, revertedAt)
+	if err != nil {
+		if revertErr := s.fileUploadService.RevertUpload(ctx, uploadID, revertedAt); revertErr != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": uploadID,
+				"error":          revertErr.Error(),
+			}).Warn("Failed to reset file upload status after restore error")
+		}
+		return nil, err
+	}
+
+	s.refreshImportBalances(ctx, userID, uploadID)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"file_upload_id": uploadID,
+		"restored":       restored,
+	}).Info("Import restored")
+
+	return &ImportRevertResult{
+		FileUploadID: uploadID,
+		Status:       fileupload.StatusCompleted,
+		Total:        int(restored),
+		Restored:     int(restored),
+	}, nil
+}
+
+// ========================================
+// IMPORT PROFILES
+// ========================================
+
+func (s *TransactionService) GetImportProfiles(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID) ([]ImportProfile, error) {
+	return s.repo.GetImportProfiles(ctx, userID, accountID)
+}
+
+func (s *TransactionService) GetImportProfile(ctx context.Context, userID, profileID uuid.UUID) (*ImportProfile, error) {
+	return s.repo.GetImportProfileByID(ctx, userID, profileID)
+}
+
+func (s *TransactionService) CreateImportProfile(ctx context.Context, userID uuid.UUID, req *CreateImportProfileRequest) (*ImportProfile, error) {
+	profile, err := NewImportProfile(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProfileAccount(ctx, userID, profile.AccountID); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateImportProfile(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"profile_id": profile.ID,
+		"name":       profile.Name,
+	}).Info("Import profile created")
+
+	return profile, nil
+}
+
+func (s *TransactionService) UpdateImportProfile(ctx context.Context, userID, profileID uuid.UUID, req *UpdateImportProfileRequest) (*ImportProfile, error) {
+	existing, err := s.repo.GetImportProfileByID(ctx, userID, profileID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]any)
+
+	if req.ClearAccount {
+		existing.AccountID = nil
+		updates["account_id"] = nil
+	} else if req.AccountID != nil {
+		if err := s.checkProfileAccount(ctx, userID, req.AccountID); err != nil {
+			return nil, err
+		}
+		existing.AccountID = req.AccountID
+		updates["account_id"] = *req.AccountID
+	}
+	if req.Name != nil {
+		existing.Name = strings.TrimSpace(*req.Name)
+		updates["name"] = existing.Name
+	}
+	if req.BankName != nil {
+		existing.BankName = req.BankName
+		updates["bank_name"] = *req.BankName
+	}
+	if req.Delimiter != nil {
+		existing.Delimiter = *req.Delimiter
+		updates["delimiter"] = *req.Delimiter
+	}
+	if req.SkipRows != nil {
+		existing.SkipRows = *req.SkipRows
+		updates["skip_rows"] = *req.SkipRows
+	}
+	if req.DateLayout != nil {
+		existing.DateLayout = strings.TrimSpace(*req.DateLayout)
+		updates["date_layout"] = existing.DateLayout
+	}
+	if req.DecimalSeparator != nil {
+		existing.DecimalSeparator = *req.DecimalSeparator
+		updates["decimal_separator"] = *req.DecimalSeparator
+	}
+	if req.SignConvention != nil {
+		existing.SignConvention = *req.SignConvention
+		updates["sign_convention"] = *req.SignConvention
+	}
+	if req.DefaultCurrency != nil {
+		existing.DefaultCurrency = strings.ToUpper(*req.DefaultCurrency)
+		updates["default_currency"] = existing.DefaultCurrency
+	}
+	if req.Columns != nil {
+		existing.Columns = *req.Columns
+		updates["col_date"] = req.Columns.Date
+		updates["col_description"] = req.Columns.Description
+		updates["col_amount"] = req.Columns.Amount
+		updates["col_debit"] = req.Columns.Debit
+		updates["col_credit"] = req.Columns.Credit
+		updates["col_merchant"] = req.Columns.Merchant
+		updates["col_memo"] = req.Columns.Memo
+		updates["col_reference"] = req.Columns.Reference
+		updates["col_currency"] = req.Columns.Currency
+	}
+
+	// Validate the merged profile, not just the changed fields
+	if err := existing.Validate(); err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, err.Error()).WithDomain("transaction")
+	}
+
+	return s.repo.UpdateImportProfile(ctx, userID, profileID, updates)
+}
+
+func (s *TransactionService) DeleteImportProfile(ctx context.Context, userID, profileID uuid.UUID) error {
+	return s.repo.DeleteImportProfile(ctx, userID, profileID)
+}
+
+// checkProfileAccount makes sure a profile is only bound to the user's own account
+func (s *TransactionService) checkProfileAccount(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID) error {
+	if accountID == nil || s.accountService == nil {
+		return nil
+	}
+	_, err := s.accountService.GetAccountByID(ctx, userID, *accountID)
+	return err
+}
+
+// ResolveImportProfile picks the profile for a CSV import: the requested one
+// when profileID is set, otherwise the most recently updated profile bound to
+// the account, falling back to the user's account-agnostic profiles
+func (s *TransactionService) ResolveImportProfile(ctx context.Context, userID, accountID uuid.UUID, profileID *uuid.UUID) (*ImportProfile, error) {
+	if profileID != nil {
+		profile, err := s.repo.GetImportProfileByID(ctx, userID, *profileID)
+		if err != nil {
+			return nil, err
+		}
+		if profile.AccountID != nil && *profile.AccountID != accountID {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "import profile belongs to a different account").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"profile_id": profile.ID,
+					"account_id": accountID,
+				})
+		}
+		return profile, nil
+	}
+
+	profiles, err := s.repo.GetImportProfiles(ctx, userID, &accountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "no import profile found for this account, create one or pass profile_id").
+			WithDomain("transaction").
+			WithDetail("account_id", accountID)
+	}
+	return &profiles[0], nil
+}
+
+// NewImportProfile builds a profile from a request, applying defaults and
+// validating the column mapping. The result is not persisted.
+func NewImportProfile(userID uuid.UUID, req *CreateImportProfileRequest) (*ImportProfile, error) {
+	profile := &ImportProfile{
+		UserID:           userID,
+		AccountID:        req.AccountID,
+		Name:             strings.TrimSpace(req.Name),
+		BankName:         req.BankName,
+		Delimiter:        req.Delimiter,
+		SkipRows:         1,
+		DateLayout:       strings.TrimSpace(req.DateLayout),
+		DecimalSeparator: req.DecimalSeparator,
+		SignConvention:   req.SignConvention,
+		DefaultCurrency:  strings.ToUpper(req.DefaultCurrency),
+		Columns:          req.Columns,
+	}
+
+	if req.SkipRows != nil {
+		profile.SkipRows = *req.SkipRows
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+	if profile.DecimalSeparator == "" {
+		profile.DecimalSeparator = "."
+	}
+	if profile.SignConvention == "" {
+		profile.SignConvention = "signed"
+	}
+	if profile.DefaultCurrency == "" {
+		profile.DefaultCurrency = "USD"
+	}
+
+	if err := profile.Validate(); err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, err.Error()).WithDomain("transaction")
+	}
+
+	return profile, nil
+}
@@ -0,0 +1,729 @@
+package transaction
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"hi-cfo/server/internal/domains/fileupload"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// IMPORT JOB QUEUE
+// ========================================
+
+const (
+	importQueueKey       = "import:queue"
+	importProcessingKey  = "import:processing" // Jobs a worker has taken but not acknowledged yet
+	importJobTTL         = 24 * time.Hour
+	importDequeueWait    = 5 * time.Second
+	importChunkSize      = 250 // Rows per ProcessTransactionBatch call, also the progress granularity
+	importLeaseTTL       = time.Minute
+	importSweepInterval  = time.Minute
+	importStaleAfter     = 10 * time.Minute // Unfinished queued uploads older than this without a job are failed
+	importMaxAttempts    = 3
+	importRequeueTimeout = 30 * time.Second
+)
+
+var errImportCancelled = errors.New("import cancelled")
+
+// importJob is the payload stored in Redis for a queued import
+type importJob struct {
+	UploadID  uuid.UUID     `json:"upload_id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	AccountID uuid.UUID     `json:"account_id"`
+	Options   ImportOptions `json:"options"`
+	Content   []byte        `json:"content"`
+	Attempts  int           `json:"attempts,omitempty"` // Runs that were interrupted before finishing
+}
+
+// ImportJobQueue keeps queued imports, their progress and cancellation requests
+// in Redis, so whichever server instance is free can run them. The queue itself
+// only holds upload IDs; payloads live under their own key with a TTL.
+//
+// Taking a job moves its ID to a processing list and gives the worker a lease
+// that it keeps renewing. Jobs stay there until they are acknowledged, so the
+// sweeper can requeue the ones whose worker died.
+type ImportJobQueue struct {
+	client *redis.Client
+}
+
+func NewImportJobQueue(client *redis.Client) *ImportJobQueue {
+	return &ImportJobQueue{client: client}
+}
+
+func importJobKey(uploadID string) string {
+	return "import:job:" + uploadID
+}
+
+func importProgressKey(uploadID string) string {
+	return "import:job:" + uploadID + ":progress"
+}
+
+func importCancelKey(uploadID string) string {
+	return "import:job:" + uploadID + ":cancel"
+}
+
+func importLeaseKey(uploadID string) string {
+	return "import:job:" + uploadID + ":lease"
+}
+
+func (q *ImportJobQueue) Enqueue(ctx context.Context, job *importJob) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode import job: %w", err)
+	}
+
+	id := job.UploadID.String()
+	pipe := q.client.TxPipeline()
+	pipe.Set(ctx, importJobKey(id), payload, importJobTTL)
+	pipe.LPush(ctx, importQueueKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to queue import job: %w", err)
+	}
+	return nil
+}
+
+// Dequeue blocks for up to wait for the next job and leases it to the caller.
+// It returns nil, nil when the queue stayed empty. A job whose payload is gone
+// is left without a lease for the sweeper.
+func (q *ImportJobQueue) Dequeue(ctx context.Context, wait time.Duration) (*importJob, error) {
+	id, err := q.client.BLMove(ctx, importQueueKey, importProcessingKey, "RIGHT", "LEFT", wait).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import queue: %w", err)
+	}
+
+	job, err := q.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("payload for import job %s has expired", id)
+	}
+	if err := q.RenewLease(ctx, job.UploadID); err != nil {
+		return nil, fmt.Errorf("failed to lease import job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+// Load returns the payload of a job, nil when it expired or was acknowledged
+func (q *ImportJobQueue) Load(ctx context.Context, id string) (*importJob, error) {
+	payload, err := q.client.Get(ctx, importJobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load import job %s: %w", id, err)
+	}
+
+	var job importJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode import job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+// HasJob reports whether the payload of a job is still stored
+func (q *ImportJobQueue) HasJob(ctx context.Context, uploadID uuid.UUID) (bool, error) {
+	exists, err := q.client.Exists(ctx, importJobKey(uploadID.String())).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// RenewLease tells the sweeper the job's worker is still alive
+func (q *ImportJobQueue) RenewLease(ctx context.Context, uploadID uuid.UUID) error {
+	return q.client.Set(ctx, importLeaseKey(uploadID.String()), "1", importLeaseTTL).Err()
+}
+
+// ExpiredLeases lists taken jobs whose worker stopped renewing the lease
+func (q *ImportJobQueue) ExpiredLeases(ctx context.Context) ([]string, error) {
+	ids, err := q.client.LRange(ctx, importProcessingKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import processing list: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := q.client.Pipeline()
+	leases := make([]*redis.IntCmd, len(ids))
+	for i, id := range ids {
+		leases[i] = pipe.Exists(ctx, importLeaseKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check import job leases: %w", err)
+	}
+
+	expired := make([]string, 0)
+	for i, id := range ids {
+		if leases[i].Val() == 0 {
+			expired = append(expired, id)
+		}
+	}
+	return expired, nil
+}
+
+// Claim takes a job off the processing list. Only one caller gets true, so a
+// job is requeued or dropped once even with several sweepers.
+func (q *ImportJobQueue) Claim(ctx context.Context, id string) (bool, error) {
+	removed, err := q.client.LRem(ctx, importProcessingKey, 1, id).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim import job %s: %w", id, err)
+	}
+	return removed > 0, nil
+}
+
+// Requeue puts a claimed job back at the head of the queue, counting the
+// interrupted run
+func (q *ImportJobQueue) Requeue(ctx context.Context, job *importJob) error {
+	job.Attempts++
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode import job: %w", err)
+	}
+
+	id := job.UploadID.String()
+	pipe := q.client.TxPipeline()
+	pipe.Set(ctx, importJobKey(id), payload, redis.KeepTTL)
+	pipe.Del(ctx, importLeaseKey(id))
+	pipe.RPush(ctx, importQueueKey, id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to requeue import job: %w", err)
+	}
+	return nil
+}
+
+func (q *ImportJobQueue) SetProgress(ctx context.Context, uploadID uuid.UUID, progress ImportJobProgress) error {
+	payload, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return q.client.Set(ctx, importProgressKey(uploadID.String()), payload, importJobTTL).Err()
+}
+
+// GetProgress returns nil when the job never started or its progress expired
+func (q *ImportJobQueue) GetProgress(ctx context.Context, uploadID uuid.UUID) (*ImportJobProgress, error) {
+	payload, err := q.client.Get(ctx, importProgressKey(uploadID.String())).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var progress ImportJobProgress
+	if err := json.Unmarshal(payload, &progress); err != nil {
+		return nil, err
+	}
+	return &progress, nil
+}
+
+func (q *ImportJobQueue) RequestCancel(ctx context.Context, uploadID uuid.UUID) error {
+	return q.client.Set(ctx, importCancelKey(uploadID.String()), "1", importJobTTL).Err()
+}
+
+func (q *ImportJobQueue) IsCancelRequested(ctx context.Context, uploadID uuid.UUID) (bool, error) {
+	exists, err := q.client.Exists(ctx, importCancelKey(uploadID.String())).Result()
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+// Finish acknowledges a job, dropping it from the processing list along with
+// its payload, lease and cancel flag. Progress is kept until it expires so the
+// status endpoint can still show the final counts.
+func (q *ImportJobQueue) Finish(ctx context.Context, uploadID uuid.UUID) error {
+	id := uploadID.String()
+	pipe := q.client.TxPipeline()
+	pipe.LRem(ctx, importProcessingKey, 1, id)
+	pipe.Del(ctx, importJobKey(id), importCancelKey(id), importLeaseKey(id))
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ========================================
+// IMPORT JOBS
+// ========================================
+
+// EnqueueImport records the file as a pending upload and queues it for a
+// worker. The format and duplicate file checks still run synchronously.
+func (s *TransactionService) EnqueueImport(ctx context.Context, userID, accountID uuid.UUID, opts ImportOptions, content []byte) (*fileupload.FileUpload, error) {
+	if s.jobQueue == nil || s.fileUploadService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "background imports are not available").WithDomain("transaction")
+	}
+
+	if _, err := s.importParser(userID, accountID, opts); err != nil {
+		return nil, err
+	}
+
+	upload, err := s.beginImport(ctx, userID, accountID, opts, content, true)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &importJob{
+		UploadID:  upload.ID,
+		UserID:    userID,
+		AccountID: accountID,
+		Options:   opts,
+		Content:   content,
+	}
+	if err := s.jobQueue.Enqueue(ctx, job); err != nil {
+		if failErr := s.fileUploadService.FailUpload(ctx, upload.ID, err.Error()); failErr != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": upload.ID,
+				"error":          failErr.Error(),
+			}).Warn("Failed to mark file upload as failed")
+		}
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeInternal, "failed to queue import").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"account_id":     accountID,
+		"file_upload_id": upload.ID,
+		"format":         opts.Format,
+		"size":           len(content),
+	}).Info("Import queued")
+
+	return upload, nil
+}
+
+// GetImportJob combines the upload record with the row progress kept in Redis
+func (s *TransactionService) GetImportJob(ctx context.Context, userID, uploadID uuid.UUID) (*ImportJobStatus, error) {
+	if s.fileUploadService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "file upload service not available").WithDomain("transaction")
+	}
+
+	upload, err := s.fileUploadService.GetFileUploadByID(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &ImportJobStatus{Upload: upload}
+	if s.jobQueue != nil {
+		progress, err := s.jobQueue.GetProgress(ctx, uploadID)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": uploadID,
+				"error":          err.Error(),
+			}).Warn("Failed to read import progress")
+		}
+		status.Progress = progress
+
+		if upload.ProcessingStatus == fileupload.StatusProcessing {
+			if requested, err := s.jobQueue.IsCancelRequested(ctx, uploadID); err == nil {
+				status.CancelRequested = requested
+			}
+		}
+	}
+
+	switch {
+	case upload.ProcessingStatus == fileupload.StatusCompleted:
+		status.Percent = 100
+	case status.Progress != nil && status.Progress.TotalRows > 0:
+		status.Percent = float64(status.Progress.ProcessedRows) / float64(status.Progress.TotalRows) * 100
+	}
+
+	return status, nil
+}
+
+// CancelImportJob cancels a queued import straight away. A running import is
+// flagged and stops after its current chunk, removing the rows it already created.
+func (s *TransactionService) CancelImportJob(ctx context.Context, userID, uploadID uuid.UUID) (*ImportJobStatus, error) {
+	if s.jobQueue == nil || s.fileUploadService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "background imports are not available").WithDomain("transaction")
+	}
+
+	upload, err := s.fileUploadService.GetFileUploadByID(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch upload.ProcessingStatus {
+	case fileupload.StatusPending:
+		cancelled, err := s.fileUploadService.CancelUpload(ctx, uploadID, fileupload.StatusPending, "cancelled before processing started")
+		if err != nil {
+			return nil, err
+		}
+		if cancelled {
+			break
+		}
+		// A worker picked it up in the meantime
+		fallthrough
+	case fileupload.StatusProcessing:
+		if err := s.jobQueue.RequestCancel(ctx, uploadID); err != nil {
+			return nil, customerrors.Wrap(err, customerrors.ErrCodeInternal, "failed to request import cancellation").
+				WithDomain("transaction").
+				WithUserID(userID)
+		}
+	default:
+		return nil, customerrors.New(customerrors.ErrCodeConflict, fmt.Sprintf("only pending or processing imports can be cancelled (status is '%s')", upload.ProcessingStatus)).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("file_upload_id", uploadID)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"file_upload_id": uploadID,
+		"status":         upload.ProcessingStatus,
+	}).Info("Import cancellation requested")
+
+	return s.GetImportJob(ctx, userID, uploadID)
+}
+
+// StartImportWorkers launches workers that run queued imports, and a sweeper
+// for jobs whose worker died, until ctx is done. The returned channel is
+// closed once every worker has put back or finished its job.
+func (s *TransactionService) StartImportWorkers(ctx context.Context, workers int) <-chan struct{} {
+	done := make(chan struct{})
+	if s.jobQueue == nil || s.fileUploadService == nil || workers <= 0 {
+		s.logger.Warn("Background import workers not started")
+		close(done)
+		return done
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			s.importWorker(ctx, worker)
+		}(i)
+	}
+	go s.importSweeper(ctx)
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	s.logger.WithFields(logrus.Fields{
+		"workers": workers,
+	}).Info("Background import workers started")
+
+	return done
+}
+
+func (s *TransactionService) importWorker(ctx context.Context, worker int) {
+	for ctx.Err() == nil {
+		job, err := s.jobQueue.Dequeue(ctx, importDequeueWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.WithFields(logrus.Fields{
+				"worker": worker,
+				"error":  err.Error(),
+			}).Error("Failed to take import job from queue")
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(importDequeueWait):
+			}
+			continue
+		}
+		if job == nil {
+			continue
+		}
+
+		s.runImportJob(ctx, job)
+	}
+}
+
+// runImportJob executes one queued import. Failures are recorded on the upload
+// by executeImport; cancellation removes whatever the job had already created.
+// A run interrupted by shutdown is rolled back and put back on the queue.
+func (s *TransactionService) runImportJob(ctx context.Context, job *importJob) {
+	jobLogger := s.logger.WithFields(logrus.Fields{
+		"user_id":        job.UserID,
+		"file_upload_id": job.UploadID,
+		"format":         job.Options.Format,
+	})
+
+	stopLease := s.keepImportLease(ctx, job, jobLogger)
+	defer func() {
+		stopLease()
+		if ctx.Err() != nil {
+			requeueCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), importRequeueTimeout)
+			defer cancel()
+			s.requeueImportJob(requeueCtx, job, jobLogger)
+			return
+		}
+		if err := s.jobQueue.Finish(ctx, job.UploadID); err != nil {
+			jobLogger.WithField("error", err.Error()).Warn("Failed to clean up import job")
+		}
+	}()
+
+	started, err := s.fileUploadService.StartUpload(ctx, job.UploadID)
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to start import job")
+		return
+	}
+	if !started {
+		jobLogger.Info("Skipping import job that is no longer pending")
+		return
+	}
+
+	parse, err := s.importParser(job.UserID, job.AccountID, job.Options)
+	if err != nil {
+		if failErr := s.fileUploadService.FailUpload(ctx, job.UploadID, err.Error()); failErr != nil {
+			jobLogger.WithField("error", failErr.Error()).Warn("Failed to mark file upload as failed")
+		}
+		return
+	}
+
+	progress := func(processed, total int, result *BatchOperationResult) error {
+		if err := s.jobQueue.SetProgress(ctx, job.UploadID, ImportJobProgress{
+			TotalRows:     total,
+			ProcessedRows: processed,
+			Created:       result.Created,
+			Skipped:       result.Skipped,
+			UpdatedAt:     time.Now().UTC(),
+		}); err != nil {
+			jobLogger.WithField("error", err.Error()).Warn("Failed to record import progress")
+		}
+
+		cancelled, err := s.jobQueue.IsCancelRequested(ctx, job.UploadID)
+		if err != nil {
+			jobLogger.WithField("error", err.Error()).Warn("Failed to check import cancellation")
+			return nil
+		}
+		if cancelled {
+			return errImportCancelled
+		}
+		return nil
+	}
+
+	result, err := s.executeImport(ctx, job.UserID, job.AccountID, &job.UploadID, job.Options, job.Content, parse, progress)
+	if errors.Is(err, errImportCancelled) {
+		s.cancelRunningImport(ctx, job, jobLogger)
+		return
+	}
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Warn("Import job failed")
+		return
+	}
+
+	jobLogger.WithFields(logrus.Fields{
+		"created": result.Created,
+		"skipped": result.Skipped,
+		"errors":  len(result.Errors),
+	}).Info("Import job completed")
+}
+
+// keepImportLease renews the job's lease while it runs. The returned func stops it.
+func (s *TransactionService) keepImportLease(ctx context.Context, job *importJob, jobLogger *logrus.Entry) func() {
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(importLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				if err := s.jobQueue.RenewLease(ctx, job.UploadID); err != nil {
+					jobLogger.WithField("error", err.Error()).Warn("Failed to renew import job lease")
+				}
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+func (s *TransactionService) cancelRunningImport(ctx context.Context, job *importJob, jobLogger *logrus.Entry) {
+	removed := s.discardImportRows(ctx, job.UserID, job.UploadID, jobLogger)
+
+	if _, err := s.fileUploadService.CancelUpload(ctx, job.UploadID, fileupload.StatusProcessing, "cancelled by user"); err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to mark import as cancelled")
+		return
+	}
+
+	jobLogger.WithFields(logrus.Fields{
+		"removed": removed,
+	}).Info("Import job cancelled")
+}
+
+// discardImportRows rolls back the rows an unfinished import created, including
+// a chunk whose insert committed before the run was interrupted
+func (s *TransactionService) discardImportRows(ctx context.Context, userID, uploadID uuid.UUID, jobLogger *logrus.Entry) int64 {
+	accountIDs, err := s.repo.GetFileUploadAccountIDs(ctx, userID, uploadID)
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Warn("Failed to find accounts of import for balance recalculation")
+	}
+
+	removed, attachments, err := s.repo.DiscardFileUploadTransactions(ctx, userID, uploadID)
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to remove transactions of unfinished import")
+		return 0
+	}
+	s.removeAttachmentFiles(ctx, attachments)
+	if removed > 0 {
+		s.refreshBalances(ctx, userID, accountIDs...)
+	}
+	return removed
+}
+
+// requeueImportJob rolls back an interrupted run and puts the job back on the
+// queue, or fails the upload once the job ran out of attempts
+func (s *TransactionService) requeueImportJob(ctx context.Context, job *importJob, jobLogger *logrus.Entry) {
+	claimed, err := s.jobQueue.Claim(ctx, job.UploadID.String())
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to claim interrupted import job")
+		return
+	}
+	if !claimed {
+		// Another sweeper got to it first
+		return
+	}
+
+	if job.Attempts+1 >= importMaxAttempts {
+		removed := s.discardImportRows(ctx, job.UserID, job.UploadID, jobLogger)
+		if _, err := s.fileUploadService.FailUnfinishedUpload(ctx, job.UploadID, "import was interrupted too many times"); err != nil {
+			jobLogger.WithField("error", err.Error()).Error("Failed to mark interrupted import as failed")
+		}
+		if err := s.jobQueue.Finish(ctx, job.UploadID); err != nil {
+			jobLogger.WithField("error", err.Error()).Warn("Failed to clean up import job")
+		}
+		jobLogger.WithFields(logrus.Fields{
+			"attempts": job.Attempts + 1,
+			"removed":  removed,
+		}).Warn("Import job failed after repeated interruptions")
+		return
+	}
+
+	requeued, err := s.fileUploadService.RequeueUpload(ctx, job.UploadID)
+	if err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to put interrupted import back to pending")
+	}
+	if !requeued {
+		// The run got as far as recording an outcome, or the upload is gone
+		if err := s.jobQueue.Finish(ctx, job.UploadID); err != nil {
+			jobLogger.WithField("error", err.Error()).Warn("Failed to clean up import job")
+		}
+		return
+	}
+
+	removed := s.discardImportRows(ctx, job.UserID, job.UploadID, jobLogger)
+	if err := s.jobQueue.Requeue(ctx, job); err != nil {
+		jobLogger.WithField("error", err.Error()).Error("Failed to requeue interrupted import job")
+		return
+	}
+
+	jobLogger.WithFields(logrus.Fields{
+		"attempts": job.Attempts,
+		"removed":  removed,
+	}).Info("Interrupted import job requeued")
+}
+
+// ========================================
+// IMPORT JOB SWEEPER
+// ========================================
+
+// importSweeper requeues jobs whose worker stopped renewing its lease and fails
+// queued uploads left pending or processing without a job, which would
+// otherwise block re-uploading the same file for good. Synchronous imports
+// never have a job and are not swept.
+func (s *TransactionService) importSweeper(ctx context.Context) {
+	ticker := time.NewTicker(importSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		s.sweepExpiredLeases(ctx)
+		s.sweepOrphanedUploads(ctx)
+	}
+}
+
+func (s *TransactionService) sweepExpiredLeases(ctx context.Context) {
+	ids, err := s.jobQueue.ExpiredLeases(ctx)
+	if err != nil {
+		s.logger.WithField("error", err.Error()).Warn("Failed to check import job leases")
+		return
+	}
+
+	for _, id := range ids {
+		job, err := s.jobQueue.Load(ctx, id)
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"file_upload_id": id,
+				"error":          err.Error(),
+			}).Warn("Failed to load interrupted import job")
+		}
+		if job == nil {
+			// Nothing to run again, sweepOrphanedUploads fails the upload
+			if _, err := s.jobQueue.Claim(ctx, id); err != nil {
+				s.logger.WithFields(logrus.Fields{
+					"file_upload_id": id,
+					"error":          err.Error(),
+				}).Warn("Failed to drop import job without payload")
+			}
+			continue
+		}
+
+		s.requeueImportJob(ctx, job, s.logger.WithFields(logrus.Fields{
+			"user_id":        job.UserID,
+			"file_upload_id": job.UploadID,
+			"format":         job.Options.Format,
+		}))
+	}
+}
+
+func (s *TransactionService) sweepOrphanedUploads(ctx context.Context) {
+	uploads, err := s.fileUploadService.GetUnfinishedUploads(ctx, time.Now().Add(-importStaleAfter), 100)
+	if err != nil {
+		return
+	}
+
+	for _, upload := range uploads {
+		uploadLogger := s.logger.WithFields(logrus.Fields{
+			"user_id":        upload.UserID,
+			"file_upload_id": upload.ID,
+		})
+
+		queued, err := s.jobQueue.HasJob(ctx, upload.ID)
+		if err != nil {
+			uploadLogger.WithField("error", err.Error()).Warn("Failed to look up import job")
+			continue
+		}
+		if queued {
+			continue
+		}
+
+		failed, err := s.fileUploadService.FailUnfinishedUpload(ctx, upload.ID, "import was interrupted and its job was lost")
+		if err != nil || !failed {
+			continue
+		}
+		removed := s.discardImportRows(ctx, upload.UserID, upload.ID, uploadLogger)
+
+		uploadLogger.WithFields(logrus.Fields{
+			"status":  upload.ProcessingStatus,
+			"removed": removed,
+		}).Warn("Failed import left without a job")
+	}
+}
@@ -0,0 +1,275 @@
+package transaction
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"hi-cfo/server/internal/domains/merchant"
+)
+
+// ========================================
+// MERCHANT NORMALIZATION
+// ========================================
+
+const merchantNameMaxLength = 200 // Size of transactions.merchant_name
+
+var (
+	// Payment method wording banks put in front of the merchant
+	merchantLeadingPhrases = regexp.MustCompile(`^(?:(?:CARD PAYMENT TO|CARD PURCHASE|DEBIT CARD PURCHASE|CHECKCARD|CHECK CARD|POS PURCHASE|POS|` +
+		`PURCHASE AUTHORI[SZ]ED ON \d{1,2}/\d{1,2}|PURCHASE|CONTACTLESS|VISA|MASTERCARD|DEBIT|` +
+		`RECURRING PAYMENT|PAYMENT TO|BILL PAYMENT)\b[\s:-]*)+`)
+
+	// Transfers, cash withdrawals and salaries name no merchant
+	merchantlessDescriptors = regexp.MustCompile(`^(?:TRANSFER|TFR|BANK TRANSFER|INTERNAL TRANSFER|FASTER PAYMENTS?|FPS|BACS|` +
+		`ATM|CASH WITHDRAWAL|CASH MACHINE|CASHPOINT|SALARY|PAYROLL|WAGES)\b`)
+
+	// Direct debits and standing orders, followed by the payee
+	merchantMandatePrefix = regexp.MustCompile(`^(?:DIRECT DEBIT|DD|STANDING ORDER|S/O)\b[\s:-]*(?:(?:PAYMENT )?TO\b\s*)?`)
+
+	// Cash withdrawals, which never form a recurring series
+	cashWithdrawalDescriptors = regexp.MustCompile(`^(?:ATM|CASH WITHDRAWAL|CASH MACHINE|CASHPOINT)\b`)
+
+	// Payment processors in front of the actual merchant, like "SQ *", "TST* ", "CRV*" or "PAYPAL *"
+	merchantProcessorPrefix = regexp.MustCompile(`^(?:SQ|TST|SP|CRV|IZ|IZI|ZTL|SUMUP|PAYPAL|PP|DRI|WPY|CKO|PY|GOOGLE|GGL) ?\* ?`)
+
+	// Dates and times like 2024-03-12, 12/03, 12.03.24, ON 29 JUN or 14:05
+	merchantDates = []*regexp.Regexp{
+		regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`),
+		regexp.MustCompile(`\b\d{1,2}[/.-]\d{1,2}(?:[/.-]\d{2,4})?\b`),
+		regexp.MustCompile(`\b(?:ON )?\d{1,2} ?(?:JAN|FEB|MAR|APR|MAY|JUN|JUL|AUG|SEP|OCT|NOV|DEC)[A-Z]*(?: ?\d{2,4})?\b`),
+		regexp.MustCompile(`\b\d{1,2}:\d{2}(?::\d{2})?\b`),
+	}
+
+	// NETFLIX.COM, AMAZON.CO.UK/BILL or WWW.EXAMPLE.COM keep only the name
+	merchantDomain = regexp.MustCompile(`\b(?:WWW\.)?([A-Z0-9-]+)\.(?:CO\.UK|COM|NET|ORG|IO|CO|UK|DE|FR|NL|IE|EU)\b(?:/\S*)?`)
+
+	// Store and reference numbers after "#", or labelled as references
+	merchantReferences = regexp.MustCompile(`#\S*|\bREF(?:ERENCE)?\b[:.\s]*\S+`)
+
+	merchantPunctuation = regexp.MustCompile(`[^A-Z0-9&' -]+`)
+)
+
+// Trailing tokens dropped from merchant names: countries, cities with their
+// US state, card scheme codes and legal suffixes
+var (
+	merchantCountryCodes = setOf("GB", "GBR", "UK", "US", "USA", "IE", "IRL", "FR", "FRA", "DE", "DEU", "NL", "NLD",
+		"ES", "ESP", "IT", "ITA", "BE", "BEL", "LU", "LUX", "CH", "CHE", "AT", "AUT", "PT", "PRT", "PL", "POL",
+		"SE", "SWE", "DK", "DNK", "CA", "CAN", "AU", "AUS")
+	merchantNoiseTokens = setOf("CLP", "CNP", "BCC", "DDR", "POS", "LTD", "LIMITED", "PLC", "INC", "LLC", "GMBH",
+		"CORP", "CO", "SARL", "BV", "AG", "SA")
+	merchantStateCodes = setOf("AL", "AK", "AZ", "AR", "CA", "CO", "CT", "DE", "FL", "GA", "HI", "ID", "IL", "IN",
+		"IA", "KS", "KY", "LA", "ME", "MD", "MA", "MI", "MN", "MS", "MO", "MT", "NE", "NV", "NH", "NJ", "NM", "NY",
+		"NC", "ND", "OH", "OK", "OR", "PA", "RI", "SC", "SD", "TN", "TX", "UT", "VT", "VA", "WA", "WV", "WI", "WY", "DC")
+	merchantCities = [][]string{
+		{"LONDON"}, {"MANCHESTER"}, {"BIRMINGHAM"}, {"LEEDS"}, {"GLASGOW"}, {"EDINBURGH"}, {"LIVERPOOL"},
+		{"BRISTOL"}, {"CARDIFF"}, {"BELFAST"}, {"DUBLIN"}, {"PARIS"}, {"BERLIN"}, {"AMSTERDAM"}, {"MADRID"},
+		{"BARCELONA"}, {"ROME"}, {"MILAN"}, {"LISBON"}, {"BRUSSELS"}, {"LUXEMBOURG"}, {"ZURICH"}, {"VIENNA"},
+		{"NEW", "YORK"}, {"LOS", "ANGELES"}, {"SAN", "FRANCISCO"}, {"SEATTLE"}, {"CHICAGO"}, {"BOSTON"},
+		{"TORONTO"}, {"SYDNEY"},
+	}
+)
+
+// merchantAliasIndex maps the spellings of the system merchants to their
+// canonical names. Spellings are matched against the leading words of the
+// cleaned descriptor in alias key form; the longest match wins.
+var merchantAliasIndex = func() map[string]string {
+	index := make(map[string]string)
+	for canonical, spellings := range merchant.SystemAliases {
+		for _, spelling := range spellings {
+			index[spelling] = canonical
+		}
+	}
+	return index
+}()
+
+// normalizeMerchantName derives a canonical merchant name from a raw bank
+// descriptor, e.g. "TESCO STORES 3345 LONDON GB" becomes "Tesco" and
+// "CRV*ZIPCAR 12/03" becomes "Zipcar". Unknown merchants come back cleaned
+// and title-cased, direct debits and standing orders as their payee. It
+// returns "" for transfers, cash withdrawals and salaries, and when nothing
+// that looks like a name is left.
+func normalizeMerchantName(raw string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(raw), " "))
+	if merchantlessDescriptors.MatchString(name) {
+		return ""
+	}
+	if prefix := merchantMandatePrefix.FindString(name); prefix != "" {
+		if name = strings.TrimSpace(name[len(prefix):]); name == "" {
+			return ""
+		}
+	}
+
+	name = stripMerchantPattern(name, merchantLeadingPhrases)
+	name = stripMerchantPattern(name, merchantProcessorPrefix)
+	for _, pattern := range merchantDates {
+		name = pattern.ReplaceAllString(name, " ")
+	}
+	name = merchantDomain.ReplaceAllString(name, "${1}")
+	name = merchantReferences.ReplaceAllString(name, " ")
+	name = merchantPunctuation.ReplaceAllString(name, " ")
+
+	tokens := dropMerchantCodes(strings.Fields(name))
+	if canonical, ok := matchMerchantAlias(tokens); ok {
+		return canonical
+	}
+
+	tokens = trimMerchantLocation(tokens)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	name = titleCaseMerchant(strings.Join(tokens, " "))
+	if utf8.RuneCountInString(name) > merchantNameMaxLength {
+		name = strings.TrimSpace(string([]rune(name)[:merchantNameMaxLength]))
+	}
+	return name
+}
+
+// importedMerchantName normalizes a payee read from an import file, which
+// carries the bank's descriptor much like the description does
+func importedMerchantName(raw string) *string {
+	if name := normalizeMerchantName(cleanOFXMerchantName(raw)); name != "" {
+		return &name
+	}
+	return nil
+}
+
+// descriptorKey reduces a descriptor without a merchant to the words that
+// repeat from one occurrence to the next, dropping dates, times and references
+func descriptorKey(raw string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(raw), " "))
+	if cashWithdrawalDescriptors.MatchString(name) {
+		return ""
+	}
+	for _, pattern := range merchantDates {
+		name = pattern.ReplaceAllString(name, " ")
+	}
+	name = merchantReferences.ReplaceAllString(name, " ")
+	name = merchantPunctuation.ReplaceAllString(name, " ")
+	return strings.ToLower(strings.Join(dropMerchantCodes(strings.Fields(name)), " "))
+}
+
+// stripMerchantPattern removes a leading pattern unless nothing would be left
+func stripMerchantPattern(name string, pattern *regexp.Regexp) string {
+	if stripped := strings.TrimSpace(pattern.ReplaceAllString(name, "")); stripped != "" {
+		return stripped
+	}
+	return name
+}
+
+// dropMerchantCodes drops reference codes and cuts the name at the first
+// store number, since what follows a store number is its location
+func dropMerchantCodes(tokens []string) []string {
+	kept := make([]string, 0, len(tokens))
+	for i, token := range tokens {
+		digits := 0
+		for _, r := range token {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		switch {
+		case digits == 0:
+			kept = append(kept, token)
+		case digits == len(token) && i > 0 && digits >= 3:
+			return kept
+		case digits == len(token) && digits >= 4:
+			// Leading reference number
+		case isAlphanumeric(token) && (len(token) >= 6 || digits >= 4):
+			// Mixed reference code like 2K3AB1
+		default:
+			kept = append(kept, token)
+		}
+	}
+	return kept
+}
+
+// trimMerchantLocation drops trailing countries, cities and noise tokens,
+// always keeping at least one token
+func trimMerchantLocation(tokens []string) []string {
+	for len(tokens) > 1 {
+		last := tokens[len(tokens)-1]
+		if merchantCountryCodes[last] || merchantNoiseTokens[last] {
+			tokens = tokens[:len(tokens)-1]
+			continue
+		}
+		if city := trailingMerchantCity(tokens); city > 0 && city < len(tokens) {
+			tokens = tokens[:len(tokens)-city]
+			continue
+		}
+		if merchantStateCodes[last] {
+			if city := trailingMerchantCity(tokens[:len(tokens)-1]); city > 0 && city < len(tokens)-1 {
+				tokens = tokens[:len(tokens)-1-city]
+				continue
+			}
+		}
+		break
+	}
+	return tokens
+}
+
+// trailingMerchantCity returns how many trailing tokens name a known city
+func trailingMerchantCity(tokens []string) int {
+	for _, city := range merchantCities {
+		if len(city) > len(tokens) {
+			continue
+		}
+		tail := tokens[len(tokens)-len(city):]
+		matched := true
+		for i := range city {
+			if tail[i] != city[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return len(city)
+		}
+	}
+	return 0
+}
+
+// matchMerchantAlias finds the longest known spelling the tokens start with
+func matchMerchantAlias(tokens []string) (string, bool) {
+	words := strings.Fields(merchant.AliasKey(strings.Join(tokens, " ")))
+	for n := len(words); n > 0; n-- {
+		if canonical, ok := merchantAliasIndex[strings.Join(words[:n], " ")]; ok {
+			return canonical, true
+		}
+	}
+	return "", false
+}
+
+// titleCaseMerchant turns "TESCO STORES" into "Tesco Stores" and "B&Q" into "B&Q"
+func titleCaseMerchant(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range strings.ToLower(name) {
+		if upper {
+			b.WriteRune(unicode.ToUpper(r))
+		} else {
+			b.WriteRune(r)
+		}
+		upper = r == ' ' || r == '-' || r == '&'
+	}
+	return b.String()
+}
+
+func isAlphanumeric(token string) bool {
+	for _, r := range token {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func setOf(values ...string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,205 @@
+package transaction
+
+import (
+	"context"
+
+	"hi-cfo/server/internal/domains/merchant"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// MERCHANT DIRECTORY
+// ========================================
+
+// resolveMerchants links transactions to directory merchants by their
+// normalized merchant name and raw description. The merchant's name replaces
+// the normalized one, and its default category is used when none is set.
+// Transfers between accounts have no merchant.
+func (s *TransactionService) resolveMerchants(ctx context.Context, userID uuid.UUID, transactions []*ProcessedTransaction) error {
+	pending := make([]*ProcessedTransaction, 0, len(transactions))
+	queries := make([]merchant.MerchantQuery, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.TransactionType == "transfer" {
+			continue
+		}
+		query := merchant.MerchantQuery{Description: tx.Description}
+		if tx.MerchantName != nil {
+			query.Name = *tx.MerchantName
+		}
+		pending = append(pending, tx)
+		queries = append(queries, query)
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+
+	resolved, err := s.merchantService.ResolveMerchants(ctx, userID, queries)
+	if err != nil {
+		return customerrors.Wrap(err, customerrors.ErrCodeInternal, "merchant resolution failed").WithDomain("transaction")
+	}
+
+	defaulted := 0
+	for i, tx := range pending {
+		m := resolved[i]
+		if m == nil {
+			continue
+		}
+		name := m.Name
+		tx.MerchantID = &m.ID
+		tx.MerchantName = &name
+		if tx.CategoryID == nil && m.DefaultCategoryID != nil {
+			categoryID := *m.DefaultCategoryID
+			tx.CategoryID = &categoryID
+			defaulted++
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"transaction_count": len(pending),
+		"defaulted_count":   defaulted,
+	}).Debug("Merchants resolved")
+
+	return nil
+}
+
+// RenameMerchant renames a merchant and every transaction linked to it
+func (s *TransactionService) RenameMerchant(ctx context.Context, userID, merchantID uuid.UUID, req merchant.RenameMerchantRequest) (*merchant.RenameMerchantResult, error) {
+	if s.merchantService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "merchant service not available").WithDomain("transaction")
+	}
+
+	editable, err := s.merchantService.EditableMerchant(ctx, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkMerchantUnlocked(ctx, userID, merchantRecordIDs(editable)); err != nil {
+		return nil, err
+	}
+
+	renamed, err := s.merchantService.RenameMerchant(ctx, userID, editable.ID, req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.ReassignMerchant(ctx, userID, merchantRecordIDs(renamed), renamed, ChangeSourceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":              userID,
+		"merchant_id":          renamed.ID,
+		"transactions_updated": updated,
+	}).Info("Merchant renamed successfully")
+
+	return &merchant.RenameMerchantResult{
+		Merchant:            renamed,
+		TransactionsUpdated: updated,
+	}, nil
+}
+
+// MergeMerchants moves the transactions of the source merchants to the
+// target and folds the sources into it
+func (s *TransactionService) MergeMerchants(ctx context.Context, userID uuid.UUID, req merchant.MergeMerchantsRequest) (*merchant.MergeMerchantsResult, error) {
+	if s.merchantService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "merchant service not available").WithDomain("transaction")
+	}
+
+	target, sources, err := s.merchantService.PrepareMerge(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceIDs := make([]uuid.UUID, len(sources))
+	fromIDs := merchantRecordIDs(target)
+	for i, source := range sources {
+		sourceIDs[i] = source.ID
+		fromIDs = append(fromIDs, merchantRecordIDs(&source)...)
+	}
+
+	updated, err := s.repo.ReassignMerchant(ctx, userID, fromIDs, target, ChangeSourceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	merged, err := s.merchantService.CompleteMerge(ctx, userID, target, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	return &merchant.MergeMerchantsResult{
+		Merchant:            merged,
+		MergedIDs:           sourceIDs,
+		TransactionsUpdated: updated,
+	}, nil
+}
+
+// UpdateMerchant changes a merchant's rules and details. A system merchant
+// gets the user's own copy, which their transactions then link to.
+func (s *TransactionService) UpdateMerchant(ctx context.Context, userID, merchantID uuid.UUID, req *merchant.UpdateMerchantRequest) (*merchant.Merchant, error) {
+	if s.merchantService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "merchant service not available").WithDomain("transaction")
+	}
+
+	editable, err := s.merchantService.EditableMerchant(ctx, userID, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	if editable.OverridesID != nil {
+		if err := s.checkMerchantUnlocked(ctx, userID, []uuid.UUID{*editable.OverridesID}); err != nil {
+			return nil, err
+		}
+	}
+
+	updated, err := s.merchantService.UpdateMerchant(ctx, userID, editable.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.OverridesID != nil {
+		if _, err := s.repo.ReassignMerchant(ctx, userID, []uuid.UUID{*updated.OverridesID}, updated, ChangeSourceAPI); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":     userID,
+		"merchant_id": updated.ID,
+	}).Info("Merchant updated successfully")
+
+	return updated, nil
+}
+
+// checkMerchantUnlocked refuses a merchant change that would move reconciled
+// transactions
+func (s *TransactionService) checkMerchantUnlocked(ctx context.Context, userID uuid.UUID, merchantIDs []uuid.UUID) error {
+	locked, err := s.repo.GetReconciledMerchantTransactions(ctx, userID, merchantIDs)
+	if err != nil {
+		return err
+	}
+	if len(locked) > 0 {
+		return reconciledMerchantError(locked).WithUserID(userID)
+	}
+	return nil
+}
+
+// merchantRecordIDs are the merchants whose transactions belong to m: m
+// itself and, for the user's copy of a system merchant, the system merchant
+func merchantRecordIDs(m *merchant.Merchant) []uuid.UUID {
+	ids := []uuid.UUID{m.ID}
+	if m.OverridesID != nil {
+		ids = append(ids, *m.OverridesID)
+	}
+	return ids
+}
+
+// GetMerchantSpend lists what the user spent per merchant, biggest first
+func (s *TransactionService) GetMerchantSpend(ctx context.Context, userID uuid.UUID, filter merchant.SpendFilter) ([]merchant.MerchantSpend, error) {
+	if filter.Limit == 0 {
+		filter.Limit = 50
+	}
+	return s.repo.GetMerchantSpend(ctx, userID, filter)
+}
@@ -0,0 +1,73 @@
+package transaction
+
+import "testing"
+
+func TestNormalizeMerchantName(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "TESCO STORES 3345 LONDON GB", want: "Tesco"},
+		{raw: "CRV*ZIPCAR 12/03", want: "Zipcar"},
+		{raw: "CARD PAYMENT TO NETFLIX.COM ON 29 JUN", want: "Netflix"},
+		{raw: "SQ *BLUE BOTTLE COFFEE SAN FRANCISCO CA", want: "Blue Bottle Coffee"},
+		{raw: "JOE'S DINER #1234 14:05", want: "Joe's Diner"},
+		{raw: "  acme   widgets ltd  ", want: "Acme Widgets"},
+		{raw: "DD BRITISH GAS", want: "British Gas"},
+		{raw: "DIRECT DEBIT PAYMENT TO THAMES WATER REF 123456", want: "Thames Water"},
+		{raw: "STANDING ORDER TO LANDLORD LTD", want: "Landlord"},
+		{raw: "S/O GYM CO", want: "Gym"},
+		{raw: "DIRECT DEBIT", want: ""},
+		{raw: "TRANSFER TO SAVINGS", want: ""},
+		{raw: "ATM WITHDRAWAL 12JAN", want: ""},
+		{raw: "SALARY ACME LTD", want: ""},
+		{raw: "12/03 14:05", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := normalizeMerchantName(tt.raw); got != tt.want {
+				t.Errorf("normalizeMerchantName(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportedMerchantName(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "AMZN MKTP UK ON 29 JUN CLP", want: "Amazon"},
+		{raw: "DD BRITISH GAS 1234567890123", want: "British Gas"},
+		{raw: "FASTER PAYMENT J SMITH", want: ""},
+		{raw: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := derefString(importedMerchantName(tt.raw)); got != tt.want {
+				t.Errorf("importedMerchantName(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescriptorKey(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "SALARY ACME LTD 25JAN", want: "salary acme ltd"},
+		{raw: "BACS REF 123456 PENSION", want: "bacs pension"},
+		{raw: "ATM WITHDRAWAL 12JAN", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := descriptorKey(tt.raw); got != tt.want {
+				t.Errorf("descriptorKey(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
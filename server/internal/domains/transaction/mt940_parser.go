@@ -0,0 +1,281 @@
+package transaction
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================
+// PARSING
+// ========================================
+
+var (
+	mt940TagPattern     = regexp.MustCompile(`^:(\d{2}[A-Z]?):(.*)$`)
+	mt940BalancePattern = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)`)
+	// :61: value date, optional entry date, D/C mark (RC/RD are reversals),
+	// optional funds code, amount, type code, customer and bank references
+	mt940StatementLinePattern = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})([^/]*?)(?://(.*))?$`)
+	mt940GermanSubfield       = regexp.MustCompile(`\?(\d{2})`)
+	mt940SwiftSubfield        = regexp.MustCompile(`/(NAME|REMI|EREF|IREF|CNTP|ORDP|BENM)/`)
+)
+
+type mt940Field struct {
+	tag   string
+	value string
+}
+
+// ParseMT940 reads a SWIFT MT940 customer statement, with or without the
+// {1:}{2:}{4: block envelope. Files holding several statements are merged.
+func ParseMT940(r io.Reader) (*BankStatement, error) {
+	fields, err := readMT940Fields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &BankStatement{
+		Format:  "mt940",
+		Entries: make([]BankStatementEntry, 0),
+	}
+
+	var current *BankStatementEntry
+	flush := func() {
+		if current != nil {
+			statement.Entries = append(statement.Entries, *current)
+			current = nil
+		}
+	}
+
+	for _, field := range fields {
+		switch field.tag {
+		case "25":
+			if statement.AccountID == "" {
+				statement.AccountID = strings.TrimSpace(field.value)
+			}
+		case "60F", "60M":
+			flush()
+			if statement.OpeningBalance != nil {
+				continue
+			}
+			amount, date, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, fmt.Errorf(":%s: %w", field.tag, err)
+			}
+			statement.OpeningBalance = &amount
+			statement.OpeningDate = &date
+			statement.Currency = currency
+		case "62F", "62M":
+			flush()
+			amount, date, currency, err := parseMT940Balance(field.value)
+			if err != nil {
+				return nil, fmt.Errorf(":%s: %w", field.tag, err)
+			}
+			statement.ClosingBalance = &amount
+			statement.ClosingDate = &date
+			if statement.Currency == "" {
+				statement.Currency = currency
+			}
+		case "61":
+			flush()
+			entry, err := parseMT940StatementLine(field.value)
+			if err != nil {
+				return nil, fmt.Errorf(":61: %w", err)
+			}
+			current = entry
+		case "86":
+			if current != nil {
+				counterparty, description := parseMT940Information(field.value)
+				current.Counterparty = counterparty
+				if description != "" {
+					current.Description = description
+				}
+				flush()
+			}
+		}
+	}
+	flush()
+
+	if statement.Currency == "" {
+		statement.Currency = "EUR"
+	}
+	if len(statement.Entries) == 0 {
+		return nil, errors.New("no :61: statement lines found in MT940 file")
+	}
+
+	return statement, nil
+}
+
+// readMT940Fields splits the message into tagged fields, joining continuation lines
+func readMT940Fields(r io.Reader) ([]mt940Field, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	fields := make([]mt940Field, 0)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+
+		// Strip the block envelope; text after "{4:" on the same line is data
+		if strings.HasPrefix(line, "{") {
+			if idx := strings.Index(line, "{4:"); idx >= 0 {
+				line = line[idx+3:]
+			} else {
+				continue
+			}
+		}
+		if line == "" || line == "-" || strings.HasPrefix(line, "-}") {
+			continue
+		}
+
+		if m := mt940TagPattern.FindStringSubmatch(line); m != nil {
+			fields = append(fields, mt940Field{tag: m[1], value: m[2]})
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].value += "\n" + line
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read MT940 content: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("invalid MT940 format: no tagged fields found")
+	}
+	return fields, nil
+}
+
+func parseMT940Balance(value string) (float64, time.Time, string, error) {
+	m := mt940BalancePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid balance '%s'", value)
+	}
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid balance date '%s'", m[2])
+	}
+	amount, err := parseMT940Amount(m[4])
+	if err != nil {
+		return 0, time.Time{}, "", fmt.Errorf("invalid balance amount '%s'", m[4])
+	}
+	return signedAmount(amount, m[1] == "D"), date, m[3], nil
+}
+
+func parseMT940StatementLine(value string) (*BankStatementEntry, error) {
+	// Supplementary details may follow on a second line
+	line, supplementary, _ := strings.Cut(value, "\n")
+	m := mt940StatementLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return nil, fmt.Errorf("invalid statement line '%s'", line)
+	}
+
+	valueDate, err := time.Parse("060102", m[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid value date '%s'", m[1])
+	}
+
+	bookingDate := valueDate
+	if m[2] != "" {
+		month, day := atoi(m[2][:2]), atoi(m[2][2:])
+		year := valueDate.Year()
+		// The entry date has no year; correct for statements spanning new year
+		switch {
+		case month == 12 && valueDate.Month() == time.January:
+			year--
+		case month == 1 && valueDate.Month() == time.December:
+			year++
+		}
+		if bookingDate, err = civilDate(year, month, day, m[2]); err != nil {
+			return nil, fmt.Errorf("invalid entry date '%s'", m[2])
+		}
+	}
+
+	amount, err := parseMT940Amount(m[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount '%s'", m[5])
+	}
+	// RC is a reversal of a credit (money out), RD a reversal of a debit (money in)
+	debit := m[3] == "D" || m[3] == "RC"
+
+	entry := &BankStatementEntry{
+		BookingDate:     bookingDate,
+		ValueDate:       &valueDate,
+		Amount:          signedAmount(amount, debit),
+		TransactionCode: m[6],
+		Description:     strings.TrimSpace(supplementary),
+	}
+
+	bankRef := strings.TrimSpace(m[8])
+	customerRef := strings.TrimSpace(m[7])
+	if strings.EqualFold(customerRef, "NONREF") {
+		customerRef = ""
+	}
+	entry.Reference = firstNonEmpty(bankRef, customerRef)
+
+	return entry, nil
+}
+
+// parseMT940Information extracts the counterparty and remittance text from
+// :86:. German banks use ?NN subfields (?20-?29 remittance, ?32/?33 name),
+// Dutch and SWIFT style banks use /NAME/ and /REMI/; anything else is kept
+// as free text.
+func parseMT940Information(value string) (counterparty, description string) {
+	value = strings.ReplaceAll(value, "\n", "")
+
+	if locs := mt940GermanSubfield.FindAllStringSubmatchIndex(value, -1); len(locs) > 1 {
+		var name, remittance []string
+		postingText := ""
+		for i, loc := range locs {
+			end := len(value)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			code := atoi(value[loc[2]:loc[3]])
+			text := strings.TrimSpace(value[loc[1]:end])
+			switch {
+			case code == 0:
+				postingText = text
+			case code >= 20 && code <= 29, code >= 60 && code <= 63:
+				remittance = append(remittance, text)
+			case code == 32 || code == 33:
+				name = append(name, text)
+			}
+		}
+		description = strings.Join(strings.Fields(strings.Join(remittance, " ")), " ")
+		if description == "" {
+			description = postingText
+		}
+		return strings.Join(name, ""), description
+	}
+
+	if locs := mt940SwiftSubfield.FindAllStringSubmatchIndex(value, -1); len(locs) > 0 {
+		for i, loc := range locs {
+			end := len(value)
+			if i+1 < len(locs) {
+				end = locs[i+1][0]
+			}
+			text := strings.Trim(strings.TrimSpace(value[loc[1]:end]), "/")
+			switch value[loc[2]:loc[3]] {
+			case "NAME":
+				counterparty = text
+			case "CNTP":
+				// account/BIC/name/city
+				if parts := strings.Split(text, "/"); len(parts) > 2 && counterparty == "" {
+					counterparty = strings.TrimSpace(parts[2])
+				}
+			case "REMI":
+				description = strings.TrimPrefix(text, "USTD//")
+			}
+		}
+		return counterparty, strings.TrimSpace(description)
+	}
+
+	return "", strings.Join(strings.Fields(value), " ")
+}
+
+func parseMT940Amount(value string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+}
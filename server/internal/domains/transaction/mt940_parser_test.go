@@ -0,0 +1,147 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+)
+
+const mt940StatementText = `{1:F01BANKDEFFXXXX0000000000}{2:O9400000000000BANKDEFFXXXX00000000000000000000N}{4:
+:20:STARTUMSE
+:25:10020030/1234567
+:28C:00001/001
+:60F:C241230EUR1000,00
+:61:2412301230D50,00NMSCNONREF//BANKREF1
+:86:166?00SEPA-UEBERWEISUNG?20Invoice 42?21December?32ACME Gm?33bH
+:61:2501020102RC12,34NTRFCUSTREF
+:86:/NAME/Shop Ltd/REMI/USTD//Refund order 7/
+:61:2501030102RD5,00NCHG//BANKREF3
+:86:Monthly   fee
+:62F:C250103EUR947,66
+-}`
+
+func TestParseMT940(t *testing.T) {
+	statement, err := ParseMT940(strings.NewReader(mt940StatementText))
+	if err != nil {
+		t.Fatalf("ParseMT940() error = %v", err)
+	}
+	if statement.AccountID != "10020030/1234567" || statement.Currency != "EUR" {
+		t.Errorf("account/currency = %s/%s", statement.AccountID, statement.Currency)
+	}
+	if statement.OpeningBalance == nil || *statement.OpeningBalance != 1000 {
+		t.Errorf("OpeningBalance = %v, want 1000", statement.OpeningBalance)
+	}
+	if statement.ClosingBalance == nil || *statement.ClosingBalance != 947.66 {
+		t.Errorf("ClosingBalance = %v, want 947.66", statement.ClosingBalance)
+	}
+
+	tests := []struct {
+		name         string
+		reference    string
+		booking      string
+		value        string
+		amount       float64
+		code         string
+		counterparty string
+		description  string
+	}{
+		{
+			name:         "German subfields, NONREF falls back to the bank reference",
+			reference:    "BANKREF1",
+			booking:      "2024-12-30",
+			value:        "2024-12-30",
+			amount:       -50,
+			code:         "NMSC",
+			counterparty: "ACME GmbH",
+			description:  "Invoice 42 December",
+		},
+		{
+			name:         "SWIFT subfields, credit reversal is money out",
+			reference:    "CUSTREF",
+			booking:      "2025-01-02",
+			value:        "2025-01-02",
+			amount:       -12.34,
+			code:         "NTRF",
+			counterparty: "Shop Ltd",
+			description:  "Refund order 7",
+		},
+		{
+			name:        "entry date in the previous year, debit reversal is money in",
+			reference:   "BANKREF3",
+			booking:     "2025-01-02",
+			value:       "2025-01-03",
+			amount:      5,
+			code:        "NCHG",
+			description: "Monthly fee",
+		},
+	}
+
+	if len(statement.Entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(statement.Entries), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := statement.Entries[i]
+			if got.Reference != tt.reference || got.BookingDate.Format("2006-01-02") != tt.booking ||
+				got.ValueDate.Format("2006-01-02") != tt.value || got.Amount != tt.amount || got.TransactionCode != tt.code ||
+				got.Counterparty != tt.counterparty || got.Description != tt.description {
+				t.Errorf("entry = %+v, want %+v", got, tt)
+			}
+		})
+	}
+}
+
+func TestParseMT940StatementLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		booking string
+		amount  float64
+		wantErr bool
+	}{
+		{line: "250115D100,NMSCNONREF", booking: "2025-01-15", amount: -100},
+		{line: "2501150116C1,5NTRFREF1//BANK1", booking: "2025-01-16", amount: 1.5},
+		{line: "2412311231RD0,99NCHG", booking: "2024-12-31", amount: 0.99},
+		{line: "2501150230D1,00NMSC", wantErr: true},
+		{line: "250115X1,00NMSC", wantErr: true},
+		{line: "991399D1,00NMSC", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			entry, err := parseMT940StatementLine(tt.line)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseMT940StatementLine(%q) = %+v, want an error", tt.line, entry)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseMT940StatementLine(%q) error = %v", tt.line, err)
+			}
+			if entry.BookingDate.Format("2006-01-02") != tt.booking || entry.Amount != tt.amount {
+				t.Errorf("entry = %s %v, want %s %v", entry.BookingDate.Format("2006-01-02"), entry.Amount, tt.booking, tt.amount)
+			}
+		})
+	}
+}
+
+func TestParseMT940Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr string
+	}{
+		{name: "no fields", input: "hello\nworld", wantErr: "no tagged fields"},
+		{name: "bad balance", input: ":60F:X250101EUR1,00\n:61:250101D1,00NMSC", wantErr: ":60F: invalid balance"},
+		{name: "bad line", input: ":61:garbage", wantErr: ":61: invalid statement line"},
+		{name: "no lines", input: ":20:REF\n:60F:C250101EUR1,00", wantErr: "no :61: statement lines"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMT940(strings.NewReader(tt.input))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("ParseMT940() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================
+// OFX MODELS
+// ========================================
+
+// OFXStatement is the parsed content of an OFX/QFX bank or credit card statement
+type OFXStatement struct {
+	Version       string           `json:"version"`
+	BankID        string           `json:"bank_id,omitempty"`
+	AccountID     string           `json:"account_id,omitempty"`
+	AccountType   string           `json:"account_type,omitempty"`
+	Currency      string           `json:"currency"`
+	StartDate     *time.Time       `json:"start_date,omitempty"`
+	EndDate       *time.Time       `json:"end_date,omitempty"`
+	LedgerBalance *float64         `json:"ledger_balance,omitempty"`
+	BalanceDate   *time.Time       `json:"balance_date,omitempty"`
+	Transactions  []OFXTransaction `json:"transactions"`
+}
+
+// OFXTransaction is a single STMTTRN record, or a STMTTRNP record of a
+// pending transaction, dated by its DTTRAN
+type OFXTransaction struct {
+	TrnType    string    `json:"trn_type"`
+	DatePosted time.Time `json:"date_posted"`
+	Pending    bool      `json:"pending,omitempty"`
+	Amount     float64   `json:"amount"`
+	FitID      string    `json:"fit_id"`
+	Name       string    `json:"name"`
+	Memo       string    `json:"memo,omitempty"`
+	CheckNum   string    `json:"check_num,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+}
+
+// ========================================
+// PARSING
+// ========================================
+
+var (
+	ofxDatePattern      = regexp.MustCompile(`^(\d{8})(?:\d{4,6})?(?:\.\d+)?(?:\[[^\]]*\])?$`)
+	ofxMerchantTrailers = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\s+ON\s+\d{2}\s+\w{3}\s+\w{2,3}$`), // "ON 29 JUN CLP" style endings
+		regexp.MustCompile(`\s+\d{10,}$`),                          // Long reference numbers
+	}
+	ofxWhitespace = regexp.MustCompile(`\s+`)
+
+	// Aggregates nested inside STMTTRN, every other aggregate ends the record
+	ofxRecordAggregates = map[string]bool{
+		"PAYEE": true, "BANKACCTTO": true, "CCACCTTO": true,
+		"CURRENCY": true, "ORIGCURRENCY": true, "IMAGEDATA": true,
+	}
+)
+
+// ParseOFX parses OFX 1.x (SGML) and OFX 2.x (XML) statements. Both versions are
+// read with the same tokenizer: SGML leaf elements have no closing tag, so a
+// leaf's value is the text between its opening tag and the next tag.
+func ParseOFX(r io.Reader) (*OFXStatement, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OFX content: %w", err)
+	}
+
+	start := bytes.Index(bytes.ToUpper(content), []byte("<OFX>"))
+	if start == -1 {
+		return nil, errors.New("invalid OFX format: missing <OFX> tag")
+	}
+
+	statement := &OFXStatement{
+		Version:      detectOFXVersion(string(content[:start])),
+		Currency:     "USD",
+		Transactions: make([]OFXTransaction, 0),
+	}
+
+	var (
+		current     *OFXTransaction
+		inLedgerBal bool
+	)
+
+	body := string(content[start:])
+	for len(body) > 0 {
+		open := strings.IndexByte(body, '<')
+		if open == -1 {
+			break
+		}
+		end := strings.IndexByte(body[open:], '>')
+		if end == -1 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(body[open+1 : open+end]))
+		body = body[open+end+1:]
+
+		// Skip processing instructions, comments and self-closing tags
+		if tag == "" || tag[0] == '?' || tag[0] == '!' || strings.HasSuffix(tag, "/") {
+			continue
+		}
+
+		if tag[0] == '/' {
+			switch tag[1:] {
+			case "STMTTRN", "STMTTRNP", "BANKTRANLIST":
+				// SGML files may leave the last record open until the list closes
+				if current != nil {
+					statement.Transactions = append(statement.Transactions, *current)
+					current = nil
+				}
+			case "LEDGERBAL":
+				inLedgerBal = false
+			}
+			continue
+		}
+
+		next := strings.IndexByte(body, '<')
+		if next == -1 {
+			next = len(body)
+		}
+		value := strings.TrimSpace(html.UnescapeString(body[:next]))
+
+		if value == "" {
+			// An empty XML leaf such as <MEMO></MEMO> is not an aggregate
+			if strings.HasPrefix(strings.ToUpper(body[next:]), "</"+tag+">") {
+				continue
+			}
+			// Aggregate element. One that cannot sit inside a record, such as
+			// LEDGERBAL, ends a record left open by an SGML file.
+			if current != nil && !ofxRecordAggregates[tag] {
+				statement.Transactions = append(statement.Transactions, *current)
+				current = nil
+			}
+			switch tag {
+			case "STMTTRN", "STMTTRNP":
+				current = &OFXTransaction{Pending: tag == "STMTTRNP"}
+			case "LEDGERBAL":
+				inLedgerBal = true
+			}
+			continue
+		}
+
+		if current != nil {
+			if err := current.setField(tag, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch tag {
+		case "BANKID":
+			statement.BankID = value
+		case "ACCTID":
+			if statement.AccountID == "" {
+				statement.AccountID = value
+			}
+		case "ACCTTYPE":
+			statement.AccountType = value
+		case "CURDEF":
+			statement.Currency = normalizeOFXCurrency(value)
+		case "DTSTART":
+			if date, err := ParseOFXDate(value); err == nil {
+				statement.StartDate = &date
+			}
+		case "DTEND":
+			if date, err := ParseOFXDate(value); err == nil {
+				statement.EndDate = &date
+			}
+		case "BALAMT":
+			if inLedgerBal {
+				if amount, err := parseOFXAmount(value); err == nil {
+					statement.LedgerBalance = &amount
+				}
+			}
+		case "DTASOF":
+			if inLedgerBal {
+				if date, err := ParseOFXDate(value); err == nil {
+					statement.BalanceDate = &date
+				}
+			}
+		}
+	}
+
+	// SGML files may omit </STMTTRN> before the closing list tag
+	if current != nil {
+		statement.Transactions = append(statement.Transactions, *current)
+	}
+
+	if len(statement.Transactions) == 0 {
+		return nil, errors.New("no transactions found in OFX statement")
+	}
+
+	return statement, nil
+}
+
+func (t *OFXTransaction) setField(tag, value string) error {
+	switch tag {
+	case "TRNTYPE":
+		t.TrnType = strings.ToUpper(value)
+	case "DTPOSTED":
+		date, err := ParseOFXDate(value)
+		if err != nil {
+			return err
+		}
+		t.DatePosted = date
+	case "DTTRAN":
+		// Pending transactions are not posted yet, they carry the transaction date
+		if t.Pending {
+			date, err := ParseOFXDate(value)
+			if err != nil {
+				return err
+			}
+			t.DatePosted = date
+		}
+	case "TRNAMT":
+		amount, err := parseOFXAmount(value)
+		if err != nil {
+			return fmt.Errorf("invalid TRNAMT '%s': %w", value, err)
+		}
+		t.Amount = amount
+	case "FITID":
+		t.FitID = value
+	case "NAME", "PAYEE":
+		if t.Name == "" {
+			t.Name = value
+		}
+	case "MEMO":
+		t.Memo = value
+	case "CHECKNUM":
+		t.CheckNum = value
+	case "CURDEF", "CURSYM":
+		t.Currency = normalizeOFXCurrency(value)
+	}
+	return nil
+}
+
+// ParseOFXDate parses OFX datetime values such as 20250630, 20250630120000 and
+// 20250630120000.000[-5:EST]. Only the calendar date is kept, matching how the
+// client importer has always treated DTPOSTED.
+func ParseOFXDate(value string) (time.Time, error) {
+	matches := ofxDatePattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date '%s'", value)
+	}
+	return time.Parse("20060102", matches[1])
+}
+
+func parseOFXAmount(value string) (float64, error) {
+	// Some European banks export decimal commas
+	return parseStatementAmount(value)
+}
+
+// parseStatementAmount reads an amount whose decimal separator is not known.
+// With both "." and "," the last one is the decimal separator. A lone
+// separator is decimal unless it repeats, then it separates thousands, so
+// "1,234.56", "1.234,56" and "12,50" all parse. Spaces and apostrophes used
+// as thousands separators are dropped.
+func parseStatementAmount(value string) (float64, error) {
+	value = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(strings.TrimSpace(value))
+
+	dot, comma := strings.LastIndex(value, "."), strings.LastIndex(value, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		if comma > dot {
+			value = strings.Replace(strings.ReplaceAll(value, ".", ""), ",", ".", 1)
+		} else {
+			value = strings.ReplaceAll(value, ",", "")
+		}
+	case comma >= 0:
+		if strings.Count(value, ",") > 1 {
+			value = strings.ReplaceAll(value, ",", "")
+		} else {
+			value = strings.Replace(value, ",", ".", 1)
+		}
+	case dot >= 0 && strings.Count(value, ".") > 1:
+		value = strings.ReplaceAll(value, ".", "")
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+func detectOFXVersion(header string) string {
+	upper := strings.ToUpper(header)
+	if strings.Contains(upper, "<?OFX") || strings.Contains(upper, "<?XML") {
+		return "2.x"
+	}
+	return "1.x"
+}
+
+func normalizeOFXCurrency(currency string) string {
+	normalized := strings.ToUpper(strings.TrimSpace(currency))
+	switch normalized {
+	case "", "$", "DOLLAR", "DOLLARS":
+		return "USD"
+	case "£", "POUND", "POUNDS":
+		return "GBP"
+	case "€", "EURO", "EUROS":
+		return "EUR"
+	}
+	return normalized
+}
+
+// ========================================
+// MAPPING
+// ========================================
+
+// Info summarises the statement for the import result
+func (s *OFXStatement) Info(filename string) *StatementInfo {
+	return &StatementInfo{
+		Format:           "ofx",
+		Filename:         filename,
+		BankAccountID:    s.AccountID,
+		Currency:         s.Currency,
+		StartDate:        s.StartDate,
+		EndDate:          s.EndDate,
+		ClosingBalance:   s.LedgerBalance,
+		BalanceDate:      s.BalanceDate,
+		TransactionCount: len(s.Transactions),
+	}
+}
+
+// ToTransactionRequests maps the statement into the unified batch input
+func (s *OFXStatement) ToTransactionRequests(accountID string) []TransactionRequest {
+	requests := make([]TransactionRequest, 0, len(s.Transactions))
+	for _, txn := range s.Transactions {
+		requests = append(requests, txn.toTransactionRequest(accountID, s.Currency))
+	}
+	return requests
+}
+
+func (t OFXTransaction) toTransactionRequest(accountID, statementCurrency string) TransactionRequest {
+	description := t.Name
+	if description == "" {
+		description = t.Memo
+	}
+	if description == "" {
+		description = "Imported Transaction"
+	}
+
+	currency := t.Currency
+	if currency == "" {
+		currency = statementCurrency
+	}
+
+	request := TransactionRequest{
+		AccountID:       accountID,
+		TransactionDate: t.DatePosted.Format("2006-01-02"),
+		Description:     description,
+		Amount:          t.Amount,
+		TransactionType: mapOFXTransactionType(t.TrnType, t.Amount),
+		Currency:        currency,
+	}
+
+	if t.Pending {
+		request.Status = TransactionStatusPending
+	}
+	if t.FitID != "" {
+		fitID := t.FitID
+		request.FitID = &fitID
+	}
+	request.MerchantName = importedMerchantName(t.Name)
+	if t.Memo != "" {
+		memo := t.Memo
+		request.Memo = &memo
+	}
+	if t.CheckNum != "" {
+		checkNum := t.CheckNum
+		request.ReferenceNumber = &checkNum
+	}
+
+	return request
+}
+
+// mapOFXTransactionType mirrors OFXParser.mapTransactionType in the client
+func mapOFXTransactionType(trnType string, amount float64) string {
+	switch trnType {
+	case "XFER", "TRANSFER":
+		return "transfer"
+	case "INT":
+		if amount > 0 {
+			return "interest"
+		}
+	case "DIV":
+		if amount > 0 {
+			return "dividend"
+		}
+	case "FEE", "SRVCHG":
+		if amount < 0 {
+			return "fee"
+		}
+	}
+
+	if amount > 0 {
+		return "income"
+	}
+	return "expense"
+}
+
+func cleanOFXMerchantName(name string) string {
+	for _, pattern := range ofxMerchantTrailers {
+		name = pattern.ReplaceAllString(name, "")
+	}
+	return strings.TrimSpace(ofxWhitespace.ReplaceAllString(name, " "))
+}
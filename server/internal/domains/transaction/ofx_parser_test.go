@@ -0,0 +1,281 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const ofxSGMLStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>GBP
+<BANKACCTFROM><BANKID>400000<ACCTID>12345678<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20250601
+<DTEND>20250630
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250602120000.000[0:GMT]
+<TRNAMT>-12,50
+<FITID>A1
+<NAME>TESCO STORES 1234 ON 01 JUN BCC
+<MEMO>Groceries &amp; more
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250603
+<TRNAMT>1.234,56
+<FITID>A2
+<PAYEE>ACME LTD
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1222.06<DTASOF>20250630</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+const ofxXMLStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CURDEF>EUR</CURDEF>
+<CCACCTFROM><ACCTID>4111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>FEE</TRNTYPE>
+<DTPOSTED>20250710</DTPOSTED>
+<TRNAMT>-2.00</TRNAMT>
+<FITID>F1</FITID>
+<NAME>CARD FEE</NAME>
+<MEMO></MEMO>
+<CURRENCY><CURSYM>USD</CURSYM></CURRENCY>
+</STMTTRN>
+<STMTTRNP>
+<TRNTYPE>POS</TRNTYPE>
+<DTTRAN>20250711</DTTRAN>
+<TRNAMT>-9.99</TRNAMT>
+<FITID>P1</FITID>
+<NAME>COFFEE SHOP</NAME>
+</STMTTRNP>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>`
+
+func TestParseOFX(t *testing.T) {
+	date := func(value string) time.Time {
+		parsed, _ := time.Parse("2006-01-02", value)
+		return parsed
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		wantErr      string
+		version      string
+		currency     string
+		accountID    string
+		ledger       *float64
+		transactions []OFXTransaction
+	}{
+		{
+			name:      "SGML with unclosed records and decimal commas",
+			input:     ofxSGMLStatement,
+			version:   "1.x",
+			currency:  "GBP",
+			accountID: "12345678",
+			ledger:    func() *float64 { v := 1222.06; return &v }(),
+			transactions: []OFXTransaction{
+				{TrnType: "DEBIT", DatePosted: date("2025-06-02"), Amount: -12.5, FitID: "A1", Name: "TESCO STORES 1234 ON 01 JUN BCC", Memo: "Groceries & more"},
+				{TrnType: "CREDIT", DatePosted: date("2025-06-03"), Amount: 1234.56, FitID: "A2", Name: "ACME LTD"},
+			},
+		},
+		{
+			name:      "XML with a pending record dated by DTTRAN",
+			input:     ofxXMLStatement,
+			version:   "2.x",
+			currency:  "EUR",
+			accountID: "4111",
+			transactions: []OFXTransaction{
+				{TrnType: "FEE", DatePosted: date("2025-07-10"), Amount: -2, FitID: "F1", Name: "CARD FEE", Currency: "USD"},
+				{TrnType: "POS", DatePosted: date("2025-07-11"), Pending: true, Amount: -9.99, FitID: "P1", Name: "COFFEE SHOP"},
+			},
+		},
+		{
+			name:    "missing OFX tag",
+			input:   "OFXHEADER:100\n<BANKTRANLIST></BANKTRANLIST>",
+			wantErr: "missing <OFX> tag",
+		},
+		{
+			name:    "no transactions",
+			input:   "<OFX><CURDEF>USD</CURDEF></OFX>",
+			wantErr: "no transactions found",
+		},
+		{
+			name:    "invalid amount",
+			input:   "<OFX><STMTTRN><DTPOSTED>20250101<TRNAMT>abc</STMTTRN></OFX>",
+			wantErr: "invalid TRNAMT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := ParseOFX(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseOFX() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOFX() error = %v", err)
+			}
+			if statement.Version != tt.version || statement.Currency != tt.currency || statement.AccountID != tt.accountID {
+				t.Errorf("statement = %s/%s/%s, want %s/%s/%s", statement.Version, statement.Currency, statement.AccountID, tt.version, tt.currency, tt.accountID)
+			}
+			if (statement.LedgerBalance == nil) != (tt.ledger == nil) || (tt.ledger != nil && *statement.LedgerBalance != *tt.ledger) {
+				t.Errorf("LedgerBalance = %v, want %v", statement.LedgerBalance, tt.ledger)
+			}
+			if len(statement.Transactions) != len(tt.transactions) {
+				t.Fatalf("got %d transactions, want %d", len(statement.Transactions), len(tt.transactions))
+			}
+			for i, want := range tt.transactions {
+				if got := statement.Transactions[i]; got != want {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOFXTransactionRequest(t *testing.T) {
+	posted, _ := time.Parse("2006-01-02", "2025-07-11")
+
+	tests := []struct {
+		name        string
+		transaction OFXTransaction
+		status      string
+		fitID       string
+		merchant    string
+		txType      string
+		currency    string
+	}{
+		{
+			name:        "posted debit",
+			transaction: OFXTransaction{TrnType: "DEBIT", DatePosted: posted, Amount: -12.5, FitID: "A1", Name: "TESCO STORES 1234 ON 01 JUN BCC"},
+			fitID:       "A1",
+			merchant:    "Tesco",
+			txType:      "expense",
+			currency:    "GBP",
+		},
+		{
+			name:        "pending keeps its FitID",
+			transaction: OFXTransaction{TrnType: "POS", DatePosted: posted, Pending: true, Amount: -9.99, FitID: "P1", Name: "COFFEE SHOP"},
+			status:      TransactionStatusPending,
+			fitID:       "P1",
+			merchant:    "Coffee Shop",
+			txType:      "expense",
+			currency:    "GBP",
+		},
+		{
+			name:        "interest in its own currency",
+			transaction: OFXTransaction{TrnType: "INT", DatePosted: posted, Amount: 0.42, Currency: "EUR"},
+			txType:      "interest",
+			currency:    "EUR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.transaction.toTransactionRequest("acc", "GBP")
+			if request.Status != tt.status {
+				t.Errorf("Status = %q, want %q", request.Status, tt.status)
+			}
+			if got := derefString(request.FitID); got != tt.fitID {
+				t.Errorf("FitID = %q, want %q", got, tt.fitID)
+			}
+			if got := derefString(request.MerchantName); got != tt.merchant {
+				t.Errorf("MerchantName = %q, want %q", got, tt.merchant)
+			}
+			if request.TransactionType != tt.txType || request.Currency != tt.currency {
+				t.Errorf("type/currency = %s/%s, want %s/%s", request.TransactionType, request.Currency, tt.txType, tt.currency)
+			}
+			if request.TransactionDate != "2025-07-11" {
+				t.Errorf("TransactionDate = %s, want 2025-07-11", request.TransactionDate)
+			}
+		})
+	}
+}
+
+func TestParseOFXDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "20250630", want: "2025-06-30"},
+		{value: "20250630120000", want: "2025-06-30"},
+		{value: "20250630120000.000[-5:EST]", want: "2025-06-30"},
+		{value: " 202506301200 ", want: "2025-06-30"},
+		{value: "2025-06-30", wantErr: true},
+		{value: "20251340", wantErr: true},
+		{value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseOFXDate(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseOFXDate(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOFXDate(%q) error = %v", tt.value, err)
+			}
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("ParseOFXDate(%q) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStatementAmount(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "12.50", want: 12.5},
+		{value: "-12,50", want: -12.5},
+		{value: "1,234.56", want: 1234.56},
+		{value: "1.234,56", want: 1234.56},
+		{value: "-1.234.567", want: -1234567},
+		{value: "1,234,567", want: 1234567},
+		{value: "1 234,56", want: 1234.56},
+		{value: "1 234.56", want: 1234.56},
+		{value: "1'234.56", want: 1234.56},
+		{value: " +7 ", want: 7},
+		{value: "", wantErr: true},
+		{value: "12.50 GBP", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseStatementAmount(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseStatementAmount(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStatementAmount(%q) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseStatementAmount(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package transaction
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"hi-cfo/server/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// PENDING TRANSACTIONS
+// ========================================
+
+func (s *TransactionService) getPendingMatchConfig() PendingMatchConfig {
+	return PendingMatchConfig{
+		DateWindowDays:  config.GetPendingDateWindowDays(),
+		AmountTolerance: config.GetPendingAmountTolerance(),
+		MinSimilarity:   config.GetPendingMinSimilarity(),
+	}
+}
+
+// matchPendingTransactions posts the user's pending transactions that incoming
+// rows settle, instead of creating those rows next to them. A posted row with
+// the FitID of a pending transaction settles it directly, the others are
+// matched by amount, date and description. Incoming pending rows that are
+// already known are dropped. It returns the rows still to be created, the
+// pending transactions that were posted and the number of rows dropped.
+// Failures are logged and everything is created as before.
+func (s *TransactionService) matchPendingTransactions(ctx context.Context, userID uuid.UUID, transactions []*Transaction) ([]*Transaction, []uuid.UUID, int) {
+	// Other rows whose FitID is already imported are left to duplicate detection
+	fitIDs := make([]string, 0)
+	for _, tx := range transactions {
+		if tx.FitID != nil && strings.TrimSpace(*tx.FitID) != "" {
+			fitIDs = append(fitIDs, strings.ToLower(strings.TrimSpace(*tx.FitID)))
+		}
+	}
+	existing, err := s.repo.GetTransactionsByFitIDs(ctx, userID, fitIDs)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Pending transaction matching failed, creating transactions unmatched")
+		return transactions, nil, 0
+	}
+
+	candidates := make([]*Transaction, 0, len(transactions))
+	matches := make(map[int]uuid.UUID)
+	matched := make(map[uuid.UUID]bool)
+	unmatched := make([]*Transaction, 0, len(transactions))
+	unmatchedIdx := make([]int, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.FitID != nil {
+			if known := existing[strings.ToLower(strings.TrimSpace(*tx.FitID))]; known != nil {
+				if known.Status == TransactionStatusPending && tx.Status == TransactionStatusPosted &&
+					known.AccountID == tx.AccountID && !matched[known.ID] {
+					matches[len(candidates)] = known.ID
+					matched[known.ID] = true
+					candidates = append(candidates, tx)
+				}
+				continue
+			}
+		}
+		unmatchedIdx = append(unmatchedIdx, len(candidates))
+		unmatched = append(unmatched, tx)
+		candidates = append(candidates, tx)
+	}
+	if len(unmatched) > 0 {
+		found, err := s.repo.FindPendingMatches(ctx, userID, unmatched, s.getPendingMatchConfig())
+		if err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id": userID,
+				"error":   err.Error(),
+			}).Warn("Pending transaction matching failed, creating transactions unmatched")
+			return transactions, nil, 0
+		}
+		for i, pendingID := range found {
+			// A pending transaction settled by its FitID is taken already
+			if !matched[pendingID] {
+				matches[unmatchedIdx[i]] = pendingID
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return transactions, nil, 0
+	}
+
+	pendingIDs := make([]uuid.UUID, 0, len(matches))
+	for _, pendingID := range matches {
+		pendingIDs = append(pendingIDs, pendingID)
+	}
+	pending, err := s.repo.GetTransactionsByIDs(ctx, userID, pendingIDs)
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Pending transaction matching failed, creating transactions unmatched")
+		return transactions, nil, 0
+	}
+	return s.postMatchedTransactions(ctx, userID, transactions, candidates, matches, pending)
+}
+
+// postMatchedTransactions applies the matched posted rows to their pending
+// transactions and returns what matchPendingTransactions does
+func (s *TransactionService) postMatchedTransactions(ctx context.Context, userID uuid.UUID, transactions, candidates []*Transaction, matches map[int]uuid.UUID, pending map[uuid.UUID]*Transaction) ([]*Transaction, []uuid.UUID, int) {
+	handled := make(map[*Transaction]bool, len(matches))
+	updates := make(map[uuid.UUID]map[string]any)
+	known := 0
+	for i, pendingID := range matches {
+		tx, existing := candidates[i], pending[pendingID]
+		// Reconciled transactions stay as they are, the row is created instead
+		if existing == nil || existing.ReconciledAt != nil {
+			continue
+		}
+		handled[tx] = true
+		if tx.Status == TransactionStatusPending {
+			known++
+			continue
+		}
+		updates[pendingID] = postedUpdates(existing, tx)
+	}
+
+	var posted []uuid.UUID
+	if len(updates) > 0 {
+		var err error
+		if posted, err = s.repo.PostPendingTransactions(ctx, userID, updates, ChangeSourceImport); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id": userID,
+				"error":   err.Error(),
+			}).Warn("Posting pending transactions failed, creating the posted rows instead")
+		}
+	}
+
+	// Rows whose pending transaction was not posted, because it failed or was
+	// posted or deleted in the meantime, are created instead
+	postedSet := make(map[uuid.UUID]bool, len(posted))
+	for _, id := range posted {
+		postedSet[id] = true
+	}
+	for i, pendingID := range matches {
+		if _, ok := updates[pendingID]; ok && !postedSet[pendingID] {
+			delete(handled, candidates[i])
+		}
+	}
+
+	remaining := make([]*Transaction, 0, len(transactions)-len(handled))
+	for _, tx := range transactions {
+		if !handled[tx] {
+			remaining = append(remaining, tx)
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"posted":  len(posted),
+		"known":   known,
+	}).Info("Matched incoming transactions to pending transactions")
+
+	return remaining, posted, known
+}
+
+// postedUpdates are the column updates that turn a pending transaction into
+// the posted one. What the bank settled, its description included, replaces
+// the pending details. The user's category, user_description, user_notes and
+// tags are kept.
+func postedUpdates(pending, posted *Transaction) map[string]any {
+	postedDate := posted.TransactionDate
+	if posted.PostedDate != nil {
+		postedDate = *posted.PostedDate
+	}
+
+	updates := map[string]any{
+		"status":      TransactionStatusPosted,
+		"posted_date": postedDate,
+		"amount":      posted.Amount,
+		"description": posted.Description,
+		"currency":    posted.Currency,
+	}
+	if posted.MerchantName != nil {
+		updates["merchant_name"] = posted.MerchantName
+		updates["merchant_id"] = posted.MerchantID
+	}
+	if pending.CounterpartID == nil {
+		updates["transaction_type"] = posted.TransactionType
+	}
+	if pending.CategoryID == nil && posted.CategoryID != nil {
+		updates["category_id"] = posted.CategoryID
+	}
+	if posted.FitID != nil {
+		updates["fit_id"] = posted.FitID
+	}
+	if posted.ReferenceNumber != nil {
+		updates["reference_number"] = posted.ReferenceNumber
+	}
+	if posted.Memo != nil {
+		updates["memo"] = posted.Memo
+	}
+
+	tags := slices.Clone([]string(pending.Tags))
+	for _, tag := range posted.Tags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > len(pending.Tags) {
+		updates["tags"] = pq.StringArray(tags)
+	}
+	return updates
+}
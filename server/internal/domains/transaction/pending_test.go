@@ -0,0 +1,418 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"hi-cfo/server/internal/domains/category"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestScorePendingMatch(t *testing.T) {
+	cfg := PendingMatchConfig{DateWindowDays: 7, AmountTolerance: 0.2, MinSimilarity: 0.5}
+	authorised := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	booked := authorised.AddDate(0, 0, 2)
+	merchant, otherMerchant := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name           string
+		tx             Transaction
+		row            pendingMatchRow
+		wantOK         bool
+		wantSimilarity float64
+		wantAmountDiff int64
+		wantDayDiff    float64
+	}{
+		{
+			name:           "posted row with a tip, same merchant",
+			tx:             Transaction{Status: TransactionStatusPosted, Amount: -24, Description: "RESTAURANT 123 LONDON", TransactionDate: authorised, PostedDate: &booked, MerchantID: &merchant},
+			row:            pendingMatchRow{Amount: -20, Description: "PENDING RESTAURANT", TransactionDate: authorised, MerchantID: &merchant},
+			wantOK:         true,
+			wantSimilarity: 1,
+			wantAmountDiff: 400,
+			wantDayDiff:    2,
+		},
+		{
+			name:           "same merchant name without directory merchants",
+			tx:             Transaction{Status: TransactionStatusPosted, Amount: -5, Description: "CARD 1234 COFFEE", TransactionDate: booked, MerchantName: stringPtr("Coffee Shop")},
+			row:            pendingMatchRow{Amount: -5, Description: "AUTH COFFEE", TransactionDate: authorised, MerchantName: stringPtr("coffee shop")},
+			wantOK:         true,
+			wantSimilarity: 1,
+			wantDayDiff:    2,
+		},
+		{
+			name:           "similar description",
+			tx:             Transaction{Status: TransactionStatusPosted, Amount: -5, Description: "COFFEE SHOP LONDON", TransactionDate: authorised},
+			row:            pendingMatchRow{Amount: -5, Description: "COFFEE SHOP", TransactionDate: authorised},
+			wantOK:         true,
+			wantSimilarity: (&category.JaccardMatcher{}).CalculateSimilarity("COFFEE SHOP LONDON", "COFFEE SHOP"),
+		},
+		{
+			name: "different merchants and descriptions",
+			tx:   Transaction{Status: TransactionStatusPosted, Amount: -5, Description: "BOOK STORE", TransactionDate: authorised, MerchantID: &merchant},
+			row:  pendingMatchRow{Amount: -5, Description: "COFFEE SHOP", TransactionDate: authorised, MerchantID: &otherMerchant},
+		},
+		{
+			name:           "pending row imported again",
+			tx:             Transaction{Status: TransactionStatusPending, Amount: -9.99, Description: "Coffee Shop ", TransactionDate: authorised},
+			row:            pendingMatchRow{Amount: -9.99, Description: "COFFEE SHOP", TransactionDate: authorised},
+			wantOK:         true,
+			wantSimilarity: 1,
+		},
+		{
+			name: "pending row with another amount",
+			tx:   Transaction{Status: TransactionStatusPending, Amount: -10, Description: "COFFEE SHOP", TransactionDate: authorised},
+			row:  pendingMatchRow{Amount: -9.99, Description: "COFFEE SHOP", TransactionDate: authorised},
+		},
+		{
+			name: "pending row on another day",
+			tx:   Transaction{Status: TransactionStatusPending, Amount: -9.99, Description: "COFFEE SHOP", TransactionDate: booked},
+			row:  pendingMatchRow{Amount: -9.99, Description: "COFFEE SHOP", TransactionDate: authorised},
+		},
+	}
+
+	matcher := &category.JaccardMatcher{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.row.Idx, tt.row.ID = 3, uuid.New()
+			score, ok := scorePendingMatch(&tt.tx, tt.row, cfg, matcher)
+			if ok != tt.wantOK {
+				t.Fatalf("scorePendingMatch() ok = %t, want %t", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			want := pendingScore{idx: 3, pendingID: tt.row.ID, similarity: tt.wantSimilarity, amountDiff: tt.wantAmountDiff, dayDiff: tt.wantDayDiff}
+			if score != want {
+				t.Errorf("scorePendingMatch() = %+v, want %+v", score, want)
+			}
+		})
+	}
+}
+
+func TestAssignPendingMatches(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name   string
+		scored []pendingScore
+		want   map[int]uuid.UUID
+	}{
+		{
+			name: "most similar first",
+			scored: []pendingScore{
+				{idx: 0, pendingID: a, similarity: 0.6},
+				{idx: 0, pendingID: b, similarity: 1, amountDiff: 500},
+			},
+			want: map[int]uuid.UUID{0: b},
+		},
+		{
+			name: "then the closest amount",
+			scored: []pendingScore{
+				{idx: 0, pendingID: a, similarity: 1, amountDiff: 400, dayDiff: 0},
+				{idx: 0, pendingID: b, similarity: 1, amountDiff: 0, dayDiff: 3},
+			},
+			want: map[int]uuid.UUID{0: b},
+		},
+		{
+			name: "then the closest date",
+			scored: []pendingScore{
+				{idx: 0, pendingID: a, similarity: 1, dayDiff: 3},
+				{idx: 0, pendingID: b, similarity: 1, dayDiff: 1},
+			},
+			want: map[int]uuid.UUID{0: b},
+		},
+		{
+			name: "each pending transaction settles once",
+			scored: []pendingScore{
+				{idx: 0, pendingID: a, similarity: 1, amountDiff: 0},
+				{idx: 1, pendingID: a, similarity: 1, amountDiff: 100},
+				{idx: 1, pendingID: b, similarity: 0.6},
+			},
+			want: map[int]uuid.UUID{0: a, 1: b},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := assignPendingMatches(tt.scored); !maps.Equal(got, tt.want) {
+				t.Errorf("assignPendingMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBookingDate(t *testing.T) {
+	date := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	posted := date.AddDate(0, 0, 2)
+
+	if got := bookingDate(&Transaction{TransactionDate: date}); !got.Equal(date) {
+		t.Errorf("bookingDate() without a posted date = %v, want %v", got, date)
+	}
+	if got := bookingDate(&Transaction{TransactionDate: date, PostedDate: &posted}); !got.Equal(posted) {
+		t.Errorf("bookingDate() = %v, want the posted date %v", got, posted)
+	}
+}
+
+func TestPostedUpdates(t *testing.T) {
+	authorised := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	booked := authorised.AddDate(0, 0, 2)
+	userCategory, importCategory, merchant := uuid.New(), uuid.New(), uuid.New()
+	counterpart := uuid.New()
+
+	tests := []struct {
+		name    string
+		pending Transaction
+		posted  Transaction
+		want    map[string]any
+	}{
+		{
+			name: "bank details replace the pending ones",
+			pending: Transaction{
+				Amount: -20, Description: "PENDING RESTAURANT",
+				UserDescription: stringPtr("Dinner"), UserNotes: stringPtr("with Sam"),
+			},
+			posted: Transaction{
+				Amount: -24, Description: "RESTAURANT 123 LONDON", Currency: "GBP", TransactionType: "expense",
+				TransactionDate: authorised, PostedDate: &booked,
+				MerchantID: &merchant, MerchantName: stringPtr("Restaurant"),
+				FitID: stringPtr("F1"), ReferenceNumber: stringPtr("R1"), Memo: stringPtr("memo"),
+				CategoryID: &importCategory,
+			},
+			want: map[string]any{
+				"status": TransactionStatusPosted, "posted_date": booked, "amount": -24.0,
+				"description": "RESTAURANT 123 LONDON", "currency": "GBP", "transaction_type": "expense",
+				"merchant_id": &merchant, "merchant_name": "Restaurant",
+				"fit_id": "F1", "reference_number": "R1", "memo": "memo",
+				"category_id": &importCategory,
+			},
+		},
+		{
+			name:    "the user's category, transfer link and tags are kept",
+			pending: Transaction{CategoryID: &userCategory, CounterpartID: &counterpart, Tags: pq.StringArray{"trip"}},
+			posted: Transaction{
+				Amount: -5, Description: "TRAIN", Currency: "EUR", TransactionType: "expense",
+				TransactionDate: booked, CategoryID: &importCategory, Tags: pq.StringArray{"trip", "work"},
+			},
+			want: map[string]any{
+				"status": TransactionStatusPosted, "posted_date": booked, "amount": -5.0,
+				"description": "TRAIN", "currency": "EUR",
+				"tags": pq.StringArray{"trip", "work"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := postedUpdates(&tt.pending, &tt.posted)
+			if !slices.Equal(slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(tt.want))) {
+				t.Fatalf("postedUpdates() columns = %v, want %v", slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(tt.want)))
+			}
+			for column, want := range tt.want {
+				if !sameUpdateValue(got[column], want) {
+					t.Errorf("%s = %v, want %v", column, got[column], want)
+				}
+			}
+		})
+	}
+}
+
+// sameUpdateValue compares column values, dereferencing pointers
+func sameUpdateValue(got, want any) bool {
+	switch want := want.(type) {
+	case time.Time:
+		got, ok := got.(time.Time)
+		return ok && got.Equal(want)
+	case string:
+		if ptr, ok := got.(*string); ok {
+			return ptr != nil && *ptr == want
+		}
+		return got == want
+	case *uuid.UUID:
+		got, ok := got.(*uuid.UUID)
+		return ok && got != nil && *got == *want
+	case pq.StringArray:
+		got, ok := got.(pq.StringArray)
+		return ok && slices.Equal(got, want)
+	default:
+		return got == want
+	}
+}
+
+// pendingRepository serves known FitIDs, pending matches and pending
+// transactions, and posts what it is given unless failPost is set
+type pendingRepository struct {
+	Repository
+	byFitID  map[string]*Transaction
+	found    map[int]uuid.UUID
+	pending  map[uuid.UUID]*Transaction
+	failPost bool
+	searched []*Transaction
+}
+
+func (r *pendingRepository) GetTransactionsByFitIDs(ctx context.Context, userID uuid.UUID, fitIDs []string) (map[string]*Transaction, error) {
+	return r.byFitID, nil
+}
+
+func (r *pendingRepository) FindPendingMatches(ctx context.Context, userID uuid.UUID, transactions []*Transaction, cfg PendingMatchConfig) (map[int]uuid.UUID, error) {
+	r.searched = transactions
+	return r.found, nil
+}
+
+func (r *pendingRepository) GetTransactionsByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*Transaction, error) {
+	return r.pending, nil
+}
+
+func (r *pendingRepository) PostPendingTransactions(ctx context.Context, userID uuid.UUID, updates map[uuid.UUID]map[string]any, source string) ([]uuid.UUID, error) {
+	if r.failPost {
+		return nil, errors.New("post failed")
+	}
+	return slices.Collect(maps.Keys(updates)), nil
+}
+
+func TestMatchPendingTransactions(t *testing.T) {
+	account, otherAccount := uuid.New(), uuid.New()
+	date := time.Date(2025, 6, 3, 0, 0, 0, 0, time.UTC)
+	reconciledAt := time.Now()
+
+	pendingByFitID := &Transaction{ID: uuid.New(), AccountID: account, Status: TransactionStatusPending, FitID: stringPtr("P1")}
+	pendingByMatch := &Transaction{ID: uuid.New(), AccountID: account, Status: TransactionStatusPending}
+	reconciled := &Transaction{ID: uuid.New(), AccountID: account, Status: TransactionStatusPending, ReconciledAt: &reconciledAt}
+	postedByFitID := &Transaction{ID: uuid.New(), AccountID: account, Status: TransactionStatusPosted, FitID: stringPtr("F9")}
+
+	incoming := func(status, fitID string, accountID uuid.UUID) *Transaction {
+		tx := &Transaction{AccountID: accountID, Status: status, Amount: -10, Description: "COFFEE", TransactionDate: date}
+		if fitID != "" {
+			tx.FitID = stringPtr(fitID)
+		}
+		return tx
+	}
+
+	tests := []struct {
+		name         string
+		transactions []*Transaction
+		found        map[int]uuid.UUID // FindPendingMatches result, by index into the searched rows
+		failPost     bool
+		wantSearched []int // Incoming indexes passed on to FindPendingMatches
+		wantPosted   int   // Pending transactions posted
+		wantCreated  []int // Incoming indexes still to create
+		wantKnown    int
+	}{
+		{
+			name:         "FitID settles its pending transaction directly",
+			transactions: []*Transaction{incoming(TransactionStatusPosted, " p1 ", account)},
+			wantSearched: []int{},
+			wantPosted:   1,
+			wantCreated:  []int{},
+		},
+		{
+			name:         "FitID on another account is not settled",
+			transactions: []*Transaction{incoming(TransactionStatusPosted, "P1", otherAccount)},
+			wantSearched: []int{},
+			wantCreated:  []int{0},
+		},
+		{
+			name:         "pending row with a known FitID is left to duplicate detection",
+			transactions: []*Transaction{incoming(TransactionStatusPending, "P1", account)},
+			wantSearched: []int{},
+			wantCreated:  []int{0},
+		},
+		{
+			name:         "row with an imported FitID is left to duplicate detection",
+			transactions: []*Transaction{incoming(TransactionStatusPosted, "F9", account)},
+			wantSearched: []int{},
+			wantCreated:  []int{0},
+		},
+		{
+			name: "fuzzy matches map back to the incoming rows",
+			transactions: []*Transaction{
+				incoming(TransactionStatusPosted, "P1", account),
+				incoming(TransactionStatusPosted, "", account),
+				incoming(TransactionStatusPosted, "NEW", account),
+			},
+			found:        map[int]uuid.UUID{1: pendingByMatch.ID},
+			wantSearched: []int{1, 2},
+			wantPosted:   2,
+			wantCreated:  []int{1},
+		},
+		{
+			name: "a pending transaction settled by FitID is not matched again",
+			transactions: []*Transaction{
+				incoming(TransactionStatusPosted, "P1", account),
+				incoming(TransactionStatusPosted, "", account),
+			},
+			found:        map[int]uuid.UUID{0: pendingByFitID.ID},
+			wantSearched: []int{1},
+			wantPosted:   1,
+			wantCreated:  []int{1},
+		},
+		{
+			name:         "reconciled pending transaction is left alone",
+			transactions: []*Transaction{incoming(TransactionStatusPosted, "", account)},
+			found:        map[int]uuid.UUID{0: reconciled.ID},
+			wantSearched: []int{0},
+			wantCreated:  []int{0},
+		},
+		{
+			name:         "matched pending row imported again is dropped",
+			transactions: []*Transaction{incoming(TransactionStatusPending, "", account)},
+			found:        map[int]uuid.UUID{0: pendingByMatch.ID},
+			wantSearched: []int{0},
+			wantCreated:  []int{},
+			wantKnown:    1,
+		},
+		{
+			name:         "rows are created when posting fails",
+			transactions: []*Transaction{incoming(TransactionStatusPosted, "P1", account)},
+			failPost:     true,
+			wantSearched: []int{},
+			wantCreated:  []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &pendingRepository{
+				byFitID: map[string]*Transaction{"p1": pendingByFitID, "f9": postedByFitID},
+				found:   tt.found,
+				pending: map[uuid.UUID]*Transaction{
+					pendingByFitID.ID: pendingByFitID,
+					pendingByMatch.ID: pendingByMatch,
+					reconciled.ID:     reconciled,
+				},
+				failPost: tt.failPost,
+			}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+
+			remaining, posted, known := service.matchPendingTransactions(context.Background(), uuid.New(), tt.transactions)
+
+			searched := make([]int, 0)
+			for _, tx := range repo.searched {
+				searched = append(searched, slices.Index(tt.transactions, tx))
+			}
+			if !slices.Equal(searched, tt.wantSearched) {
+				t.Errorf("searched rows %v, want %v", searched, tt.wantSearched)
+			}
+
+			created := make([]int, 0)
+			for _, tx := range remaining {
+				created = append(created, slices.Index(tt.transactions, tx))
+			}
+			if !slices.Equal(created, tt.wantCreated) {
+				t.Errorf("created rows %v, want %v", created, tt.wantCreated)
+			}
+			if len(posted) != tt.wantPosted {
+				t.Errorf("posted %d pending transactions, want %d", len(posted), tt.wantPosted)
+			}
+			if known != tt.wantKnown {
+				t.Errorf("known = %d, want %d", known, tt.wantKnown)
+			}
+		})
+	}
+}
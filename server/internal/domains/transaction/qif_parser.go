@@ -0,0 +1,417 @@
+package transaction
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ========================================
+// QIF MODELS
+// ========================================
+
+// QIF date orders for the ambiguous "D" line
+const (
+	QIFDateOrderAuto = "auto"
+	QIFDateOrderMDY  = "mdy"
+	QIFDateOrderDMY  = "dmy"
+)
+
+// QIFStatement is the parsed content of a Quicken Interchange Format file
+type QIFStatement struct {
+	AccountType  string           `json:"account_type"` // "Bank", "CCard", "Cash", ...
+	DateOrder    string           `json:"date_order"`   // Order actually used to read dates
+	Transactions []QIFTransaction `json:"transactions"`
+}
+
+// QIFTransaction is a single record terminated by "^"
+type QIFTransaction struct {
+	Date     time.Time  `json:"date"`
+	Amount   float64    `json:"amount"`
+	Payee    string     `json:"payee,omitempty"`
+	Memo     string     `json:"memo,omitempty"`
+	Number   string     `json:"number,omitempty"`
+	Category string     `json:"category,omitempty"` // "L" line without the "/class" suffix
+	Transfer string     `json:"transfer,omitempty"` // Account name when "L" is "[Account]"
+	Cleared  string     `json:"cleared,omitempty"`  // "", "cleared" or "reconciled"
+	Splits   []QIFSplit `json:"splits,omitempty"`
+
+	rawDate string
+}
+
+// QIFSplit is one "S"/"E"/"$" group of a split transaction
+type QIFSplit struct {
+	Category string  `json:"category,omitempty"`
+	Transfer string  `json:"transfer,omitempty"`
+	Memo     string  `json:"memo,omitempty"`
+	Amount   float64 `json:"amount"`
+}
+
+// ========================================
+// PARSING
+// ========================================
+
+var (
+	qifISODatePattern = regexp.MustCompile(`^(\d{4})-(\d{1,2})-(\d{1,2})$`)
+	qifDatePattern    = regexp.MustCompile(`^(\d{1,2})\s*[/.\-]\s*(\d{1,2})\s*(['/.\-])\s*(\d{1,4})$`)
+
+	// Only non-investment register types carry plain transactions
+	qifSupportedTypes = map[string]bool{
+		"BANK":  true,
+		"CCARD": true,
+		"CASH":  true,
+		"OTH A": true,
+		"OTH L": true,
+	}
+)
+
+// ParseQIF reads !Type:Bank, !Type:CCard and the other cash-like registers.
+// Other sections (categories, classes, memorised transactions, investments)
+// are skipped. dateOrder resolves "D" lines such as 03/04/2025; with
+// QIFDateOrderAuto the order is inferred from any day above 12 in the file,
+// falling back to Quicken's US month-first default.
+func ParseQIF(r io.Reader, dateOrder string) (*QIFStatement, error) {
+	statement := &QIFStatement{
+		Transactions: make([]QIFTransaction, 0),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var (
+		current   *QIFTransaction
+		split     *QIFSplit
+		inSection bool
+		lineNo    int
+	)
+
+	flushSplit := func() {
+		if current != nil && split != nil {
+			current.Splits = append(current.Splits, *split)
+		}
+		split = nil
+	}
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if lineNo == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // UTF-8 BOM
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == '!' {
+			header := strings.ToUpper(strings.TrimSpace(line))
+			if rest, ok := strings.CutPrefix(header, "!TYPE:"); ok {
+				accountType := strings.TrimSpace(rest)
+				inSection = qifSupportedTypes[accountType]
+				if inSection && statement.AccountType == "" {
+					statement.AccountType = strings.TrimSpace(line[len("!Type:"):])
+				}
+			} else if !strings.HasPrefix(header, "!OPTION") {
+				// !Account and !Clear headers start non-transaction blocks
+				inSection = false
+			}
+			continue
+		}
+
+		if !inSection {
+			continue
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		if code == '^' {
+			flushSplit()
+			if current != nil {
+				statement.Transactions = append(statement.Transactions, *current)
+			}
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			current = &QIFTransaction{}
+		}
+
+		switch code {
+		case 'D':
+			current.rawDate = value
+		case 'T', 'U':
+			amount, err := parseQIFAmount(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid amount '%s'", lineNo, value)
+			}
+			current.Amount = amount
+		case 'P':
+			current.Payee = value
+		case 'M':
+			current.Memo = value
+		case 'N':
+			current.Number = value
+		case 'C':
+			current.Cleared = parseQIFClearedStatus(value)
+		case 'L':
+			current.Category, current.Transfer = parseQIFCategory(value)
+		case 'S':
+			flushSplit()
+			split = &QIFSplit{}
+			split.Category, split.Transfer = parseQIFCategory(value)
+		case 'E':
+			if split != nil {
+				split.Memo = value
+			}
+		case '$':
+			if split != nil {
+				amount, err := parseQIFAmount(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid split amount '%s'", lineNo, value)
+				}
+				split.Amount = amount
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read QIF content: %w", err)
+	}
+
+	// Tolerate a missing final "^"
+	flushSplit()
+	if current != nil && current.rawDate != "" {
+		statement.Transactions = append(statement.Transactions, *current)
+	}
+
+	if len(statement.Transactions) == 0 {
+		return nil, errors.New("no bank or credit card transactions found in QIF file")
+	}
+
+	if dateOrder == "" || dateOrder == QIFDateOrderAuto {
+		dateOrder = detectQIFDateOrder(statement.Transactions)
+	}
+	statement.DateOrder = dateOrder
+
+	for i := range statement.Transactions {
+		txn := &statement.Transactions[i]
+		date, err := ParseQIFDate(txn.rawDate, dateOrder)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		txn.Date = date
+	}
+
+	return statement, nil
+}
+
+// ParseQIFDate parses "D" values such as 12/31/2024, 12/31'24, 1/ 5' 4,
+// 31.12.2024 and 2024-12-31. An apostrophe before the year marks 2000+.
+func ParseQIFDate(value, dateOrder string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if m := qifISODatePattern.FindStringSubmatch(value); m != nil {
+		return civilDate(atoi(m[1]), atoi(m[2]), atoi(m[3]), value)
+	}
+
+	m := qifDatePattern.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid QIF date '%s'", value)
+	}
+
+	first, second, year := atoi(m[1]), atoi(m[2]), atoi(m[4])
+	if year < 100 {
+		switch {
+		case m[3] == "'":
+			year += 2000
+		case year < 70:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+
+	if dateOrder == QIFDateOrderDMY {
+		return civilDate(year, second, first, value)
+	}
+	return civilDate(year, first, second, value)
+}
+
+// civilDate builds a UTC date, rejecting values time.Date would normalise
+func civilDate(year, month, day int, raw string) (time.Time, error) {
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Year() != year || int(date.Month()) != month || date.Day() != day {
+		return time.Time{}, fmt.Errorf("invalid date '%s'", raw)
+	}
+	return date, nil
+}
+
+// detectQIFDateOrder looks for a component above 12, which can only be a day
+func detectQIFDateOrder(transactions []QIFTransaction) string {
+	dotted := false
+	for _, txn := range transactions {
+		m := qifDatePattern.FindStringSubmatch(strings.TrimSpace(txn.rawDate))
+		if m == nil {
+			continue
+		}
+		if atoi(m[1]) > 12 {
+			return QIFDateOrderDMY
+		}
+		if atoi(m[2]) > 12 {
+			return QIFDateOrderMDY
+		}
+		if strings.Contains(txn.rawDate, ".") {
+			dotted = true
+		}
+	}
+	// Dotted dates are a European convention
+	if dotted {
+		return QIFDateOrderDMY
+	}
+	return QIFDateOrderMDY
+}
+
+func parseQIFAmount(value string) (float64, error) {
+	return parseStatementAmount(value)
+}
+
+func parseQIFClearedStatus(value string) string {
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "*", "C":
+		return "cleared"
+	case "X", "R":
+		return "reconciled"
+	}
+	return ""
+}
+
+// parseQIFCategory splits "Category:Sub/Class" into the category name, or
+// returns the account name for "[Account]" transfers
+func parseQIFCategory(value string) (category, transfer string) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "[") {
+		if end := strings.Index(value, "]"); end > 0 {
+			return "", strings.TrimSpace(value[1:end])
+		}
+	}
+	if slash := strings.Index(value, "/"); slash >= 0 {
+		value = value[:slash]
+	}
+	return strings.TrimSpace(value), ""
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(value))
+	return n
+}
+
+// ========================================
+// MAPPING
+// ========================================
+
+// Info summarises the statement for the import result
+func (s *QIFStatement) Info(filename, currency string) *StatementInfo {
+	info := &StatementInfo{
+		Format:           "qif",
+		Filename:         filename,
+		Currency:         currency,
+		TransactionCount: len(s.Transactions),
+	}
+	for i := range s.Transactions {
+		date := s.Transactions[i].Date
+		if info.StartDate == nil || date.Before(*info.StartDate) {
+			info.StartDate = &date
+		}
+		if info.EndDate == nil || date.After(*info.EndDate) {
+			info.EndDate = &date
+		}
+	}
+	return info
+}
+
+// ToTransactionRequests maps the statement into the unified batch input. QIF
+// has no currency, so the caller supplies the account's.
+func (s *QIFStatement) ToTransactionRequests(accountID, currency string) []TransactionRequest {
+	requests := make([]TransactionRequest, 0, len(s.Transactions))
+	for _, txn := range s.Transactions {
+		requests = append(requests, txn.toTransactionRequest(accountID, currency))
+	}
+	return requests
+}
+
+func (t QIFTransaction) toTransactionRequest(accountID, currency string) TransactionRequest {
+	description := t.Payee
+	if description == "" {
+		description = t.Memo
+	}
+	if description == "" && t.Transfer != "" {
+		description = "Transfer: " + t.Transfer
+	}
+	if description == "" {
+		description = "Imported Transaction"
+	}
+
+	transactionType := "expense"
+	if t.Amount > 0 {
+		transactionType = "income"
+	}
+	if t.Transfer != "" {
+		transactionType = "transfer"
+	}
+
+	request := TransactionRequest{
+		AccountID:       accountID,
+		TransactionDate: t.Date.Format("2006-01-02"),
+		Description:     description,
+		Amount:          t.Amount,
+		TransactionType: transactionType,
+		Currency:        currency,
+	}
+
+	request.MerchantName = importedMerchantName(t.Payee)
+	if t.Memo != "" {
+		memo := t.Memo
+		request.Memo = &memo
+	}
+	if t.Number != "" {
+		number := t.Number
+		request.ReferenceNumber = &number
+	}
+	if category := t.categoryHint(); category != "" {
+		request.CategoryName = &category
+	}
+	for _, split := range t.Splits {
+		line := ImportSplitLine{Amount: split.Amount}
+		if split.Category != "" {
+			category := split.Category
+			line.CategoryName = &category
+		}
+		if split.Memo != "" {
+			memo := split.Memo
+			line.Memo = &memo
+		}
+		request.Splits = append(request.Splits, line)
+	}
+
+	return request
+}
+
+// categoryHint uses the "L" category, or for split transactions the category
+// of the largest split, used when the split lines are not kept
+func (t QIFTransaction) categoryHint() string {
+	if t.Category != "" || len(t.Splits) == 0 {
+		return t.Category
+	}
+
+	hint, largest := "", -1.0
+	for _, split := range t.Splits {
+		if split.Category != "" && math.Abs(split.Amount) > largest {
+			hint, largest = split.Category, math.Abs(split.Amount)
+		}
+	}
+	return hint
+}
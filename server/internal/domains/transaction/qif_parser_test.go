@@ -0,0 +1,247 @@
+package transaction
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseQIF(t *testing.T) {
+	type want struct {
+		date     string
+		amount   float64
+		payee    string
+		category string
+		transfer string
+		cleared  string
+		splits   int
+	}
+
+	tests := []struct {
+		name         string
+		input        string
+		dateOrder    string
+		wantErr      string
+		accountType  string
+		order        string
+		transactions []want
+	}{
+		{
+			name: "month first by default",
+			input: "!Type:Bank\n" +
+				"D03/04/2025\nT-12.50\nPTESCO\nLGroceries:Food/Home\nC*\n^\n" +
+				"D3/ 5'25\nT1,234.56\nPACME LTD\nLSalary\nCX\n^\n",
+			accountType: "Bank",
+			order:       QIFDateOrderMDY,
+			transactions: []want{
+				{date: "2025-03-04", amount: -12.5, payee: "TESCO", category: "Groceries:Food", cleared: "cleared"},
+				{date: "2025-03-05", amount: 1234.56, payee: "ACME LTD", category: "Salary", cleared: "reconciled"},
+			},
+		},
+		{
+			name: "day above 12 switches to day first",
+			input: "!Type:CCard\n" +
+				"D03/04/2025\nT-1\n^\n" +
+				"D25/04/2025\nT-2\n^\n",
+			accountType: "CCard",
+			order:       QIFDateOrderDMY,
+			transactions: []want{
+				{date: "2025-04-03", amount: -1},
+				{date: "2025-04-25", amount: -2},
+			},
+		},
+		{
+			name:        "dotted dates are day first",
+			input:       "!Type:Bank\nD03.04.2025\nT-1,50\n^\n",
+			accountType: "Bank",
+			order:       QIFDateOrderDMY,
+			transactions: []want{
+				{date: "2025-04-03", amount: -1.5},
+			},
+		},
+		{
+			name:        "explicit order wins",
+			input:       "!Type:Bank\nD03/04/2025\nT-1\n^\n",
+			dateOrder:   QIFDateOrderDMY,
+			accountType: "Bank",
+			order:       QIFDateOrderDMY,
+			transactions: []want{
+				{date: "2025-04-03", amount: -1},
+			},
+		},
+		{
+			name: "splits, transfers and a missing final caret",
+			input: "!Type:Bank\n" +
+				"D2025-01-31\nT-100\nPSUPERMARKET\nSGroceries\nEFood\n$-60\nSHousehold\n$-40\n^\n" +
+				"D2025-02-01\nT-500\nL[Savings]\n",
+			accountType: "Bank",
+			order:       QIFDateOrderMDY,
+			transactions: []want{
+				{date: "2025-01-31", amount: -100, payee: "SUPERMARKET", splits: 2},
+				{date: "2025-02-01", amount: -500, transfer: "Savings"},
+			},
+		},
+		{
+			name: "other sections are skipped",
+			input: "!Type:Cat\nNGroceries\n^\n" +
+				"!Account\nNChecking\nTBank\n^\n" +
+				"!Type:Bank\nD01/02/2025\nT-3\n^\n" +
+				"!Type:Invst\nD01/03/2025\nT-4\n^\n",
+			accountType: "Bank",
+			order:       QIFDateOrderMDY,
+			transactions: []want{
+				{date: "2025-01-02", amount: -3},
+			},
+		},
+		{
+			name:    "invalid amount",
+			input:   "!Type:Bank\nD01/02/2025\nTabc\n^\n",
+			wantErr: "line 3: invalid amount",
+		},
+		{
+			name:    "invalid date",
+			input:   "!Type:Bank\nD02/30/2025\nT-1\n^\n",
+			wantErr: "transaction 1: invalid date",
+		},
+		{
+			name:    "no transactions",
+			input:   "!Type:Cat\nNGroceries\n^\n",
+			wantErr: "no bank or credit card transactions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statement, err := ParseQIF(strings.NewReader(tt.input), tt.dateOrder)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseQIF() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQIF() error = %v", err)
+			}
+			if statement.AccountType != tt.accountType || statement.DateOrder != tt.order {
+				t.Errorf("account type/order = %s/%s, want %s/%s", statement.AccountType, statement.DateOrder, tt.accountType, tt.order)
+			}
+			if len(statement.Transactions) != len(tt.transactions) {
+				t.Fatalf("got %d transactions, want %d", len(statement.Transactions), len(tt.transactions))
+			}
+			for i, w := range tt.transactions {
+				got := statement.Transactions[i]
+				if got.Date.Format("2006-01-02") != w.date || got.Amount != w.amount || got.Payee != w.payee ||
+					got.Category != w.category || got.Transfer != w.transfer || got.Cleared != w.cleared || len(got.Splits) != w.splits {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseQIFDate(t *testing.T) {
+	tests := []struct {
+		value     string
+		dateOrder string
+		want      string
+		wantErr   bool
+	}{
+		{value: "12/31/2024", dateOrder: QIFDateOrderMDY, want: "2024-12-31"},
+		{value: "12/31'24", dateOrder: QIFDateOrderMDY, want: "2024-12-31"},
+		{value: "1/ 5' 4", dateOrder: QIFDateOrderMDY, want: "2004-01-05"},
+		{value: "1/5/99", dateOrder: QIFDateOrderMDY, want: "1999-01-05"},
+		{value: "31.12.2024", dateOrder: QIFDateOrderDMY, want: "2024-12-31"},
+		{value: "05-01-2024", dateOrder: QIFDateOrderDMY, want: "2024-01-05"},
+		{value: "2024-12-31", dateOrder: QIFDateOrderDMY, want: "2024-12-31"},
+		{value: "31/12/2024", dateOrder: QIFDateOrderMDY, wantErr: true},
+		{value: "2024-02-30", dateOrder: QIFDateOrderMDY, wantErr: true},
+		{value: "yesterday", dateOrder: QIFDateOrderMDY, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseQIFDate(tt.value, tt.dateOrder)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseQIFDate(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseQIFDate(%q) error = %v", tt.value, err)
+			}
+			if got.Format("2006-01-02") != tt.want {
+				t.Errorf("ParseQIFDate(%q) = %s, want %s", tt.value, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestQIFTransactionRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		transaction QIFTransaction
+		description string
+		txType      string
+		category    string
+		splits      []ImportSplitLine
+	}{
+		{
+			name:        "payee and category",
+			transaction: QIFTransaction{Amount: -12.5, Payee: "TESCO", Category: "Groceries"},
+			description: "TESCO",
+			txType:      "expense",
+			category:    "Groceries",
+		},
+		{
+			name:        "transfer without payee",
+			transaction: QIFTransaction{Amount: -500, Transfer: "Savings"},
+			description: "Transfer: Savings",
+			txType:      "transfer",
+		},
+		{
+			name: "split lines keep their categories, the largest is the hint",
+			transaction: QIFTransaction{Amount: -100, Payee: "SUPERMARKET", Splits: []QIFSplit{
+				{Category: "Household", Amount: -40},
+				{Category: "Groceries", Memo: "Food", Amount: -60},
+			}},
+			description: "SUPERMARKET",
+			txType:      "expense",
+			category:    "Groceries",
+			splits: []ImportSplitLine{
+				{Amount: -40, CategoryName: stringPtr("Household")},
+				{Amount: -60, CategoryName: stringPtr("Groceries"), Memo: stringPtr("Food")},
+			},
+		},
+		{
+			name:        "income without details",
+			transaction: QIFTransaction{Amount: 10},
+			description: "Imported Transaction",
+			txType:      "income",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.transaction.toTransactionRequest("acc", "GBP")
+			if request.Description != tt.description || request.TransactionType != tt.txType {
+				t.Errorf("description/type = %q/%s, want %q/%s", request.Description, request.TransactionType, tt.description, tt.txType)
+			}
+			if got := derefString(request.CategoryName); got != tt.category {
+				t.Errorf("CategoryName = %q, want %q", got, tt.category)
+			}
+			if len(request.Splits) != len(tt.splits) {
+				t.Fatalf("got %d splits, want %d", len(request.Splits), len(tt.splits))
+			}
+			for i, want := range tt.splits {
+				got := request.Splits[i]
+				if got.Amount != want.Amount || derefString(got.CategoryName) != derefString(want.CategoryName) || derefString(got.Memo) != derefString(want.Memo) {
+					t.Errorf("split %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func stringPtr(value string) *string {
+	return &value
+}
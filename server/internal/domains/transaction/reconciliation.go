@@ -0,0 +1,311 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	"hi-cfo/server/internal/shared"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// RECONCILIATION
+// ========================================
+
+// StartReconciliation opens a reconciliation of the account against a
+// statement. The starting balance defaults to the closing balance of the
+// previous reconciliation.
+func (s *TransactionService) StartReconciliation(ctx context.Context, userID, accountID uuid.UUID, req StartReconciliationRequest) (*ReconciliationDetail, error) {
+	if s.accountService == nil {
+		return nil, customerrors.New(customerrors.ErrCodeInternal, "account service not available").WithDomain("transaction")
+	}
+
+	endDate, err := shared.ParseFlexibleDate(req.StatementEndDate)
+	if err != nil {
+		return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, "Invalid statement_end_date format").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("statement_end_date", req.StatementEndDate)
+	}
+	endDate = endDate.UTC().Truncate(24 * time.Hour)
+
+	acc, err := s.accountService.GetAccountByID(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	reconciliations, err := s.repo.GetReconciliations(ctx, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	var previous *Reconciliation
+	for i := range reconciliations {
+		if reconciliations[i].Status == ReconciliationInProgress {
+			return nil, customerrors.New(customerrors.ErrCodeConflict, "Account already has a reconciliation in progress, complete or cancel it first").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetails(map[string]any{
+					"account_id":        accountID,
+					"reconciliation_id": reconciliations[i].ID,
+				})
+		}
+		if previous == nil {
+			previous = &reconciliations[i]
+		}
+	}
+	if previous != nil && !endDate.After(previous.StatementEndDate) {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "statement_end_date must be after the previous reconciliation's").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"statement_end_date":          endDate.Format("2006-01-02"),
+				"previous_statement_end_date": previous.StatementEndDate.Format("2006-01-02"),
+			})
+	}
+
+	reconciliation := &Reconciliation{
+		UserID:           userID,
+		AccountID:        accountID,
+		StatementEndDate: endDate,
+		ClosingBalance:   *req.ClosingBalance,
+		Status:           ReconciliationInProgress,
+	}
+	switch {
+	case req.StartingBalance != nil:
+		reconciliation.StartingBalance = *req.StartingBalance
+	case previous != nil:
+		reconciliation.StartingBalance = previous.ClosingBalance
+	case acc.OpeningBalance != nil && acc.OpeningBalanceDate != nil:
+		// First reconciliation: everything up to the statement end date can be
+		// cleared, so start from the balance before the account's first transaction
+		delta, err := s.repo.GetBalanceDelta(ctx, userID, accountID, *acc.OpeningBalanceDate, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		reconciliation.StartingBalance = float64(toCents(*acc.OpeningBalance)+toCents(delta)) / 100
+	}
+
+	if err := s.repo.CreateReconciliation(ctx, reconciliation); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":            userID,
+		"account_id":         accountID,
+		"reconciliation_id":  reconciliation.ID,
+		"statement_end_date": reconciliation.StatementEndDate.Format("2006-01-02"),
+	}).Info("Reconciliation started")
+
+	return s.reconciliationDetail(ctx, userID, reconciliation)
+}
+
+// GetReconciliations lists the account's reconciliations, latest statement first
+func (s *TransactionService) GetReconciliations(ctx context.Context, userID, accountID uuid.UUID) ([]Reconciliation, error) {
+	if s.accountService != nil {
+		if _, err := s.accountService.GetAccountByID(ctx, userID, accountID); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetReconciliations(ctx, userID, accountID)
+}
+
+// GetReconciliation returns a reconciliation with its cleared totals and the
+// difference to the statement
+func (s *TransactionService) GetReconciliation(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) (*ReconciliationDetail, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, userID, accountID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.reconciliationDetail(ctx, userID, reconciliation)
+}
+
+// GetReconciliationTransactions lists the transactions that can be cleared in
+// an open reconciliation, or those locked by a completed one
+func (s *TransactionService) GetReconciliationTransactions(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) ([]Transaction, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, userID, accountID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetReconciliationTransactions(ctx, userID, reconciliation)
+}
+
+// ClearTransactions marks transactions cleared in an open reconciliation, or
+// takes them out again, and returns the updated difference
+func (s *TransactionService) ClearTransactions(ctx context.Context, userID, accountID, reconciliationID uuid.UUID, req ClearTransactionsRequest) (*ReconciliationDetail, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, userID, accountID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+
+	missing, err := s.repo.SetTransactionsCleared(ctx, userID, reconciliation, req.TransactionIDs, *req.Cleared)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		message := "Some transactions cannot be cleared: they are not on the account, pending, dated after the statement, flagged as duplicates or already reconciled"
+		if !*req.Cleared {
+			message = "Some transactions are not cleared in this reconciliation"
+		}
+		return nil, customerrors.New(customerrors.ErrCodeValidation, message).
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"reconciliation_id": reconciliationID,
+				"transaction_ids":   missing,
+			})
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"reconciliation_id": reconciliationID,
+		"count":             len(req.TransactionIDs),
+		"cleared":           *req.Cleared,
+	}).Info("Reconciliation transactions updated")
+
+	return s.reconciliationDetail(ctx, userID, reconciliation)
+}
+
+// CompleteReconciliation closes a reconciliation whose cleared balance matches
+// the statement and locks its transactions
+func (s *TransactionService) CompleteReconciliation(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) (*ReconciliationDetail, error) {
+	reconciliation, err := s.repo.GetReconciliationByID(ctx, userID, accountID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	if reconciliation.Status != ReconciliationInProgress {
+		return nil, customerrors.New(customerrors.ErrCodeConflict, "Reconciliation is already completed").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("reconciliation_id", reconciliationID)
+	}
+
+	// Postgres keeps microseconds, truncate so the response matches what is stored
+	completedAt := time.Now().UTC().Truncate(time.Microsecond)
+	totals, err := s.repo.CompleteReconciliation(ctx, reconciliation, completedAt)
+	if err != nil {
+		return nil, err
+	}
+	reconciliation.Status = ReconciliationCompleted
+	reconciliation.CompletedAt = &completedAt
+	detail := newReconciliationDetail(reconciliation, totals)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"account_id":        accountID,
+		"reconciliation_id": reconciliationID,
+		"cleared_count":     detail.ClearedCount,
+	}).Info("Reconciliation completed")
+
+	return detail, nil
+}
+
+// CancelReconciliation discards an open reconciliation, its cleared
+// transactions go back to uncleared
+func (s *TransactionService) CancelReconciliation(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) error {
+	if _, err := s.repo.GetReconciliationByID(ctx, userID, accountID, reconciliationID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteReconciliation(ctx, userID, reconciliationID); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"account_id":        accountID,
+		"reconciliation_id": reconciliationID,
+	}).Info("Reconciliation cancelled")
+
+	return nil
+}
+
+// UnlockTransaction releases a reconciled transaction so it can be changed
+// again. It comes back uncleared; the completed reconciliation is kept.
+func (s *TransactionService) UnlockTransaction(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if tx.ReconciledAt == nil {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Transaction is not reconciled").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+
+	unlocked, err := s.repo.UpdateTransaction(ctx, userID, transactionID, map[string]any{
+		"reconciliation_id": nil,
+		"reconciled_at":     nil,
+	}, ChangeSourceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":           userID,
+		"transaction_id":    transactionID,
+		"reconciliation_id": tx.ReconciliationID,
+	}).Info("Reconciled transaction unlocked")
+
+	return unlocked, nil
+}
+
+// reconciliationDetail adds the cleared totals and the live difference
+func (s *TransactionService) reconciliationDetail(ctx context.Context, userID uuid.UUID, reconciliation *Reconciliation) (*ReconciliationDetail, error) {
+	totals, err := s.repo.GetReconciliationTotals(ctx, userID, reconciliation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newReconciliationDetail(reconciliation, totals), nil
+}
+
+func newReconciliationDetail(reconciliation *Reconciliation, totals *ReconciliationTotals) *ReconciliationDetail {
+	return &ReconciliationDetail{
+		Reconciliation:       *reconciliation,
+		ReconciliationTotals: *totals,
+		ClearedBalance:       float64(clearedBalance(reconciliation, totals)) / 100,
+		Difference:           float64(reconciliationDifference(reconciliation, totals)) / 100,
+	}
+}
+
+// clearedBalance is the starting balance plus the cleared transactions, in cents
+func clearedBalance(reconciliation *Reconciliation, totals *ReconciliationTotals) int64 {
+	return toCents(reconciliation.StartingBalance) + toCents(totals.ClearedDeposits) + toCents(totals.ClearedPayments)
+}
+
+// reconciliationDifference is the closing balance minus the cleared balance,
+// in cents
+func reconciliationDifference(reconciliation *Reconciliation, totals *ReconciliationTotals) int64 {
+	return toCents(reconciliation.ClosingBalance) - clearedBalance(reconciliation, totals)
+}
+
+// checkUnlocked refuses changes to a transaction locked by a completed reconciliation
+func checkUnlocked(userID uuid.UUID, tx *Transaction) error {
+	if tx.ReconciledAt == nil {
+		return nil
+	}
+	return customerrors.New(customerrors.ErrCodeTransactionBlocked, "Transaction is reconciled, unlock it before changing it").
+		WithDomain("transaction").
+		WithUserID(userID).
+		WithDetails(map[string]any{
+			"transaction_id":    tx.ID,
+			"reconciliation_id": tx.ReconciliationID,
+		})
+}
+
+// unclearOnChange takes a transaction moved to another account or back to
+// pending out of the reconciliation it was cleared in
+func unclearOnChange(tx *Transaction, updates map[string]any) {
+	if tx.ReconciliationID == nil {
+		return
+	}
+	if accountID, ok := updates["account_id"]; ok && accountID != tx.AccountID {
+		updates["reconciliation_id"] = nil
+	}
+	if status, ok := updates["status"]; ok && status == TransactionStatusPending {
+		updates["reconciliation_id"] = nil
+	}
+}
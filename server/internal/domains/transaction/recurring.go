@@ -0,0 +1,438 @@
+package transaction
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"hi-cfo/server/internal/config"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// RECURRING TRANSACTIONS
+// ========================================
+
+// recurringCadence is a frequency the detector recognises
+type recurringCadence struct {
+	frequency string
+	days      float64 // Typical interval
+	slack     float64 // Days an interval may be off and still count as regular
+	fullAfter int     // Intervals of history needed for full confidence
+	minCount  int     // Occurrences needed before a series is proposed
+	months    int     // Step to the next due date
+	weeks     int
+}
+
+var recurringCadences = []recurringCadence{
+	{frequency: RecurringWeekly, days: 7, slack: 1, fullAfter: 8, minCount: 4, weeks: 1},
+	{frequency: RecurringMonthly, days: 30.44, slack: 3, fullAfter: 6, minCount: 3, months: 1},
+	{frequency: RecurringQuarterly, days: 91.31, slack: 7, fullAfter: 4, minCount: 3, months: 3},
+	{frequency: RecurringAnnual, days: 365.25, slack: 15, fullAfter: 2, minCount: 2, months: 12},
+}
+
+// next steps a due date on by one interval. Monthly steps keep the day of
+// the month, clamped to the last day of shorter months: Jan 31 is followed
+// by Feb 28 (or 29), not Mar 3.
+func (c recurringCadence) next(date time.Time) time.Time {
+	if c.months == 0 {
+		return date.AddDate(0, 0, 7*c.weeks)
+	}
+	year, month, day := date.Date()
+	first := time.Date(year, month+time.Month(c.months), 1, date.Hour(), date.Minute(), date.Second(), date.Nanosecond(), date.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(day, lastDay)-1)
+}
+
+func (s *TransactionService) getRecurringDetectionConfig() RecurringDetectionConfig {
+	return RecurringDetectionConfig{
+		LookbackDays:    config.GetRecurringLookbackDays(),
+		AmountTolerance: config.GetRecurringAmountTolerance(),
+		MinConfidence:   config.GetRecurringMinConfidence(),
+	}
+}
+
+// DetectRecurring scans the user's history for transactions that repeat on a
+// weekly, monthly, quarterly or annual cadence. Transactions are grouped by
+// account, direction and normalized merchant, then by similar amount. Series
+// that have stopped, missing their last two due dates, are not proposed.
+// Proposals are ordered by confidence, highest first.
+func (s *TransactionService) DetectRecurring(ctx context.Context, userID uuid.UUID) ([]RecurringProposal, error) {
+	cfg := s.getRecurringDetectionConfig()
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	transactions, err := s.repo.GetRecurringCandidates(ctx, userID, today.AddDate(0, 0, -cfg.LookbackDays))
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]Transaction)
+	keys := make([]string, 0)
+	for _, tx := range transactions {
+		key := recurringGroupKey(&tx)
+		if key == "" {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], tx)
+	}
+
+	proposals := make([]RecurringProposal, 0)
+	for _, key := range keys {
+		for _, cluster := range clusterByAmount(groups[key], cfg.AmountTolerance) {
+			proposal := detectRecurringSeries(cluster, "", cfg.AmountTolerance)
+			if proposal == nil || proposal.Confidence < cfg.MinConfidence {
+				continue
+			}
+			cadence, _ := cadenceFor(proposal.Frequency)
+			if today.After(cadence.next(proposal.NextDueDate).AddDate(0, 0, int(cadence.slack))) {
+				continue
+			}
+			proposals = append(proposals, *proposal)
+		}
+	}
+
+	sort.SliceStable(proposals, func(i, j int) bool {
+		if proposals[i].Confidence != proposals[j].Confidence {
+			return proposals[i].Confidence > proposals[j].Confidence
+		}
+		return proposals[i].Name < proposals[j].Name
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":   userID,
+		"scanned":   len(transactions),
+		"proposals": len(proposals),
+	}).Info("Recurring transaction detection completed")
+
+	return proposals, nil
+}
+
+// AcceptRecurring saves the transactions of a proposal as a recurring series
+// and tags them with it. The series is worked out from the transactions again,
+// so a proposal can be trimmed or extended before it is accepted.
+func (s *TransactionService) AcceptRecurring(ctx context.Context, userID uuid.UUID, req AcceptRecurringRequest) (*RecurringTransaction, error) {
+	ids := make([]uuid.UUID, 0, len(req.TransactionIDs))
+	for _, id := range req.TransactionIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	found, err := s.repo.GetTransactionsByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if found[id] == nil {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, customerrors.New(customerrors.ErrCodeNotFound, "Some transactions were not found").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_ids", missing)
+	}
+
+	transactions := make([]Transaction, 0, len(ids))
+	for _, id := range ids {
+		tx := found[id]
+		if err := checkUnlocked(userID, tx); err != nil {
+			return nil, err
+		}
+		message := ""
+		switch {
+		case tx.AccountID != found[ids[0]].AccountID:
+			message = "All transactions of a recurring series must be on the same account"
+		case tx.RecurringID != nil:
+			message = "Transaction already belongs to a recurring series"
+		case tx.CounterpartID != nil || tx.TransactionType == "transfer":
+			message = "Transfers cannot be part of a recurring series"
+		case tx.Amount == 0 || (tx.Amount > 0) != (found[ids[0]].Amount > 0):
+			message = "All transactions of a recurring series must be income, or all expenses"
+		}
+		if message != "" {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, message).
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("transaction_id", id)
+		}
+		transactions = append(transactions, *tx)
+	}
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].TransactionDate.Before(transactions[j].TransactionDate)
+	})
+
+	frequency := ""
+	if req.Frequency != nil {
+		frequency = *req.Frequency
+	}
+	proposal := detectRecurringSeries(transactions, frequency, s.getRecurringDetectionConfig().AmountTolerance)
+	if proposal == nil {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "No weekly, monthly, quarterly or annual cadence found, set the frequency").
+			WithDomain("transaction").
+			WithUserID(userID)
+	}
+
+	name := proposal.Name
+	if req.Name != nil && strings.TrimSpace(*req.Name) != "" {
+		name = strings.TrimSpace(*req.Name)
+	}
+	lastID := transactions[len(transactions)-1].ID
+	series := &RecurringTransaction{
+		UserID:                   userID,
+		AccountID:                proposal.AccountID,
+		CategoryID:               proposal.CategoryID,
+		Name:                     name,
+		MerchantName:             proposal.MerchantName,
+		Amount:                   proposal.Amount,
+		TransactionType:          proposal.TransactionType,
+		Frequency:                proposal.Frequency,
+		NextDueDate:              proposal.NextDueDate,
+		StartDate:                proposal.StartDate,
+		TypicalAmount:            &proposal.TypicalAmount,
+		AmountVariance:           &proposal.AmountVariance,
+		IsActive:                 true,
+		NotifyBeforeDays:         3,
+		LastMatchedTransactionID: &lastID,
+	}
+	if err := s.repo.CreateRecurringTransaction(ctx, series, ids); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"recurring_id": series.ID,
+		"frequency":    series.Frequency,
+		"count":        len(ids),
+	}).Info("Recurring transaction accepted")
+
+	return series, nil
+}
+
+// GetRecurringTransactions lists the user's accepted series, next due first
+func (s *TransactionService) GetRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]RecurringTransaction, error) {
+	return s.repo.GetRecurringTransactions(ctx, userID)
+}
+
+// DeleteRecurringTransaction removes a series and untags its transactions
+func (s *TransactionService) DeleteRecurringTransaction(ctx context.Context, userID, recurringID uuid.UUID) error {
+	if err := s.repo.DeleteRecurringTransaction(ctx, userID, recurringID); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":      userID,
+		"recurring_id": recurringID,
+	}).Info("Recurring transaction deleted")
+
+	return nil
+}
+
+// recurringGroupKey groups transactions on the same account, in the same
+// direction, with the same merchant. It is "" when no merchant can be told.
+func recurringGroupKey(tx *Transaction) string {
+	if tx.Amount == 0 {
+		return ""
+	}
+	direction := "expense"
+	if tx.Amount > 0 {
+		direction = "income"
+	}
+
+	var merchant string
+	switch {
+	case tx.MerchantID != nil:
+		merchant = tx.MerchantID.String()
+	case tx.MerchantName != nil && strings.TrimSpace(*tx.MerchantName) != "":
+		merchant = strings.ToLower(normalizeMerchantName(*tx.MerchantName))
+	default:
+		merchant = strings.ToLower(normalizeMerchantName(tx.Description))
+	}
+	if merchant == "" {
+		// Salaries, standing orders and direct debits have no merchant but
+		// repeat the same descriptor; cash withdrawals give no key at all
+		descriptor := descriptorKey(tx.Description)
+		if descriptor == "" {
+			return ""
+		}
+		merchant = "descriptor:" + descriptor
+	}
+	return tx.AccountID.String() + "|" + direction + "|" + merchant
+}
+
+// clusterByAmount splits date ordered transactions into clusters whose amounts
+// are within tolerance of the smallest one, each still in date order
+func clusterByAmount(transactions []Transaction, tolerance float64) [][]Transaction {
+	sorted := slices.Clone(transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return math.Abs(sorted[i].Amount) < math.Abs(sorted[j].Amount)
+	})
+
+	var clusters [][]Transaction
+	start := 0
+	for i := 1; i <= len(sorted); i++ {
+		if i < len(sorted) && math.Abs(sorted[i].Amount)-math.Abs(sorted[start].Amount) <= math.Abs(sorted[start].Amount)*tolerance {
+			continue
+		}
+		cluster := sorted[start:i]
+		sort.SliceStable(cluster, func(a, b int) bool {
+			return cluster[a].TransactionDate.Before(cluster[b].TransactionDate)
+		})
+		clusters = append(clusters, cluster)
+		start = i
+	}
+	return clusters
+}
+
+// cadenceFor looks up the cadence of a frequency
+func cadenceFor(frequency string) (recurringCadence, bool) {
+	for _, cadence := range recurringCadences {
+		if cadence.frequency == frequency {
+			return cadence, true
+		}
+	}
+	return recurringCadence{}, false
+}
+
+// detectRecurringSeries infers the cadence of date ordered transactions from
+// their median interval, or uses frequency when set, and describes them as a
+// series. Confidence weighs how many intervals fit the cadence, how close the
+// amounts are and how much history there is. It returns nil when there is no
+// cadence, or too few occurrences to propose one.
+func detectRecurringSeries(transactions []Transaction, frequency string, tolerance float64) *RecurringProposal {
+	if len(transactions) < 2 {
+		return nil
+	}
+
+	intervals := make([]float64, 0, len(transactions)-1)
+	for i := 1; i < len(transactions); i++ {
+		intervals = append(intervals, transactions[i].TransactionDate.Sub(transactions[i-1].TransactionDate).Hours()/24)
+	}
+
+	var cadence recurringCadence
+	found := false
+	if frequency != "" {
+		cadence, found = cadenceFor(frequency)
+	} else {
+		interval := median(intervals)
+		for _, candidate := range recurringCadences {
+			if math.Abs(interval-candidate.days) <= candidate.slack {
+				cadence, found = candidate, len(transactions) >= candidate.minCount
+				break
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	regular := 0
+	for _, interval := range intervals {
+		if math.Abs(interval-cadence.days) <= cadence.slack {
+			regular++
+		}
+	}
+	regularity := float64(regular) / float64(len(intervals))
+
+	amounts := make([]float64, len(transactions))
+	for i, tx := range transactions {
+		amounts[i] = tx.Amount
+	}
+	typical := math.Round(median(amounts)*100) / 100
+	var deviation, variance float64
+	for _, amount := range amounts {
+		deviation += math.Abs(amount - typical)
+		variance = math.Max(variance, math.Abs(amount-typical))
+	}
+	deviation /= float64(len(amounts))
+	consistency := 0.0
+	if deviation == 0 {
+		consistency = 1
+	} else if tolerance > 0 {
+		consistency = math.Max(0, 1-deviation/math.Abs(typical)/tolerance)
+	}
+
+	history := math.Min(1, float64(len(intervals))/float64(cadence.fullAfter))
+
+	first, last := transactions[0], transactions[len(transactions)-1]
+	proposal := &RecurringProposal{
+		AccountID:       last.AccountID,
+		CategoryID:      commonCategory(transactions),
+		Name:            recurringName(&last),
+		MerchantName:    last.MerchantName,
+		TransactionType: "expense",
+		Frequency:       cadence.frequency,
+		Amount:          last.Amount,
+		TypicalAmount:   typical,
+		AmountVariance:  math.Round(variance*100) / 100,
+		StartDate:       first.TransactionDate,
+		LastDate:        last.TransactionDate,
+		NextDueDate:     cadence.next(last.TransactionDate),
+		Occurrences:     len(transactions),
+		Confidence:      math.Round((0.5*regularity+0.2*consistency+0.3*history)*100) / 100,
+		TransactionIDs:  make([]uuid.UUID, len(transactions)),
+	}
+	if typical > 0 {
+		proposal.TransactionType = "income"
+	}
+	for i, tx := range transactions {
+		proposal.TransactionIDs[i] = tx.ID
+	}
+	return proposal
+}
+
+// commonCategory is the category most of the transactions have, the latest on a tie
+func commonCategory(transactions []Transaction) *uuid.UUID {
+	counts := make(map[uuid.UUID]int)
+	var best *uuid.UUID
+	for _, tx := range transactions {
+		if tx.CategoryID == nil {
+			continue
+		}
+		counts[*tx.CategoryID]++
+		if best == nil || counts[*tx.CategoryID] >= counts[*best] {
+			id := *tx.CategoryID
+			best = &id
+		}
+	}
+	return best
+}
+
+// recurringName names a series after its merchant, or the cleaned description
+func recurringName(tx *Transaction) string {
+	name := ""
+	if tx.MerchantName != nil {
+		name = strings.TrimSpace(*tx.MerchantName)
+	}
+	if name == "" {
+		name = normalizeMerchantName(tx.Description)
+	}
+	if name == "" {
+		name = strings.TrimSpace(tx.Description)
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = strings.TrimSpace(string(runes[:100]))
+	}
+	return name
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
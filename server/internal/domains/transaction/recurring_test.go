@@ -0,0 +1,233 @@
+package transaction
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRecurringCadenceNext(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		frequency string
+		from      time.Time
+		want      time.Time
+	}{
+		{frequency: RecurringWeekly, from: date(2025, 12, 29), want: date(2026, 1, 5)},
+		{frequency: RecurringMonthly, from: date(2025, 1, 15), want: date(2025, 2, 15)},
+		{frequency: RecurringMonthly, from: date(2025, 1, 31), want: date(2025, 2, 28)},
+		{frequency: RecurringMonthly, from: date(2024, 1, 31), want: date(2024, 2, 29)},
+		{frequency: RecurringMonthly, from: date(2025, 3, 31), want: date(2025, 4, 30)},
+		{frequency: RecurringMonthly, from: date(2025, 12, 31), want: date(2026, 1, 31)},
+		{frequency: RecurringQuarterly, from: date(2025, 11, 30), want: date(2026, 2, 28)},
+		{frequency: RecurringAnnual, from: date(2024, 2, 29), want: date(2025, 2, 28)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.frequency+" "+tt.from.Format("2006-01-02"), func(t *testing.T) {
+			cadence, ok := cadenceFor(tt.frequency)
+			if !ok {
+				t.Fatalf("cadenceFor(%q) not found", tt.frequency)
+			}
+			if got := cadence.next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("next(%s) = %s, want %s", tt.from.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestDetectRecurringSeries(t *testing.T) {
+	// series builds date ordered transactions from days after the first one
+	series := func(amounts []float64, days ...int) []Transaction {
+		start := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+		transactions := make([]Transaction, len(days))
+		for i, day := range days {
+			transactions[i] = Transaction{ID: uuid.New(), Amount: amounts[i%len(amounts)], Description: "NETFLIX.COM", TransactionDate: start.AddDate(0, 0, day)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		frequency    string
+		wantNil      bool
+		want         RecurringProposal
+	}{
+		{
+			name:         "monthly subscription",
+			transactions: series([]float64{-15.99}, 0, 28, 59, 89, 120, 150),
+			want: RecurringProposal{
+				TransactionType: "expense", Frequency: RecurringMonthly, Amount: -15.99, TypicalAmount: -15.99,
+				Occurrences: 6, Confidence: 0.95,
+			},
+		},
+		{
+			name:         "weekly with enough history",
+			transactions: series([]float64{20, 22}, 0, 7, 14, 21),
+			want: RecurringProposal{
+				TransactionType: "income", Frequency: RecurringWeekly, Amount: 22, TypicalAmount: 21, AmountVariance: 1,
+				Occurrences: 4, Confidence: 0.76,
+			},
+		},
+		{
+			name:         "two annual payments",
+			transactions: series([]float64{-99}, 0, 365),
+			want: RecurringProposal{
+				TransactionType: "expense", Frequency: RecurringAnnual, Amount: -99, TypicalAmount: -99,
+				Occurrences: 2, Confidence: 0.85,
+			},
+		},
+		{
+			name:         "too few weekly payments",
+			transactions: series([]float64{-5}, 0, 7, 14),
+			wantNil:      true,
+		},
+		{
+			name:         "no cadence",
+			transactions: series([]float64{-5}, 0, 12, 24, 36),
+			wantNil:      true,
+		},
+		{
+			name:         "a single payment",
+			transactions: series([]float64{-5}, 0),
+			wantNil:      true,
+		},
+		{
+			name:         "given frequency skips the occurrence minimum",
+			transactions: series([]float64{-15.99}, 0, 28),
+			frequency:    RecurringMonthly,
+			want: RecurringProposal{
+				TransactionType: "expense", Frequency: RecurringMonthly, Amount: -15.99, TypicalAmount: -15.99,
+				Occurrences: 2, Confidence: 0.75,
+			},
+		},
+		{
+			name:         "unknown frequency",
+			transactions: series([]float64{-15.99}, 0, 28, 59),
+			frequency:    "daily",
+			wantNil:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectRecurringSeries(tt.transactions, tt.frequency, 0.2)
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("detectRecurringSeries() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("detectRecurringSeries() = nil, want a proposal")
+			}
+
+			first, last := tt.transactions[0], tt.transactions[len(tt.transactions)-1]
+			cadence, _ := cadenceFor(tt.want.Frequency)
+			if got.TransactionType != tt.want.TransactionType || got.Frequency != tt.want.Frequency || got.Amount != tt.want.Amount ||
+				got.TypicalAmount != tt.want.TypicalAmount || got.AmountVariance != tt.want.AmountVariance ||
+				got.Occurrences != tt.want.Occurrences || got.Confidence != tt.want.Confidence {
+				t.Errorf("detectRecurringSeries() = %+v, want %+v", got, tt.want)
+			}
+			if !got.StartDate.Equal(first.TransactionDate) || !got.LastDate.Equal(last.TransactionDate) || !got.NextDueDate.Equal(cadence.next(last.TransactionDate)) {
+				t.Errorf("dates = %s..%s next %s", got.StartDate, got.LastDate, got.NextDueDate)
+			}
+			if len(got.TransactionIDs) != len(tt.transactions) || got.TransactionIDs[0] != first.ID {
+				t.Errorf("TransactionIDs = %v, want the IDs of the series", got.TransactionIDs)
+			}
+		})
+	}
+}
+
+func TestClusterByAmount(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var transactions []Transaction
+	for i, amount := range []float64{-10, -50, -10.5, -52, -11, -100} {
+		transactions = append(transactions, Transaction{Amount: amount, TransactionDate: start.AddDate(0, i, 0)})
+	}
+
+	clusters := clusterByAmount(transactions, 0.1)
+	var got [][]float64
+	for _, cluster := range clusters {
+		var amounts []float64
+		for i, tx := range cluster {
+			if i > 0 && tx.TransactionDate.Before(cluster[i-1].TransactionDate) {
+				t.Errorf("cluster %v is not in date order", cluster)
+			}
+			amounts = append(amounts, tx.Amount)
+		}
+		got = append(got, amounts)
+	}
+
+	want := [][]float64{{-10, -10.5, -11}, {-50, -52}, {-100}}
+	if !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("clusterByAmount() = %v, want %v", got, want)
+	}
+}
+
+func TestRecurringGroupKey(t *testing.T) {
+	account := uuid.New()
+	merchant := uuid.New()
+
+	key := func(tx Transaction) string {
+		tx.AccountID = account
+		return recurringGroupKey(&tx)
+	}
+
+	tests := []struct {
+		name string
+		a, b Transaction
+		same bool
+	}{
+		{
+			name: "same directory merchant",
+			a:    Transaction{Amount: -10, Description: "NETFLIX.COM 1", MerchantID: &merchant},
+			b:    Transaction{Amount: -12, Description: "NETFLIX.COM 2", MerchantID: &merchant},
+			same: true,
+		},
+		{
+			name: "merchant names ignore case",
+			a:    Transaction{Amount: -10, MerchantName: stringPtr("Gym Co")},
+			b:    Transaction{Amount: -10, MerchantName: stringPtr("GYM CO")},
+			same: true,
+		},
+		{
+			name: "money in and out stay apart",
+			a:    Transaction{Amount: -10, MerchantName: stringPtr("Gym Co")},
+			b:    Transaction{Amount: 10, MerchantName: stringPtr("Gym Co")},
+		},
+		{
+			name: "salaries group by their descriptor",
+			a:    Transaction{Amount: 2500, Description: "SALARY ACME LTD 25JAN"},
+			b:    Transaction{Amount: 2510, Description: "SALARY ACME LTD 25FEB"},
+			same: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := key(tt.a), key(tt.b)
+			if a == "" || b == "" {
+				t.Fatalf("recurringGroupKey() = %q, %q, want keys", a, b)
+			}
+			if (a == b) != tt.same {
+				t.Errorf("recurringGroupKey() = %q, %q, want same=%t", a, b, tt.same)
+			}
+		})
+	}
+
+	for _, tx := range []Transaction{
+		{Amount: 0, MerchantName: stringPtr("Gym Co")},
+		{Amount: -100, Description: "ATM WITHDRAWAL 12JAN"},
+	} {
+		if got := key(tx); got != "" {
+			t.Errorf("recurringGroupKey(%q) = %q, want none", tx.Description, got)
+		}
+	}
+}
@@ -5,30 +5,129 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/lib/pq"
 
+	"hi-cfo/server/internal/config"
+	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/domains/merchant"
 	"hi-cfo/server/internal/logger"
 	customerrors "hi-cfo/server/internal/shared/errors"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	// core CRUD operations
 	GetTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error)
 	GetTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*TransactionListResponse, error)
-	UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, updates map[string]any) (*Transaction, error)
+	UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, updates map[string]any, source string) (*Transaction, error)
 	DeleteTransaction(ctx context.Context, userID, transactionID uuid.UUID) error
 	CreateTransactions(ctx context.Context, userID uuid.UUID, transactions []*Transaction) (*BatchOperationResult, error)
 	GetTransactionsByFitIDs(ctx context.Context, userID uuid.UUID, fitIDs []string) (map[string]*Transaction, error)
 	GetTransactionStats(ctx context.Context, userID uuid.UUID, startDate, endDate *time.Time, groupBy string) (*TransactionStats, error)
+
+	// export
+	StreamTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter, fn func(*TransactionExportRow) error) error
+	GetTransactionDateRange(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*time.Time, *time.Time, error)
+
+	// change history
+	GetTransactionChanges(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionChange, error)
+
+	// bulk edit
+	BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, filter TransactionFilter, changes BulkTransactionChanges, maxRows int, dryRun bool, source string) ([]uuid.UUID, error)
+
+	// search
+	SearchTransactions(ctx context.Context, userID uuid.UUID, tsQuery string, filter TransactionFilter) (*TransactionSearchResponse, error)
+
+	// pending transactions
+	FindPendingMatches(ctx context.Context, userID uuid.UUID, transactions []*Transaction, cfg PendingMatchConfig) (map[int]uuid.UUID, error)
+	PostPendingTransactions(ctx context.Context, userID uuid.UUID, updates map[uuid.UUID]map[string]any, source string) ([]uuid.UUID, error)
+
+	// duplicate review
+	GetDuplicateCandidates(ctx context.Context, userID uuid.UUID, filter DuplicateFilter) ([]Transaction, int64, error)
+	GetTransactionsByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*Transaction, error)
+	MergeDuplicate(ctx context.Context, userID, duplicateID, originalID uuid.UUID, updates map[string]any, source string) error
+
+	// splits
+	GetSplits(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionSplit, error)
+	ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []*TransactionSplit) error
+	DeleteSplits(ctx context.Context, userID, transactionID uuid.UUID) (int64, error)
+
+	// transfers
+	FindTransferMatches(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, startDate, endDate *time.Time, windowDays int) ([]TransferMatch, error)
+	LinkTransfer(ctx context.Context, userID, transactionID, counterpartID uuid.UUID, source string) error
+	UnlinkTransfer(ctx context.Context, userID, transactionID uuid.UUID, source string) error
+
+	// import batches
+	GetFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID) ([]FileUploadTransactionRef, error)
+	RevertFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID, keepIDs []uuid.UUID, revertedAt time.Time) (int64, error)
+	RestoreFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID, revertedAt time.Time) (int64, error)
+	DiscardFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID) (int64, []TransactionAttachment, error)
+
+	// trash
+	GetDeletedTransactions(ctx context.Context, userID uuid.UUID, filter TrashFilter) ([]Transaction, int64, error)
+	GetDeletedTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error)
+	RestoreTransaction(ctx context.Context, userID, transactionID uuid.UUID) error
+	PurgeTransaction(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionAttachment, error)
+	PurgeExpiredTransactions(ctx context.Context, before time.Time) (int64, []TransactionAttachment, error)
+
+	// attachments
+	GetAttachments(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionAttachment, error)
+	GetAttachmentByID(ctx context.Context, userID, transactionID, attachmentID uuid.UUID) (*TransactionAttachment, error)
+	CreateAttachment(ctx context.Context, attachment *TransactionAttachment) error
+	DeleteAttachment(ctx context.Context, userID, transactionID, attachmentID uuid.UUID) error
+
+	// balances
+	RecalculateBalances(ctx context.Context, userID, accountID uuid.UUID) (*float64, error)
+	GetBalanceDelta(ctx context.Context, userID, accountID uuid.UUID, anchor, before time.Time) (float64, error)
+	GetDailyBalanceChanges(ctx context.Context, userID, accountID uuid.UUID, start, end time.Time) ([]DailyBalanceChange, error)
+	GetFileUploadAccountIDs(ctx context.Context, userID, uploadID uuid.UUID) ([]uuid.UUID, error)
+
+	// reconciliation
+	GetReconciliations(ctx context.Context, userID, accountID uuid.UUID) ([]Reconciliation, error)
+	GetReconciliationByID(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) (*Reconciliation, error)
+	CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error
+	GetReconciliationTotals(ctx context.Context, userID, reconciliationID uuid.UUID) (*ReconciliationTotals, error)
+	GetReconciliationTransactions(ctx context.Context, userID uuid.UUID, reconciliation *Reconciliation) ([]Transaction, error)
+	SetTransactionsCleared(ctx context.Context, userID uuid.UUID, reconciliation *Reconciliation, ids []uuid.UUID, cleared bool) ([]uuid.UUID, error)
+	CompleteReconciliation(ctx context.Context, reconciliation *Reconciliation, completedAt time.Time) (*ReconciliationTotals, error)
+	DeleteReconciliation(ctx context.Context, userID, reconciliationID uuid.UUID) error
+
+	// recurring transactions
+	GetRecurringCandidates(ctx context.Context, userID uuid.UUID, since time.Time) ([]Transaction, error)
+	GetRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]RecurringTransaction, error)
+	CreateRecurringTransaction(ctx context.Context, series *RecurringTransaction, transactionIDs []uuid.UUID) error
+	DeleteRecurringTransaction(ctx context.Context, userID, recurringID uuid.UUID) error
+
+	// merchants
+	GetReconciledMerchantTransactions(ctx context.Context, userID uuid.UUID, merchantIDs []uuid.UUID) ([]uuid.UUID, error)
+	ReassignMerchant(ctx context.Context, userID uuid.UUID, fromIDs []uuid.UUID, to *merchant.Merchant, source string) (int64, error)
+	GetMerchantSpend(ctx context.Context, userID uuid.UUID, filter merchant.SpendFilter) ([]merchant.MerchantSpend, error)
+
+	// import profiles
+	GetImportProfiles(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID) ([]ImportProfile, error)
+	GetImportProfileByID(ctx context.Context, userID, profileID uuid.UUID) (*ImportProfile, error)
+	CreateImportProfile(ctx context.Context, profile *ImportProfile) error
+	UpdateImportProfile(ctx context.Context, userID, profileID uuid.UUID, updates map[string]any) (*ImportProfile, error)
+	DeleteImportProfile(ctx context.Context, userID, profileID uuid.UUID) error
 }
 
+const (
+	insertChunkSize    = 500  // Rows per multi-row INSERT
+	duplicateChunkSize = 1000 // Rows per VALUES list in the fuzzy duplicate check, 4 parameters each
+	purgeChunkSize     = 500  // Transactions hard-deleted per database transaction by the retention job
+
+	reconciledMerchantListLimit = 50 // Reconciled transactions listed when a merchant change is refused
+)
+
 type TransactionRepository struct {
 	db     *gorm.DB
 	logger *logrus.Entry
@@ -43,61 +142,241 @@ func NewTransactionRepository(db *gorm.DB) *TransactionRepository {
 
 func (r *TransactionRepository) GetTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*TransactionListResponse, error) {
 	var transactions []Transaction
-	var total int64
+	total := int64(-1)
 
 	// Build base query
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	query := r.db.WithContext(ctx).Model(&Transaction{}).Where("transactions.user_id = ?", userID)
+	query = applyTransactionFilter(query, filter)
 
-	// Apply filters
-	if filter.AccountID != nil {
-		query = query.Where("account_id = ?", *filter.AccountID)
+	// Get total count, callers paging by cursor can skip it
+	if filter.includeTotal() {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count transactions").
+				WithDomain("transaction").
+				WithDetail("operation", "count_transactions")
+			appErr.Log()
+			return nil, appErr
+		}
 	}
-	if filter.CategoryID != nil {
-		query = query.Where("category_id = ?", *filter.CategoryID)
+
+	// One extra row tells whether there is another page in the read direction
+	page := query.Session(&gorm.Session{}).Limit(filter.Limit + 1)
+	offset := 0
+	cursor := filter.cursor
+	if cursor == nil {
+		offset = (filter.Page - 1) * filter.Limit
+		page = page.Offset(offset).Order(transactionOrder(filter))
+	} else {
+		readFilter, comparison := filter.cursorRead(cursor)
+		page = page.
+			Where("(transactions.transaction_date, transactions.created_at, transactions.id) "+comparison+" (?, ?, ?)", cursor.Date, cursor.CreatedAt, cursor.ID).
+			Order(transactionOrder(readFilter))
 	}
-	if filter.StartDate != nil {
-		query = query.Where("transaction_date >= ?", *filter.StartDate)
+
+	if err := page.Find(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"operation": "fetch_transactions",
+				"user_id":   userID,
+				"offset":    offset,
+				"limit":     filter.Limit,
+				"cursor":    filter.Cursor,
+			})
+		appErr.Log()
+		return nil, appErr
 	}
-	if filter.EndDate != nil {
-		query = query.Where("transaction_date <= ?", *filter.EndDate)
+
+	hasMore := len(transactions) > filter.Limit
+	if hasMore {
+		transactions = transactions[:filter.Limit]
 	}
-	if filter.TransactionType != nil {
-		query = query.Where("transaction_type = ?", *filter.TransactionType)
+	if cursor != nil && cursor.Backward {
+		slices.Reverse(transactions)
 	}
-	if filter.MinAmount != nil {
-		query = query.Where("amount >= ?", *filter.MinAmount)
+
+	response := &TransactionListResponse{
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: -1,
 	}
-	if filter.MaxAmount != nil {
-		query = query.Where("amount <= ?", *filter.MaxAmount)
+	if cursor != nil {
+		response.Page = 0
 	}
-	if filter.SearchTerm != nil {
-		searchPattern := "%" + *filter.SearchTerm + "%"
-		query = query.Where("description ILIKE ? OR merchant_name ILIKE ?", searchPattern, searchPattern)
+	if total >= 0 {
+		response.Pages = int(math.Ceil(float64(total) / float64(filter.Limit)))
 	}
-	// don't include deleted transactions
-	query = query.Where("deleted_at IS NULL")
 
-	// Get total count
-	if err := query.Model(&Transaction{}).Count(&total).Error; err != nil {
-		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count transactions").
-			WithDomain("transaction").
-			WithDetail("operation", "count_transactions")
+	// Convert to TransactionListItems manually to handle Tags properly
+	response.Data = make([]TransactionListItem, len(transactions))
+	for i, tx := range transactions {
+		response.Data[i] = tx.ToListItem()
+	}
+
+	if filter.sortedByDate() {
+		response.NextCursor, response.PrevCursor = pageCursors(transactions, cursor, hasMore, offset)
+	}
+
+	return response, nil
+}
+
+// BulkUpdateTransactions applies changes to the transactions in ids, or to every
+// transaction matching filter when ids is empty, in one database transaction.
+// It returns the IDs of the affected rows; a dry run only selects them.
+func (r *TransactionRepository) BulkUpdateTransactions(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, filter TransactionFilter, changes BulkTransactionChanges, maxRows int, dryRun bool, source string) ([]uuid.UUID, error) {
+	var affected []uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		query := db.Model(&Transaction{}).Where("transactions.user_id = ?", userID)
+		if len(ids) > 0 {
+			query = query.Where("transactions.id IN ? AND transactions.deleted_at IS NULL", ids)
+		} else {
+			// A filter leaves out the transactions locked by a reconciliation
+			query = applyTransactionFilter(query, filter).Where("transactions.reconciled_at IS NULL")
+		}
+		if !dryRun {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+
+		// One row over the limit is enough to refuse the edit
+		if err := query.Order(transactionListOrder).Limit(maxRows+1).Pluck("transactions.id", &affected).Error; err != nil {
+			return err
+		}
+		if len(affected) > maxRows {
+			return customerrors.New(customerrors.ErrCodeValidation,
+				fmt.Sprintf("Bulk edit matches more than %d transactions, narrow the selection", maxRows)).
+				WithDomain("transaction")
+		}
+		if len(ids) > 0 && len(affected) > 0 {
+			var locked []uuid.UUID
+			if err := db.Model(&Transaction{}).
+				Where("user_id = ? AND id IN ? AND reconciled_at IS NOT NULL", userID, affected).
+				Pluck("id", &locked).Error; err != nil {
+				return err
+			}
+			if len(locked) > 0 {
+				return customerrors.New(customerrors.ErrCodeTransactionBlocked, "Some transactions are reconciled, unlock them before changing them").
+					WithDomain("transaction").
+					WithDetail("transaction_ids", locked)
+			}
+		}
+		if dryRun || len(affected) == 0 {
+			return nil
+		}
+
+		_, err := updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("id IN ?", affected)
+		}, bulkUpdateColumns(changes), source)
+		return err
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to bulk update transactions").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":   userID,
+			"ids":       len(ids),
+			"max_rows":  maxRows,
+			"dry_run":   dryRun,
+			"operation": "bulk_update_transactions",
+		})
 		appErr.Log()
 		return nil, appErr
 	}
+	return affected, nil
+}
+
+// bulkUpdateColumns turns bulk changes into column updates. Tags are edited
+// in SQL so each row keeps its own tags and their order.
+func bulkUpdateColumns(changes BulkTransactionChanges) map[string]any {
+	updates := map[string]any{"updated_at": time.Now()}
+	if changes.ClearCategory {
+		updates["category_id"] = nil
+	} else if changes.CategoryID != nil {
+		updates["category_id"] = *changes.CategoryID
+	}
+	if changes.IsHidden != nil {
+		updates["is_hidden"] = *changes.IsHidden
+	}
+	if changes.NeedsReview != nil {
+		updates["needs_review"] = *changes.NeedsReview
+	}
+	if changes.UserNotes != nil {
+		if *changes.UserNotes == "" {
+			updates["user_notes"] = nil
+		} else {
+			updates["user_notes"] = *changes.UserNotes
+		}
+	}
+
+	if len(changes.AddTags) > 0 || len(changes.RemoveTags) > 0 {
+		tags := gorm.Expr("coalesce(tags, '{}')")
+		if len(changes.AddTags) > 0 {
+			tags = gorm.Expr("array_cat(?, ARRAY(SELECT unnest(?::text[]) EXCEPT SELECT unnest(?)))",
+				tags, pq.StringArray(changes.AddTags), tags)
+		}
+		if len(changes.RemoveTags) > 0 {
+			tags = gorm.Expr("ARRAY(SELECT t FROM unnest(?) WITH ORDINALITY AS u(t, n) WHERE t <> ALL(?::text[]) ORDER BY n)",
+				tags, pq.StringArray(changes.RemoveTags))
+		}
+		updates["tags"] = tags
+	}
+	return updates
+}
+
+// transactionSearchRow is a search match with its rank and highlighted snippet
+type transactionSearchRow struct {
+	Transaction
+	Rank    float64
+	Snippet string
+}
+
+// searchHeadlineOptions configure ts_headline for search snippets
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MinWords=5, MaxWords=20, MaxFragments=2, FragmentDelimiter=" … "`
+
+// SearchTransactions returns the transactions matching tsQuery, best match
+// first. The other filter conditions narrow the matches down as in GetTransactions.
+func (r *TransactionRepository) SearchTransactions(ctx context.Context, userID uuid.UUID, tsQuery string, filter TransactionFilter) (*TransactionSearchResponse, error) {
+	filter.SearchTerm = nil
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Joins("CROSS JOIN to_tsquery('simple', ?) AS q(query)", tsQuery).
+		Where("transactions.user_id = ? AND transactions.search_vector @@ q.query", userID)
+	query = applyTransactionFilter(query, filter)
+
+	total := int64(-1)
+	if filter.includeTotal() {
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count search results").
+				WithDomain("transaction").
+				WithDetail("operation", "count_search_results")
+			appErr.Log()
+			return nil, appErr
+		}
+	}
 
-	// Fetch full transactions first, then convert to list items
+	// Snippets are built from escaped text so only the <mark> tags are markup
+	var rows []transactionSearchRow
 	offset := (filter.Page - 1) * filter.Limit
-	if err := query.
+	err := query.Session(&gorm.Session{}).
+		Select(`transactions.*,
+			ts_rank_cd(transactions.search_vector, q.query) AS rank,
+			ts_headline('simple', replace(replace(replace(
+				concat_ws(' · ', coalesce(transactions.user_description, transactions.description),
+					transactions.merchant_name, transactions.memo, transactions.user_notes),
+				'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), q.query, ?) AS snippet`, searchHeadlineOptions).
+		Order("rank DESC, " + transactionListOrder).
 		Offset(offset).
 		Limit(filter.Limit).
-		Order("transaction_date DESC, created_at DESC").
-		Find(&transactions).Error; err != nil {
-		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transactions").
+		Find(&rows).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to search transactions").
 			WithDomain("transaction").
 			WithDetails(map[string]any{
-				"operation": "fetch_transactions",
+				"operation": "search_transactions",
 				"user_id":   userID,
+				"query":     tsQuery,
 				"offset":    offset,
 				"limit":     filter.Limit,
 			})
@@ -105,26 +384,118 @@ func (r *TransactionRepository) GetTransactions(ctx context.Context, userID uuid
 		return nil, appErr
 	}
 
-	// Convert to TransactionListItems manually to handle Tags properly
-	transactionItems := make([]TransactionListItem, len(transactions))
-	for i, tx := range transactions {
-		transactionItems[i] = tx.ToListItem()
+	results := make([]TransactionSearchResult, len(rows))
+	for i := range rows {
+		results[i] = TransactionSearchResult{
+			TransactionListItem: rows[i].ToListItem(),
+			Rank:                rows[i].Rank,
+			Snippet:             rows[i].Snippet,
+		}
 	}
-	// Calculate pages
-	pages := int(math.Ceil(float64(total) / float64(filter.Limit)))
 
-	return &TransactionListResponse{
-		Data:  transactionItems,
+	response := &TransactionSearchResponse{
+		Data:  results,
 		Total: total,
 		Page:  filter.Page,
 		Limit: filter.Limit,
-		Pages: pages,
-	}, nil
+		Pages: -1,
+	}
+	if total >= 0 {
+		response.Pages = int(math.Ceil(float64(total) / float64(filter.Limit)))
+	}
+	return response, nil
+}
+
+// applyTransactionFilter adds the TransactionFilter conditions to query.
+// Columns are qualified so the conditions also work when query joins other tables.
+func applyTransactionFilter(query *gorm.DB, filter TransactionFilter) *gorm.DB {
+	if len(filter.AccountIDs) > 0 {
+		query = query.Where("transactions.account_id IN ?", filter.AccountIDs)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where(`(transactions.category_id IN ? OR EXISTS (
+			SELECT 1 FROM transaction_splits s WHERE s.transaction_id = transactions.id AND s.category_id IN ?))`,
+			filter.CategoryIDs, filter.CategoryIDs)
+	}
+	if filter.Uncategorized {
+		query = query.Where(`transactions.category_id IS NULL AND NOT EXISTS (
+			SELECT 1 FROM transaction_splits s WHERE s.transaction_id = transactions.id)`)
+	}
+	if filter.FileUploadID != nil {
+		query = query.Where("transactions.file_upload_id = ?", *filter.FileUploadID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("transactions.transaction_date >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("transactions.transaction_date <= ?", *filter.EndDate)
+	}
+	if filter.TransactionType != nil {
+		query = query.Where("transactions.transaction_type = ?", *filter.TransactionType)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("transactions.amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("transactions.amount <= ?", *filter.MaxAmount)
+	}
+	if filter.Currency != nil {
+		query = query.Where("transactions.currency = ?", strings.ToUpper(*filter.Currency))
+	}
+	if len(filter.Tags) > 0 {
+		if filter.TagMatch == TagMatchAll {
+			query = query.Where("transactions.tags @> ?", pq.StringArray(filter.Tags))
+		} else {
+			query = query.Where("transactions.tags && ?", pq.StringArray(filter.Tags))
+		}
+	}
+	if filter.NeedsReview != nil {
+		query = query.Where("transactions.needs_review = ?", *filter.NeedsReview)
+	}
+	if filter.IsHidden != nil {
+		query = query.Where("transactions.is_hidden = ?", *filter.IsHidden)
+	}
+	if filter.Status != nil {
+		query = query.Where("transactions.status = ?", *filter.Status)
+	}
+	if filter.IsRecurring != nil {
+		query = query.Where("transactions.is_recurring = ?", *filter.IsRecurring)
+	}
+	if filter.SearchTerm != nil {
+		if tsQuery := searchQuery(*filter.SearchTerm); tsQuery != "" {
+			query = query.Where("transactions.search_vector @@ to_tsquery('simple', ?)", tsQuery)
+		}
+	}
+	// don't include deleted transactions
+	return query.Where("transactions.deleted_at IS NULL")
+}
+
+// transactionOrder returns the ORDER BY for the filter's sort. Ties fall back
+// to the default listing order so pages never overlap.
+func transactionOrder(filter TransactionFilter) string {
+	direction := " ASC"
+	if filter.sortDescending() {
+		direction = " DESC"
+	}
+
+	switch filter.SortBy {
+	case TransactionSortAmount:
+		return "transactions.amount" + direction + ", " + transactionListOrder
+	case TransactionSortMerchant:
+		return "lower(coalesce(transactions.merchant_name, transactions.description))" + direction + ", " + transactionListOrder
+	case TransactionSortCreatedAt:
+		return "transactions.created_at" + direction + ", transactions.id" + direction
+	default:
+		return "transactions.transaction_date" + direction + ", transactions.created_at" + direction + ", transactions.id" + direction
+	}
 }
 
 func (r *TransactionRepository) GetTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
 	var transaction Transaction
-	err := r.db.WithContext(ctx).Where("user_id = ? AND id = ? AND deleted_at IS NULL", userID, transactionID).First(&transaction).Error
+	err := r.db.WithContext(ctx).
+		Preload("Splits", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Where("user_id = ? AND id = ? AND deleted_at IS NULL", userID, transactionID).
+		First(&transaction).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found").
@@ -203,12 +574,12 @@ func (r *TransactionRepository) CreateTransactions(ctx context.Context, userID u
 		return result, nil
 	}
 
-	if err := r.insertTransactionsIndividually(ctx, validTransactions, result); err != nil {
+	if err := r.insertTransactionsInChunks(ctx, validTransactions, result); err != nil {
 		r.logger.WithFields(logrus.Fields{
 			"error":       err,
 			"user_id":     userID,
 			"valid_count": len(validTransactions),
-		}).Error("Individual insert failed")
+		}).Error("Batch insert failed")
 		return result, customerrors.Wrap(err, customerrors.ErrCodeInternal, "failed to insert transactions").
 			WithDomain("transaction")
 	}
@@ -305,48 +676,89 @@ func (r *TransactionRepository) validateTransactionData(tx *Transaction) bool {
 	return true
 }
 
-// Individual inserts with comprehensive error handling
-func (r *TransactionRepository) insertTransactionsIndividually(ctx context.Context, transactions []*Transaction, result *BatchOperationResult) error {
-	for i, tx := range transactions {
-		r.logger.WithFields(logrus.Fields{
-			"index":       i + 1,
-			"total":       len(transactions),
-			"description": tx.Description,
-			"fit_id": func() string {
-				if tx.FitID != nil {
-					return *tx.FitID
+// insertTransactionsInChunks inserts the batch inside one database transaction
+// using multi-row INSERTs of insertChunkSize rows. A chunk that fails is rolled
+// back to its savepoint and retried row by row, so only the offending rows are
+// skipped and each gets its own entry in result.Errors.
+func (r *TransactionRepository) insertTransactionsInChunks(ctx context.Context, transactions []*Transaction, result *BatchOperationResult) error {
+	createdIDs := make([]uuid.UUID, 0, len(transactions))
+	rowErrors := make([]string, 0)
+	flagged := 0
+
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		for start := 0; start < len(transactions); start += insertChunkSize {
+			end := min(start+insertChunkSize, len(transactions))
+			chunk := transactions[start:end]
+
+			if err := db.SavePoint("chunk").Error; err != nil {
+				return err
+			}
+			err := db.Create(chunk).Error
+			if err == nil {
+				if err := db.Exec("RELEASE SAVEPOINT chunk").Error; err != nil {
+					return err
 				}
-				return "nil"
-			}(),
-		}).Debug("Inserting transaction")
+				for _, tx := range chunk {
+					createdIDs = append(createdIDs, tx.ID)
+					if tx.IsDuplicate {
+						flagged++
+					}
+				}
+				continue
+			}
 
-		if err := r.db.WithContext(ctx).Create(tx).Error; err != nil {
-			// Enhanced error logging
 			r.logger.WithFields(logrus.Fields{
-				"error":       err,
-				"index":       i + 1,
-				"id":          tx.ID,
-				"user_id":     tx.UserID,
-				"account_id":  tx.AccountID,
-				"description": tx.Description,
-			}).Error("Failed to insert transaction")
+				"error":      err,
+				"chunk_from": start,
+				"chunk_size": len(chunk),
+			}).Warn("Chunk insert failed, retrying row by row")
 
-			// Check for specific error types
-			errorMsg := r.categorizeError(err, tx)
-			result.Errors = append(result.Errors, errorMsg)
-			result.Skipped++
-			continue
-		}
+			if err := db.RollbackTo("chunk").Error; err != nil {
+				return err
+			}
 
-		result.CreatedIDs = append(result.CreatedIDs, tx.ID)
-		result.Created++
-		r.logger.WithFields(logrus.Fields{
-			"index":       i + 1,
-			"description": tx.Description,
-			"id":          tx.ID,
-		}).Debug("Successfully inserted transaction")
+			for i, tx := range chunk {
+				if err := db.SavePoint("row").Error; err != nil {
+					return err
+				}
+				if err := db.Create(tx).Error; err != nil {
+					r.logger.WithFields(logrus.Fields{
+						"error":       err,
+						"index":       start + i + 1,
+						"id":          tx.ID,
+						"user_id":     tx.UserID,
+						"account_id":  tx.AccountID,
+						"description": tx.Description,
+					}).Error("Failed to insert transaction")
+
+					if err := db.RollbackTo("row").Error; err != nil {
+						return err
+					}
+					rowErrors = append(rowErrors, r.categorizeError(err, tx))
+					continue
+				}
+				if err := db.Exec("RELEASE SAVEPOINT row").Error; err != nil {
+					return err
+				}
+				createdIDs = append(createdIDs, tx.ID)
+				if tx.IsDuplicate {
+					flagged++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
+	// Only count rows once the database transaction has committed
+	result.CreatedIDs = append(result.CreatedIDs, createdIDs...)
+	result.Created += len(createdIDs)
+	result.Flagged += flagged
+	result.Errors = append(result.Errors, rowErrors...)
+	result.Skipped += len(rowErrors)
+
 	return nil
 }
 
@@ -392,59 +804,53 @@ func (r *TransactionRepository) categorizeError(err error, tx *Transaction) stri
 		err)
 }
 
-// Enhanced duplicate detection with better error handling and robust FitID comparison
+// processDuplicateDetection skips rows whose FitID is already on an active
+// transaction. Every other row is compared with the active transactions on its
+// account; close matches are still created, but flagged with IsDuplicate and
+// NeedsReview so the user can resolve them from the duplicate review queue.
 func (r *TransactionRepository) processDuplicateDetection(ctx context.Context, userID uuid.UUID, transactions []*Transaction, result *BatchOperationResult, toCreate *[]*Transaction) error {
 	fitIDs := make([]string, 0)
-	fitIDToTransaction := make(map[string]*Transaction)
-	transactionsWithoutFitID := make([]*Transaction, 0)
-
 	for _, tx := range transactions {
-		if tx.FitID != nil && strings.TrimSpace(*tx.FitID) != "" {
-			// ✅ Normalize FitID: trim whitespace and convert to lowercase for comparison
-			fitIDValue := strings.ToLower(strings.TrimSpace(*tx.FitID))
-			fitIDs = append(fitIDs, fitIDValue)
-			fitIDToTransaction[fitIDValue] = tx
-		} else {
-			transactionsWithoutFitID = append(transactionsWithoutFitID, tx)
+		if fitID := normalizedFitID(tx); fitID != "" {
+			fitIDs = append(fitIDs, fitID)
 		}
 	}
 
 	// Handle FitID-based deduplication (only against active transactions)
+	existingTransactions := make(map[string]*Transaction)
 	if len(fitIDs) > 0 {
-		existingTransactions, err := r.GetTransactionsByFitIDs(ctx, userID, fitIDs)
+		var err error
+		existingTransactions, err = r.GetTransactionsByFitIDs(ctx, userID, fitIDs)
 		if err != nil {
 			return customerrors.Wrap(err, customerrors.ErrCodeInternal, "failed to check existing transactions").
 				WithDomain("transaction")
 		}
+	}
 
-		for fitID, tx := range fitIDToTransaction {
-			if existingTransactions[fitID] != nil {
-				result.Duplicates = append(result.Duplicates, *tx.FitID) // Use original FitID, not normalized
-				result.Skipped++
-			} else {
-				*toCreate = append(*toCreate, tx)
-			}
-		}
+	candidates, known := splitKnownFitIDs(transactions, existingTransactions)
+	for _, tx := range known {
+		result.Duplicates = append(result.Duplicates, *tx.FitID) // Use original FitID, not normalized
+		result.Skipped++
 	}
 
-	// Handle signature-based deduplication for transactions without FitID (only against active transactions)
-	if len(transactionsWithoutFitID) > 0 {
-		duplicateMap, err := r.CheckExistingTransactionsBySignature(ctx, userID, transactionsWithoutFitID)
+	// Flag fuzzy matches for review (only against active transactions)
+	if len(candidates) > 0 {
+		matches, err := r.FindDuplicateMatches(ctx, userID, candidates, r.getDuplicateMatchConfig())
 		if err != nil {
-			// Continue with processing - add all transactions without FitID
-			*toCreate = append(*toCreate, transactionsWithoutFitID...)
+			// Continue with processing - create the rows unflagged
+			r.logger.WithFields(logrus.Fields{
+				"error":   err,
+				"user_id": userID,
+			}).Warn("Fuzzy duplicate check failed, creating transactions unflagged")
 		} else {
-			// Add only non-duplicates
-			for i, tx := range transactionsWithoutFitID {
-				if duplicateMap[i] {
-					sigID := fmt.Sprintf("SIG_%s_%.2f_%s", tx.Description, tx.Amount, tx.TransactionDate.Format("2006-01-02"))
-					result.Duplicates = append(result.Duplicates, sigID)
-					result.Skipped++
-				} else {
-					*toCreate = append(*toCreate, tx)
-				}
+			for i, originalID := range matches {
+				tx := candidates[i]
+				tx.IsDuplicate = true
+				tx.NeedsReview = true
+				tx.DuplicateOfID = &originalID
 			}
 		}
+		*toCreate = append(*toCreate, candidates...)
 	}
 
 	r.logger.WithFields(logrus.Fields{
@@ -455,6 +861,40 @@ func (r *TransactionRepository) processDuplicateDetection(ctx context.Context, u
 	return nil
 }
 
+// normalizedFitID returns the FitID trimmed and lower-cased for comparison, or
+// "" when the row has none
+func normalizedFitID(tx *Transaction) string {
+	if tx.FitID == nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(*tx.FitID))
+}
+
+// splitKnownFitIDs separates rows whose FitID is already on an active
+// transaction from the rows still to check for fuzzy duplicates. Both keep the
+// input order, so within-batch matching takes the same earlier row as the
+// original on every run. Banks re-issue FitIDs, so a new one still gets the
+// fuzzy check.
+func splitKnownFitIDs(transactions []*Transaction, existing map[string]*Transaction) (candidates, known []*Transaction) {
+	candidates = make([]*Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if fitID := normalizedFitID(tx); fitID != "" && existing[fitID] != nil {
+			known = append(known, tx)
+		} else {
+			candidates = append(candidates, tx)
+		}
+	}
+	return candidates, known
+}
+
+func (r *TransactionRepository) getDuplicateMatchConfig() DuplicateMatchConfig {
+	return DuplicateMatchConfig{
+		DateWindowDays:  config.GetDuplicateDateWindowDays(),
+		AmountTolerance: config.GetDuplicateAmountTolerance(),
+		MinSimilarity:   config.GetDuplicateMinSimilarity(),
+	}
+}
+
 func (r *TransactionRepository) GetTransactionsByFitIDs(ctx context.Context, userID uuid.UUID, fitIDs []string) (map[string]*Transaction, error) {
 	if len(fitIDs) == 0 {
 		return make(map[string]*Transaction), nil
@@ -496,53 +936,189 @@ func (r *TransactionRepository) GetTransactionsByFitIDs(ctx context.Context, use
 	return result, nil
 }
 
-func (r *TransactionRepository) CheckExistingTransactionsBySignature(ctx context.Context, userID uuid.UUID, transactions []*Transaction) (map[int]bool, error) {
-	if len(transactions) == 0 {
-		return make(map[int]bool), nil
+// duplicateMatchRow - An active transaction within the date and amount window
+// of the incoming row at Idx
+type duplicateMatchRow struct {
+	Idx             int
+	ID              uuid.UUID
+	Description     string
+	TransactionDate time.Time
+}
+
+// FindDuplicateMatches maps the index of each transaction that looks like a
+// duplicate to the active transaction it matches. Rows within the date and
+// amount window are fetched by joining a VALUES list, one query per
+// duplicateChunkSize rows; descriptions are then compared in Go. Each existing
+// transaction is matched at most once, best similarity first, so two identical
+// purchases on one day only pair up with two existing ones. Rows that are
+// flagged duplicates themselves are never originals, and rows left unmatched
+// are also checked against earlier rows of the same batch.
+func (r *TransactionRepository) FindDuplicateMatches(ctx context.Context, userID uuid.UUID, transactions []*Transaction, cfg DuplicateMatchConfig) (map[int]uuid.UUID, error) {
+	matcher := &category.JaccardMatcher{}
+	scored := make([]duplicateScore, 0)
+
+	for start := 0; start < len(transactions); start += duplicateChunkSize {
+		end := min(start+duplicateChunkSize, len(transactions))
+
+		rows := make([]string, 0, end-start)
+		args := make([]any, 0, (end-start)*4+3)
+		for i := start; i < end; i++ {
+			tx := transactions[i]
+			rows = append(rows, "(CAST(? AS integer), CAST(? AS uuid), CAST(? AS numeric), CAST(? AS date))")
+			args = append(args, i, tx.AccountID, tx.Amount, tx.TransactionDate.Format("2006-01-02"))
+		}
+		args = append(args, userID, cfg.AmountTolerance, cfg.DateWindowDays)
+
+		var found []duplicateMatchRow
+		err := r.db.WithContext(ctx).Raw(`
+			SELECT v.idx, t.id, t.description, t.transaction_date
+			FROM (VALUES `+strings.Join(rows, ", ")+`) AS v(idx, account_id, amount, transaction_date)
+			JOIN transactions t ON t.account_id = v.account_id
+			WHERE t.user_id = ? AND
+				ABS(t.amount - v.amount) <= ? AND
+				ABS(t.transaction_date - v.transaction_date) <= ? AND
+				NOT t.is_duplicate AND
+				t.deleted_at IS NULL
+		`, args...).Scan(&found).Error
+		if err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Duplicate candidate query failed").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":    userID,
+					"chunk_from": start,
+					"chunk_size": end - start,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+
+		for _, row := range found {
+			tx := transactions[row.Idx]
+			similarity := matcher.CalculateSimilarity(strings.TrimSpace(tx.Description), strings.TrimSpace(row.Description))
+			if similarity < cfg.MinSimilarity {
+				continue
+			}
+			scored = append(scored, duplicateScore{
+				idx:        row.Idx,
+				originalID: row.ID,
+				similarity: similarity,
+				dayDiff:    math.Abs(tx.TransactionDate.Sub(row.TransactionDate).Hours() / 24),
+			})
+		}
 	}
 
-	duplicateMap := make(map[int]bool)
+	matches := assignDuplicateMatches(scored)
+	matchWithinBatch(transactions, matches, cfg)
 
-	for i, tx := range transactions {
-		var count int64
+	r.logger.WithFields(logrus.Fields{
+		"checked_count":   len(transactions),
+		"duplicate_count": len(matches),
+		"user_id":         userID,
+		"search_type":     "fuzzy",
+	}).Debug("Checked transactions for possible duplicates")
 
-		err := r.db.WithContext(ctx).Model(&Transaction{}).Where(`
-			user_id = ? AND 
-			account_id = ? AND 
-			ABS(amount - ?) < 0.01 AND 
-			transaction_date = ? AND 
-			LOWER(TRIM(description)) = LOWER(TRIM(?)) AND
-			deleted_at IS NULL
-		`, userID, tx.AccountID, tx.Amount, tx.TransactionDate, tx.Description).Count(&count).Error
+	return matches, nil
+}
 
-		if err != nil {
-			continue
+// duplicateScore - A possible original for the incoming row at idx
+type duplicateScore struct {
+	idx        int
+	originalID uuid.UUID
+	similarity float64
+	dayDiff    float64
+}
+
+// assignDuplicateMatches pairs incoming rows with originals, most similar and
+// closest in date first, using each original at most once
+func assignDuplicateMatches(scored []duplicateScore) map[int]uuid.UUID {
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].similarity != scored[j].similarity {
+			return scored[i].similarity > scored[j].similarity
 		}
+		return scored[i].dayDiff < scored[j].dayDiff
+	})
 
-		if count > 0 {
-			duplicateMap[i] = true
+	matches := make(map[int]uuid.UUID)
+	usedOriginals := make(map[uuid.UUID]bool)
+	for _, m := range scored {
+		if _, ok := matches[m.idx]; ok || usedOriginals[m.originalID] {
+			continue
 		}
+		matches[m.idx] = m.originalID
+		usedOriginals[m.originalID] = true
 	}
+	return matches
+}
 
-	return duplicateMap, nil
+// matchWithinBatch flags rows that repeat an earlier row of the same batch,
+// which the database check cannot see yet. Rows are taken in order and only
+// earlier rows that are not flagged themselves serve as originals, each once.
+// Originals get their ID assigned here so the duplicate can point at them.
+func matchWithinBatch(transactions []*Transaction, matches map[int]uuid.UUID, cfg DuplicateMatchConfig) {
+	matcher := &category.JaccardMatcher{}
+	usedOriginals := make(map[int]bool)
+	for i, tx := range transactions {
+		if _, ok := matches[i]; ok {
+			continue
+		}
+
+		best, bestSimilarity, bestDayDiff := -1, 0.0, 0.0
+		for j := 0; j < i; j++ {
+			original := transactions[j]
+			if _, ok := matches[j]; ok || usedOriginals[j] || original.AccountID != tx.AccountID {
+				continue
+			}
+			dayDiff := math.Abs(tx.TransactionDate.Sub(original.TransactionDate).Hours() / 24)
+			if math.Abs(tx.Amount-original.Amount) > cfg.AmountTolerance || dayDiff > float64(cfg.DateWindowDays) {
+				continue
+			}
+			similarity := matcher.CalculateSimilarity(strings.TrimSpace(tx.Description), strings.TrimSpace(original.Description))
+			if similarity < cfg.MinSimilarity {
+				continue
+			}
+			if best < 0 || similarity > bestSimilarity || (similarity == bestSimilarity && dayDiff < bestDayDiff) {
+				best, bestSimilarity, bestDayDiff = j, similarity, dayDiff
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		original := transactions[best]
+		if original.ID == uuid.Nil {
+			original.ID = uuid.New()
+		}
+		matches[i] = original.ID
+		usedOriginals[best] = true
+	}
 }
 
-func (r *TransactionRepository) UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, updates map[string]interface{}) (*Transaction, error) {
+func (r *TransactionRepository) UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, updates map[string]interface{}, source string) (*Transaction, error) {
 	updates["updated_at"] = time.Now()
 
-	result := r.db.WithContext(ctx).Model(&Transaction{}).Where("user_id = ? AND id = ?", userID, transactionID).Updates(updates)
-	if result.Error != nil {
-		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to update transaction").
-			WithDomain("transaction").
-			WithDetails(map[string]any{
-				"user_id":        userID,
-				"transaction_id": transactionID,
-				"updates":        updates,
-			})
+	var updated int64
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		var err error
+		updated, err = updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("id = ?", transactionID)
+		}, updates, source)
+		return err
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to update transaction").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"updates":        updates,
+		})
 		appErr.Log()
 		return nil, appErr
 	}
-	if result.RowsAffected == 0 {
+	if updated == 0 {
 		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found or no changes made").
 			WithDomain("transaction").
 			WithDetails(map[string]any{
@@ -557,24 +1133,22 @@ func (r *TransactionRepository) UpdateTransaction(ctx context.Context, userID, t
 }
 
 func (r *TransactionRepository) DeleteTransaction(ctx context.Context, userID, transactionID uuid.UUID) error {
-	result := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, transactionID).Delete(&Transaction{})
-	if result.Error != nil {
-		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to delete transaction").
-			WithDomain("transaction").
-			WithDetails(map[string]any{
-				"user_id":        userID,
-				"transaction_id": transactionID,
-			})
-		appErr.Log()
-		return appErr
-	}
-	if result.RowsAffected == 0 {
-		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found").
-			WithDomain("transaction").
-			WithDetails(map[string]any{
-				"user_id":        userID,
-				"transaction_id": transactionID,
-			})
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := lockUnreconciled(db, userID, transactionID); err != nil {
+			return err
+		}
+		return db.Where("user_id = ? AND id = ?", userID, transactionID).Delete(&Transaction{}).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete transaction").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+		})
 		appErr.Log()
 		return appErr
 	}
@@ -582,14 +1156,20 @@ func (r *TransactionRepository) DeleteTransaction(ctx context.Context, userID, t
 }
 
 func (r *TransactionRepository) GetTransactionStats(ctx context.Context, userID uuid.UUID, startDate, endDate *time.Time, groupBy string) (*TransactionStats, error) {
-	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	// Columns are qualified because the category breakdown joins the split lines
+	query := r.db.WithContext(ctx).Model(&Transaction{}).Where("transactions.user_id = ?", userID)
+
+	// Flagged duplicates would count the same money twice until they are resolved
+	query = query.Where(NotLinkedTransfer("transactions")).Where("NOT transactions.is_duplicate")
 
 	if startDate != nil {
-		query = query.Where("transaction_date >= ?", *startDate)
+		query = query.Where("transactions.transaction_date >= ?", *startDate)
 	}
 	if endDate != nil {
-		query = query.Where("transaction_date <= ?", *endDate)
+		query = query.Where("transactions.transaction_date <= ?", *endDate)
 	}
+	// Both aggregates below start from these conditions
+	query = query.Session(&gorm.Session{})
 
 	var stats TransactionStats
 
@@ -626,12 +1206,14 @@ func (r *TransactionRepository) GetTransactionStats(ctx context.Context, userID
 	stats.NetIncome = stats.TotalIncome - stats.TotalExpenses
 
 	if groupBy == "category" {
+		// Split transactions contribute their lines instead of the parent
 		var categoryStats []CategoryStat
-		err = query.Select(`
-			category_id,
-			SUM(amount) as amount,
-			COUNT(*) as count
-		`).Group("category_id").Find(&categoryStats).Error
+		err = query.Joins("LEFT JOIN transaction_splits s ON s.transaction_id = transactions.id").
+			Select(`
+				COALESCE(s.category_id, transactions.category_id) as category_id,
+				SUM(COALESCE(s.amount, transactions.amount)) as amount,
+				COUNT(*) as count
+			`).Group("COALESCE(s.category_id, transactions.category_id)").Find(&categoryStats).Error
 		if err != nil {
 			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get category stats").
 				WithDomain("transaction").
@@ -647,5 +1229,2098 @@ func (r *TransactionRepository) GetTransactionStats(ctx context.Context, userID
 		stats.ByCategory = categoryStats
 	}
 
+	if groupBy == "merchant" {
+		// Rows without a directory merchant fall back to their merchant name
+		var merchantStats []MerchantStat
+		err = query.Joins("LEFT JOIN merchants m ON m.id = transactions.merchant_id").
+			Select(`
+				transactions.merchant_id,
+				COALESCE(m.name, transactions.merchant_name) as merchant_name,
+				SUM(transactions.amount) as amount,
+				COUNT(*) as count
+			`).Group("transactions.merchant_id, COALESCE(m.name, transactions.merchant_name)").
+			Order("count DESC").
+			Find(&merchantStats).Error
+		if err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get merchant stats").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":    userID,
+					"start_date": startDate,
+					"end_date":   endDate,
+					"group_by":   groupBy,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		stats.ByMerchant = merchantStats
+	}
+
 	return &stats, nil
 }
+
+// ========================================
+// EXPORT
+// ========================================
+
+// StreamTransactions calls fn for every transaction matching filter, newest
+// first, with category and account names joined in. Rows are read from the
+// cursor one at a time; Page and Limit are ignored.
+func (r *TransactionRepository) StreamTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter, fn func(*TransactionExportRow) error) error {
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Select(`transactions.id, transactions.transaction_date, transactions.posted_date,
+			transactions.account_id, a.account_name,
+			transactions.description, transactions.merchant_name, transactions.amount,
+			transactions.currency, transactions.transaction_type,
+			transactions.category_id, c.name AS category_name,
+			transactions.fit_id, transactions.reference_number, transactions.memo, transactions.tags`).
+		Joins("LEFT JOIN accounts a ON a.id = transactions.account_id").
+		Joins("LEFT JOIN categories c ON c.id = transactions.category_id").
+		Where("transactions.user_id = ?", userID)
+	query = applyTransactionFilter(query, filter)
+
+	rows, err := query.Order(transactionOrder(filter)).Rows()
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to query transactions for export").
+			WithDomain("transaction").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return appErr
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var row TransactionExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to read exported transaction").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id": userID,
+					"row":     count + 1,
+				})
+			appErr.Log()
+			return appErr
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Transaction export cursor failed").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"rows":    count,
+			})
+		appErr.Log()
+		return appErr
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"rows":    count,
+	}).Debug("Streamed transactions for export")
+
+	return nil
+}
+
+// GetTransactionDateRange returns the earliest and latest transaction date
+// matching filter, both nil when nothing matches
+func (r *TransactionRepository) GetTransactionDateRange(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*time.Time, *time.Time, error) {
+	var dateRange struct {
+		StartDate *time.Time
+		EndDate   *time.Time
+	}
+
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Select("MIN(transactions.transaction_date) AS start_date, MAX(transactions.transaction_date) AS end_date").
+		Where("transactions.user_id = ?", userID)
+	if err := applyTransactionFilter(query, filter).Scan(&dateRange).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get transaction date range").
+			WithDomain("transaction").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, nil, appErr
+	}
+	return dateRange.StartDate, dateRange.EndDate, nil
+}
+
+// ========================================
+// PENDING TRANSACTIONS
+// ========================================
+
+// pendingMatchRow - A pending transaction within the date and amount window
+// of the incoming row at Idx
+type pendingMatchRow struct {
+	Idx             int
+	ID              uuid.UUID
+	Description     string
+	MerchantID      *uuid.UUID
+	MerchantName    *string
+	Amount          float64
+	TransactionDate time.Time
+}
+
+// FindPendingMatches maps the index of each incoming transaction to the
+// pending transaction on its account it corresponds to. A posted row matches
+// the authorisation it settles: same sign, within the amount tolerance, booked
+// up to DateWindowDays later, and with the same merchant or a similar
+// description. A pending row only matches an identical pending one, which is
+// the same authorisation imported again. Each pending transaction is matched
+// at most once, best match first.
+func (r *TransactionRepository) FindPendingMatches(ctx context.Context, userID uuid.UUID, transactions []*Transaction, cfg PendingMatchConfig) (map[int]uuid.UUID, error) {
+	matcher := &category.JaccardMatcher{}
+	scored := make([]pendingScore, 0)
+
+	for start := 0; start < len(transactions); start += duplicateChunkSize {
+		end := min(start+duplicateChunkSize, len(transactions))
+
+		rows := make([]string, 0, end-start)
+		args := make([]any, 0, (end-start)*4+3)
+		for i := start; i < end; i++ {
+			tx := transactions[i]
+			rows = append(rows, "(CAST(? AS integer), CAST(? AS uuid), CAST(? AS numeric), CAST(? AS date))")
+			args = append(args, i, tx.AccountID, tx.Amount, bookingDate(tx).Format("2006-01-02"))
+		}
+		args = append(args, userID, TransactionStatusPending, cfg.AmountTolerance, cfg.DateWindowDays)
+
+		var found []pendingMatchRow
+		err := r.db.WithContext(ctx).Raw(`
+			SELECT v.idx, t.id, t.description, t.merchant_id, t.merchant_name, t.amount, t.transaction_date
+			FROM (VALUES `+strings.Join(rows, ", ")+`) AS v(idx, account_id, amount, booking_date)
+			JOIN transactions t ON t.account_id = v.account_id
+			WHERE t.user_id = ? AND t.status = ? AND
+				SIGN(t.amount) = SIGN(v.amount) AND
+				ABS(t.amount - v.amount) <= ABS(t.amount) * ? AND
+				v.booking_date - t.transaction_date BETWEEN -1 AND ? AND
+				t.deleted_at IS NULL
+		`, args...).Scan(&found).Error
+		if err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Pending match query failed").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":    userID,
+					"chunk_from": start,
+					"chunk_size": end - start,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+
+		for _, row := range found {
+			if score, ok := scorePendingMatch(transactions[row.Idx], row, cfg, matcher); ok {
+				scored = append(scored, score)
+			}
+		}
+	}
+
+	matches := assignPendingMatches(scored)
+
+	r.logger.WithFields(logrus.Fields{
+		"checked_count": len(transactions),
+		"match_count":   len(matches),
+		"user_id":       userID,
+	}).Debug("Checked transactions for pending matches")
+
+	return matches, nil
+}
+
+// pendingScore - How well the incoming row at idx matches a pending transaction
+type pendingScore struct {
+	idx        int
+	pendingID  uuid.UUID
+	similarity float64
+	amountDiff int64
+	dayDiff    float64
+}
+
+// scorePendingMatch scores a pending transaction found for an incoming row,
+// or reports false when they do not match
+func scorePendingMatch(tx *Transaction, row pendingMatchRow, cfg PendingMatchConfig, matcher *category.JaccardMatcher) (pendingScore, bool) {
+	description := strings.TrimSpace(tx.Description)
+	pendingDescription := strings.TrimSpace(row.Description)
+	amountDiff := toCents(tx.Amount) - toCents(row.Amount)
+	if amountDiff < 0 {
+		amountDiff = -amountDiff
+	}
+
+	var similarity float64
+	if tx.Status == TransactionStatusPending {
+		if amountDiff != 0 || !tx.TransactionDate.Equal(row.TransactionDate) || !strings.EqualFold(description, pendingDescription) {
+			return pendingScore{}, false
+		}
+		similarity = 1
+	} else if sameMerchant(tx.MerchantID, tx.MerchantName, row.MerchantID, row.MerchantName) {
+		similarity = 1
+	} else {
+		similarity = matcher.CalculateSimilarity(description, pendingDescription)
+		if similarity < cfg.MinSimilarity {
+			return pendingScore{}, false
+		}
+	}
+
+	return pendingScore{
+		idx:        row.Idx,
+		pendingID:  row.ID,
+		similarity: similarity,
+		amountDiff: amountDiff,
+		dayDiff:    math.Abs(bookingDate(tx).Sub(row.TransactionDate).Hours() / 24),
+	}, true
+}
+
+// assignPendingMatches pairs incoming rows with pending transactions, most
+// similar first, then closest amount, then closest date. Each side is used
+// at most once.
+func assignPendingMatches(scored []pendingScore) map[int]uuid.UUID {
+	sort.SliceStable(scored, func(i, j int) bool {
+		if scored[i].similarity != scored[j].similarity {
+			return scored[i].similarity > scored[j].similarity
+		}
+		if scored[i].amountDiff != scored[j].amountDiff {
+			return scored[i].amountDiff < scored[j].amountDiff
+		}
+		return scored[i].dayDiff < scored[j].dayDiff
+	})
+
+	matches := make(map[int]uuid.UUID)
+	usedPending := make(map[uuid.UUID]bool)
+	for _, m := range scored {
+		if _, ok := matches[m.idx]; ok || usedPending[m.pendingID] {
+			continue
+		}
+		matches[m.idx] = m.pendingID
+		usedPending[m.pendingID] = true
+	}
+	return matches
+}
+
+// bookingDate is the date the bank booked a transaction: its posted date
+// when the bank gives one, else its transaction date
+func bookingDate(tx *Transaction) time.Time {
+	if tx.PostedDate != nil {
+		return *tx.PostedDate
+	}
+	return tx.TransactionDate
+}
+
+// sameMerchant reports whether two transactions are from the same directory
+// merchant or, without one, carry the same merchant name
+func sameMerchant(aID *uuid.UUID, aName *string, bID *uuid.UUID, bName *string) bool {
+	if aID != nil && bID != nil {
+		return *aID == *bID
+	}
+	return aName != nil && bName != nil && *aName != "" && strings.EqualFold(*aName, *bName)
+}
+
+// PostPendingTransactions applies the posted details in updates to their
+// pending transactions, recording the change. Transactions posted or deleted
+// in the meantime are left alone; the IDs that were posted are returned.
+// Split lines that no longer add up to the posted amount are removed and the
+// transaction flagged for review.
+func (r *TransactionRepository) PostPendingTransactions(ctx context.Context, userID uuid.UUID, updates map[uuid.UUID]map[string]any, source string) ([]uuid.UUID, error) {
+	// Lock rows in a stable order so concurrent imports cannot deadlock
+	ids := make([]uuid.UUID, 0, len(updates))
+	for id := range updates {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b uuid.UUID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	posted := make([]uuid.UUID, 0, len(ids))
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		for _, id := range ids {
+			changes := updates[id]
+			changes["updated_at"] = time.Now()
+
+			var splitTotal *float64
+			if err := db.Model(&TransactionSplit{}).
+				Select("SUM(amount)").
+				Where("user_id = ? AND transaction_id = ?", userID, id).
+				Scan(&splitTotal).Error; err != nil {
+				return err
+			}
+			amount, _ := changes["amount"].(float64)
+			staleSplits := splitTotal != nil && toCents(*splitTotal) != toCents(amount)
+			if staleSplits {
+				changes["needs_review"] = true
+			}
+
+			updated, err := updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+				return q.Where("id = ? AND status = ?", id, TransactionStatusPending)
+			}, changes, source)
+			if err != nil {
+				return err
+			}
+			if updated == 0 {
+				continue
+			}
+			if staleSplits {
+				if err := db.Where("user_id = ? AND transaction_id = ?", userID, id).Delete(&TransactionSplit{}).Error; err != nil {
+					return err
+				}
+			}
+			posted = append(posted, id)
+		}
+		return nil
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to post pending transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"count":   len(ids),
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return posted, nil
+}
+
+// ========================================
+// DUPLICATE REVIEW
+// ========================================
+
+// GetDuplicateCandidates pages through the active transactions flagged as
+// possible duplicates that are still waiting for review
+func (r *TransactionRepository) GetDuplicateCandidates(ctx context.Context, userID uuid.UUID, filter DuplicateFilter) ([]Transaction, int64, error) {
+	var transactions []Transaction
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Where("user_id = ? AND is_duplicate = ? AND needs_review = ?", userID, true, true)
+	if filter.AccountID != nil {
+		query = query.Where("account_id = ?", *filter.AccountID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count duplicate candidates").
+			WithDomain("transaction").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, 0, appErr
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Offset(offset).
+		Limit(filter.Limit).
+		Order("transaction_date DESC, created_at DESC").
+		Find(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch duplicate candidates").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"offset":  offset,
+				"limit":   filter.Limit,
+			})
+		appErr.Log()
+		return nil, 0, appErr
+	}
+
+	return transactions, total, nil
+}
+
+// GetTransactionsByIDs loads the active transactions among ids, keyed by ID
+func (r *TransactionRepository) GetTransactionsByIDs(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]*Transaction, error) {
+	result := make(map[uuid.UUID]*Transaction, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var transactions []Transaction
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND id IN ?", userID, ids).
+		Find(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transactions by ID").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":  userID,
+				"id_count": len(ids),
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	for i := range transactions {
+		result[transactions[i].ID] = &transactions[i]
+	}
+	return result, nil
+}
+
+// MergeDuplicate soft-deletes the duplicate and applies updates to the
+// original in one database transaction. The duplicate goes first so a FitID
+// moved onto the original does not collide with the active FitID index.
+func (r *TransactionRepository) MergeDuplicate(ctx context.Context, userID, duplicateID, originalID uuid.UUID, updates map[string]any, source string) error {
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		result := db.Where("user_id = ? AND id = ?", userID, duplicateID).Delete(&Transaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Duplicate transaction not found").
+				WithDomain("transaction")
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+		updates["updated_at"] = time.Now()
+		updated, err := updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("id = ?", originalID)
+		}, updates, source)
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Original transaction not found").
+				WithDomain("transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to merge duplicate transaction").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"duplicate_id":   duplicateID,
+			"transaction_id": originalID,
+		})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// ========================================
+// SPLITS
+// ========================================
+
+// GetSplits lists the split lines of a transaction in the order they were saved
+func (r *TransactionRepository) GetSplits(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionSplit, error) {
+	var splits []TransactionSplit
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Order("created_at, id").
+		Find(&splits).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transaction splits").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return splits, nil
+}
+
+// ReplaceSplits swaps the split lines of a transaction for splits in one
+// database transaction
+func (r *TransactionRepository) ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []*TransactionSplit) error {
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := lockUnreconciled(db, userID, transactionID); err != nil {
+			return err
+		}
+		if err := db.Where("user_id = ? AND transaction_id = ?", userID, transactionID).Delete(&TransactionSplit{}).Error; err != nil {
+			return err
+		}
+		if len(splits) == 0 {
+			return nil
+		}
+		return db.Create(splits).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to save transaction splits").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"split_count":    len(splits),
+		})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// DeleteSplits removes all split lines of a transaction
+func (r *TransactionRepository) DeleteSplits(ctx context.Context, userID, transactionID uuid.UUID) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		if err := lockUnreconciled(db, userID, transactionID); err != nil {
+			return err
+		}
+		result := db.Where("user_id = ? AND transaction_id = ?", userID, transactionID).Delete(&TransactionSplit{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete transaction splits").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+		})
+		appErr.Log()
+		return 0, appErr
+	}
+	return deleted, nil
+}
+
+// ========================================
+// TRANSFERS
+// ========================================
+
+// FindTransferMatches pairs unlinked, unreconciled transactions with an
+// opposite amount in the same currency on another of the user's accounts,
+// booked at most windowDays apart. The scan covers pairs where either side is in ids, or,
+// when ids is nil, outflows dated within startDate and endDate. Pairs are
+// assigned closest date first and each transaction is used at most once.
+func (r *TransactionRepository) FindTransferMatches(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, startDate, endDate *time.Time, windowDays int) ([]TransferMatch, error) {
+	query := r.db.WithContext(ctx).Table("transactions AS o").
+		Select("o.id AS outflow_id, i.id AS inflow_id").
+		Joins(`JOIN transactions AS i ON i.user_id = o.user_id AND
+			i.account_id <> o.account_id AND
+			i.amount = -o.amount AND
+			i.currency = o.currency AND
+			ABS(i.transaction_date - o.transaction_date) <= ? AND
+			i.transaction_type IN ('income', 'expense', 'transfer') AND
+			i.counterpart_id IS NULL AND
+			i.reconciled_at IS NULL AND
+			NOT i.is_duplicate AND
+			i.deleted_at IS NULL`, windowDays).
+		Where(`o.user_id = ? AND
+			o.amount < 0 AND
+			o.transaction_type IN ('income', 'expense', 'transfer') AND
+			o.counterpart_id IS NULL AND
+			o.reconciled_at IS NULL AND
+			NOT o.is_duplicate AND
+			o.deleted_at IS NULL`, userID)
+
+	if ids != nil {
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		query = query.Where("(o.id IN ? OR i.id IN ?)", ids, ids)
+	} else {
+		if startDate != nil {
+			query = query.Where("o.transaction_date >= ?", *startDate)
+		}
+		if endDate != nil {
+			query = query.Where("o.transaction_date <= ?", *endDate)
+		}
+	}
+
+	var candidates []transferCandidate
+	err := query.
+		Order("ABS(i.transaction_date - o.transaction_date), o.transaction_date, o.id, i.id").
+		Scan(&candidates).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Transfer candidate query failed").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":     userID,
+				"id_count":    len(ids),
+				"start_date":  startDate,
+				"end_date":    endDate,
+				"window_days": windowDays,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	matches := pairTransferCandidates(candidates)
+
+	r.logger.WithFields(logrus.Fields{
+		"user_id":    userID,
+		"candidates": len(candidates),
+		"matches":    len(matches),
+	}).Debug("Found transfer matches")
+
+	return matches, nil
+}
+
+// transferCandidate - An outflow and an inflow that could be the two sides of a transfer
+type transferCandidate struct {
+	OutflowID uuid.UUID
+	InflowID  uuid.UUID
+}
+
+// pairTransferCandidates takes candidates in order of preference and uses
+// each transaction at most once
+func pairTransferCandidates(candidates []transferCandidate) []TransferMatch {
+	matches := make([]TransferMatch, 0)
+	used := make(map[uuid.UUID]bool)
+	for _, c := range candidates {
+		if used[c.OutflowID] || used[c.InflowID] {
+			continue
+		}
+		used[c.OutflowID] = true
+		used[c.InflowID] = true
+		matches = append(matches, TransferMatch{TransactionID: c.OutflowID, CounterpartID: c.InflowID})
+	}
+	return matches
+}
+
+// LinkTransfer points two unlinked, unreconciled transactions at each other
+// and marks both as transfers. Either both rows are linked or neither is.
+func (r *TransactionRepository) LinkTransfer(ctx context.Context, userID, transactionID, counterpartID uuid.UUID, source string) error {
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		linked, err := updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("id IN ? AND counterpart_id IS NULL AND reconciled_at IS NULL", []uuid.UUID{transactionID, counterpartID})
+		}, map[string]any{
+			"counterpart_id":   gorm.Expr("CASE WHEN id = ? THEN CAST(? AS uuid) ELSE CAST(? AS uuid) END", transactionID, counterpartID, transactionID),
+			"type_before_link": gorm.Expr("transaction_type"),
+			"transaction_type": "transfer",
+			"updated_at":       time.Now(),
+		}, source)
+		if err != nil {
+			return err
+		}
+		if linked != 2 {
+			return customerrors.New(customerrors.ErrCodeConflict, "Transaction is missing, reconciled or already linked to a transfer").
+				WithDomain("transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to link transfer").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+			"counterpart_id": counterpartID,
+		})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// UnlinkTransfer clears the link on a transaction and its counterpart. Both
+// go back to the type they had before the link, or to income or expense by
+// the sign of their amount when it was not recorded.
+func (r *TransactionRepository) UnlinkTransfer(ctx context.Context, userID, transactionID uuid.UUID, source string) error {
+	var unlinked int64
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		var err error
+		unlinked, err = updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("(id = ? OR counterpart_id = ?) AND counterpart_id IS NOT NULL", transactionID, transactionID)
+		}, map[string]any{
+			"counterpart_id":   nil,
+			"transaction_type": gorm.Expr("COALESCE(type_before_link, CASE WHEN amount > 0 THEN 'income' ELSE 'expense' END)"),
+			"type_before_link": nil,
+			"updated_at":       time.Now(),
+		}, source)
+		return err
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to unlink transfer").
+				WithDomain("transaction")
+		}
+		appErr = appErr.WithDetails(map[string]any{
+			"user_id":        userID,
+			"transaction_id": transactionID,
+		})
+		appErr.Log()
+		return appErr
+	}
+	if unlinked == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found or not linked to a transfer").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// ========================================
+// IMPORT BATCHES
+// ========================================
+
+// GetFileUploadTransactions lists the active transactions created by an import.
+// A row counts as edited when it was updated more than a second after insert.
+func (r *TransactionRepository) GetFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID) ([]FileUploadTransactionRef, error) {
+	var refs []FileUploadTransactionRef
+	err := r.db.WithContext(ctx).Model(&Transaction{}).
+		Select("id, updated_at > created_at + INTERVAL '1 second' AS edited, reconciled_at IS NOT NULL AS reconciled").
+		Where("user_id = ? AND file_upload_id = ?", userID, uploadID).
+		Find(&refs).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch import transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"file_upload_id": uploadID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return refs, nil
+}
+
+// RevertFileUploadTransactions soft-deletes an import's transactions except
+// keepIDs. deleted_at is set to revertedAt so the rows can be found again on restore.
+func (r *TransactionRepository) RevertFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID, keepIDs []uuid.UUID, revertedAt time.Time) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Where("user_id = ? AND file_upload_id = ?", userID, uploadID)
+	if len(keepIDs) > 0 {
+		query = query.Where("id NOT IN ?", keepIDs)
+	}
+
+	// UpdateColumn leaves updated_at alone, which the edited check relies on
+	result := query.UpdateColumn("deleted_at", revertedAt)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to revert import transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"file_upload_id": uploadID,
+				"kept":           len(keepIDs),
+			})
+		appErr.Log()
+		return 0, appErr
+	}
+	return result.RowsAffected, nil
+}
+
+// RestoreFileUploadTransactions undeletes the transactions removed by the
+// revert at revertedAt. Rows deleted individually before or after are left alone.
+func (r *TransactionRepository) RestoreFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID, revertedAt time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&Transaction{}).
+		Where("user_id = ? AND file_upload_id = ? AND deleted_at = ?", userID, uploadID, revertedAt).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to restore import transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"file_upload_id": uploadID,
+			})
+		appErr.Log()
+		return 0, appErr
+	}
+	return result.RowsAffected, nil
+}
+
+// DiscardFileUploadTransactions permanently deletes every transaction an
+// unfinished import created, rolling the import back rather than reverting it
+// to the trash. The attachments of deleted transactions are returned.
+func (r *TransactionRepository) DiscardFileUploadTransactions(ctx context.Context, userID, uploadID uuid.UUID) (int64, []TransactionAttachment, error) {
+	var ids []uuid.UUID
+	var attachments []TransactionAttachment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND file_upload_id = ?", userID, uploadID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		var err error
+		attachments, err = purgeTransactions(tx, ids)
+		return err
+	})
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to discard import transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"file_upload_id": uploadID,
+			})
+		appErr.Log()
+		return 0, nil, appErr
+	}
+	return int64(len(ids)), attachments, nil
+}
+
+// ========================================
+// TRASH
+// ========================================
+
+// GetDeletedTransactions pages through the user's soft-deleted transactions,
+// most recently deleted first
+func (r *TransactionRepository) GetDeletedTransactions(ctx context.Context, userID uuid.UUID, filter TrashFilter) ([]Transaction, int64, error) {
+	var transactions []Transaction
+	var total int64
+
+	query := r.db.WithContext(ctx).Unscoped().Model(&Transaction{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to count deleted transactions").
+			WithDomain("transaction").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, 0, appErr
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	if err := query.
+		Offset(offset).
+		Limit(filter.Limit).
+		Order("deleted_at DESC, id").
+		Find(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch deleted transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"offset":  offset,
+				"limit":   filter.Limit,
+			})
+		appErr.Log()
+		return nil, 0, appErr
+	}
+
+	return transactions, total, nil
+}
+
+func (r *TransactionRepository) GetDeletedTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	var transaction Transaction
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, transactionID).
+		First(&transaction).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found in trash").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":        userID,
+					"transaction_id": transactionID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get deleted transaction").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &transaction, nil
+}
+
+func (r *TransactionRepository) RestoreTransaction(ctx context.Context, userID, transactionID uuid.UUID) error {
+	// UpdateColumn leaves updated_at alone, which the import revert edited check relies on
+	result := r.db.WithContext(ctx).Unscoped().Model(&Transaction{}).
+		Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, transactionID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to restore transaction").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if result.RowsAffected == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found in trash").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// PurgeTransaction permanently deletes a transaction from the trash along
+// with its split lines, change history and attachments. The attachments are
+// returned so their files can be removed from storage.
+func (r *TransactionRepository) PurgeTransaction(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionAttachment, error) {
+	var attachments []TransactionAttachment
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, transactionID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found in trash")
+		}
+		var err error
+		attachments, err = purgeTransactions(tx, ids)
+		return err
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to purge transaction")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return attachments, nil
+}
+
+// PurgeExpiredTransactions permanently deletes transactions of every user
+// that were deleted before the cutoff, or whose account was, purgeChunkSize at
+// a time. The attachments of purged transactions are returned even when a
+// later chunk fails.
+func (r *TransactionRepository) PurgeExpiredTransactions(ctx context.Context, before time.Time) (int64, []TransactionAttachment, error) {
+	var purged int64
+	var attachments []TransactionAttachment
+	for {
+		var ids []uuid.UUID
+		var chunkAttachments []TransactionAttachment
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Transactions of expired accounts go too, or the accounts could never be purged
+			if err := tx.Unscoped().Model(&Transaction{}).
+				Where("deleted_at < ? OR account_id IN (SELECT id FROM accounts WHERE deleted_at < ?)", before, before).
+				Limit(purgeChunkSize).
+				Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			var err error
+			chunkAttachments, err = purgeTransactions(tx, ids)
+			return err
+		})
+		if err != nil {
+			appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to purge expired transactions").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"before": before,
+					"purged": purged,
+				})
+			appErr.Log()
+			return purged, attachments, appErr
+		}
+
+		purged += int64(len(ids))
+		attachments = append(attachments, chunkAttachments...)
+		if len(ids) < purgeChunkSize {
+			return purged, attachments, nil
+		}
+	}
+}
+
+// purgeTransactions hard-deletes transactions with their split lines,
+// change history and attachment records, returning the deleted attachments.
+// Transfers, duplicates and recurring series pointing at them are detached.
+func purgeTransactions(tx *gorm.DB, ids []uuid.UUID) ([]TransactionAttachment, error) {
+	for _, column := range []string{"counterpart_id", "duplicate_of_id"} {
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where(column+" IN ?", ids).
+			UpdateColumn(column, nil).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(&RecurringTransaction{}).
+		Where("last_matched_transaction_id IN ?", ids).
+		UpdateColumn("last_matched_transaction_id", nil).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("transaction_id IN ?", ids).Delete(&TransactionChange{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("transaction_id IN ?", ids).Delete(&TransactionSplit{}).Error; err != nil {
+		return nil, err
+	}
+	var attachments []TransactionAttachment
+	if err := tx.Clauses(clause.Returning{}).Where("transaction_id IN ?", ids).Delete(&attachments).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("id IN ?", ids).Delete(&Transaction{}).Error; err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// ========================================
+// ATTACHMENTS
+// ========================================
+
+// GetAttachments lists the attachments of a transaction, oldest first
+func (r *TransactionRepository) GetAttachments(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionAttachment, error) {
+	var attachments []TransactionAttachment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Order("created_at, id").
+		Find(&attachments).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transaction attachments").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return attachments, nil
+}
+
+func (r *TransactionRepository) GetAttachmentByID(ctx context.Context, userID, transactionID, attachmentID uuid.UUID) (*TransactionAttachment, error) {
+	var attachment TransactionAttachment
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ? AND id = ?", userID, transactionID, attachmentID).
+		First(&attachment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Attachment not found").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":        userID,
+					"transaction_id": transactionID,
+					"attachment_id":  attachmentID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get attachment").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+				"attachment_id":  attachmentID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &attachment, nil
+}
+
+func (r *TransactionRepository) CreateAttachment(ctx context.Context, attachment *TransactionAttachment) error {
+	if err := r.db.WithContext(ctx).Create(attachment).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to save attachment").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        attachment.UserID,
+				"transaction_id": attachment.TransactionID,
+				"filename":       attachment.Filename,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+func (r *TransactionRepository) DeleteAttachment(ctx context.Context, userID, transactionID, attachmentID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ? AND id = ?", userID, transactionID, attachmentID).
+		Delete(&TransactionAttachment{})
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to delete attachment").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+				"attachment_id":  attachmentID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if result.RowsAffected == 0 {
+		appErr := customerrors.New(customerrors.ErrCodeNotFound, "Attachment not found").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+				"attachment_id":  attachmentID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// ========================================
+// BALANCES
+// ========================================
+
+// balanceScope selects the transactions that make up an account's balance:
+// active posted ones that are not waiting in the duplicate review queue
+func balanceScope(userID, accountID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ? AND account_id = ? AND status = ? AND NOT is_duplicate", userID, accountID, TransactionStatusPosted)
+	}
+}
+
+// RecalculateBalances recomputes BalanceAfter of every transaction on the
+// account and its current balance from the opening balance. Transactions are
+// ordered by date, then creation. Those before the opening balance date are
+// counted backwards from it. Accounts without an opening balance are left
+// alone and nil is returned. Pending transactions get no BalanceAfter.
+func (r *TransactionRepository) RecalculateBalances(ctx context.Context, userID, accountID uuid.UUID) (*float64, error) {
+	var balance *float64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var anchor struct {
+			OpeningBalance     *float64
+			OpeningBalanceDate *time.Time
+		}
+		// Locking the account row keeps concurrent recalculations of it in order
+		if err := tx.Table("accounts").
+			Select("opening_balance, opening_balance_date").
+			Where("user_id = ? AND id = ?", userID, accountID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Take(&anchor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return customerrors.New(customerrors.ErrCodeNotFound, "Account not found")
+			}
+			return err
+		}
+		if anchor.OpeningBalance == nil || anchor.OpeningBalanceDate == nil {
+			return nil
+		}
+
+		// Raw updates leave updated_at alone, which the import revert edited check relies on
+		if err := tx.Exec(`
+			UPDATE transactions AS t SET balance_after = r.balance
+			FROM (
+				SELECT id,
+					CAST(? AS numeric)
+					+ SUM(amount) OVER (ORDER BY transaction_date, created_at, id)
+					- COALESCE(SUM(amount) FILTER (WHERE transaction_date < ?) OVER (), 0) AS balance
+				FROM transactions
+				WHERE user_id = ? AND account_id = ? AND status = ? AND NOT is_duplicate AND deleted_at IS NULL
+			) AS r
+			WHERE t.id = r.id AND t.balance_after IS DISTINCT FROM r.balance`,
+			*anchor.OpeningBalance, *anchor.OpeningBalanceDate, userID, accountID, TransactionStatusPosted,
+		).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND account_id = ? AND (status <> ? OR is_duplicate OR deleted_at IS NOT NULL) AND balance_after IS NOT NULL", userID, accountID, TransactionStatusPosted).
+			UpdateColumn("balance_after", nil).Error; err != nil {
+			return err
+		}
+
+		var current float64
+		if err := tx.Model(&Transaction{}).
+			Scopes(balanceScope(userID, accountID)).
+			Select("CAST(? AS numeric) + COALESCE(SUM(amount) FILTER (WHERE transaction_date >= ?), 0)", *anchor.OpeningBalance, *anchor.OpeningBalanceDate).
+			Scan(&current).Error; err != nil {
+			return err
+		}
+		current = math.Round(current*100) / 100
+		if err := tx.Table("accounts").
+			Where("user_id = ? AND id = ?", userID, accountID).
+			UpdateColumn("current_balance", current).Error; err != nil {
+			return err
+		}
+		balance = &current
+		return nil
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to recalculate account balance")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return balance, nil
+}
+
+// GetBalanceDelta returns how far the balance at the start of before is from
+// the opening balance at the start of anchor
+func (r *TransactionRepository) GetBalanceDelta(ctx context.Context, userID, accountID uuid.UUID, anchor, before time.Time) (float64, error) {
+	var delta float64
+	err := r.db.WithContext(ctx).Model(&Transaction{}).
+		Scopes(balanceScope(userID, accountID)).
+		Select(`COALESCE(SUM(CASE
+			WHEN transaction_date >= ? AND transaction_date < ? THEN amount
+			WHEN transaction_date >= ? AND transaction_date < ? THEN -amount
+			ELSE 0 END), 0)`, anchor, before, before, anchor).
+		Scan(&delta).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to compute account balance").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+				"before":     before,
+			})
+		appErr.Log()
+		return 0, appErr
+	}
+	return delta, nil
+}
+
+// GetDailyBalanceChanges sums the account's transactions per day from start
+// up to, not including, end. Days without transactions are left out.
+func (r *TransactionRepository) GetDailyBalanceChanges(ctx context.Context, userID, accountID uuid.UUID, start, end time.Time) ([]DailyBalanceChange, error) {
+	var changes []DailyBalanceChange
+	err := r.db.WithContext(ctx).Model(&Transaction{}).
+		Scopes(balanceScope(userID, accountID)).
+		Select("DATE(transaction_date) AS date, SUM(amount) AS change, COUNT(*) AS transaction_count").
+		Where("transaction_date >= ? AND transaction_date < ?", start, end).
+		Group("DATE(transaction_date)").
+		Order("date").
+		Scan(&changes).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch daily balance changes").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+				"start":      start,
+				"end":        end,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return changes, nil
+}
+
+// GetFileUploadAccountIDs lists the accounts an import created transactions
+// on, deleted or not
+func (r *TransactionRepository) GetFileUploadAccountIDs(ctx context.Context, userID, uploadID uuid.UUID) ([]uuid.UUID, error) {
+	var accountIDs []uuid.UUID
+	err := r.db.WithContext(ctx).Unscoped().Model(&Transaction{}).
+		Where("user_id = ? AND file_upload_id = ?", userID, uploadID).
+		Distinct().
+		Pluck("account_id", &accountIDs).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch import accounts").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"file_upload_id": uploadID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return accountIDs, nil
+}
+
+// ========================================
+// RECONCILIATION
+// ========================================
+
+// GetReconciliations lists the account's reconciliations, latest statement first
+func (r *TransactionRepository) GetReconciliations(ctx context.Context, userID, accountID uuid.UUID) ([]Reconciliation, error) {
+	var reconciliations []Reconciliation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND account_id = ?", userID, accountID).
+		Order("statement_end_date DESC, created_at DESC").
+		Find(&reconciliations).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch reconciliations").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return reconciliations, nil
+}
+
+func (r *TransactionRepository) GetReconciliationByID(ctx context.Context, userID, accountID, reconciliationID uuid.UUID) (*Reconciliation, error) {
+	var reconciliation Reconciliation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND account_id = ? AND id = ?", userID, accountID, reconciliationID).
+		First(&reconciliation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customerrors.New(customerrors.ErrCodeNotFound, "Reconciliation not found").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":           userID,
+					"account_id":        accountID,
+					"reconciliation_id": reconciliationID,
+				})
+			appErr.Log()
+			return nil, appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get reconciliation").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"account_id":        accountID,
+				"reconciliation_id": reconciliationID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &reconciliation, nil
+}
+
+func (r *TransactionRepository) CreateReconciliation(ctx context.Context, reconciliation *Reconciliation) error {
+	if err := r.db.WithContext(ctx).Create(reconciliation).Error; err != nil {
+		// idx_account_reconciliations_open allows one open reconciliation per account
+		if strings.Contains(err.Error(), "duplicate key") || strings.Contains(err.Error(), "unique constraint") {
+			appErr := customerrors.New(customerrors.ErrCodeConflict, "Account already has a reconciliation in progress").
+				WithDomain("transaction").
+				WithUserID(reconciliation.UserID).
+				WithDetail("account_id", reconciliation.AccountID)
+			appErr.Log()
+			return appErr
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create reconciliation").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    reconciliation.UserID,
+				"account_id": reconciliation.AccountID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// GetReconciliationTotals sums the active transactions cleared into a reconciliation
+func (r *TransactionRepository) GetReconciliationTotals(ctx context.Context, userID, reconciliationID uuid.UUID) (*ReconciliationTotals, error) {
+	totals, err := reconciliationTotals(r.db.WithContext(ctx), userID, reconciliationID)
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to compute reconciliation totals").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"reconciliation_id": reconciliationID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return totals, nil
+}
+
+func reconciliationTotals(db *gorm.DB, userID, reconciliationID uuid.UUID) (*ReconciliationTotals, error) {
+	var totals ReconciliationTotals
+	err := db.Model(&Transaction{}).
+		Select(`COUNT(*) AS cleared_count,
+			COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0) AS cleared_deposits,
+			COALESCE(SUM(amount) FILTER (WHERE amount < 0), 0) AS cleared_payments`).
+		Where("user_id = ? AND reconciliation_id = ? AND NOT is_duplicate", userID, reconciliationID).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return &totals, nil
+}
+
+// GetReconciliationTransactions lists the transactions of a reconciliation in
+// date order. While it is in progress that is everything on the account up to
+// the statement end date that an earlier reconciliation has not locked, cleared
+// or not; once completed only the transactions it locked.
+func (r *TransactionRepository) GetReconciliationTransactions(ctx context.Context, userID uuid.UUID, reconciliation *Reconciliation) ([]Transaction, error) {
+	query := r.db.WithContext(ctx).
+		Where("user_id = ? AND account_id = ? AND NOT is_duplicate", userID, reconciliation.AccountID)
+	if reconciliation.Status == ReconciliationInProgress {
+		query = query.Where("reconciliation_id = ? OR (reconciliation_id IS NULL AND reconciled_at IS NULL AND status = ? AND transaction_date < ?)",
+			reconciliation.ID, TransactionStatusPosted, reconciliation.StatementEndDate.AddDate(0, 0, 1))
+	} else {
+		query = query.Where("reconciliation_id = ?", reconciliation.ID)
+	}
+
+	var transactions []Transaction
+	if err := query.Order("transaction_date, created_at, id").Find(&transactions).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch reconciliation transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"reconciliation_id": reconciliation.ID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return transactions, nil
+}
+
+// SetTransactionsCleared clears the transactions into an open reconciliation,
+// or takes them out of it. Either all of ids change or none: the IDs that
+// cannot change are returned and nothing is written.
+func (r *TransactionRepository) SetTransactionsCleared(ctx context.Context, userID uuid.UUID, reconciliation *Reconciliation, ids []uuid.UUID, cleared bool) ([]uuid.UUID, error) {
+	var missing []uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenReconciliation(tx, userID, reconciliation.ID); err != nil {
+			return err
+		}
+
+		query := tx.Model(&Transaction{}).
+			Where("user_id = ? AND account_id = ? AND id IN ? AND reconciled_at IS NULL", userID, reconciliation.AccountID, ids)
+		var value any
+		if cleared {
+			query = query.Where("NOT is_duplicate AND status = ? AND transaction_date < ? AND (reconciliation_id IS NULL OR reconciliation_id = ?)",
+				TransactionStatusPosted, reconciliation.StatementEndDate.AddDate(0, 0, 1), reconciliation.ID)
+			value = reconciliation.ID
+		} else {
+			query = query.Where("reconciliation_id = ?", reconciliation.ID)
+		}
+
+		var eligible []uuid.UUID
+		if err := query.Session(&gorm.Session{}).Pluck("id", &eligible).Error; err != nil {
+			return err
+		}
+		found := make(map[uuid.UUID]bool, len(eligible))
+		for _, id := range eligible {
+			found[id] = true
+		}
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, id)
+				found[id] = true
+			}
+		}
+		if len(missing) > 0 {
+			return nil
+		}
+
+		// Clearing is bookkeeping, not an edit: UpdateColumn leaves updated_at alone
+		return query.UpdateColumn("reconciliation_id", value).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to update cleared transactions")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"reconciliation_id": reconciliation.ID,
+				"id_count":          len(ids),
+				"cleared":           cleared,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return missing, nil
+}
+
+// CompleteReconciliation closes an open reconciliation and locks the
+// transactions cleared into it. The cleared rows are locked and summed first,
+// so a concurrent edit cannot change the balance between the check and the
+// lock. Deleted transactions are released instead, so they come back
+// uncleared if restored.
+func (r *TransactionRepository) CompleteReconciliation(ctx context.Context, reconciliation *Reconciliation, completedAt time.Time) (*ReconciliationTotals, error) {
+	userID, reconciliationID := reconciliation.UserID, reconciliation.ID
+	var totals *ReconciliationTotals
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenReconciliation(tx, userID, reconciliationID); err != nil {
+			return err
+		}
+
+		var cleared []uuid.UUID
+		if err := tx.Model(&Transaction{}).
+			Where("user_id = ? AND reconciliation_id = ?", userID, reconciliationID).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &cleared).Error; err != nil {
+			return err
+		}
+		var err error
+		if totals, err = reconciliationTotals(tx, userID, reconciliationID); err != nil {
+			return err
+		}
+		if difference := reconciliationDifference(reconciliation, totals); difference != 0 {
+			return customerrors.New(customerrors.ErrCodeValidation, "Cleared balance does not match the statement closing balance").
+				WithDetails(map[string]any{
+					"cleared_balance": float64(clearedBalance(reconciliation, totals)) / 100,
+					"closing_balance": reconciliation.ClosingBalance,
+					"difference":      float64(difference) / 100,
+				})
+		}
+
+		if err := tx.Model(&Transaction{}).
+			Where("user_id = ? AND reconciliation_id = ?", userID, reconciliationID).
+			UpdateColumn("reconciled_at", completedAt).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND reconciliation_id = ? AND deleted_at IS NOT NULL", userID, reconciliationID).
+			UpdateColumn("reconciliation_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Reconciliation{}).
+			Where("user_id = ? AND id = ?", userID, reconciliationID).
+			Updates(map[string]any{
+				"status":       ReconciliationCompleted,
+				"completed_at": completedAt,
+			}).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to complete reconciliation")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"reconciliation_id": reconciliationID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return totals, nil
+}
+
+// DeleteReconciliation discards an open reconciliation, its transactions go
+// back to uncleared
+func (r *TransactionRepository) DeleteReconciliation(ctx context.Context, userID, reconciliationID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockOpenReconciliation(tx, userID, reconciliationID); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND reconciliation_id = ?", userID, reconciliationID).
+			UpdateColumn("reconciliation_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND id = ?", userID, reconciliationID).Delete(&Reconciliation{}).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete reconciliation")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":           userID,
+				"reconciliation_id": reconciliationID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// lockOpenReconciliation locks the reconciliation row for the rest of the
+// database transaction and makes sure it is still in progress
+func lockOpenReconciliation(tx *gorm.DB, userID, reconciliationID uuid.UUID) error {
+	var status string
+	if err := tx.Model(&Reconciliation{}).
+		Select("status").
+		Where("user_id = ? AND id = ?", userID, reconciliationID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(&status).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Reconciliation not found")
+		}
+		return err
+	}
+	if status != ReconciliationInProgress {
+		return customerrors.New(customerrors.ErrCodeConflict, "Reconciliation is already completed")
+	}
+	return nil
+}
+
+// ========================================
+// RECURRING TRANSACTIONS
+// ========================================
+
+// GetRecurringCandidates lists the transactions since the date the recurring
+// detector can group: active, posted, not flagged as duplicates, not transfers
+// and not already part of a series. They are ordered by date.
+func (r *TransactionRepository) GetRecurringCandidates(ctx context.Context, userID uuid.UUID, since time.Time) ([]Transaction, error) {
+	var transactions []Transaction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_date >= ? AND status = ? AND NOT is_duplicate", userID, since, TransactionStatusPosted).
+		Where("recurring_id IS NULL AND counterpart_id IS NULL AND transaction_type <> 'transfer'").
+		Order("transaction_date, created_at, id").
+		Find(&transactions).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transactions for recurring detection").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": userID,
+				"since":   since.Format("2006-01-02"),
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return transactions, nil
+}
+
+// GetRecurringTransactions lists the user's accepted series, next due first
+func (r *TransactionRepository) GetRecurringTransactions(ctx context.Context, userID uuid.UUID) ([]RecurringTransaction, error) {
+	var series []RecurringTransaction
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("next_due_date, name, id").
+		Find(&series).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch recurring transactions").
+			WithDomain("transaction").
+			WithDetail("user_id", userID)
+		appErr.Log()
+		return nil, appErr
+	}
+	return series, nil
+}
+
+// CreateRecurringTransaction saves an accepted series and tags its
+// transactions with it. Either all transactions are tagged or nothing is saved.
+func (r *TransactionRepository) CreateRecurringTransaction(ctx context.Context, series *RecurringTransaction, transactionIDs []uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return err
+		}
+		// Tagging is bookkeeping, not an edit: UpdateColumns leaves updated_at alone
+		result := tx.Model(&Transaction{}).
+			Where("user_id = ? AND id IN ? AND recurring_id IS NULL", series.UserID, transactionIDs).
+			UpdateColumns(map[string]any{
+				"recurring_id":      series.ID,
+				"is_recurring":      true,
+				"recurring_pattern": series.Frequency,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(transactionIDs)) {
+			return customerrors.New(customerrors.ErrCodeConflict, "Some transactions were deleted or added to another recurring series")
+		}
+		return nil
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create recurring transaction")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    series.UserID,
+				"account_id": series.AccountID,
+				"count":      len(transactionIDs),
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// DeleteRecurringTransaction removes a series; its transactions, deleted ones
+// included, are no longer tagged as recurring
+func (r *TransactionRepository) DeleteRecurringTransaction(ctx context.Context, userID, recurringID uuid.UUID) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND id = ?", userID, recurringID).Delete(&RecurringTransaction{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return customerrors.New(customerrors.ErrCodeNotFound, "Recurring transaction not found")
+		}
+		return tx.Unscoped().Model(&Transaction{}).
+			Where("user_id = ? AND recurring_id = ?", userID, recurringID).
+			UpdateColumns(map[string]any{
+				"recurring_id":      nil,
+				"is_recurring":      false,
+				"recurring_pattern": nil,
+			}).Error
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to delete recurring transaction")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"recurring_id": recurringID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+// ========================================
+// MERCHANTS
+// ========================================
+
+// ReassignMerchant links the user's transactions of the merchants in fromIDs
+// to the given merchant and gives them its name, recording the change. Nothing
+// moves while one of them is reconciled.
+func (r *TransactionRepository) ReassignMerchant(ctx context.Context, userID uuid.UUID, fromIDs []uuid.UUID, to *merchant.Merchant, source string) (int64, error) {
+	var updated int64
+	err := r.db.WithContext(ctx).Transaction(func(db *gorm.DB) error {
+		// Lock the rows first so a reconciliation cannot complete under the check
+		var moving []uuid.UUID
+		if err := db.Model(&Transaction{}).
+			Where("user_id = ? AND merchant_id IN ?", userID, fromIDs).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Pluck("id", &moving).Error; err != nil {
+			return err
+		}
+		locked, err := reconciledMerchantTransactions(db, userID, fromIDs)
+		if err != nil {
+			return err
+		}
+		if len(locked) > 0 {
+			return reconciledMerchantError(locked)
+		}
+
+		updated, err = updateWithHistory(db, userID, func(q *gorm.DB) *gorm.DB {
+			return q.Where("merchant_id IN ?", fromIDs)
+		}, map[string]any{
+			"merchant_id":   to.ID,
+			"merchant_name": to.Name,
+			"updated_at":    time.Now(),
+		}, source)
+		return err
+	})
+	if err != nil {
+		appErr, ok := err.(*customerrors.AppError)
+		if !ok {
+			appErr = customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to reassign merchant transactions")
+		}
+		appErr = appErr.WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"merchant_ids": fromIDs,
+				"merchant_id":  to.ID,
+			})
+		appErr.Log()
+		return 0, appErr
+	}
+	return updated, nil
+}
+
+// GetReconciledMerchantTransactions lists the reconciled transactions linked
+// to the merchants, which a rename or merge cannot move
+func (r *TransactionRepository) GetReconciledMerchantTransactions(ctx context.Context, userID uuid.UUID, merchantIDs []uuid.UUID) ([]uuid.UUID, error) {
+	locked, err := reconciledMerchantTransactions(r.db.WithContext(ctx), userID, merchantIDs)
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to check merchant transactions").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":      userID,
+				"merchant_ids": merchantIDs,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return locked, nil
+}
+
+func reconciledMerchantTransactions(db *gorm.DB, userID uuid.UUID, merchantIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var locked []uuid.UUID
+	err := db.Model(&Transaction{}).
+		Where("user_id = ? AND merchant_id IN ? AND reconciled_at IS NOT NULL", userID, merchantIDs).
+		Order("transaction_date, id").
+		Limit(reconciledMerchantListLimit).
+		Pluck("id", &locked).Error
+	return locked, err
+}
+
+// reconciledMerchantError refuses a merchant change that would move reconciled transactions
+func reconciledMerchantError(locked []uuid.UUID) *customerrors.AppError {
+	return customerrors.New(customerrors.ErrCodeTransactionBlocked, "Some transactions of this merchant are reconciled, unlock them before changing the merchant").
+		WithDomain("transaction").
+		WithDetail("transaction_ids", locked)
+}
+
+// GetMerchantSpend sums the user's spending per directory merchant, ordered
+// by net spend
+func (r *TransactionRepository) GetMerchantSpend(ctx context.Context, userID uuid.UUID, filter merchant.SpendFilter) ([]merchant.MerchantSpend, error) {
+	query := r.db.WithContext(ctx).Model(&Transaction{}).
+		Joins("JOIN merchants m ON m.id = transactions.merchant_id").
+		Where("transactions.user_id = ?", userID)
+	if filter.StartDate != nil {
+		query = query.Where("transactions.transaction_date >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("transactions.transaction_date <= ?", *filter.EndDate)
+	}
+
+	spend := make([]merchant.MerchantSpend, 0)
+	err := query.Select(`
+			m.id AS merchant_id, m.name AS merchant_name, m.logo_url,
+			COALESCE(SUM(ABS(transactions.amount)) FILTER (WHERE transactions.transaction_type IN ('expense', 'fee')), 0) AS total_spent,
+			COALESCE(SUM(ABS(transactions.amount)) FILTER (WHERE transactions.transaction_type = 'refund'), 0) AS total_refunded,
+			SUM(CASE WHEN transactions.transaction_type = 'refund' THEN -ABS(transactions.amount) ELSE ABS(transactions.amount) END) AS net_spent,
+			COUNT(*) AS transaction_count,
+			MAX(transactions.transaction_date) AS last_transaction_date
+		`).
+		Where("transactions.transaction_type IN ('expense', 'fee', 'refund')").
+		Group("m.id, m.name, m.logo_url").
+		Order("net_spent DESC, m.name").
+		Limit(filter.Limit).
+		Scan(&spend).Error
+	if err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get merchant spend").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"start_date": filter.StartDate,
+				"end_date":   filter.EndDate,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return spend, nil
+}
+
+// ========================================
+// IMPORT PROFILES
+// ========================================
+
+// GetImportProfiles returns the user's profiles. When accountID is set, profiles
+// bound to that account come first, followed by the account-agnostic ones.
+func (r *TransactionRepository) GetImportProfiles(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID) ([]ImportProfile, error) {
+	var profiles []ImportProfile
+
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if accountID != nil {
+		query = query.Where("account_id = ? OR account_id IS NULL", *accountID).
+			Order("account_id IS NULL, updated_at DESC")
+	} else {
+		query = query.Order("name ASC")
+	}
+
+	if err := query.Find(&profiles).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch import profiles").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"account_id": accountID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+
+	return profiles, nil
+}
+
+func (r *TransactionRepository) GetImportProfileByID(ctx context.Context, userID, profileID uuid.UUID) (*ImportProfile, error) {
+	var profile ImportProfile
+	err := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, profileID).First(&profile).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, customerrors.New(customerrors.ErrCodeNotFound, "Import profile not found").
+				WithDomain("transaction").
+				WithDetails(map[string]any{
+					"user_id":    userID,
+					"profile_id": profileID,
+				})
+		}
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to get import profile").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"profile_id": profileID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return &profile, nil
+}
+
+func (r *TransactionRepository) CreateImportProfile(ctx context.Context, profile *ImportProfile) error {
+	if err := r.db.WithContext(ctx).Create(profile).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to create import profile").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id": profile.UserID,
+				"name":    profile.Name,
+			})
+		appErr.Log()
+		return appErr
+	}
+	return nil
+}
+
+func (r *TransactionRepository) UpdateImportProfile(ctx context.Context, userID, profileID uuid.UUID, updates map[string]any) (*ImportProfile, error) {
+	updates["updated_at"] = time.Now()
+
+	result := r.db.WithContext(ctx).Model(&ImportProfile{}).Where("user_id = ? AND id = ?", userID, profileID).Updates(updates)
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to update import profile").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"profile_id": profileID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	if result.RowsAffected == 0 {
+		return nil, customerrors.New(customerrors.ErrCodeNotFound, "Import profile not found").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"profile_id": profileID,
+			})
+	}
+
+	return r.GetImportProfileByID(ctx, userID, profileID)
+}
+
+func (r *TransactionRepository) DeleteImportProfile(ctx context.Context, userID, profileID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, profileID).Delete(&ImportProfile{})
+	if result.Error != nil {
+		appErr := customerrors.Wrap(result.Error, customerrors.ErrCodeInternal, "Failed to delete import profile").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"profile_id": profileID,
+			})
+		appErr.Log()
+		return appErr
+	}
+	if result.RowsAffected == 0 {
+		return customerrors.New(customerrors.ErrCodeNotFound, "Import profile not found").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":    userID,
+				"profile_id": profileID,
+			})
+	}
+	return nil
+}
+
+// ========================================
+// CHANGE HISTORY
+// ========================================
+
+// updateWithHistory applies updates to the user's transactions selected by
+// scope and records each tracked field it changed, one new version per
+// transaction. It runs inside a database transaction, the rows stay locked
+// until it ends. A reconciled row fails the whole update, checked under the
+// lock so a reconciliation completing after the caller's own check cannot
+// slip through. Returns the number of updated transactions.
+func updateWithHistory(db *gorm.DB, userID uuid.UUID, scope func(*gorm.DB) *gorm.DB, updates map[string]any, source string) (int64, error) {
+	var before []Transaction
+	if err := scope(db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID)).Find(&before).Error; err != nil {
+		return 0, err
+	}
+	if len(before) == 0 {
+		return 0, nil
+	}
+	for i := range before {
+		if err := checkUnlocked(userID, &before[i]); err != nil {
+			return 0, err
+		}
+	}
+	ids := make([]uuid.UUID, len(before))
+	for i := range before {
+		ids[i] = before[i].ID
+	}
+
+	result := db.Model(&Transaction{}).Where("user_id = ? AND id IN ?", userID, ids).Updates(updates)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	var after []Transaction
+	if err := db.Where("user_id = ? AND id IN ?", userID, ids).Find(&after).Error; err != nil {
+		return 0, err
+	}
+	updated := make(map[uuid.UUID]*Transaction, len(after))
+	for i := range after {
+		updated[after[i].ID] = &after[i]
+	}
+
+	var versions []struct {
+		TransactionID uuid.UUID
+		Version       int
+	}
+	if err := db.Model(&TransactionChange{}).
+		Select("transaction_id, MAX(version) AS version").
+		Where("transaction_id IN ?", ids).
+		Group("transaction_id").
+		Scan(&versions).Error; err != nil {
+		return 0, err
+	}
+	latest := make(map[uuid.UUID]int, len(versions))
+	for _, v := range versions {
+		latest[v.TransactionID] = v.Version
+	}
+
+	actor := changeActor(userID, source)
+	var changes []TransactionChange
+	for i := range before {
+		tx, ok := updated[before[i].ID]
+		if !ok {
+			continue
+		}
+		diff := diffTransaction(&before[i], tx)
+		for j := range diff {
+			diff[j].Version = latest[tx.ID] + 1
+			diff[j].Source = source
+			diff[j].ActorID = actor
+		}
+		changes = append(changes, diff...)
+	}
+	if len(changes) > 0 {
+		if err := db.CreateInBatches(changes, 500).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	return result.RowsAffected, nil
+}
+
+// lockUnreconciled locks a transaction for the rest of the database
+// transaction and refuses it when missing or reconciled, for writes that do
+// not go through updateWithHistory
+func lockUnreconciled(db *gorm.DB, userID, transactionID uuid.UUID) error {
+	var tx Transaction
+	err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND id = ?", userID, transactionID).
+		First(&tx).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found").
+			WithDomain("transaction")
+	}
+	if err != nil {
+		return err
+	}
+	return checkUnlocked(userID, &tx)
+}
+
+// GetTransactionChanges lists the recorded changes of a transaction, oldest first
+func (r *TransactionRepository) GetTransactionChanges(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionChange, error) {
+	var changes []TransactionChange
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND transaction_id = ?", userID, transactionID).
+		Order("version, created_at, id").
+		Find(&changes).Error; err != nil {
+		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to fetch transaction history").
+			WithDomain("transaction").
+			WithDetails(map[string]any{
+				"user_id":        userID,
+				"transaction_id": transactionID,
+			})
+		appErr.Log()
+		return nil, appErr
+	}
+	return changes, nil
+}
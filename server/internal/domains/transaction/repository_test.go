@@ -0,0 +1,332 @@
+package transaction
+
+import (
+	"context"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"hi-cfo/server/internal/logger"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// testDB connects to the Postgres database in TEST_DATABASE_URL and migrates the
+// transaction tables into a schema of their own, dropped when the test ends.
+// The test is skipped when the variable is not set.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if logger.DefaultLogger == nil {
+		logger.InitLogger("test").SetOutput(io.Discard)
+	}
+
+	config := &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+
+	// Every connection of the pool uses the schema, so it goes in the DSN
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		dsn = u.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect to schema: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if err := db.AutoMigrate(&Transaction{}, &TransactionSplit{}, &TransactionChange{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// seedTransactions inserts rows as given, filling in the required fields they leave empty
+func seedTransactions(t *testing.T, db *gorm.DB, transactions ...*Transaction) {
+	t.Helper()
+	for _, tx := range transactions {
+		if tx.Description == "" {
+			tx.Description = "SEED"
+		}
+		if tx.TransactionType == "" {
+			tx.TransactionType = "expense"
+		}
+		if err := db.Create(tx).Error; err != nil {
+			t.Fatalf("seed %q: %v", tx.Description, err)
+		}
+	}
+}
+
+func TestFindDuplicateMatchesQuery(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID, otherUser := uuid.New(), uuid.New()
+	checking, savings := uuid.New(), uuid.New()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+
+	original := &Transaction{UserID: userID, AccountID: checking, TransactionDate: day(10), Amount: -25, Description: "COFFEE SHOP LONDON"}
+	seedTransactions(t, db,
+		original,
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: day(10), Amount: -40, Description: "BOOK STORE", IsDuplicate: true},
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: day(10), Amount: -60, Description: "GYM", DeletedAt: gorm.DeletedAt{Time: day(11), Valid: true}},
+		&Transaction{UserID: otherUser, AccountID: checking, TransactionDate: day(10), Amount: -70, Description: "TAXI"},
+	)
+
+	incoming := []*Transaction{
+		{AccountID: checking, TransactionDate: day(12), Amount: -25, Description: "COFFEE SHOP LONDON"},
+		{AccountID: checking, TransactionDate: day(20), Amount: -25, Description: "COFFEE SHOP LONDON"},   // Outside the date window
+		{AccountID: checking, TransactionDate: day(10), Amount: -40, Description: "BOOK STORE"},           // Original is a flagged duplicate
+		{AccountID: checking, TransactionDate: day(10), Amount: -60, Description: "GYM"},                  // Original is deleted
+		{AccountID: checking, TransactionDate: day(10), Amount: -70, Description: "TAXI"},                 // Original belongs to another user
+		{AccountID: savings, TransactionDate: day(10), Amount: -25, Description: "COFFEE SHOP LONDON"},    // Other account
+		{AccountID: checking, TransactionDate: day(10), Amount: -25.5, Description: "COFFEE SHOP LONDON"}, // Outside the amount tolerance
+	}
+
+	cfg := DuplicateMatchConfig{DateWindowDays: 3, AmountTolerance: 0.01, MinSimilarity: 0.6}
+	got, err := repo.FindDuplicateMatches(context.Background(), userID, incoming, cfg)
+	if err != nil {
+		t.Fatalf("FindDuplicateMatches() error = %v", err)
+	}
+	if want := map[int]uuid.UUID{0: original.ID}; !maps.Equal(got, want) {
+		t.Errorf("FindDuplicateMatches() = %v, want %v", got, want)
+	}
+}
+
+func TestFindTransferMatchesQuery(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID := uuid.New()
+	checking, savings := uuid.New(), uuid.New()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	leg := func(account uuid.UUID, d int, amount float64) *Transaction {
+		return &Transaction{UserID: userID, AccountID: account, TransactionDate: day(d), Amount: amount}
+	}
+
+	outflow, laterOutflow := leg(checking, 10, -100), leg(checking, 13, -100)
+	inflow, closerInflow := leg(savings, 12, 100), leg(savings, 11, 100)
+	duplicateInflow := leg(savings, 10, 20)
+	duplicateInflow.IsDuplicate = true
+	seedTransactions(t, db,
+		outflow, laterOutflow, inflow, closerInflow,
+		leg(checking, 10, -50), leg(savings, 15, 50), // Outside the date window
+		leg(checking, 10, -30), leg(checking, 10, 30), // Same account
+		leg(checking, 10, -20), duplicateInflow,
+	)
+
+	end := day(11)
+	tests := []struct {
+		name    string
+		ids     []uuid.UUID
+		endDate *time.Time
+		want    []TransferMatch
+	}{
+		{
+			name: "closest dates pair first",
+			want: []TransferMatch{
+				{TransactionID: outflow.ID, CounterpartID: closerInflow.ID},
+				{TransactionID: laterOutflow.ID, CounterpartID: inflow.ID},
+			},
+		},
+		{
+			name: "limited to ids",
+			ids:  []uuid.UUID{laterOutflow.ID},
+			want: []TransferMatch{{TransactionID: laterOutflow.ID, CounterpartID: inflow.ID}},
+		},
+		{
+			name:    "limited to a date range",
+			endDate: &end,
+			want:    []TransferMatch{{TransactionID: outflow.ID, CounterpartID: closerInflow.ID}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := repo.FindTransferMatches(context.Background(), userID, tt.ids, nil, tt.endDate, 3)
+			if err != nil {
+				t.Fatalf("FindTransferMatches() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FindTransferMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPendingMatchesQuery(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID, checking := uuid.New(), uuid.New()
+	day := func(d int) time.Time { return time.Date(2025, 6, d, 0, 0, 0, 0, time.UTC) }
+	pending := func(d int, amount float64, description string) *Transaction {
+		return &Transaction{UserID: userID, AccountID: checking, TransactionDate: day(d), Amount: amount, Description: description, Status: TransactionStatusPending}
+	}
+
+	cafe, petrol := pending(10, -20, "CAFE NERO"), pending(10, -50, "PETROL STATION")
+	seedTransactions(t, db,
+		cafe, petrol, pending(1, -40, "HOTEL"),
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: day(10), Amount: -30, Description: "BOOKS", Status: TransactionStatusPosted},
+	)
+
+	posted := day(12)
+	incoming := []*Transaction{
+		{AccountID: checking, TransactionDate: day(10), PostedDate: &posted, Amount: -20, Description: "CAFE NERO"},
+		{AccountID: checking, TransactionDate: day(9), Amount: -52, Description: "PETROL STATION"}, // Booked the day before, with a tip
+		{AccountID: checking, TransactionDate: day(10), Amount: -30, Description: "BOOKS"},         // Already posted
+		{AccountID: checking, TransactionDate: day(20), Amount: -40, Description: "HOTEL"},         // Outside the date window
+		{AccountID: checking, TransactionDate: day(2), Amount: 40, Description: "HOTEL"},           // Refund, not the charge
+	}
+
+	cfg := PendingMatchConfig{DateWindowDays: 5, AmountTolerance: 0.1, MinSimilarity: 0.5}
+	got, err := repo.FindPendingMatches(context.Background(), userID, incoming, cfg)
+	if err != nil {
+		t.Fatalf("FindPendingMatches() error = %v", err)
+	}
+	if want := map[int]uuid.UUID{0: cafe.ID, 1: petrol.ID}; !maps.Equal(got, want) {
+		t.Errorf("FindPendingMatches() = %v, want %v", got, want)
+	}
+}
+
+func TestGetTransactionStatsQuery(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID, checking := uuid.New(), uuid.New()
+	date := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	seedTransactions(t, db,
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: date, Amount: 1000, TransactionType: "income"},
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: date, Amount: -40},
+		&Transaction{UserID: userID, AccountID: checking, TransactionDate: date, Amount: -40, IsDuplicate: true, NeedsReview: true},
+	)
+
+	stats, err := repo.GetTransactionStats(context.Background(), userID, nil, nil, "")
+	if err != nil {
+		t.Fatalf("GetTransactionStats() error = %v", err)
+	}
+	if stats.TransactionCount != 2 || stats.TotalIncome != 1000 || stats.TotalExpenses != 40 || stats.NetIncome != 960 {
+		t.Errorf("GetTransactionStats() = %+v, want the flagged duplicate left out", stats)
+	}
+}
+
+func TestLinkTransferRestoresTypeOnUnlink(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID := uuid.New()
+	date := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	// A refund and a fee would come back as income and expense by their sign
+	outflow := &Transaction{UserID: userID, AccountID: uuid.New(), TransactionDate: date, Amount: -100, TransactionType: "fee"}
+	inflow := &Transaction{UserID: userID, AccountID: uuid.New(), TransactionDate: date, Amount: 100, TransactionType: "refund"}
+	seedTransactions(t, db, outflow, inflow)
+
+	ctx := context.Background()
+	if err := repo.LinkTransfer(ctx, userID, outflow.ID, inflow.ID, ChangeSourceAPI); err != nil {
+		t.Fatalf("LinkTransfer() error = %v", err)
+	}
+	for _, tx := range []*Transaction{outflow, inflow} {
+		got, err := repo.GetTransactionByID(ctx, userID, tx.ID)
+		if err != nil {
+			t.Fatalf("GetTransactionByID() error = %v", err)
+		}
+		if got.TransactionType != "transfer" || derefString(got.TypeBeforeLink) != tx.TransactionType {
+			t.Errorf("linked = %s saved %v, want transfer saving %s", got.TransactionType, derefString(got.TypeBeforeLink), tx.TransactionType)
+		}
+	}
+
+	if err := repo.UnlinkTransfer(ctx, userID, inflow.ID, ChangeSourceAPI); err != nil {
+		t.Fatalf("UnlinkTransfer() error = %v", err)
+	}
+	for _, tx := range []*Transaction{outflow, inflow} {
+		got, err := repo.GetTransactionByID(ctx, userID, tx.ID)
+		if err != nil {
+			t.Fatalf("GetTransactionByID() error = %v", err)
+		}
+		if got.TransactionType != tx.TransactionType || got.CounterpartID != nil || got.TypeBeforeLink != nil {
+			t.Errorf("unlinked = %s counterpart %v saved %v, want %s", got.TransactionType, got.CounterpartID, got.TypeBeforeLink, tx.TransactionType)
+		}
+	}
+}
+
+func TestReconciledTransactionWritesAreBlocked(t *testing.T) {
+	db := testDB(t)
+	repo := NewTransactionRepository(db)
+
+	userID := uuid.New()
+	date := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	reconciledAt := time.Now()
+	// Reconciled after the service checked it, so only the repository sees the lock
+	reconciled := &Transaction{UserID: userID, AccountID: uuid.New(), TransactionDate: date, Amount: -40, ReconciledAt: &reconciledAt}
+	open := &Transaction{UserID: userID, AccountID: uuid.New(), TransactionDate: date, Amount: -40}
+	seedTransactions(t, db, reconciled, open)
+	ctx := context.Background()
+
+	writes := []struct {
+		name  string
+		write func(id uuid.UUID) error
+	}{
+		{name: "update", write: func(id uuid.UUID) error {
+			_, err := repo.UpdateTransaction(ctx, userID, id, map[string]any{"memo": "edited"}, ChangeSourceAPI)
+			return err
+		}},
+		{name: "replace splits", write: func(id uuid.UUID) error {
+			return repo.ReplaceSplits(ctx, userID, id, []*TransactionSplit{{TransactionID: id, UserID: userID, Amount: -40}})
+		}},
+		{name: "delete splits", write: func(id uuid.UUID) error {
+			_, err := repo.DeleteSplits(ctx, userID, id)
+			return err
+		}},
+		{name: "delete", write: func(id uuid.UUID) error {
+			return repo.DeleteTransaction(ctx, userID, id)
+		}},
+	}
+
+	for _, w := range writes {
+		t.Run(w.name, func(t *testing.T) {
+			if err := w.write(reconciled.ID); !customerrors.Is(err, customerrors.ErrCodeTransactionBlocked) {
+				t.Errorf("reconciled %s error = %v, want %s", w.name, err, customerrors.ErrCodeTransactionBlocked)
+			}
+			if err := w.write(open.ID); err != nil {
+				t.Errorf("open %s error = %v", w.name, err)
+			}
+		})
+	}
+
+	if got, err := repo.GetTransactionByID(ctx, userID, reconciled.ID); err != nil || got.Memo != nil {
+		t.Errorf("reconciled transaction = %+v, %v, want it unchanged", got, err)
+	}
+}
@@ -0,0 +1,64 @@
+package transaction
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// FULL-TEXT SEARCH
+// ========================================
+
+// searchQuery turns free text into a prefix tsquery: "coffee sh" becomes
+// "coffee:* & sh:*". Words are reduced to letters and digits so user input
+// cannot break the tsquery syntax. Empty when term has no words.
+func searchQuery(term string) string {
+	words := strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+// SearchTransactions ranks the user's transactions against filter.SearchTerm
+// across description, merchant, notes, memo and tags
+func (s *TransactionService) SearchTransactions(ctx context.Context, userID uuid.UUID, filter TransactionFilter) (*TransactionSearchResponse, error) {
+	tsQuery := ""
+	if filter.SearchTerm != nil {
+		tsQuery = searchQuery(*filter.SearchTerm)
+	}
+	if tsQuery == "" {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Search needs at least one word").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("field", "search")
+	}
+	if filter.Cursor != "" {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Search results are ranked and paged by page number, not cursor").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("field", "cursor")
+	}
+
+	result, err := s.repo.SearchTransactions(ctx, userID, tsQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"query":   tsQuery,
+		"count":   len(result.Data),
+		"total":   result.Total,
+	}).Debug("Searched transactions")
+
+	return result, nil
+}
@@ -8,7 +8,11 @@ import (
 
 	"gorm.io/gorm"
 
+	"hi-cfo/server/internal/domains/account"
 	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/domains/fileupload"
+	"hi-cfo/server/internal/domains/merchant"
+	"hi-cfo/server/internal/infrastructure/storage"
 	"hi-cfo/server/internal/logger"
 	customerrors "hi-cfo/server/internal/shared/errors"
 
@@ -38,9 +42,14 @@ type TransactionStore interface {
 }
 
 type TransactionService struct {
-	repo            Repository
-	categoryService *category.CategoryService
-	logger          *logrus.Entry
+	repo              Repository
+	categoryService   *category.CategoryService
+	accountService    *account.AccountService
+	fileUploadService *fileupload.FileUploadService
+	merchantService   *merchant.MerchantService
+	attachmentStore   storage.Storage
+	jobQueue          *ImportJobQueue
+	logger            *logrus.Entry
 }
 
 type AutoCategorizationConfig struct {
@@ -49,11 +58,16 @@ type AutoCategorizationConfig struct {
 	MaxBatchSize        int
 }
 
-func NewTransactionService(repo Repository, categoryService *category.CategoryService) *TransactionService {
+func NewTransactionService(repo Repository, categoryService *category.CategoryService, accountService *account.AccountService, fileUploadService *fileupload.FileUploadService, merchantService *merchant.MerchantService, attachmentStore storage.Storage, jobQueue *ImportJobQueue) *TransactionService {
 	return &TransactionService{
-		repo:            repo,
-		categoryService: categoryService,
-		logger:          logger.WithDomain("transaction"),
+		repo:              repo,
+		categoryService:   categoryService,
+		accountService:    accountService,
+		fileUploadService: fileUploadService,
+		merchantService:   merchantService,
+		attachmentStore:   attachmentStore,
+		jobQueue:          jobQueue,
+		logger:            logger.WithDomain("transaction"),
 	}
 }
 
@@ -108,18 +122,54 @@ func (s *TransactionService) ProcessTransactionInput(ctx context.Context, userID
 	}
 	processed.TransactionDate = transactionDate
 
+	// Parse PostedDate (optional)
+	if input.PostedDate != nil && *input.PostedDate != "" {
+		postedDate, err := s.parseTransactionDate(*input.PostedDate)
+		if err != nil {
+			return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, fmt.Sprintf("invalid posted_date '%s'", *input.PostedDate)).WithDomain("transaction")
+		}
+		processed.PostedDate = &postedDate
+	}
+
 	// Copy simple fields
 	processed.FitID = input.FitID
 	processed.Description = strings.TrimSpace(input.Description)
 	processed.Amount = input.Amount
 	processed.TransactionType = strings.ToLower(strings.TrimSpace(input.TransactionType))
 	processed.Currency = strings.ToUpper(strings.TrimSpace(input.Currency))
+	processed.Status = strings.ToLower(strings.TrimSpace(input.Status))
+	if processed.Status == "" {
+		processed.Status = TransactionStatusPosted
+	}
 	processed.MerchantName = s.cleanStringPointer(input.MerchantName)
 	processed.Memo = s.cleanStringPointer(input.Memo)
 	processed.Tags = s.cleanStringSlice(input.Tags)
 	processed.ReferenceNumber = s.cleanStringPointer(input.ReferenceNumber)
 	processed.UserNotes = s.cleanStringPointer(input.UserNotes)
 
+	// Split lines from the source file are kept only when they balance, like SetSplits
+	if len(input.Splits) > 0 {
+		if importSplitsBalance(input.Amount, input.Splits) {
+			for _, line := range input.Splits {
+				processed.Splits = append(processed.Splits, ProcessedSplit{
+					Amount:       line.Amount,
+					CategoryName: s.cleanStringPointer(line.CategoryName),
+					Memo:         s.cleanStringPointer(line.Memo),
+				})
+			}
+		} else {
+			processed.ParseErrors = append(processed.ParseErrors, "split lines ignored: they do not add up to the amount")
+		}
+	}
+
+	// Canonical merchant from the raw description, which itself is stored as
+	// sent. A merchant name from the client is kept as it is.
+	if processed.MerchantName == nil {
+		if name := normalizeMerchantName(processed.Description); name != "" {
+			processed.MerchantName = &name
+		}
+	}
+
 	// Validate business rules
 	if err := s.validateProcessedTransaction(processed); err != nil {
 		return nil, err
@@ -158,7 +208,26 @@ func (s *TransactionService) ProcessTransactionBatch(ctx context.Context, userID
 		}, nil
 	}
 
-	// Step 2: Auto-categorize uncategorized transactions
+	// Step 2: Resolve category names supplied by the source file
+	if s.categoryService != nil {
+		if err := s.resolveCategoryHints(ctx, userID, processedTransactions); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Category hint resolution warning")
+		}
+	}
+
+	// Step 3: Link merchants from the directory, whose default category fills
+	// in what is still uncategorized
+	if s.merchantService != nil {
+		if err := s.resolveMerchants(ctx, userID, processedTransactions); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Warn("Merchant resolution warning")
+		}
+	}
+
+	// Step 4: Auto-categorize whatever is still uncategorized
 	if s.categoryService != nil {
 		config := s.getAutoCategorizationConfig()
 		if config.Enabled {
@@ -170,19 +239,37 @@ func (s *TransactionService) ProcessTransactionBatch(ctx context.Context, userID
 		}
 	}
 
-	// Step 3: Convert to database models
+	// Step 5: Convert to database models
 	dbTransactions := make([]*Transaction, len(processedTransactions))
 	for i, processed := range processedTransactions {
 		dbTransactions[i] = s.convertToDBModel(userID, processed)
 	}
 
-	// Step 4: Use unified repository method (handles both single and bulk)
-	result, err := s.repo.CreateTransactions(ctx, userID, dbTransactions)
+	// Step 6: Post the pending transactions that incoming rows settle, then
+	// create the rest with the unified repository method (handles both single and bulk)
+	toCreate, matchedIDs, knownPending := s.matchPendingTransactions(ctx, userID, dbTransactions)
+	result, err := s.repo.CreateTransactions(ctx, userID, toCreate)
 	if err != nil {
 		return nil, customerrors.Wrap(err, customerrors.ErrCodeInternal, "database operation failed").WithDomain("transaction")
 	}
+	result.Total = len(dbTransactions)
+	result.Matched = len(matchedIDs)
+	result.MatchedIDs = matchedIDs
+	result.Skipped += knownPending
 
-	// Step 5: Add any service-level metadata to the result
+	// Step 7: Pair new rows with transfer counterparts on the user's other accounts
+	result.Transfers = s.pairNewTransfers(ctx, userID, result.CreatedIDs)
+
+	// Step 8: Bring the running balances of the accounts written to up to date
+	if result.Created > 0 || result.Matched > 0 {
+		accountIDs := make([]uuid.UUID, len(dbTransactions))
+		for i, tx := range dbTransactions {
+			accountIDs[i] = tx.AccountID
+		}
+		s.refreshBalances(ctx, userID, accountIDs...)
+	}
+
+	// Step 9: Add any service-level metadata to the result
 	result.Source = batch.Source
 	result.Skipped += skippedCount // Add validation failures to skip count
 
@@ -205,6 +292,14 @@ func (s *TransactionService) CreateTransaction(ctx context.Context, userID uuid.
 		return nil, err
 	}
 
+	if result.Created == 0 && len(result.MatchedIDs) > 0 {
+		s.logger.WithFields(logrus.Fields{
+			"user_id":        userID,
+			"transaction_id": result.MatchedIDs[0],
+		}).Info("Pending transaction posted")
+		return s.repo.GetTransactionByID(ctx, userID, result.MatchedIDs[0])
+	}
+
 	if result.Created == 0 {
 		if len(result.Errors) > 0 {
 			return nil, customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("transaction creation failed: %s", result.Errors[0])).WithDomain("transaction")
@@ -313,6 +408,13 @@ func (s *TransactionService) validateProcessedTransaction(pt *ProcessedTransacti
 		return customerrors.New(customerrors.ErrCodeValidation, "currency is required").WithDomain("transaction")
 	}
 
+	if pt.Status != TransactionStatusPending && pt.Status != TransactionStatusPosted {
+		return customerrors.New(customerrors.ErrCodeValidation, fmt.Sprintf("invalid transaction status: %s", pt.Status)).WithDomain("transaction")
+	}
+	if pt.Status == TransactionStatusPending && pt.PostedDate != nil {
+		return customerrors.New(customerrors.ErrCodeValidation, "a pending transaction cannot have a posted_date").WithDomain("transaction")
+	}
+
 	if pt.AccountID == uuid.Nil {
 		return customerrors.New(customerrors.ErrCodeValidation, "account ID is required").WithDomain("transaction")
 	}
@@ -372,6 +474,92 @@ func (s *TransactionService) autoCategorizeProcessedTransactions(ctx context.Con
 	return nil
 }
 
+// resolveCategoryHints matches CategoryName hints (e.g. QIF "L" lines) of
+// transactions and their split lines against the user's and system categories
+// by name. Quicken style "Parent:Child" names try the full name first, then the
+// child, then the parent.
+func (s *TransactionService) resolveCategoryHints(ctx context.Context, userID uuid.UUID, transactions []*ProcessedTransaction) error {
+	hinted := make([]*ProcessedTransaction, 0)
+	for _, tx := range transactions {
+		if (tx.CategoryID == nil && hasCategoryName(tx.OriginalInput.CategoryName)) || splitsHaveCategoryNames(tx.Splits) {
+			hinted = append(hinted, tx)
+		}
+	}
+	if len(hinted) == 0 {
+		return nil
+	}
+
+	active := true
+	filter := category.CategoryFilter{Page: 1, Limit: 100, IsActive: &active}
+	byName := make(map[string]uuid.UUID)
+	for {
+		response, err := s.categoryService.GetCategories(ctx, userID, filter)
+		if err != nil {
+			return customerrors.Wrap(err, customerrors.ErrCodeInternal, "failed to load categories").WithDomain("transaction")
+		}
+		for _, c := range response.Data {
+			key := strings.ToLower(strings.TrimSpace(c.Name))
+			// User categories take precedence over system ones with the same name
+			if _, exists := byName[key]; !exists || c.UserID != nil {
+				byName[key] = c.ID
+			}
+		}
+		if filter.Page >= response.Pages {
+			break
+		}
+		filter.Page++
+	}
+
+	resolved := 0
+	for _, tx := range hinted {
+		if tx.CategoryID == nil && hasCategoryName(tx.OriginalInput.CategoryName) {
+			if id := lookupCategoryName(byName, *tx.OriginalInput.CategoryName); id != nil {
+				tx.CategoryID = id
+				resolved++
+			}
+		}
+		for i := range tx.Splits {
+			if hasCategoryName(tx.Splits[i].CategoryName) {
+				tx.Splits[i].CategoryID = lookupCategoryName(byName, *tx.Splits[i].CategoryName)
+			}
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"hinted_count":   len(hinted),
+		"resolved_count": resolved,
+	}).Debug("Category hints resolved")
+
+	return nil
+}
+
+func hasCategoryName(name *string) bool {
+	return name != nil && strings.TrimSpace(*name) != ""
+}
+
+func splitsHaveCategoryNames(splits []ProcessedSplit) bool {
+	for _, split := range splits {
+		if hasCategoryName(split.CategoryName) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookupCategoryName(byName map[string]uuid.UUID, name string) *uuid.UUID {
+	name = strings.ToLower(strings.TrimSpace(name))
+	candidates := []string{name}
+	if parts := strings.Split(name, ":"); len(parts) > 1 {
+		candidates = append(candidates, strings.TrimSpace(parts[len(parts)-1]), strings.TrimSpace(parts[0]))
+	}
+	for _, candidate := range candidates {
+		if id, ok := byName[candidate]; ok {
+			return &id
+		}
+	}
+	return nil
+}
+
 func (s *TransactionService) getSearchTextFromProcessed(tx *ProcessedTransaction) string {
 	if tx.MerchantName != nil && *tx.MerchantName != "" {
 		return *tx.MerchantName
@@ -384,6 +572,17 @@ func (s *TransactionService) getSearchTextFromProcessed(tx *ProcessedTransaction
 // ========================================
 
 func (s *TransactionService) convertToDBModel(userID uuid.UUID, processed *ProcessedTransaction) *Transaction {
+	// Split lines are inserted together with the transaction
+	splits := make([]TransactionSplit, 0, len(processed.Splits))
+	for _, line := range processed.Splits {
+		splits = append(splits, TransactionSplit{
+			UserID:     userID,
+			CategoryID: line.CategoryID,
+			Amount:     line.Amount,
+			Memo:       line.Memo,
+		})
+	}
+
 	return &Transaction{
 		UserID:          userID,
 		AccountID:       processed.AccountID,
@@ -391,15 +590,19 @@ func (s *TransactionService) convertToDBModel(userID uuid.UUID, processed *Proce
 		FitID:           processed.FitID,
 		FileUploadID:    processed.FileUploadID,
 		TransactionDate: processed.TransactionDate,
+		PostedDate:      processed.PostedDate,
 		Description:     processed.Description,
 		Amount:          processed.Amount,
 		TransactionType: processed.TransactionType,
 		Currency:        processed.Currency,
+		Status:          processed.Status,
 		MerchantName:    processed.MerchantName,
+		MerchantID:      processed.MerchantID,
 		Memo:            processed.Memo,
 		Tags:            pq.StringArray(processed.Tags),
 		ReferenceNumber: processed.ReferenceNumber,
 		UserNotes:       processed.UserNotes,
+		Splits:          splits,
 	}
 }
 
@@ -652,6 +855,23 @@ func (s *TransactionService) GetTransactions(ctx context.Context, userID uuid.UU
 		"filter":  filter,
 	}).Debug("Getting transactions for user")
 
+	if filter.Cursor != "" {
+		if !filter.sortedByDate() {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "Cursor pagination needs the date sort, use page with other sorts").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("sort_by", filter.SortBy)
+		}
+		cursor, err := decodeTransactionCursor(filter.Cursor)
+		if err != nil {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "Invalid pagination cursor").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("cursor", filter.Cursor)
+		}
+		filter.cursor = cursor
+	}
+
 	result, err := s.repo.GetTransactions(ctx, userID, filter)
 	if err != nil {
 		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to retrieve transactions").
@@ -703,6 +923,14 @@ func (s *TransactionService) GetTransactionByID(ctx context.Context, userID, tra
 }
 
 func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, req *UpdateTransactionRequest) (*Transaction, error) {
+	existing, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(userID, existing); err != nil {
+		return nil, err
+	}
+
 	updates := make(map[string]interface{})
 
 	if req.AccountID != nil {
@@ -718,13 +946,51 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		updates["description"] = *req.Description
 	}
 	if req.Amount != nil {
+		// Split lines have to keep adding up to the amount
+		splits, err := s.repo.GetSplits(ctx, userID, transactionID)
+		if err != nil {
+			return nil, err
+		}
+		if len(splits) > 0 {
+			var splitCents int64
+			for _, split := range splits {
+				splitCents += toCents(split.Amount)
+			}
+			if splitCents != toCents(*req.Amount) {
+				return nil, customerrors.New(customerrors.ErrCodeValidation, "Amount no longer matches the split lines, update or remove the splits first").
+					WithDomain("transaction").
+					WithUserID(userID).
+					WithDetail("transaction_id", transactionID)
+			}
+		}
 		updates["amount"] = *req.Amount
 	}
 	if req.TransactionType != nil {
+		// A linked leg stays a transfer, unlinking restores its own type
+		if existing.CounterpartID != nil && *req.TransactionType != existing.TransactionType {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "Unlink the transfer before changing the transaction type").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetails(map[string]any{
+					"transaction_id": transactionID,
+					"counterpart_id": *existing.CounterpartID,
+				})
+		}
 		updates["transaction_type"] = *req.TransactionType
 	}
 	if req.MerchantName != nil {
 		updates["merchant_name"] = *req.MerchantName
+		updates["merchant_id"] = nil
+		if name := strings.TrimSpace(*req.MerchantName); name != "" && s.merchantService != nil {
+			resolved, err := s.merchantService.ResolveMerchants(ctx, userID, []merchant.MerchantQuery{{Name: name}})
+			if err != nil {
+				return nil, err
+			}
+			if resolved[0] != nil {
+				updates["merchant_name"] = resolved[0].Name
+				updates["merchant_id"] = resolved[0].ID
+			}
+		}
 	}
 	if req.Memo != nil {
 		updates["memo"] = *req.Memo
@@ -735,8 +1001,27 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 	if req.UserNotes != nil {
 		updates["user_notes"] = *req.UserNotes
 	}
+	if req.Status != nil && *req.Status != existing.Status {
+		updates["status"] = *req.Status
+		if *req.Status == TransactionStatusPending {
+			updates["posted_date"] = nil
+		} else if existing.PostedDate == nil {
+			// Posted by hand: the bank's posting date is not known, today stands in
+			updates["posted_date"] = time.Now().UTC().Truncate(24 * time.Hour)
+		}
+	}
+
+	// Moving a transaction changes the balance of the account it leaves too
+	var previousAccountID uuid.UUID
+	if req.AccountID != nil {
+		previousAccountID = existing.AccountID
+	}
+	unclearOnChange(existing, updates)
 
-	updatedTransaction, err := s.repo.UpdateTransaction(ctx, userID, transactionID, updates)
+	updatedTransaction, err := s.repo.UpdateTransaction(ctx, userID, transactionID, updates, ChangeSourceAPI)
+	if customerrors.Is(err, customerrors.ErrCodeTransactionBlocked) {
+		return nil, err
+	}
 	if err != nil {
 		appErr := customerrors.Wrap(err, customerrors.ErrCodeInternal, "Failed to update transaction").
 			WithDomain("transaction").
@@ -749,6 +1034,14 @@ func (s *TransactionService) UpdateTransaction(ctx context.Context, userID, tran
 		return nil, appErr
 	}
 
+	if balanceFieldsChanged(updates) {
+		s.refreshBalances(ctx, userID, updatedTransaction.AccountID, previousAccountID)
+		updatedTransaction, err = s.repo.GetTransactionByID(ctx, userID, transactionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	s.logger.WithFields(logrus.Fields{
 		"user_id":        userID,
 		"transaction_id": transactionID,
@@ -763,7 +1056,27 @@ func (s *TransactionService) DeleteTransaction(ctx context.Context, userID, tran
 		"user_id":        userID,
 		"transaction_id": transactionID,
 	}).Debug("Deleting transaction")
-	return s.repo.DeleteTransaction(ctx, userID, transactionID)
+
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkTransferUnlocked(ctx, userID, tx); err != nil {
+		return err
+	}
+
+	// Release the other side of a transfer so it counts as income/expense again
+	if tx.CounterpartID != nil {
+		if err := s.repo.UnlinkTransfer(ctx, userID, transactionID, ChangeSourceAPI); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteTransaction(ctx, userID, transactionID); err != nil {
+		return err
+	}
+
+	s.refreshBalances(ctx, userID, tx.AccountID)
+	return nil
 }
 
 func (s *TransactionService) GetTransactionStats(ctx context.Context, userID uuid.UUID, startDate, endDate *time.Time, groupBy string) (*TransactionStats, error) {
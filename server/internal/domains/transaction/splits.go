@@ -0,0 +1,145 @@
+package transaction
+
+import (
+	"context"
+	"math"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// SPLITS
+// ========================================
+
+// GetSplits lists the split lines of a transaction, empty when it is not split
+func (s *TransactionService) GetSplits(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionSplit, error) {
+	if _, err := s.repo.GetTransactionByID(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSplits(ctx, userID, transactionID)
+}
+
+// SetSplits replaces the split lines of a transaction. The lines have to add
+// up to the transaction amount to the cent.
+func (s *TransactionService) SetSplits(ctx context.Context, userID, transactionID uuid.UUID, req SetSplitsRequest) ([]TransactionSplit, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(userID, tx); err != nil {
+		return nil, err
+	}
+
+	var totalCents int64
+	for i, line := range req.Splits {
+		if line.Amount == 0 {
+			return nil, customerrors.New(customerrors.ErrCodeValidation, "Split amount cannot be zero").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetail("split_index", i)
+		}
+		totalCents += toCents(line.Amount)
+	}
+	if totalCents != toCents(tx.Amount) {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Split amounts must add up to the transaction amount").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"transaction_id": transactionID,
+				"amount":         tx.Amount,
+				"split_total":    float64(totalCents) / 100,
+			})
+	}
+
+	if s.categoryService != nil {
+		checked := make(map[uuid.UUID]bool)
+		for _, line := range req.Splits {
+			if line.CategoryID == nil || checked[*line.CategoryID] {
+				continue
+			}
+			if _, err := s.categoryService.GetCategoryByID(ctx, userID, *line.CategoryID); err != nil {
+				return nil, err
+			}
+			checked[*line.CategoryID] = true
+		}
+	}
+
+	splits := make([]*TransactionSplit, len(req.Splits))
+	for i, line := range req.Splits {
+		splits[i] = &TransactionSplit{
+			TransactionID: transactionID,
+			UserID:        userID,
+			CategoryID:    line.CategoryID,
+			Amount:        line.Amount,
+			Memo:          s.cleanStringPointer(line.Memo),
+			Tags:          pq.StringArray(s.cleanStringSlice(line.Tags)),
+		}
+	}
+
+	if err := s.repo.ReplaceSplits(ctx, userID, transactionID, splits); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"split_count":    len(splits),
+	}).Info("Transaction splits saved")
+
+	return s.repo.GetSplits(ctx, userID, transactionID)
+}
+
+// DeleteSplits removes all split lines, the transaction falls back to its own category
+func (s *TransactionService) DeleteSplits(ctx context.Context, userID, transactionID uuid.UUID) error {
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return err
+	}
+	if err := checkUnlocked(userID, tx); err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteSplits(ctx, userID, transactionID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return customerrors.New(customerrors.ErrCodeNotFound, "Transaction is not split").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"deleted":        deleted,
+	}).Info("Transaction splits deleted")
+
+	return nil
+}
+
+// toCents rounds an amount to whole cents so split totals compare exactly
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// importSplitsBalance reports whether imported split lines can be kept: at
+// least two non-zero lines adding up to the amount to the cent
+func importSplitsBalance(amount float64, lines []ImportSplitLine) bool {
+	if len(lines) < 2 {
+		return false
+	}
+	var totalCents int64
+	for _, line := range lines {
+		if line.Amount == 0 {
+			return false
+		}
+		totalCents += toCents(line.Amount)
+	}
+	return totalCents == toCents(amount)
+}
@@ -0,0 +1,149 @@
+package transaction
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// splitsRepository serves one transaction and keeps the split lines saved for it
+type splitsRepository struct {
+	Repository
+	tx    *Transaction
+	saved []*TransactionSplit
+}
+
+func (r *splitsRepository) GetTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	return r.tx, nil
+}
+
+func (r *splitsRepository) ReplaceSplits(ctx context.Context, userID, transactionID uuid.UUID, splits []*TransactionSplit) error {
+	r.saved = splits
+	return nil
+}
+
+func (r *splitsRepository) GetSplits(ctx context.Context, userID, transactionID uuid.UUID) ([]TransactionSplit, error) {
+	splits := make([]TransactionSplit, len(r.saved))
+	for i, split := range r.saved {
+		splits[i] = *split
+	}
+	return splits, nil
+}
+
+// testLogger discards log output, the shared logger is only set up in main
+func testLogger() *logrus.Entry {
+	log := logrus.New()
+	log.SetOutput(io.Discard)
+	return logrus.NewEntry(log)
+}
+
+func TestSetSplits(t *testing.T) {
+	reconciledAt := time.Now()
+
+	tests := []struct {
+		name       string
+		amount     float64
+		reconciled bool
+		lines      []float64
+		wantCode   customerrors.ErrorCode
+	}{
+		{name: "lines add up", amount: -100, lines: []float64{-60, -40}},
+		{name: "float sums compare in cents", amount: -0.3, lines: []float64{-0.1, -0.2}},
+		{name: "many small lines", amount: 1, lines: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1}},
+		{name: "a cent short", amount: -100, lines: []float64{-60, -39.99}, wantCode: customerrors.ErrCodeValidation},
+		{name: "sign mismatch", amount: -100, lines: []float64{60, 40}, wantCode: customerrors.ErrCodeValidation},
+		{name: "zero line", amount: -100, lines: []float64{-100, 0}, wantCode: customerrors.ErrCodeValidation},
+		{name: "reconciled", amount: -100, reconciled: true, lines: []float64{-60, -40}, wantCode: customerrors.ErrCodeTransactionBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Transaction{ID: uuid.New(), Amount: tt.amount}
+			if tt.reconciled {
+				tx.ReconciledAt = &reconciledAt
+			}
+			repo := &splitsRepository{tx: tx}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+
+			req := SetSplitsRequest{}
+			for _, amount := range tt.lines {
+				req.Splits = append(req.Splits, SplitLineRequest{Amount: amount})
+			}
+
+			splits, err := service.SetSplits(context.Background(), uuid.New(), tx.ID, req)
+			if tt.wantCode != "" {
+				appErr, ok := err.(*customerrors.AppError)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("SetSplits() error = %v, want %s", err, tt.wantCode)
+				}
+				if repo.saved != nil {
+					t.Errorf("SetSplits() saved %d lines after an error", len(repo.saved))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SetSplits() error = %v", err)
+			}
+			if len(splits) != len(tt.lines) {
+				t.Errorf("got %d splits, want %d", len(splits), len(tt.lines))
+			}
+		})
+	}
+}
+
+func TestImportSplitsBalance(t *testing.T) {
+	lines := func(amounts ...float64) []ImportSplitLine {
+		result := make([]ImportSplitLine, len(amounts))
+		for i, amount := range amounts {
+			result[i] = ImportSplitLine{Amount: amount}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		amount float64
+		lines  []ImportSplitLine
+		want   bool
+	}{
+		{name: "balanced", amount: -100, lines: lines(-60, -40), want: true},
+		{name: "float sums compare in cents", amount: 0.3, lines: lines(0.1, 0.2), want: true},
+		{name: "sub-cent noise rounds away", amount: -10, lines: lines(-3.333, -6.667), want: true},
+		{name: "a cent off", amount: -100, lines: lines(-60, -40.01)},
+		{name: "one line is not a split", amount: -100, lines: lines(-100)},
+		{name: "no lines", amount: -100},
+		{name: "zero line", amount: -100, lines: lines(-100, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := importSplitsBalance(tt.amount, tt.lines); got != tt.want {
+				t.Errorf("importSplitsBalance(%v, %v) = %v, want %v", tt.amount, tt.lines, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   int64
+	}{
+		{amount: 0.1 + 0.2, want: 30},
+		{amount: 19.99, want: 1999},
+		{amount: -19.99, want: -1999},
+		{amount: 0.004, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := toCents(tt.amount); got != tt.want {
+			t.Errorf("toCents(%v) = %d, want %d", tt.amount, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	"hi-cfo/server/internal/config"
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// TRANSFERS
+// ========================================
+
+// NotLinkedTransfer is the SQL condition for rows of table that count towards
+// income and spending. Linked transfers only move money between the user's own
+// accounts; once the counterpart is deleted the remaining side counts again.
+func NotLinkedTransfer(table string) string {
+	return `(` + table + `.counterpart_id IS NULL OR NOT EXISTS (
+		SELECT 1 FROM transactions c WHERE c.id = ` + table + `.counterpart_id AND c.deleted_at IS NULL
+	))`
+}
+
+// pairNewTransfers links freshly created transactions with their counterparts
+// on the user's other accounts. Failures are logged and never fail the batch.
+func (s *TransactionService) pairNewTransfers(ctx context.Context, userID uuid.UUID, createdIDs []uuid.UUID) int {
+	if len(createdIDs) == 0 {
+		return 0
+	}
+
+	matches, err := s.repo.FindTransferMatches(ctx, userID, createdIDs, nil, nil, config.GetTransferDateWindowDays())
+	if err != nil {
+		s.logger.WithFields(logrus.Fields{
+			"user_id": userID,
+			"error":   err.Error(),
+		}).Warn("Transfer detection failed")
+		return 0
+	}
+
+	created := make(map[uuid.UUID]bool, len(createdIDs))
+	for _, id := range createdIDs {
+		created[id] = true
+	}
+
+	paired := 0
+	for _, match := range s.linkTransferMatches(ctx, userID, matches, ChangeSourceImport) {
+		// Count each created row, a transfer inside one batch counts twice
+		if created[match.TransactionID] {
+			paired++
+		}
+		if created[match.CounterpartID] {
+			paired++
+		}
+	}
+	return paired
+}
+
+// linkTransferMatches links each match and returns the ones that succeeded.
+// A pair that was linked concurrently is skipped.
+func (s *TransactionService) linkTransferMatches(ctx context.Context, userID uuid.UUID, matches []TransferMatch, source string) []TransferMatch {
+	linked := make([]TransferMatch, 0, len(matches))
+	for _, match := range matches {
+		if err := s.repo.LinkTransfer(ctx, userID, match.TransactionID, match.CounterpartID, source); err != nil {
+			s.logger.WithFields(logrus.Fields{
+				"user_id":        userID,
+				"transaction_id": match.TransactionID,
+				"counterpart_id": match.CounterpartID,
+				"error":          err.Error(),
+			}).Warn("Skipping transfer pair")
+			continue
+		}
+		linked = append(linked, match)
+	}
+	return linked
+}
+
+// DetectTransfers scans the user's unlinked transactions in a date range and
+// links every transfer pair it finds
+func (s *TransactionService) DetectTransfers(ctx context.Context, userID uuid.UUID, startDate, endDate *time.Time) (*TransferDetectionResult, error) {
+	matches, err := s.repo.FindTransferMatches(ctx, userID, nil, startDate, endDate, config.GetTransferDateWindowDays())
+	if err != nil {
+		return nil, err
+	}
+
+	linked := s.linkTransferMatches(ctx, userID, matches, ChangeSourceRule)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id": userID,
+		"found":   len(matches),
+		"linked":  len(linked),
+	}).Info("Transfer detection completed")
+
+	return &TransferDetectionResult{
+		Linked: len(linked),
+		Pairs:  linked,
+	}, nil
+}
+
+// LinkTransfer manually pairs a transaction with the other side of a transfer.
+// Both have to be on different accounts and move money in opposite directions.
+func (s *TransactionService) LinkTransfer(ctx context.Context, userID, transactionID, counterpartID uuid.UUID) (*Transaction, error) {
+	if transactionID == counterpartID {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "A transaction cannot be its own transfer counterpart").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetail("transaction_id", transactionID)
+	}
+
+	tx, err := s.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	counterpart, err := s.GetTransactionByID(ctx, userID, counterpartID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(userID, tx); err != nil {
+		return nil, err
+	}
+	if err := checkUnlocked(userID, counterpart); err != nil {
+		return nil, err
+	}
+
+	if tx.AccountID == counterpart.AccountID {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Transfer counterpart must be on a different account").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"transaction_id": transactionID,
+				"counterpart_id": counterpartID,
+			})
+	}
+	if (tx.Amount < 0) == (counterpart.Amount < 0) {
+		return nil, customerrors.New(customerrors.ErrCodeValidation, "Transfer counterpart must move money in the opposite direction").
+			WithDomain("transaction").
+			WithUserID(userID).
+			WithDetails(map[string]any{
+				"transaction_id": transactionID,
+				"counterpart_id": counterpartID,
+			})
+	}
+
+	if err := s.repo.LinkTransfer(ctx, userID, transactionID, counterpartID, ChangeSourceAPI); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+		"counterpart_id": counterpartID,
+	}).Info("Transfer linked")
+
+	return s.GetTransactionByID(ctx, userID, transactionID)
+}
+
+// UnlinkTransfer removes the transfer link from a transaction and its
+// counterpart. Neither side may be reconciled.
+func (s *TransactionService) UnlinkTransfer(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	tx, err := s.repo.GetTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkTransferUnlocked(ctx, userID, tx); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UnlinkTransfer(ctx, userID, transactionID, ChangeSourceAPI); err != nil {
+		return nil, err
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Info("Transfer unlinked")
+
+	return s.GetTransactionByID(ctx, userID, transactionID)
+}
+
+// checkTransferUnlocked refuses changes to a transaction when it or the other
+// side of its transfer is reconciled, as unlinking changes both
+func (s *TransactionService) checkTransferUnlocked(ctx context.Context, userID uuid.UUID, tx *Transaction) error {
+	if err := checkUnlocked(userID, tx); err != nil {
+		return err
+	}
+	if tx.CounterpartID == nil {
+		return nil
+	}
+	counterpart, err := s.repo.GetTransactionByID(ctx, userID, *tx.CounterpartID)
+	if err != nil {
+		if customerrors.Is(err, customerrors.ErrCodeNotFound) {
+			return nil
+		}
+		return err
+	}
+	return checkUnlocked(userID, counterpart)
+}
@@ -0,0 +1,288 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+
+	"github.com/google/uuid"
+)
+
+func TestPairTransferCandidates(t *testing.T) {
+	out1, out2, in1, in2 := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name       string
+		candidates []transferCandidate
+		want       []TransferMatch
+	}{
+		{
+			name:       "one pair",
+			candidates: []transferCandidate{{OutflowID: out1, InflowID: in1}},
+			want:       []TransferMatch{{TransactionID: out1, CounterpartID: in1}},
+		},
+		{
+			name: "an outflow pairs once, the closest candidate first",
+			candidates: []transferCandidate{
+				{OutflowID: out1, InflowID: in1},
+				{OutflowID: out1, InflowID: in2},
+			},
+			want: []TransferMatch{{TransactionID: out1, CounterpartID: in1}},
+		},
+		{
+			name: "an inflow pairs once, later outflows take the next one",
+			candidates: []transferCandidate{
+				{OutflowID: out1, InflowID: in1},
+				{OutflowID: out2, InflowID: in1},
+				{OutflowID: out2, InflowID: in2},
+			},
+			want: []TransferMatch{
+				{TransactionID: out1, CounterpartID: in1},
+				{TransactionID: out2, CounterpartID: in2},
+			},
+		},
+		{
+			name: "nothing to pair",
+			want: []TransferMatch{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pairTransferCandidates(tt.candidates); !slices.Equal(got, tt.want) {
+				t.Errorf("pairTransferCandidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// transferRepository serves transactions by ID, returns preset matches and
+// links every pair except the ones in failing
+type transferRepository struct {
+	Repository
+	transactions map[uuid.UUID]*Transaction
+	matches      []TransferMatch
+	failing      map[uuid.UUID]bool
+	linked       []TransferMatch
+	unlinked     []uuid.UUID
+	updates      map[string]any
+}
+
+func (r *transferRepository) GetTransactionByID(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	tx, ok := r.transactions[transactionID]
+	if !ok {
+		return nil, customerrors.New(customerrors.ErrCodeNotFound, "Transaction not found")
+	}
+	return tx, nil
+}
+
+func (r *transferRepository) FindTransferMatches(ctx context.Context, userID uuid.UUID, ids []uuid.UUID, startDate, endDate *time.Time, windowDays int) ([]TransferMatch, error) {
+	return r.matches, nil
+}
+
+func (r *transferRepository) LinkTransfer(ctx context.Context, userID, transactionID, counterpartID uuid.UUID, source string) error {
+	if r.failing[transactionID] {
+		return errors.New("already linked")
+	}
+	r.linked = append(r.linked, TransferMatch{TransactionID: transactionID, CounterpartID: counterpartID})
+	return nil
+}
+
+func (r *transferRepository) UnlinkTransfer(ctx context.Context, userID, transactionID uuid.UUID, source string) error {
+	r.unlinked = append(r.unlinked, transactionID)
+	return nil
+}
+
+func (r *transferRepository) UpdateTransaction(ctx context.Context, userID, transactionID uuid.UUID, updates map[string]interface{}, source string) (*Transaction, error) {
+	r.updates = updates
+	return r.transactions[transactionID], nil
+}
+
+func TestPairNewTransfers(t *testing.T) {
+	a, b, c, d, e, f := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	matches := []TransferMatch{
+		{TransactionID: a, CounterpartID: b}, // Both sides created in this batch
+		{TransactionID: c, CounterpartID: d}, // Only c is new
+		{TransactionID: e, CounterpartID: f}, // Linked concurrently
+	}
+
+	tests := []struct {
+		name    string
+		created []uuid.UUID
+		failing map[uuid.UUID]bool
+		want    int
+	}{
+		{name: "both sides in the batch count twice", created: []uuid.UUID{a, b}, want: 2},
+		{name: "one side in the batch counts once", created: []uuid.UUID{a, b, c}, want: 3},
+		{name: "failed links are skipped", created: []uuid.UUID{a, b, c, e}, failing: map[uuid.UUID]bool{e: true}, want: 3},
+		{name: "nothing created", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &transferRepository{matches: matches, failing: tt.failing}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+			if got := service.pairNewTransfers(context.Background(), uuid.New(), tt.created); got != tt.want {
+				t.Errorf("pairNewTransfers() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkTransfer(t *testing.T) {
+	reconciledAt := time.Now()
+	checking, savings := uuid.New(), uuid.New()
+
+	outflow := &Transaction{ID: uuid.New(), AccountID: checking, Amount: -100}
+	inflow := &Transaction{ID: uuid.New(), AccountID: savings, Amount: 100}
+	sameAccount := &Transaction{ID: uuid.New(), AccountID: checking, Amount: 100}
+	sameDirection := &Transaction{ID: uuid.New(), AccountID: savings, Amount: -100}
+	reconciled := &Transaction{ID: uuid.New(), AccountID: savings, Amount: 100, ReconciledAt: &reconciledAt}
+
+	tests := []struct {
+		name        string
+		counterpart *Transaction
+		wantCode    customerrors.ErrorCode
+		wantErr     string
+	}{
+		{name: "opposite sides on two accounts", counterpart: inflow},
+		{name: "own counterpart", counterpart: outflow, wantCode: customerrors.ErrCodeValidation, wantErr: "its own transfer counterpart"},
+		{name: "same account", counterpart: sameAccount, wantCode: customerrors.ErrCodeValidation, wantErr: "different account"},
+		{name: "same direction", counterpart: sameDirection, wantCode: customerrors.ErrCodeValidation, wantErr: "opposite direction"},
+		{name: "reconciled counterpart", counterpart: reconciled, wantCode: customerrors.ErrCodeTransactionBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &transferRepository{transactions: map[uuid.UUID]*Transaction{}}
+			for _, tx := range []*Transaction{outflow, inflow, sameAccount, sameDirection, reconciled} {
+				repo.transactions[tx.ID] = tx
+			}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+
+			_, err := service.LinkTransfer(context.Background(), uuid.New(), outflow.ID, tt.counterpart.ID)
+			if tt.wantCode != "" {
+				appErr, ok := err.(*customerrors.AppError)
+				if !ok || appErr.Code != tt.wantCode || !strings.Contains(appErr.Message, tt.wantErr) {
+					t.Fatalf("LinkTransfer() error = %v, want %s %q", err, tt.wantCode, tt.wantErr)
+				}
+				if len(repo.linked) != 0 {
+					t.Errorf("LinkTransfer() linked %v after an error", repo.linked)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LinkTransfer() error = %v", err)
+			}
+			if want := []TransferMatch{{TransactionID: outflow.ID, CounterpartID: inflow.ID}}; !slices.Equal(repo.linked, want) {
+				t.Errorf("linked = %v, want %v", repo.linked, want)
+			}
+		})
+	}
+}
+
+func TestUnlinkTransfer(t *testing.T) {
+	reconciledAt := time.Now()
+
+	tests := []struct {
+		name        string
+		reconciled  bool
+		counterpart string // "open", "reconciled", "deleted" or "" for none
+		wantCode    customerrors.ErrorCode
+	}{
+		{name: "both sides open", counterpart: "open"},
+		{name: "no counterpart", counterpart: ""},
+		{name: "counterpart deleted", counterpart: "deleted"},
+		{name: "transaction reconciled", reconciled: true, counterpart: "open", wantCode: customerrors.ErrCodeTransactionBlocked},
+		{name: "counterpart reconciled", counterpart: "reconciled", wantCode: customerrors.ErrCodeTransactionBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Transaction{ID: uuid.New(), Amount: -100}
+			if tt.reconciled {
+				tx.ReconciledAt = &reconciledAt
+			}
+			repo := &transferRepository{transactions: map[uuid.UUID]*Transaction{tx.ID: tx}}
+			if tt.counterpart != "" {
+				counterpart := &Transaction{ID: uuid.New(), Amount: 100}
+				if tt.counterpart == "reconciled" {
+					counterpart.ReconciledAt = &reconciledAt
+				}
+				if tt.counterpart != "deleted" {
+					repo.transactions[counterpart.ID] = counterpart
+				}
+				tx.CounterpartID = &counterpart.ID
+			}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+
+			_, err := service.UnlinkTransfer(context.Background(), uuid.New(), tx.ID)
+			if tt.wantCode != "" {
+				appErr, ok := err.(*customerrors.AppError)
+				if !ok || appErr.Code != tt.wantCode {
+					t.Fatalf("UnlinkTransfer() error = %v, want %s", err, tt.wantCode)
+				}
+				if len(repo.unlinked) != 0 {
+					t.Errorf("UnlinkTransfer() unlinked %v after an error", repo.unlinked)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnlinkTransfer() error = %v", err)
+			}
+			if !slices.Equal(repo.unlinked, []uuid.UUID{tx.ID}) {
+				t.Errorf("unlinked = %v, want %v", repo.unlinked, tx.ID)
+			}
+		})
+	}
+}
+
+func TestUpdateTransactionTypeOfLinkedTransfer(t *testing.T) {
+	counterpartID := uuid.New()
+
+	tests := []struct {
+		name            string
+		linked          bool
+		transactionType string
+		wantErr         bool
+	}{
+		{name: "linked leg changes type", linked: true, transactionType: "expense", wantErr: true},
+		{name: "linked leg keeps transfer", linked: true, transactionType: "transfer"},
+		{name: "unlinked transaction changes type", transactionType: "fee"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &Transaction{ID: uuid.New(), Amount: -100, TransactionType: "expense"}
+			if tt.linked {
+				tx.TransactionType = "transfer"
+				tx.CounterpartID = &counterpartID
+			}
+			repo := &transferRepository{transactions: map[uuid.UUID]*Transaction{tx.ID: tx}}
+			service := &TransactionService{repo: repo, logger: testLogger()}
+
+			_, err := service.UpdateTransaction(context.Background(), uuid.New(), tx.ID, &UpdateTransactionRequest{TransactionType: &tt.transactionType})
+			if tt.wantErr {
+				appErr, ok := err.(*customerrors.AppError)
+				if !ok || appErr.Code != customerrors.ErrCodeValidation || !strings.Contains(appErr.Message, "Unlink the transfer") {
+					t.Fatalf("UpdateTransaction() error = %v, want a validation error", err)
+				}
+				if repo.updates != nil {
+					t.Errorf("UpdateTransaction() stored %v after an error", repo.updates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateTransaction() error = %v", err)
+			}
+			if repo.updates["transaction_type"] != tt.transactionType {
+				t.Errorf("transaction_type = %v, want %s", repo.updates["transaction_type"], tt.transactionType)
+			}
+		})
+	}
+}
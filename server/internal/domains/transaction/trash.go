@@ -0,0 +1,107 @@
+package transaction
+
+import (
+	"context"
+	"math"
+	"time"
+
+	customerrors "hi-cfo/server/internal/shared/errors"
+	"hi-cfo/server/internal/shared/trash"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// TRASH
+// ========================================
+
+// GetDeletedTransactions lists the user's soft-deleted transactions, most recently deleted first
+func (s *TransactionService) GetDeletedTransactions(ctx context.Context, userID uuid.UUID, filter TrashFilter) (*DeletedTransactionResponse, error) {
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.Limit == 0 {
+		filter.Limit = 20
+	}
+
+	transactions, total, err := s.repo.GetDeletedTransactions(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]DeletedTransaction, 0, len(transactions))
+	for _, tx := range transactions {
+		deleted = append(deleted, DeletedTransaction{
+			Transaction: tx,
+			PurgeAt:     trash.PurgeAt(tx.DeletedAt.Time),
+		})
+	}
+
+	return &DeletedTransactionResponse{
+		Data:  deleted,
+		Total: total,
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Pages: int(math.Ceil(float64(total) / float64(filter.Limit))),
+	}, nil
+}
+
+// RestoreTransaction moves a transaction out of the trash. Its account has to
+// be active; a transfer link released on delete is not restored.
+func (s *TransactionService) RestoreTransaction(ctx context.Context, userID, transactionID uuid.UUID) (*Transaction, error) {
+	deleted, err := s.repo.GetDeletedTransactionByID(ctx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.accountService != nil {
+		if _, err := s.accountService.GetAccountByID(ctx, userID, deleted.AccountID); err != nil {
+			return nil, customerrors.Wrap(err, customerrors.ErrCodeValidation, "Restore the transaction's account first").
+				WithDomain("transaction").
+				WithUserID(userID).
+				WithDetails(map[string]any{
+					"transaction_id": transactionID,
+					"account_id":     deleted.AccountID,
+				})
+		}
+	}
+
+	if err := s.repo.RestoreTransaction(ctx, userID, transactionID); err != nil {
+		return nil, err
+	}
+	s.refreshBalances(ctx, userID, deleted.AccountID)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":        userID,
+		"transaction_id": transactionID,
+	}).Info("Transaction restored successfully")
+
+	return s.repo.GetTransactionByID(ctx, userID, transactionID)
+}
+
+// PurgeTransaction permanently deletes a transaction that is in the trash,
+// together with its attachment files
+func (s *TransactionService) PurgeTransaction(ctx context.Context, userID, transactionID uuid.UUID) error {
+	attachments, err := s.repo.PurgeTransaction(ctx, userID, transactionID)
+	if err != nil {
+		return err
+	}
+	s.removeAttachmentFiles(ctx, attachments)
+
+	s.logger.WithFields(logrus.Fields{
+		"user_id":          userID,
+		"transaction_id":   transactionID,
+		"attachment_count": len(attachments),
+	}).Info("Transaction purged successfully")
+
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes transactions deleted before the cutoff, for all users
+func (s *TransactionService) PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error) {
+	purged, attachments, err := s.repo.PurgeExpiredTransactions(ctx, before)
+	// Files of chunks that were committed go even if a later chunk failed
+	s.removeAttachmentFiles(ctx, attachments)
+	return purged, err
+}
@@ -8,6 +8,8 @@ import (
 
 	"hi-cfo/server/internal/domains/account"
 	"hi-cfo/server/internal/domains/category"
+	"hi-cfo/server/internal/domains/fileupload"
+	"hi-cfo/server/internal/domains/merchant"
 	"hi-cfo/server/internal/domains/transaction"
 	"hi-cfo/server/internal/domains/user"
 
@@ -93,13 +95,82 @@ func runTableMigrations(db *DB) error {
 		&user.User{},
 		&account.Account{},
 		&category.Category{},
+		&fileupload.FileUpload{},
+		&merchant.Merchant{},
+		&transaction.Reconciliation{},
+		&transaction.RecurringTransaction{},
 		&transaction.Transaction{},
+		&transaction.TransactionSplit{},
+		&transaction.TransactionChange{},
+		&transaction.TransactionAttachment{},
+		&transaction.ImportProfile{},
 	}
 
 	if err := db.AutoMigrate(models...); err != nil {
 		return err
 	}
 
+	// AutoMigrate only adds indexes, the ones replaced under a new name go here
+	for _, index := range retiredIndexes {
+		if err := db.Exec("DROP INDEX IF EXISTS " + index).Error; err != nil {
+			return fmt.Errorf("failed to drop index %s: %w", index, err)
+		}
+	}
+
+	if err := runSearchMigrations(db); err != nil {
+		return fmt.Errorf("search migrations failed: %w", err)
+	}
+
 	log.Println("Table migrations completed")
 	return nil
 }
+
+// retiredIndexes were replaced by indexes with a different definition
+var retiredIndexes = []string{
+	"idx_file_uploads_active_hash", // Now idx_file_uploads_imported_hash, which leaves out empty imports
+}
+
+// transactionSearchMigrations maintain transactions.search_vector, the
+// full-text document behind transaction search. They mirror sql/schema.sql
+// and are safe to run on every start.
+var transactionSearchMigrations = []string{
+	`ALTER TABLE transactions ADD COLUMN IF NOT EXISTS search_vector TSVECTOR`,
+	`CREATE OR REPLACE FUNCTION transaction_search_document(
+		description TEXT, merchant_name TEXT, user_description TEXT,
+		tags TEXT[], memo TEXT, user_notes TEXT
+	) RETURNS TSVECTOR AS $$
+		SELECT setweight(to_tsvector('simple', coalesce(description, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(merchant_name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(user_description, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(memo, '')), 'C') ||
+			setweight(to_tsvector('simple', coalesce(user_notes, '')), 'C')
+	$$ LANGUAGE sql IMMUTABLE`,
+	`CREATE OR REPLACE FUNCTION update_transaction_search_vector()
+	RETURNS TRIGGER AS $$
+	BEGIN
+		NEW.search_vector = transaction_search_document(
+			NEW.description, NEW.merchant_name, NEW.user_description,
+			NEW.tags, NEW.memo, NEW.user_notes);
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS update_transactions_search_vector ON transactions`,
+	`CREATE TRIGGER update_transactions_search_vector
+		BEFORE INSERT OR UPDATE OF description, merchant_name, user_description, tags, memo, user_notes ON transactions
+		FOR EACH ROW EXECUTE FUNCTION update_transaction_search_vector()`,
+	`UPDATE transactions SET search_vector = transaction_search_document(
+		description, merchant_name, user_description, tags, memo, user_notes)
+	WHERE search_vector IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_transactions_search ON transactions USING GIN(search_vector)`,
+}
+
+// runSearchMigrations installs the full-text search column, trigger and index
+func runSearchMigrations(db *DB) error {
+	for _, statement := range transactionSearchMigrations {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey is returned for keys that are empty or escape the storage root
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// Storage keeps uploaded files as opaque objects under slash-separated keys
+// such as "user/transaction/attachment". Implementations must be safe for
+// concurrent use.
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the object stored under key, ErrNotFound if there is none
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -14,6 +14,8 @@ import (
 	"hi-cfo/server/internal/domains/account"
 	"hi-cfo/server/internal/domains/category"
 	"hi-cfo/server/internal/domains/dashboard"
+	"hi-cfo/server/internal/domains/fileupload"
+	"hi-cfo/server/internal/domains/merchant"
 	"hi-cfo/server/internal/domains/transaction"
 	"hi-cfo/server/internal/domains/user"
 	customerrors "hi-cfo/server/internal/shared/errors"
@@ -39,6 +41,8 @@ type Dependencies struct {
 	TransactionHandler *transaction.TransactionHandler
 	AccountHandler     *account.AccountHandler
 	CategoryHandler    *category.CategoryHandler
+	FileUploadHandler  *fileupload.FileUploadHandler
+	MerchantHandler    *merchant.MerchantHandler
 	AuthService        *auth.Service
 	DB                 *gorm.DB
 	RedisClient        *redis.Client
@@ -91,7 +95,7 @@ func SetupRoutes(deps *Dependencies) *gin.Engine {
 	router.Use(middleware.RequestSizeLimit(10 * 1024 * 1024)) // 10MB limit
 	router.Use(middleware.GlobalRateLimit(100, 200))          // 100 req/sec, burst 200
 	router.Use(middleware.CORSMiddleware())
-	
+
 	// Metrics middleware
 	router.Use(middleware.PrometheusMiddleware())
 
@@ -173,6 +177,8 @@ func setupProtectedRoutes(v1 *gin.RouterGroup, deps *Dependencies) {
 		setupTransactionRoutes(protected, deps)
 		setupAccountRoutes(protected, deps)
 		setupCategoryRoutes(protected, deps)
+		setupMerchantRoutes(protected, deps)
+		setupFileUploadRoutes(protected, deps)
 	}
 }
 
@@ -224,10 +230,69 @@ func setupTransactionRoutes(protected *gin.RouterGroup, deps *Dependencies) {
 		transactionRoutes.GET("", deps.TransactionHandler.GetTransactions)               // Get all transactions
 		transactionRoutes.POST("", deps.TransactionHandler.CreateTransaction)            // Create a new transaction
 		transactionRoutes.GET("/stats", deps.TransactionHandler.GetTransactionStats)     // Get transaction stats
+		transactionRoutes.GET("/export", deps.TransactionHandler.ExportTransactions)     // Download transactions as csv, ofx, json or xlsx
 		transactionRoutes.GET("/:id", deps.TransactionHandler.GetTransactionByID)        // Get transaction by ID
 		transactionRoutes.PUT("/:id", deps.TransactionHandler.UpdateTransaction)         // Update transaction by ID
 		transactionRoutes.DELETE("/:id", deps.TransactionHandler.DeleteTransaction)      // Delete transaction by ID
 		transactionRoutes.POST("/bulk", deps.TransactionHandler.CreateBatchTransactions) // Bulk upload transactions
+		transactionRoutes.POST("/import/ofx", deps.TransactionHandler.ImportOFX)         // Import OFX/QFX statement file
+		transactionRoutes.POST("/import/qif", deps.TransactionHandler.ImportQIF)         // Import QIF file
+		transactionRoutes.POST("/import/camt053", deps.TransactionHandler.ImportCAMT053) // Import ISO 20022 camt.053 statement
+		transactionRoutes.POST("/import/mt940", deps.TransactionHandler.ImportMT940)     // Import SWIFT MT940 statement
+
+		// Edit many transactions at once, by id list or query filter
+		transactionRoutes.PATCH("/bulk", deps.TransactionHandler.BulkUpdateTransactions) // Bulk edit transactions
+
+		// Ranked full-text search with highlighted snippets
+		transactionRoutes.GET("/search", deps.TransactionHandler.SearchTransactions) // Search transactions
+
+		// Review queue for possible duplicates flagged on import
+		transactionRoutes.GET("/duplicates", deps.TransactionHandler.GetDuplicateCandidates)        // List duplicates awaiting review
+		transactionRoutes.POST("/duplicates/:id/resolve", deps.TransactionHandler.ResolveDuplicate) // Keep both, merge or delete
+
+		// Split a transaction across several categories
+		transactionRoutes.GET("/:id/splits", deps.TransactionHandler.GetTransactionSplits)       // List split lines
+		transactionRoutes.PUT("/:id/splits", deps.TransactionHandler.SetTransactionSplits)       // Replace split lines
+		transactionRoutes.DELETE("/:id/splits", deps.TransactionHandler.DeleteTransactionSplits) // Remove all split lines
+
+		// Receipts and documents attached to a transaction
+		transactionRoutes.GET("/:id/attachments", deps.TransactionHandler.GetTransactionAttachments)                                  // List attachments
+		transactionRoutes.POST("/:id/attachments", deps.TransactionHandler.UploadTransactionAttachment)                               // Upload an attachment
+		transactionRoutes.GET("/:id/attachments/:attachment_id", deps.TransactionHandler.DownloadTransactionAttachment)               // Download an attachment
+		transactionRoutes.GET("/:id/attachments/:attachment_id/thumbnail", deps.TransactionHandler.GetTransactionAttachmentThumbnail) // Image thumbnail
+		transactionRoutes.DELETE("/:id/attachments/:attachment_id", deps.TransactionHandler.DeleteTransactionAttachment)              // Delete an attachment
+
+		// Soft-deleted transactions, purged for good after the retention period
+		transactionRoutes.GET("/trash", deps.TransactionHandler.GetDeletedTransactions)          // List deleted transactions
+		transactionRoutes.POST("/trash/:id/restore", deps.TransactionHandler.RestoreTransaction) // Restore a deleted transaction
+		transactionRoutes.DELETE("/trash/:id", deps.TransactionHandler.PurgeTransaction)         // Permanently delete a transaction
+
+		// Change history with revert to an earlier version
+		transactionRoutes.GET("/:id/history", deps.TransactionHandler.GetTransactionHistory) // List recorded changes
+		transactionRoutes.POST("/:id/revert", deps.TransactionHandler.RevertTransaction)     // Restore an earlier version
+
+		// Reconciled transactions are locked until unlocked
+		transactionRoutes.POST("/:id/unlock", deps.TransactionHandler.UnlockTransaction) // Unlock a reconciled transaction
+
+		// Recurring series proposed by the detector and accepted by the user
+		transactionRoutes.GET("/recurring", deps.TransactionHandler.GetRecurringTransactions)              // List accepted series
+		transactionRoutes.GET("/recurring/proposals", deps.TransactionHandler.DetectRecurringTransactions) // Detect series to propose
+		transactionRoutes.POST("/recurring", deps.TransactionHandler.AcceptRecurringTransaction)           // Accept a proposal
+		transactionRoutes.DELETE("/recurring/:id", deps.TransactionHandler.DeleteRecurringTransaction)     // Delete a series and untag its transactions
+
+		// Transfers between the user's own accounts
+		transactionRoutes.POST("/transfers/detect", deps.TransactionHandler.DetectTransfers) // Pair unlinked transfers
+		transactionRoutes.POST("/:id/transfer", deps.TransactionHandler.LinkTransfer)        // Link a transfer counterpart
+		transactionRoutes.DELETE("/:id/transfer", deps.TransactionHandler.UnlinkTransfer)    // Unlink a transfer
+
+		// CSV imports with saved per-bank column mappings
+		transactionRoutes.POST("/import/csv", deps.TransactionHandler.ImportCSV)                      // Import CSV file
+		transactionRoutes.POST("/import/csv/preview", deps.TransactionHandler.PreviewCSVImport)       // Preview parsed rows without saving
+		transactionRoutes.GET("/import/profiles", deps.TransactionHandler.GetImportProfiles)          // List import profiles
+		transactionRoutes.GET("/import/profiles/:id", deps.TransactionHandler.GetImportProfile)       // Get import profile
+		transactionRoutes.POST("/import/profiles", deps.TransactionHandler.CreateImportProfile)       // Create import profile
+		transactionRoutes.PUT("/import/profiles/:id", deps.TransactionHandler.UpdateImportProfile)    // Update import profile
+		transactionRoutes.DELETE("/import/profiles/:id", deps.TransactionHandler.DeleteImportProfile) // Delete import profile
 
 		transactionRoutes.POST("/categorization/preview", deps.TransactionHandler.PreviewCategorization)
 		transactionRoutes.POST("/categorization/analyze", deps.TransactionHandler.AnalyzeTransactionCategorization)
@@ -247,6 +312,24 @@ func setupAccountRoutes(protected *gin.RouterGroup, deps *Dependencies) {
 		accounts.GET("/:id", deps.AccountHandler.GetAccountByID)        // Get account by ID
 		accounts.PUT("/:id", deps.AccountHandler.UpdateAccount)         // Update account by ID
 		accounts.DELETE("/:id", deps.AccountHandler.DeleteAccount)      // Delete account by ID
+
+		// Running balance anchored at an opening balance
+		accounts.GET("/:id/balance-history", deps.TransactionHandler.GetAccountBalanceHistory) // Daily balance series
+		accounts.PUT("/:id/opening-balance", deps.TransactionHandler.SetAccountOpeningBalance) // Set opening balance and recalculate
+
+		// Reconciliation against bank statements, completed ones lock their transactions
+		accounts.GET("/:id/reconciliations", deps.TransactionHandler.GetReconciliations)                                            // List reconciliations
+		accounts.POST("/:id/reconciliations", deps.TransactionHandler.StartReconciliation)                                          // Start a reconciliation
+		accounts.GET("/:id/reconciliations/:reconciliation_id", deps.TransactionHandler.GetReconciliation)                          // Reconciliation with live difference
+		accounts.GET("/:id/reconciliations/:reconciliation_id/transactions", deps.TransactionHandler.GetReconciliationTransactions) // Transactions to clear
+		accounts.POST("/:id/reconciliations/:reconciliation_id/clear", deps.TransactionHandler.ClearReconciliationTransactions)     // Mark transactions cleared or uncleared
+		accounts.POST("/:id/reconciliations/:reconciliation_id/complete", deps.TransactionHandler.CompleteReconciliation)           // Complete and lock
+		accounts.DELETE("/:id/reconciliations/:reconciliation_id", deps.TransactionHandler.CancelReconciliation)                    // Cancel an open reconciliation
+
+		// Soft-deleted accounts, purged for good after the retention period
+		accounts.GET("/trash", deps.AccountHandler.GetDeletedAccounts)          // List deleted accounts
+		accounts.POST("/trash/:id/restore", deps.AccountHandler.RestoreAccount) // Restore a deleted account
+		accounts.DELETE("/trash/:id", deps.AccountHandler.PurgeAccount)         // Permanently delete an account
 	}
 }
 
@@ -261,6 +344,39 @@ func setupCategoryRoutes(protected *gin.RouterGroup, deps *Dependencies) {
 		categories.DELETE("/:id", deps.CategoryHandler.DeleteCategory)      // Delete category by ID
 		categories.POST("/auto-categorize", deps.CategoryHandler.AutoCategorize)
 
+		// Soft-deleted categories, purged for good after the retention period
+		categories.GET("/trash", deps.CategoryHandler.GetDeletedCategories)         // List deleted categories
+		categories.POST("/trash/:id/restore", deps.CategoryHandler.RestoreCategory) // Restore a deleted category
+		categories.DELETE("/trash/:id", deps.CategoryHandler.PurgeCategory)         // Permanently delete a category
+
+	}
+}
+
+func setupMerchantRoutes(protected *gin.RouterGroup, deps *Dependencies) {
+	merchants := protected.Group("/merchants")
+	{
+		merchants.GET("", deps.MerchantHandler.GetMerchants)          // Get system and own merchants
+		merchants.POST("", deps.MerchantHandler.CreateMerchant)       // Create a new merchant
+		merchants.GET("/:id", deps.MerchantHandler.GetMerchantByID)   // Get merchant by ID
+		merchants.DELETE("/:id", deps.MerchantHandler.DeleteMerchant) // Delete an own merchant
+
+		// Changes that follow through to the linked transactions
+		merchants.GET("/spend", deps.TransactionHandler.GetMerchantSpend)     // Spend per merchant over a date range
+		merchants.POST("/merge", deps.TransactionHandler.MergeMerchants)      // Fold merchants into another one
+		merchants.PUT("/:id", deps.TransactionHandler.UpdateMerchant)         // Update aliases, patterns and details, system merchants get a per-user copy
+		merchants.POST("/:id/rename", deps.TransactionHandler.RenameMerchant) // Rename a merchant and its transactions
+	}
+}
+
+func setupFileUploadRoutes(protected *gin.RouterGroup, deps *Dependencies) {
+	fileUploads := protected.Group("/file-uploads")
+	{
+		fileUploads.GET("", deps.FileUploadHandler.GetFileUploads)               // Get all file uploads
+		fileUploads.GET("/:id", deps.FileUploadHandler.GetFileUploadByID)        // Get file upload by ID
+		fileUploads.POST("/:id/revert", deps.TransactionHandler.RevertImport)    // Soft-delete the transactions an import created
+		fileUploads.POST("/:id/restore", deps.TransactionHandler.RestoreImport)  // Bring back a reverted import
+		fileUploads.GET("/:id/job", deps.TransactionHandler.GetImportJob)        // Status and row progress of a queued import
+		fileUploads.POST("/:id/cancel", deps.TransactionHandler.CancelImportJob) // Cancel a queued or running import
 	}
 }
 
@@ -0,0 +1,80 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"hi-cfo/server/internal/config"
+	"hi-cfo/server/internal/logger"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ========================================
+// TRASH RETENTION
+// ========================================
+
+// Purger permanently deletes the soft-deleted records of one domain that
+// were deleted before the cutoff
+type Purger interface {
+	PurgeExpiredTrash(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Retention is how long soft-deleted records are kept, 0 when they are kept forever
+func Retention() time.Duration {
+	return time.Duration(config.GetTrashRetentionDays()) * 24 * time.Hour
+}
+
+// PurgeAt is when a record deleted at deletedAt is purged, nil when trash is kept forever
+func PurgeAt(deletedAt time.Time) *time.Time {
+	retention := Retention()
+	if retention == 0 {
+		return nil
+	}
+	purgeAt := deletedAt.Add(retention)
+	return &purgeAt
+}
+
+// StartPurgeJob runs the purgers every interval until ctx is cancelled.
+// Purgers run in the order given, so records that others depend on go last.
+func StartPurgeJob(ctx context.Context, interval time.Duration, purgers ...Purger) {
+	log := logger.WithDomain("trash")
+	if Retention() == 0 {
+		log.Info("Trash retention disabled, deleted records are kept")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purgeExpired(ctx, log, purgers)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func purgeExpired(ctx context.Context, log *logrus.Entry, purgers []Purger) {
+	before := time.Now().Add(-Retention())
+	for _, purger := range purgers {
+		purged, err := purger.PurgeExpiredTrash(ctx, before)
+		if err != nil {
+			// Already logged by the purger, the next run tries again
+			continue
+		}
+		if purged > 0 {
+			log.WithFields(logrus.Fields{
+				"purger": fmt.Sprintf("%T", purger),
+				"purged": purged,
+				"before": before,
+			}).Info("Purged expired trash")
+		}
+	}
+}
@@ -40,7 +40,10 @@ CREATE TABLE accounts (
     
     -- Account status and metadata
     is_active BOOLEAN DEFAULT TRUE,
-    current_balance DECIMAL(12,2), -- Current balance if known
+    current_balance DECIMAL(12,2), -- Computed from the opening balance and transactions once one is set
+    opening_balance DECIMAL(12,2), -- Balance at the start of opening_balance_date, anchors the running balance
+    opening_balance_date DATE,
+    balance_drift DECIMAL(12,2), -- Last imported statement balance minus the computed one, NULL when they agreed
     currency VARCHAR(3) DEFAULT 'USD',
     
     -- Timestamps
@@ -82,6 +85,32 @@ CREATE TABLE categories (
     UNIQUE(user_id, name)
 );
 
+-- Merchant directory - system merchants are seeded by the server on start,
+-- user merchants are created on import or override a system merchant
+CREATE TABLE merchants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE, -- NULL for system merchants
+    
+    -- Merchant information
+    name VARCHAR(200) NOT NULL,
+    website VARCHAR(255),
+    logo_url VARCHAR(500),
+    default_category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    
+    -- Matching rules
+    aliases TEXT[], -- Descriptor spellings, matched against the leading words of the cleaned descriptor
+    patterns TEXT[], -- Case-insensitive regular expressions matched against the raw description
+    
+    overrides_id UUID REFERENCES merchants(id) ON DELETE CASCADE, -- System merchant this user record replaces
+    merged_into_id UUID REFERENCES merchants(id) ON DELETE CASCADE, -- Set when the overridden system merchant was merged away; the record then only hides it
+    is_system_merchant BOOLEAN DEFAULT FALSE,
+    
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    
+    UNIQUE(user_id, name)
+);
+
 -- File uploads - track what bank statements have been processed
 CREATE TABLE file_uploads (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
@@ -90,15 +119,16 @@ CREATE TABLE file_uploads (
     
     -- File information
     original_filename VARCHAR(255) NOT NULL,
-    file_type VARCHAR(20) NOT NULL CHECK (file_type IN ('pdf', 'csv', 'ofx', 'qif', 'xlsx')),
+    file_type VARCHAR(20) NOT NULL CHECK (file_type IN ('pdf', 'csv', 'ofx', 'qif', 'xlsx', 'camt053', 'mt940', 'json')),
     file_size_bytes INTEGER NOT NULL,
-    file_hash VARCHAR(64) UNIQUE, -- SHA-256 hash to detect duplicates
+    file_hash VARCHAR(64), -- SHA-256 hash to detect duplicates (re-uploads are kept with status 'duplicate')
     storage_path VARCHAR(500), -- Path where file is stored
     
     -- Processing status
     processing_status VARCHAR(20) DEFAULT 'pending' CHECK (processing_status IN (
-        'pending', 'processing', 'completed', 'failed', 'duplicate'
+        'pending', 'processing', 'completed', 'failed', 'duplicate', 'reverted', 'cancelled'
     )),
+    queued BOOLEAN NOT NULL DEFAULT FALSE, -- Run by a background worker, only these are swept when their job is lost
     processing_started_at TIMESTAMP WITH TIME ZONE,
     processing_completed_at TIMESTAMP WITH TIME ZONE,
     error_message TEXT,
@@ -108,11 +138,59 @@ CREATE TABLE file_uploads (
     transactions_duplicates INTEGER DEFAULT 0,
     date_range_start DATE,
     date_range_end DATE,
+    reverted_at TIMESTAMP WITH TIME ZONE, -- Set while the import is rolled back
     
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- CSV import profiles (saved column mapping per bank export)
+CREATE TABLE import_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID REFERENCES accounts(id) ON DELETE CASCADE, -- NULL = any account
+    name VARCHAR(100) NOT NULL,
+    bank_name VARCHAR(100),
+    
+    -- File layout
+    delimiter VARCHAR(1) DEFAULT ',',
+    skip_rows INTEGER DEFAULT 1,
+    date_layout VARCHAR(30) NOT NULL, -- e.g. 'DD/MM/YYYY'
+    decimal_separator VARCHAR(1) DEFAULT '.',
+    sign_convention VARCHAR(20) DEFAULT 'signed' CHECK (sign_convention IN ('signed', 'inverted', 'debit_credit')),
+    default_currency VARCHAR(3) DEFAULT 'USD',
+    
+    -- Zero-based column positions
+    col_date INTEGER NOT NULL,
+    col_description INTEGER NOT NULL,
+    col_amount INTEGER,
+    col_debit INTEGER,
+    col_credit INTEGER,
+    col_merchant INTEGER,
+    col_memo INTEGER,
+    col_reference INTEGER,
+    col_currency INTEGER,
+    
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
 -- Core transactions table - the heart of the financial data
+-- Account reconciliations - an account checked against one bank statement
+CREATE TABLE account_reconciliations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id UUID NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    statement_end_date DATE NOT NULL,
+    starting_balance DECIMAL(12,2) NOT NULL, -- Closing balance of the previous reconciliation
+    closing_balance DECIMAL(12,2) NOT NULL, -- Balance printed on the statement
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 CREATE TABLE transactions (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -123,29 +201,37 @@ CREATE TABLE transactions (
     
     -- Transaction identification
     transaction_date DATE NOT NULL,
-    posted_date DATE, -- When transaction actually posted to account
+    posted_date DATE, -- When transaction actually posted to account, NULL while pending
+    status VARCHAR(20) NOT NULL DEFAULT 'posted' CHECK (status IN ('pending', 'posted')), -- Pending rows stay out of balances until the posted row replaces them
     description TEXT NOT NULL,
     merchant_name VARCHAR(200), -- Cleaned up merchant name
+    merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL, -- Directory merchant, merchant_name mirrors its name
     
     -- Amount and type
     amount DECIMAL(12,2) NOT NULL, -- Positive for income, negative for expenses
     transaction_type VARCHAR(20) DEFAULT 'expense' CHECK (transaction_type IN (
         'income', 'expense', 'transfer', 'fee', 'interest', 'dividend', 'refund'
     )),
+    counterpart_id UUID REFERENCES transactions(id) ON DELETE SET NULL, -- Other side of a transfer between the user's own accounts
+    type_before_link VARCHAR(20), -- Transaction type to restore when the transfer is unlinked
     currency VARCHAR(3), -- Currency code 
     
     -- Additional transaction details
     reference_number VARCHAR(100), -- Check number, confirmation number, etc.
     memo TEXT, -- Additional notes from bank or user
     balance_after DECIMAL(12,2), -- Account balance after this transaction
+    reconciliation_id UUID REFERENCES account_reconciliations(id) ON DELETE SET NULL, -- Reconciliation the transaction was cleared in
+    reconciled_at TIMESTAMP WITH TIME ZONE, -- Set when that reconciliation is completed, locks the transaction
     
     -- Categorization and tagging
     is_recurring BOOLEAN DEFAULT FALSE,
     recurring_pattern VARCHAR(50), -- 'monthly', 'weekly', 'annual', etc.
+    recurring_id UUID, -- Accepted recurring_transactions series, the table is created further down
     tags TEXT[], -- User-defined tags for flexible organization
     
     -- Data quality and processing
     is_duplicate BOOLEAN DEFAULT FALSE,
+    duplicate_of_id UUID REFERENCES transactions(id) ON DELETE SET NULL, -- Existing transaction a flagged duplicate was matched against
     confidence_score DECIMAL(3,2), -- 0-1 score for auto-categorization confidence
     needs_review BOOLEAN DEFAULT FALSE, -- Flag for transactions needing user review
     is_hidden BOOLEAN DEFAULT FALSE, -- Allow users to hide transactions
@@ -154,11 +240,59 @@ CREATE TABLE transactions (
     user_description TEXT, -- User can override the bank description
     user_notes TEXT,
     
+    -- Full-text search document, maintained by update_transactions_search_vector
+    search_vector TSVECTOR,
+    
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE
+    -- No unique signature: identical purchases on one day are legitimate,
+    -- likely duplicates are flagged with is_duplicate/needs_review instead
+);
+
+-- Split lines - one transaction spread over several categories
+CREATE TABLE transaction_splits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES categories(id) ON DELETE SET NULL,
+    amount DECIMAL(12,2) NOT NULL, -- Lines of a transaction add up to its amount
+    memo TEXT,
+    tags TEXT[],
+    
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transaction change history - one row per changed field, rows written
+-- together share a version
+CREATE TABLE transaction_changes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    field VARCHAR(50) NOT NULL,
+    old_value TEXT, -- JSON encoded, NULL when the field was not set
+    new_value TEXT,
+    source VARCHAR(30) NOT NULL CHECK (source IN ('api', 'import', 'rule', 'auto_categorizer', 'revert')),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL for automatic changes
+    
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Transaction attachments - receipts and documents, files kept in attachment storage
+CREATE TABLE transaction_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL, -- sniffed from the content
+    size_bytes BIGINT NOT NULL,
+    checksum VARCHAR(64) NOT NULL, -- hex SHA-256
+    storage_key VARCHAR(500) NOT NULL,
+    has_thumbnail BOOLEAN DEFAULT false,
     
-    -- Prevent duplicate transactions from same upload
-    UNIQUE(user_id, account_id, transaction_date, amount, description)
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
 -- Budgets - users can set spending limits by category
@@ -298,6 +432,9 @@ CREATE INDEX idx_users_created_at ON users(created_at);
 -- Account queries
 CREATE INDEX idx_accounts_user_id ON accounts(user_id);
 CREATE INDEX idx_accounts_user_active ON accounts(user_id, is_active);
+CREATE INDEX idx_account_reconciliations_user_id ON account_reconciliations(user_id);
+CREATE INDEX idx_account_reconciliations_account_id ON account_reconciliations(account_id);
+CREATE UNIQUE INDEX idx_account_reconciliations_open ON account_reconciliations(account_id) WHERE status = 'in_progress'; -- One open reconciliation per account
 
 -- Transaction queries (most critical for performance)
 CREATE INDEX idx_transactions_user_id ON transactions(user_id);
@@ -305,9 +442,22 @@ CREATE INDEX idx_transactions_account_id ON transactions(account_id);
 CREATE INDEX idx_transactions_category_id ON transactions(category_id);
 CREATE INDEX idx_transactions_date ON transactions(transaction_date);
 CREATE INDEX idx_transactions_user_date ON transactions(user_id, transaction_date);
+CREATE INDEX idx_transactions_user_keyset ON transactions(user_id, transaction_date DESC, created_at DESC, id DESC);
 CREATE INDEX idx_transactions_user_category ON transactions(user_id, category_id);
 CREATE INDEX idx_transactions_amount ON transactions(amount);
 CREATE INDEX idx_transactions_type ON transactions(transaction_type);
+CREATE INDEX idx_transactions_duplicate_of_id ON transactions(duplicate_of_id);
+CREATE INDEX idx_transactions_counterpart_id ON transactions(counterpart_id);
+CREATE INDEX idx_transactions_merchant_id ON transactions(merchant_id);
+CREATE INDEX idx_transactions_reconciliation_id ON transactions(reconciliation_id);
+CREATE INDEX idx_transaction_splits_transaction_id ON transaction_splits(transaction_id);
+CREATE INDEX idx_transaction_splits_user_category ON transaction_splits(user_id, category_id);
+CREATE INDEX idx_transaction_changes_version ON transaction_changes(transaction_id, version);
+CREATE INDEX idx_transaction_changes_user_id ON transaction_changes(user_id);
+CREATE INDEX idx_transaction_attachments_transaction_id ON transaction_attachments(transaction_id);
+CREATE INDEX idx_transaction_attachments_user_id ON transaction_attachments(user_id);
+CREATE INDEX idx_transactions_review ON transactions(user_id, transaction_date) WHERE is_duplicate AND needs_review AND deleted_at IS NULL;
+CREATE INDEX idx_transactions_pending ON transactions(account_id, transaction_date) WHERE status = 'pending' AND deleted_at IS NULL;
 
 -- Category queries
 CREATE INDEX idx_categories_user_id ON categories(user_id);
@@ -317,6 +467,10 @@ CREATE INDEX idx_categories_system ON categories(is_system_category);
 CREATE INDEX IF NOT EXISTS idx_categories_user_active ON categories (user_id, is_active);
 CREATE INDEX IF NOT EXISTS idx_categories_system_active ON categories (is_system_category, is_active);
 
+-- Merchant indexes
+CREATE INDEX idx_merchants_user_id ON merchants(user_id);
+CREATE INDEX idx_merchants_overrides_id ON merchants(overrides_id);
+
 -- Budget and goal queries
 CREATE INDEX idx_budgets_user_id ON budgets(user_id);
 CREATE INDEX idx_budgets_category_id ON budgets(category_id);
@@ -326,8 +480,11 @@ CREATE INDEX idx_financial_goals_status ON financial_goals(status);
 
 -- File upload queries
 CREATE INDEX idx_file_uploads_user_id ON file_uploads(user_id);
-CREATE INDEX idx_file_uploads_hash ON file_uploads(file_hash);
+CREATE INDEX idx_file_uploads_user_hash ON file_uploads(user_id, file_hash);
+CREATE UNIQUE INDEX idx_file_uploads_imported_hash ON file_uploads(user_id, file_hash) WHERE processing_status IN ('pending', 'processing') OR (processing_status = 'completed' AND transactions_imported > 0); -- One queued, running or imported upload per file, empty and reverted imports can be uploaded again
+CREATE INDEX idx_file_uploads_account_id ON file_uploads(account_id);
 CREATE INDEX idx_file_uploads_status ON file_uploads(processing_status);
+CREATE INDEX idx_import_profiles_user_account ON import_profiles(user_id, account_id);
 
 -- Insight queries
 CREATE INDEX idx_insights_user_id ON financial_insights(user_id);
@@ -338,10 +495,12 @@ CREATE INDEX idx_insights_dismissed ON financial_insights(is_dismissed);
 CREATE INDEX idx_recurring_user_id ON recurring_transactions(user_id);
 CREATE INDEX idx_recurring_due_date ON recurring_transactions(next_due_date);
 CREATE INDEX idx_recurring_active ON recurring_transactions(is_active);
+CREATE INDEX idx_transactions_recurring_id ON transactions(recurring_id);
 
 -- GIN indexes for array and JSONB columns
 CREATE INDEX idx_categories_keywords ON categories USING GIN(keywords);
 CREATE INDEX idx_transactions_tags ON transactions USING GIN(tags);
+CREATE INDEX idx_transactions_search ON transactions USING GIN(search_vector);
 CREATE INDEX idx_insights_data ON financial_insights USING GIN(insight_data);
 
 CREATE UNIQUE INDEX idx_transactions_user_fit_id_active ON transactions (user_id, fit_id) 
@@ -366,6 +525,9 @@ CREATE TRIGGER update_accounts_updated_at BEFORE UPDATE ON accounts
 CREATE TRIGGER update_categories_updated_at BEFORE UPDATE ON categories
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_merchants_updated_at BEFORE UPDATE ON merchants
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 CREATE TRIGGER update_transactions_updated_at BEFORE UPDATE ON transactions
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -378,6 +540,34 @@ CREATE TRIGGER update_financial_goals_updated_at BEFORE UPDATE ON financial_goal
 CREATE TRIGGER update_recurring_transactions_updated_at BEFORE UPDATE ON recurring_transactions
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+-- Full-text search document for transactions. The 'simple' configuration
+-- keeps merchant names and references unstemmed so prefix search stays predictable.
+CREATE OR REPLACE FUNCTION transaction_search_document(
+    description TEXT, merchant_name TEXT, user_description TEXT,
+    tags TEXT[], memo TEXT, user_notes TEXT
+) RETURNS TSVECTOR AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(description, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(merchant_name, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(user_description, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(array_to_string(tags, ' '), '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(memo, '')), 'C') ||
+        setweight(to_tsvector('simple', coalesce(user_notes, '')), 'C')
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION update_transaction_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector = transaction_search_document(
+        NEW.description, NEW.merchant_name, NEW.user_description,
+        NEW.tags, NEW.memo, NEW.user_notes);
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER update_transactions_search_vector
+    BEFORE INSERT OR UPDATE OF description, merchant_name, user_description, tags, memo, user_notes ON transactions
+    FOR EACH ROW EXECUTE FUNCTION update_transaction_search_vector();
+
 -- Create default system categories
 
 DELETE FROM categories; -- Clear existing categories